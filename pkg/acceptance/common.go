@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 
-	"github.com/elixirhealth/service-base/pkg/util"
+	api "github.com/elixirhealth/key/pkg/keyapi"
 )
 
 // CreateTestEntityKeys creates a new entity (from index i) and some random author and reader
@@ -13,8 +13,8 @@ func CreateTestEntityKeys(rng *rand.Rand, i, nKeyTypeKeys uint) (string, [][]byt
 	authorKeys := make([][]byte, nKeyTypeKeys)
 	readerKeys := make([][]byte, nKeyTypeKeys)
 	for i := range authorKeys {
-		authorKeys[i] = util.RandBytes(rng, 33)
-		readerKeys[i] = util.RandBytes(rng, 33)
+		authorKeys[i] = api.NewTestPublicKey(rng)
+		readerKeys[i] = api.NewTestPublicKey(rng)
 	}
 	return GetTestEntityID(i), authorKeys, readerKeys
 }
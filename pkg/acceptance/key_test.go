@@ -4,6 +4,7 @@ package acceptance
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/hex"
 	"math/rand"
 	"net"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/logging"
+	"github.com/elixirhealth/key/pkg/client"
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"github.com/elixirhealth/key/pkg/server"
 	"github.com/elixirhealth/key/pkg/server/storage"
@@ -40,6 +42,7 @@ type state struct {
 	rng              *rand.Rand
 	dbURL            string
 	tearDownPostgres func() error
+	signingKey       ed25519.PrivateKey
 
 	entityAuthorKeys  map[string][][]byte
 	entityReaderKeys  map[string][][]byte
@@ -72,6 +75,16 @@ func TestAcceptance(t *testing.T) {
 
 	testSample(t, params, st)
 
+	testRevoke(t, params, st)
+
+	testRotate(t, params, st)
+
+	testList(t, params, st)
+
+	testVerifyAuditLog(t, params, st)
+
+	testTransparencyLog(t, params, st)
+
 	tearDown(t, st)
 }
 
@@ -89,6 +102,7 @@ func testAdd(t *testing.T, params *parameters, st *state) {
 			EntityId:   entityID,
 			KeyType:    api.KeyType_AUTHOR,
 			PublicKeys: authorKeys,
+			KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
 		_, err := st.randClient().AddPublicKeys(ctx, rq)
@@ -99,6 +113,7 @@ func testAdd(t *testing.T, params *parameters, st *state) {
 			EntityId:   entityID,
 			KeyType:    api.KeyType_READER,
 			PublicKeys: readerKeys,
+			KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
 		}
 		ctx, cancel = context.WithTimeout(context.Background(), params.timeout)
 		_, err = st.randClient().AddPublicKeys(ctx, rq)
@@ -172,6 +187,155 @@ func testSample(t *testing.T, params *parameters, st *state) {
 	}
 }
 
+func testRevoke(t *testing.T, params *parameters, st *state) {
+	for c := uint(0); c < params.nEntities; c++ {
+		entityID := GetTestEntityID(c)
+		revokedKey := st.entityAuthorKeys[entityID][0]
+		rq := &api.RevokePublicKeysRequest{
+			EntityId:   entityID,
+			PublicKeys: [][]byte{revokedKey},
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+		_, err := st.randClient().RevokePublicKeys(ctx, rq)
+		cancel()
+		assert.Nil(t, err)
+		st.entityAuthorKeys[entityID] = st.entityAuthorKeys[entityID][1:]
+
+		// revoked key should no longer be among entity's keys
+		getRq := &api.GetPublicKeysRequest{
+			EntityId: entityID,
+			KeyType:  api.KeyType_AUTHOR,
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), params.timeout)
+		getRp, err := st.randClient().GetPublicKeys(ctx, getRq)
+		cancel()
+		assert.Nil(t, err)
+		assert.Equal(t, getPKSet(st.entityAuthorKeys[entityID]), getPKSet(getRp.PublicKeys))
+
+		// but its details should still be available
+		detailsRq := &api.GetPublicKeyDetailsRequest{
+			PublicKeys: [][]byte{revokedKey},
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), params.timeout)
+		detailsRp, err := st.randClient().GetPublicKeyDetails(ctx, detailsRq)
+		cancel()
+		assert.Nil(t, err)
+		assert.Equal(t, entityID, detailsRp.PublicKeyDetails[0].EntityId)
+		assert.True(t, detailsRp.PublicKeyDetails[0].Disabled)
+	}
+}
+
+func testRotate(t *testing.T, params *parameters, st *state) {
+	for c := uint(0); c < params.nEntities; c++ {
+		entityID := GetTestEntityID(c)
+		oldKey := st.entityReaderKeys[entityID][0]
+		newKey := api.NewTestPublicKey(st.rng)
+		rq := &api.RotatePublicKeysRequest{
+			EntityId:      entityID,
+			KeyType:       api.KeyType_READER,
+			OldPublicKeys: [][]byte{oldKey},
+			NewPublicKeys: [][]byte{newKey},
+			NewKeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+		_, err := st.randClient().RotatePublicKeys(ctx, rq)
+		cancel()
+		assert.Nil(t, err)
+		readerKeys := append(st.entityReaderKeys[entityID][1:], newKey)
+		st.entityReaderKeys[entityID] = readerKeys
+		st.readerKeyEntities[hex.EncodeToString(newKey)] = entityID
+
+		getRq := &api.GetPublicKeysRequest{
+			EntityId: entityID,
+			KeyType:  api.KeyType_READER,
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), params.timeout)
+		getRp, err := st.randClient().GetPublicKeys(ctx, getRq)
+		cancel()
+		assert.Nil(t, err)
+		assert.Equal(t, getPKSet(readerKeys), getPKSet(getRp.PublicKeys))
+	}
+}
+
+func testList(t *testing.T, params *parameters, st *state) {
+	for c := uint(0); c < params.nEntities; c++ {
+		entityID := GetTestEntityID(c)
+		rq := &api.ListPublicKeysRequest{
+			EntityId: entityID,
+			KeyTypes: []api.KeyType{api.KeyType_AUTHOR},
+			Status:   api.KeyStatus_ACTIVE,
+			PageSize: 10,
+		}
+		listedKeys := make([][]byte, 0, len(st.entityAuthorKeys[entityID]))
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+			rp, err := st.randClient().ListPublicKeys(ctx, rq)
+			cancel()
+			assert.Nil(t, err)
+			for _, pkd := range rp.PublicKeyDetails {
+				listedKeys = append(listedKeys, pkd.PublicKey)
+			}
+			if rp.NextPageToken == "" {
+				break
+			}
+			rq.PageToken = rp.NextPageToken
+		}
+		assert.Equal(t, getPKSet(st.entityAuthorKeys[entityID]), getPKSet(listedKeys))
+	}
+}
+
+func testVerifyAuditLog(t *testing.T, params *parameters, st *state) {
+	ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+	rp1, err := st.randClient().VerifyAuditLog(ctx, &api.VerifyAuditLogRequest{})
+	cancel()
+	assert.Nil(t, err)
+	assert.True(t, rp1.Valid)
+	assert.NotZero(t, rp1.NRecords)
+
+	// the head is a checkpoint for verifying through another instance
+	rq := &api.VerifyAuditLogRequest{
+		CheckpointSequence: rp1.HeadSequence,
+		CheckpointHash:     rp1.HeadHash,
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), params.timeout)
+	rp2, err := st.randClient().VerifyAuditLog(ctx, rq)
+	cancel()
+	assert.Nil(t, err)
+	assert.Equal(t, rp1, rp2)
+}
+
+func testTransparencyLog(t *testing.T, params *parameters, st *state) {
+	signingKey := st.signingKey.Public().(ed25519.PublicKey)
+	ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+	th, err := client.CheckConsistency(ctx, st.randClient(), signingKey, nil)
+	cancel()
+	assert.Nil(t, err)
+	assert.NotZero(t, th.TreeSize)
+
+	// the verifying clients check the inclusion proofs
+	for c := uint(0); c < params.nEntities; c++ {
+		entityID := GetTestEntityID(c)
+		authorKeys, readerKeys := st.entityAuthorKeys[entityID], st.entityReaderKeys[entityID]
+		rq := &api.GetPublicKeyDetailsRequest{
+			PublicKeys:      [][]byte{authorKeys[0], readerKeys[len(readerKeys)-1]},
+			InclusionProofs: true,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+		rp, err := st.randClient().GetPublicKeyDetails(ctx, rq)
+		cancel()
+		assert.Nil(t, err)
+		assert.Len(t, rp.InclusionProofs, 2)
+	}
+
+	// every instance has a log consistent with the first tree head
+	for _, kc := range st.keyClients {
+		ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+		_, err := client.CheckConsistency(ctx, kc, signingKey, th)
+		cancel()
+		assert.Nil(t, err)
+	}
+}
+
 func setUp(t *testing.T, params *parameters) *state {
 	rng := rand.New(rand.NewSource(0))
 	dbURL, cleanup, err := bstorage.StartTestPostgres()
@@ -179,10 +343,14 @@ func setUp(t *testing.T, params *parameters) *state {
 		t.Fatal(err)
 	}
 
+	_, signingKey, err := ed25519.GenerateKey(rng)
+	errors.MaybePanic(err)
+
 	st := &state{
 		rng:              rng,
 		dbURL:            dbURL,
 		tearDownPostgres: cleanup,
+		signingKey:       signingKey,
 
 		entityAuthorKeys:  make(map[string][][]byte),
 		entityReaderKeys:  make(map[string][][]byte),
@@ -208,10 +376,17 @@ func createAndStartKeys(params *parameters, st *state) {
 		// wait for server to come up
 		keys[i] = <-up
 
-		// set up client to it
+		// set up client to it, verifying its signed responses
 		conn, err := grpc.Dial(addrs[i].String(), grpc.WithInsecure())
 		errors.MaybePanic(err)
-		keyClients[i] = api.NewKeyClient(conn)
+		signingKey := st.signingKey.Public().(ed25519.PublicKey)
+		ctx, cancel := context.WithTimeout(context.Background(), params.timeout)
+		err = client.CheckSigningKey(ctx, api.NewKeyClient(conn), signingKey)
+		cancel()
+		errors.MaybePanic(err)
+		keyClients[i], err = client.NewVerifying(api.NewKeyClient(conn), signingKey,
+			params.timeout)
+		errors.MaybePanic(err)
 	}
 
 	st.keys = keys
@@ -225,13 +400,14 @@ func newKeyConfigs(params *parameters, st *state) ([]*server.Config, []*net.TCPA
 
 	// set eviction params to ensure that evictions actually happen during test
 	storageParams := storage.NewDefaultParameters()
-	storageParams.Type = bstorage.Postgres
+	storageParams.Type = storage.Postgres
 
 	for i := uint(0); i < params.nKeys; i++ {
 		serverPort, metricsPort := startPort+i*10, startPort+i*10+1
 		configs[i] = server.NewDefaultConfig().
 			WithStorage(storageParams).
-			WithDBUrl(st.dbURL)
+			WithDBUrl(st.dbURL).
+			WithSigningKey(st.signingKey)
 		configs[i].WithServerPort(uint(serverPort)).
 			WithMetricsPort(uint(metricsPort)).
 			WithLogLevel(params.logLevel)
@@ -1,10 +1,20 @@
 package client
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+
 	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/auth"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// ErrNoCertificates indicates when a CA file has no PEM certificates.
+var ErrNoCertificates = errors.New("no PEM certificates found")
+
 // NewInsecure returns a new KeyClient without any TLS on the connection.
 func NewInsecure(address string) (api.KeyClient, error) {
 	cc, err := grpc.Dial(address, grpc.WithInsecure())
@@ -13,3 +23,69 @@ func NewInsecure(address string) (api.KeyClient, error) {
 	}
 	return api.NewKeyClient(cc), nil
 }
+
+// NewTLS returns a new KeyClient whose connection uses TLS, verifying the server's certificate
+// with the CA certificates in the given PEM file or, if it is empty, the system's root CAs.
+func NewTLS(address string, caFile string) (api.KeyClient, error) {
+	tlsConfig, err := newTLSConfig(caFile)
+	if err != nil {
+		return nil, err
+	}
+	return newWithTLSConfig(address, tlsConfig)
+}
+
+// NewMutualTLS returns a new KeyClient whose connection uses mutual TLS, verifying the server's
+// certificate like NewTLS and presenting the client certificate and private key in the given PEM
+// files to the server.
+func NewMutualTLS(address, caFile, certFile, keyFile string) (api.KeyClient, error) {
+	tlsConfig, err := newTLSConfig(caFile)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	return newWithTLSConfig(address, tlsConfig)
+}
+
+// NewTLSWithToken returns a new KeyClient whose connection uses TLS like NewTLS and that sends the
+// given signed token with each request to authenticate as the token's entity.
+func NewTLSWithToken(address, caFile, token string) (api.KeyClient, error) {
+	tlsConfig, err := newTLSConfig(caFile)
+	if err != nil {
+		return nil, err
+	}
+	return newWithTLSConfig(address, tlsConfig,
+		grpc.WithPerRPCCredentials(auth.NewTokenCredentials(token, true)))
+}
+
+func newWithTLSConfig(
+	address string, tlsConfig *tls.Config, opts ...grpc.DialOption,
+) (api.KeyClient, error) {
+	creds := credentials.NewTLS(tlsConfig)
+	opts = append(opts, grpc.WithTransportCredentials(creds))
+	cc, err := grpc.Dial(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewKeyClient(cc), nil
+}
+
+func newTLSConfig(caFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return nil, ErrNoCertificates
+	}
+	tlsConfig.RootCAs = rootCAs
+	return tlsConfig, nil
+}
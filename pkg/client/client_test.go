@@ -0,0 +1,55 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInsecure(t *testing.T) {
+	c, err := NewInsecure("localhost:10100")
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+}
+
+func TestNewTLS(t *testing.T) {
+	c, err := NewTLS("localhost:10100", "")
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+
+	c, err = NewTLS("localhost:10100", "does-not-exist.crt")
+	assert.NotNil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestNewTLSWithToken(t *testing.T) {
+	c, err := NewTLSWithToken("localhost:10100", "", "some token")
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+
+	c, err = NewTLSWithToken("localhost:10100", "does-not-exist.crt", "some token")
+	assert.NotNil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestNewMutualTLS_err(t *testing.T) {
+	c, err := NewMutualTLS("localhost:10100", "", "does-not-exist.crt",
+		"does-not-exist.key")
+	assert.NotNil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestNewTLSConfig_noCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-client-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	caFile := filepath.Join(dir, "ca.crt")
+	assert.Nil(t, ioutil.WriteFile(caFile, []byte("not PEM"), 0600))
+
+	tlsConfig, err := newTLSConfig(caFile)
+	assert.Equal(t, ErrNoCertificates, err)
+	assert.Nil(t, tlsConfig)
+}
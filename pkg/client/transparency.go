@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"google.golang.org/grpc"
+)
+
+var (
+	// ErrTreeHeadRollback indicates when the server's tree head is smaller than one it returned
+	// earlier.
+	ErrTreeHeadRollback = errors.New("tree head smaller than earlier tree head")
+
+	// ErrInconsistentTreeHead indicates when the server's tree head has the same size as one it
+	// returned earlier but a different root hash.
+	ErrInconsistentTreeHead = errors.New("tree head root hash differs from earlier tree head")
+)
+
+// CheckConsistency gets the server's current tree head and checks that it has a valid signature
+// by the signing key and that its log extends the log of the earlier tree head, which may be nil
+// for the first check. Clients should keep the returned tree head for their next check, so a
+// server can't show them one log now and a different one later.
+func CheckConsistency(
+	ctx context.Context, c api.KeyClient, signingKey ed25519.PublicKey, earlier *api.TreeHead,
+) (*api.TreeHead, error) {
+	rp, err := c.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	if err != nil {
+		return nil, err
+	}
+	th := rp.TreeHead
+	if th == nil {
+		return nil, api.ErrUnsignedTreeHead
+	}
+	if err := api.VerifyTreeHead(signingKey, th); err != nil {
+		return nil, err
+	}
+	if earlier == nil || earlier.TreeSize == 0 {
+		return th, nil
+	}
+	if th.TreeSize < earlier.TreeSize {
+		return nil, ErrTreeHeadRollback
+	}
+	if th.TreeSize == earlier.TreeSize {
+		if !bytes.Equal(th.RootHash, earlier.RootHash) {
+			return nil, ErrInconsistentTreeHead
+		}
+		return th, nil
+	}
+	proofRp, err := c.GetConsistencyProof(ctx, &api.GetConsistencyProofRequest{
+		FirstTreeSize:  earlier.TreeSize,
+		SecondTreeSize: th.TreeSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = api.VerifyConsistencyProof(earlier.TreeSize, th.TreeSize, earlier.RootHash,
+		th.RootHash, proofRp.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	return th, nil
+}
+
+func (c *verifyingClient) GetTreeHead(
+	ctx context.Context, rq *api.GetTreeHeadRequest, opts ...grpc.CallOption,
+) (*api.GetTreeHeadResponse, error) {
+	rp, err := c.KeyClient.GetTreeHead(ctx, rq, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if rp.TreeHead == nil {
+		return nil, api.ErrUnsignedTreeHead
+	}
+	if err := api.VerifyTreeHead(c.signingKey, rp.TreeHead); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+// verifyInclusion checks that the response's tree head is signed by the server and that each of
+// its public key details is in the tree head's log.
+func (c *verifyingClient) verifyInclusion(rp *api.GetPublicKeyDetailsResponse) error {
+	if rp.TreeHead == nil {
+		return api.ErrMissingInclusionProofs
+	}
+	if err := api.VerifyTreeHead(c.signingKey, rp.TreeHead); err != nil {
+		return err
+	}
+	return api.VerifyPublicKeyDetailsInclusion(rp)
+}
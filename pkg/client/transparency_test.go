@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"math/rand"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConsistency_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	th2, th3, proof := newTestTreeHeads(rng, sk)
+
+	// first check has nothing to be consistent with
+	c := &fixedClient{treeHeadRp: &api.GetTreeHeadResponse{TreeHead: th2}}
+	th, err := CheckConsistency(ctx, c, pk, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, th2, th)
+
+	c = &fixedClient{
+		treeHeadRp: &api.GetTreeHeadResponse{TreeHead: th3},
+		proofRp:    &api.GetConsistencyProofResponse{Hashes: proof},
+	}
+	th, err = CheckConsistency(ctx, c, pk, th2)
+	assert.Nil(t, err)
+	assert.Equal(t, th3, th)
+
+	// same size doesn't need a consistency proof
+	c = &fixedClient{
+		treeHeadRp: &api.GetTreeHeadResponse{TreeHead: th3},
+		proofErr:   errTest,
+	}
+	th, err = CheckConsistency(ctx, c, pk, th3)
+	assert.Nil(t, err)
+	assert.Equal(t, th3, th)
+}
+
+func TestCheckConsistency_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	th2, th3, proof := newTestTreeHeads(rng, sk)
+	otherTH3 := &api.TreeHead{TreeSize: 3, RootHash: th2.RootHash, Time: th3.Time}
+	api.SignTreeHead(sk, otherTH3)
+	unsignedTH3 := &api.TreeHead{TreeSize: 3, RootHash: th3.RootHash, Time: th3.Time}
+
+	cases := map[string]struct {
+		c        *fixedClient
+		earlier  *api.TreeHead
+		expected error
+	}{
+		"rpc error": {
+			c:        &fixedClient{err: errTest},
+			earlier:  th2,
+			expected: errTest,
+		},
+		"missing tree head": {
+			c:        &fixedClient{treeHeadRp: &api.GetTreeHeadResponse{}},
+			earlier:  th2,
+			expected: api.ErrUnsignedTreeHead,
+		},
+		"unsigned tree head": {
+			c:        &fixedClient{treeHeadRp: &api.GetTreeHeadResponse{TreeHead: unsignedTH3}},
+			earlier:  th2,
+			expected: api.ErrUnsignedTreeHead,
+		},
+		"rollback": {
+			c:        &fixedClient{treeHeadRp: &api.GetTreeHeadResponse{TreeHead: th2}},
+			earlier:  th3,
+			expected: ErrTreeHeadRollback,
+		},
+		"inconsistent same size": {
+			c:        &fixedClient{treeHeadRp: &api.GetTreeHeadResponse{TreeHead: otherTH3}},
+			earlier:  th3,
+			expected: ErrInconsistentTreeHead,
+		},
+		"consistency proof rpc error": {
+			c: &fixedClient{
+				treeHeadRp: &api.GetTreeHeadResponse{TreeHead: th3},
+				proofErr:   errTest,
+			},
+			earlier:  th2,
+			expected: errTest,
+		},
+		"invalid consistency proof": {
+			c: &fixedClient{
+				treeHeadRp: &api.GetTreeHeadResponse{TreeHead: otherTH3},
+				proofRp:    &api.GetConsistencyProofResponse{Hashes: proof},
+			},
+			earlier:  th2,
+			expected: api.ErrInvalidConsistencyProof,
+		},
+	}
+	for desc, c := range cases {
+		th, err := CheckConsistency(ctx, c.c, pk, c.earlier)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, th, desc)
+	}
+}
+
+func TestVerifyingClient_GetTreeHead(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	th2, _, _ := newTestTreeHeads(rng, sk)
+
+	vc, err := NewVerifying(&fixedClient{
+		treeHeadRp: &api.GetTreeHeadResponse{TreeHead: th2},
+	}, pk, time.Minute)
+	assert.Nil(t, err)
+	rp, err := vc.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, th2, rp.TreeHead)
+
+	unsigned := &api.TreeHead{TreeSize: th2.TreeSize, RootHash: th2.RootHash}
+	vc, err = NewVerifying(&fixedClient{
+		treeHeadRp: &api.GetTreeHeadResponse{TreeHead: unsigned},
+	}, pk, time.Minute)
+	assert.Nil(t, err)
+	rp, err = vc.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Equal(t, api.ErrUnsignedTreeHead, err)
+	assert.Nil(t, rp)
+}
+
+func TestVerifyingClient_GetPublicKeyDetails_inclusionProofs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	now := time.Now().UnixNano() / int64(time.Microsecond)
+	pkds := api.NewTestPublicKeyDetails(rng, 2)
+	a, b := api.PublicKeyDetailLeafHash(pkds[0]), api.PublicKeyDetailLeafHash(pkds[1])
+	th := &api.TreeHead{TreeSize: 2, RootHash: api.LogNodeHash(a, b), Time: now}
+	api.SignTreeHead(sk, th)
+	unsignedTH := &api.TreeHead{TreeSize: th.TreeSize, RootHash: th.RootHash, Time: now}
+	rq := &api.GetPublicKeyDetailsRequest{
+		PublicKeys:      [][]byte{pkds[1].PublicKey},
+		InclusionProofs: true,
+	}
+	newRp := func(th *api.TreeHead, proof *api.InclusionProof) *api.GetPublicKeyDetailsResponse {
+		rp := &api.GetPublicKeyDetailsResponse{
+			PublicKeyDetails: pkds[1:],
+			TreeHead:         th,
+			InclusionProofs:  []*api.InclusionProof{proof},
+		}
+		api.SignGetPublicKeyDetailsResponse(sk, rq, rp, now)
+		return rp
+	}
+	okProof := &api.InclusionProof{Found: true, LeafIndex: 1, Hashes: [][]byte{a}}
+
+	okRp := newRp(th, okProof)
+	vc, err := NewVerifying(&fixedClient{getPKDsRp: okRp}, pk, time.Minute)
+	assert.Nil(t, err)
+	rp, err := vc.GetPublicKeyDetails(ctx, rq)
+	assert.Nil(t, err)
+	assert.Equal(t, okRp, rp)
+
+	cases := map[string]struct {
+		rp       *api.GetPublicKeyDetailsResponse
+		expected error
+	}{
+		"missing tree head": {
+			rp:       newRp(nil, okProof),
+			expected: api.ErrMissingInclusionProofs,
+		},
+		"unsigned tree head": {
+			rp:       newRp(unsignedTH, okProof),
+			expected: api.ErrUnsignedTreeHead,
+		},
+		"invalid inclusion proof": {
+			rp: newRp(th, &api.InclusionProof{
+				Found: true, LeafIndex: 0, Hashes: [][]byte{a},
+			}),
+			expected: api.ErrInvalidInclusionProof,
+		},
+	}
+	for desc, c := range cases {
+		vc, err := NewVerifying(&fixedClient{getPKDsRp: c.rp}, pk, time.Minute)
+		assert.Nil(t, err)
+		rp, err := vc.GetPublicKeyDetails(ctx, rq)
+		assert.Equal(t, c.expected, errors.Cause(err), desc)
+		assert.Nil(t, rp, desc)
+	}
+}
+
+// newTestTreeHeads returns signed tree heads of a log of 2 and then 3 leaves and the consistency
+// proof between them.
+func newTestTreeHeads(
+	rng *rand.Rand, sk ed25519.PrivateKey,
+) (*api.TreeHead, *api.TreeHead, [][]byte) {
+	pkds := api.NewTestPublicKeyDetails(rng, 3)
+	a, b := api.PublicKeyDetailLeafHash(pkds[0]), api.PublicKeyDetailLeafHash(pkds[1])
+	c := api.PublicKeyDetailLeafHash(pkds[2])
+	ab := api.LogNodeHash(a, b)
+	now := time.Now().UnixNano() / int64(time.Microsecond)
+	th2 := &api.TreeHead{TreeSize: 2, RootHash: ab, Time: now}
+	th3 := &api.TreeHead{TreeSize: 3, RootHash: api.LogNodeHash(ab, c), Time: now}
+	api.SignTreeHead(sk, th2)
+	api.SignTreeHead(sk, th3)
+	return th2, th3, [][]byte{c}
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"google.golang.org/grpc"
+)
+
+var (
+	// ErrInvalidSigningKey indicates when a signing key isn't an Ed25519 public key.
+	ErrInvalidSigningKey = errors.New("signing key is not an Ed25519 public key")
+
+	// ErrUnexpectedSigningKey indicates when the server's signing key isn't the expected one.
+	ErrUnexpectedSigningKey = errors.New("server signing key doesn't match expected key")
+
+	// ErrStaleResponse indicates when a response was signed longer ago than the maximum age.
+	ErrStaleResponse = errors.New("response signature older than maximum age")
+)
+
+// verifyingClient is a KeyClient that checks the server's signatures of public key lookup
+// responses.
+type verifyingClient struct {
+	api.KeyClient
+	signingKey ed25519.PublicKey
+	maxAge     time.Duration
+}
+
+// NewVerifying returns a KeyClient that checks that each GetPublicKeys, GetPublicKeyDetails, and
+// SamplePublicKeys response has a valid signature by the server's signing key, failing the call
+// otherwise. When maxAge is positive, it also fails calls whose responses were signed longer ago
+// than it, so old responses can't be replayed after the public keys change. It also checks the
+// signatures of tree heads and, when requested, the inclusion proofs of public key details. The
+// signing key should come from the server's operator, since a GetSigningKey response isn't itself
+// signed.
+func NewVerifying(
+	c api.KeyClient, signingKey ed25519.PublicKey, maxAge time.Duration,
+) (api.KeyClient, error) {
+	if len(signingKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidSigningKey
+	}
+	return &verifyingClient{
+		KeyClient:  c,
+		signingKey: signingKey,
+		maxAge:     maxAge,
+	}, nil
+}
+
+// CheckSigningKey checks that the server's signing key is the expected one.
+func CheckSigningKey(ctx context.Context, c api.KeyClient, expected ed25519.PublicKey) error {
+	rp, err := c.GetSigningKey(ctx, &api.GetSigningKeyRequest{})
+	if err != nil {
+		return err
+	}
+	if rp.KeyFormat != api.KeyFormat_ED25519 || !bytes.Equal(rp.SigningKey, expected) {
+		return ErrUnexpectedSigningKey
+	}
+	return nil
+}
+
+func (c *verifyingClient) GetPublicKeys(
+	ctx context.Context, rq *api.GetPublicKeysRequest, opts ...grpc.CallOption,
+) (*api.GetPublicKeysResponse, error) {
+	rp, err := c.KeyClient.GetPublicKeys(ctx, rq, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.VerifyGetPublicKeysResponse(c.signingKey, rq, rp); err != nil {
+		return nil, err
+	}
+	if err := c.checkAge(rp.Signature); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+func (c *verifyingClient) GetPublicKeyDetails(
+	ctx context.Context, rq *api.GetPublicKeyDetailsRequest, opts ...grpc.CallOption,
+) (*api.GetPublicKeyDetailsResponse, error) {
+	rp, err := c.KeyClient.GetPublicKeyDetails(ctx, rq, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.VerifyGetPublicKeyDetailsResponse(c.signingKey, rq, rp); err != nil {
+		return nil, err
+	}
+	if err := c.checkAge(rp.Signature); err != nil {
+		return nil, err
+	}
+	if rq.InclusionProofs {
+		if err := c.verifyInclusion(rp); err != nil {
+			return nil, err
+		}
+	}
+	return rp, nil
+}
+
+func (c *verifyingClient) SamplePublicKeys(
+	ctx context.Context, rq *api.SamplePublicKeysRequest, opts ...grpc.CallOption,
+) (*api.SamplePublicKeysResponse, error) {
+	rp, err := c.KeyClient.SamplePublicKeys(ctx, rq, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := api.VerifySamplePublicKeysResponse(c.signingKey, rq, rp); err != nil {
+		return nil, err
+	}
+	if err := c.checkAge(rp.Signature); err != nil {
+		return nil, err
+	}
+	return rp, nil
+}
+
+func (c *verifyingClient) checkAge(sig *api.ResponseSignature) error {
+	if c.maxAge <= 0 {
+		return nil
+	}
+	signed := time.Unix(0, sig.Time*int64(time.Microsecond))
+	if time.Since(signed) > c.maxAge {
+		return ErrStaleResponse
+	}
+	return nil
+}
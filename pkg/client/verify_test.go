@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+var errTest = errors.New("some test error")
+
+func TestNewVerifying(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pk, _, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+
+	c, err := NewVerifying(&fixedClient{}, pk, time.Minute)
+	assert.Nil(t, err)
+	assert.NotNil(t, c)
+
+	c, err = NewVerifying(&fixedClient{}, pk[:16], time.Minute)
+	assert.Equal(t, ErrInvalidSigningKey, err)
+	assert.Nil(t, c)
+}
+
+func TestCheckSigningKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pk, _, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	otherPK, _, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+
+	cases := map[string]struct {
+		c        *fixedClient
+		expected error
+	}{
+		"ok": {
+			c: &fixedClient{signingKeyRp: &api.GetSigningKeyResponse{
+				SigningKey: pk,
+				KeyFormat:  api.KeyFormat_ED25519,
+			}},
+		},
+		"other key": {
+			c: &fixedClient{signingKeyRp: &api.GetSigningKeyResponse{
+				SigningKey: otherPK,
+				KeyFormat:  api.KeyFormat_ED25519,
+			}},
+			expected: ErrUnexpectedSigningKey,
+		},
+		"other format": {
+			c: &fixedClient{signingKeyRp: &api.GetSigningKeyResponse{
+				SigningKey: pk,
+				KeyFormat:  api.KeyFormat_X25519,
+			}},
+			expected: ErrUnexpectedSigningKey,
+		},
+		"rpc error": {
+			c:        &fixedClient{err: errTest},
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		err := CheckSigningKey(context.Background(), c.c, pk)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestVerifyingClient_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	now := time.Now().UnixNano() / int64(time.Microsecond)
+	pkds := api.NewTestPublicKeyDetails(rng, 2)
+
+	rq1 := &api.GetPublicKeysRequest{EntityId: pkds[0].EntityId}
+	rp1 := &api.GetPublicKeysResponse{PublicKeys: [][]byte{pkds[0].PublicKey}}
+	api.SignGetPublicKeysResponse(sk, rq1, rp1, now)
+	rq2 := &api.GetPublicKeyDetailsRequest{PublicKeys: [][]byte{pkds[0].PublicKey}}
+	rp2 := &api.GetPublicKeyDetailsResponse{PublicKeyDetails: pkds[:1]}
+	api.SignGetPublicKeyDetailsResponse(sk, rq2, rp2, now)
+	rq3 := &api.SamplePublicKeysRequest{OfEntityId: pkds[0].EntityId, NPublicKeys: 2}
+	rp3 := &api.SamplePublicKeysResponse{PublicKeyDetails: pkds}
+	api.SignSamplePublicKeysResponse(sk, rq3, rp3, now)
+
+	c, err := NewVerifying(&fixedClient{
+		getPKsRp:     rp1,
+		getPKDsRp:    rp2,
+		samplePKDsRp: rp3,
+	}, pk, time.Minute)
+	assert.Nil(t, err)
+
+	gotRp1, err := c.GetPublicKeys(ctx, rq1)
+	assert.Nil(t, err)
+	assert.Equal(t, rp1, gotRp1)
+	gotRp2, err := c.GetPublicKeyDetails(ctx, rq2)
+	assert.Nil(t, err)
+	assert.Equal(t, rp2, gotRp2)
+	gotRp3, err := c.SamplePublicKeys(ctx, rq3)
+	assert.Nil(t, err)
+	assert.Equal(t, rp3, gotRp3)
+}
+
+func TestVerifyingClient_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	now := time.Now().UnixNano() / int64(time.Microsecond)
+	old := time.Now().Add(-time.Hour).UnixNano() / int64(time.Microsecond)
+	rq := &api.GetPublicKeysRequest{EntityId: "some entity ID"}
+	otherRq := &api.GetPublicKeysRequest{EntityId: "other entity ID"}
+	rp := &api.GetPublicKeysResponse{PublicKeys: [][]byte{api.NewTestPublicKey(rng)}}
+	api.SignGetPublicKeysResponse(sk, rq, rp, now)
+	oldRp := &api.GetPublicKeysResponse{PublicKeys: rp.PublicKeys}
+	api.SignGetPublicKeysResponse(sk, rq, oldRp, old)
+
+	cases := map[string]struct {
+		c        *fixedClient
+		rq       *api.GetPublicKeysRequest
+		expected error
+	}{
+		"rpc error": {
+			c:        &fixedClient{err: errTest},
+			rq:       rq,
+			expected: errTest,
+		},
+		"unsigned": {
+			c:        &fixedClient{getPKsRp: &api.GetPublicKeysResponse{}},
+			rq:       rq,
+			expected: api.ErrMissingResponseSignature,
+		},
+		"other request": {
+			c:        &fixedClient{getPKsRp: rp},
+			rq:       otherRq,
+			expected: api.ErrInvalidResponseSignature,
+		},
+		"stale": {
+			c:        &fixedClient{getPKsRp: oldRp},
+			rq:       rq,
+			expected: ErrStaleResponse,
+		},
+	}
+	for desc, c := range cases {
+		vc, err := NewVerifying(c.c, pk, time.Minute)
+		assert.Nil(t, err)
+		rp, err := vc.GetPublicKeys(ctx, c.rq)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, rp, desc)
+	}
+
+	// old responses are fine without a max age
+	vc, err := NewVerifying(&fixedClient{getPKsRp: oldRp}, pk, 0)
+	assert.Nil(t, err)
+	gotRp, err := vc.GetPublicKeys(ctx, rq)
+	assert.Nil(t, err)
+	assert.Equal(t, oldRp, gotRp)
+
+	// the other lookups are verified too
+	vc, err = NewVerifying(&fixedClient{
+		getPKDsRp:    &api.GetPublicKeyDetailsResponse{},
+		samplePKDsRp: &api.SamplePublicKeysResponse{},
+	}, pk, time.Minute)
+	assert.Nil(t, err)
+	rp2, err := vc.GetPublicKeyDetails(ctx, &api.GetPublicKeyDetailsRequest{})
+	assert.Equal(t, api.ErrMissingResponseSignature, err)
+	assert.Nil(t, rp2)
+	rp3, err := vc.SamplePublicKeys(ctx, &api.SamplePublicKeysRequest{})
+	assert.Equal(t, api.ErrMissingResponseSignature, err)
+	assert.Nil(t, rp3)
+}
+
+type fixedClient struct {
+	api.KeyClient
+	getPKsRp     *api.GetPublicKeysResponse
+	getPKDsRp    *api.GetPublicKeyDetailsResponse
+	samplePKDsRp *api.SamplePublicKeysResponse
+	signingKeyRp *api.GetSigningKeyResponse
+	treeHeadRp   *api.GetTreeHeadResponse
+	proofRp      *api.GetConsistencyProofResponse
+	err          error
+	proofErr     error
+}
+
+func (f *fixedClient) GetPublicKeys(
+	ctx context.Context, rq *api.GetPublicKeysRequest, opts ...grpc.CallOption,
+) (*api.GetPublicKeysResponse, error) {
+	return f.getPKsRp, f.err
+}
+
+func (f *fixedClient) GetPublicKeyDetails(
+	ctx context.Context, rq *api.GetPublicKeyDetailsRequest, opts ...grpc.CallOption,
+) (*api.GetPublicKeyDetailsResponse, error) {
+	return f.getPKDsRp, f.err
+}
+
+func (f *fixedClient) SamplePublicKeys(
+	ctx context.Context, rq *api.SamplePublicKeysRequest, opts ...grpc.CallOption,
+) (*api.SamplePublicKeysResponse, error) {
+	return f.samplePKDsRp, f.err
+}
+
+func (f *fixedClient) GetSigningKey(
+	ctx context.Context, rq *api.GetSigningKeyRequest, opts ...grpc.CallOption,
+) (*api.GetSigningKeyResponse, error) {
+	return f.signingKeyRp, f.err
+}
+
+func (f *fixedClient) GetTreeHead(
+	ctx context.Context, rq *api.GetTreeHeadRequest, opts ...grpc.CallOption,
+) (*api.GetTreeHeadResponse, error) {
+	return f.treeHeadRp, f.err
+}
+
+func (f *fixedClient) GetConsistencyProof(
+	ctx context.Context, rq *api.GetConsistencyProofRequest, opts ...grpc.CallOption,
+) (*api.GetConsistencyProofResponse, error) {
+	return f.proofRp, f.proofErr
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/drausin/libri/libri/common/logging"
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	checkpointSequenceFlag = "checkpointSequence"
+	checkpointHashFlag     = "checkpointHash"
+
+	logHeadSequence    = "head_sequence"
+	logHeadHash        = "head_hash"
+	logInvalidSequence = "invalid_sequence"
+	logInvalidReason   = "invalid_reason"
+)
+
+var (
+	errAuditLogInvalid = errors.New("audit log invalid")
+
+	verifyAuditLogCmd = &cobra.Command{
+		Use:     "verify-audit-log",
+		Short:   "verify that the audit log of public key mutations has no gaps or edits",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyAuditLog()
+		},
+	}
+)
+
+func init() {
+	verifyAuditLogCmd.Flags().String(addressFlag, "", "address of the Key server to verify")
+	verifyAuditLogCmd.Flags().String(tlsCAFlag, "",
+		"PEM file of the CA certificates for verifying the server's TLS certificate, if it "+
+			"uses TLS")
+	addClientAuthFlags(verifyAuditLogCmd)
+	verifyAuditLogCmd.Flags().Uint64(checkpointSequenceFlag, 0,
+		"sequence number of the head audit record from an earlier verification, which the "+
+			"audit log must still contain")
+	verifyAuditLogCmd.Flags().String(checkpointHashFlag, "",
+		"hex hash of the head audit record from an earlier verification")
+
+	rootCmd.AddCommand(verifyAuditLogCmd)
+}
+
+func verifyAuditLog() error {
+	logger := logging.NewDevLogger(logging.GetLogLevel(viper.GetString(logLevelFlag)))
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	rq, err := getVerifyAuditLogRequest()
+	if err != nil {
+		return err
+	}
+	rp, err := checkAuditLog(c, rq)
+	if err == errAuditLogInvalid {
+		logger.Error("audit log invalid",
+			zap.Uint64(logNRecords, rp.NRecords),
+			zap.Uint64(logInvalidSequence, rp.InvalidSequence),
+			zap.String(logInvalidReason, rp.InvalidReason),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("verified audit log",
+		zap.Uint64(logNRecords, rp.NRecords),
+		zap.Uint64(logHeadSequence, rp.HeadSequence),
+		zap.String(logHeadHash, hex.EncodeToString(rp.HeadHash)),
+	)
+	return nil
+}
+
+func getVerifyAuditLogRequest() (*api.VerifyAuditLogRequest, error) {
+	hash, err := hex.DecodeString(viper.GetString(checkpointHashFlag))
+	if err != nil {
+		return nil, err
+	}
+	rq := &api.VerifyAuditLogRequest{
+		CheckpointSequence: uint64(viper.GetInt64(checkpointSequenceFlag)),
+		CheckpointHash:     hash,
+	}
+	if err := api.ValidateVerifyAuditLogRequest(rq); err != nil {
+		return nil, err
+	}
+	return rq, nil
+}
+
+// checkAuditLog asks the Key server to verify its audit log, returning errAuditLogInvalid along
+// with the response if it isn't valid.
+func checkAuditLog(
+	c api.KeyClient, rq *api.VerifyAuditLogRequest,
+) (*api.VerifyAuditLogResponse, error) {
+	rp, err := c.VerifyAuditLog(context.Background(), rq)
+	if err != nil {
+		return nil, err
+	}
+	if !rp.Valid {
+		return rp, errAuditLogInvalid
+	}
+	return rp, nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestGetVerifyAuditLogRequest(t *testing.T) {
+	viper.Set(checkpointSequenceFlag, uint64(3))
+	viper.Set(checkpointHashFlag, "0a0b0c")
+	rq, err := getVerifyAuditLogRequest()
+	assert.Nil(t, err)
+	assert.Equal(t, &api.VerifyAuditLogRequest{
+		CheckpointSequence: 3,
+		CheckpointHash:     []byte{10, 11, 12},
+	}, rq)
+
+	// bad hex
+	viper.Set(checkpointHashFlag, "not hex")
+	rq, err = getVerifyAuditLogRequest()
+	assert.NotNil(t, err)
+	assert.Nil(t, rq)
+
+	// checkpoint without hash
+	viper.Set(checkpointHashFlag, "")
+	rq, err = getVerifyAuditLogRequest()
+	assert.Equal(t, api.ErrIncompleteCheckpoint, err)
+	assert.Nil(t, rq)
+
+	viper.Set(checkpointSequenceFlag, uint64(0))
+}
+
+func TestCheckAuditLog(t *testing.T) {
+	valid := &api.VerifyAuditLogResponse{Valid: true, NRecords: 2, HeadSequence: 2}
+	invalid := &api.VerifyAuditLogResponse{NRecords: 1, InvalidSequence: 2}
+	cases := map[string]struct {
+		c           *fixedAuditClient
+		expectedRp  *api.VerifyAuditLogResponse
+		expectedErr error
+	}{
+		"valid": {
+			c:          &fixedAuditClient{rp: valid},
+			expectedRp: valid,
+		},
+		"invalid": {
+			c:           &fixedAuditClient{rp: invalid},
+			expectedRp:  invalid,
+			expectedErr: errAuditLogInvalid,
+		},
+		"rpc error": {
+			c:           &fixedAuditClient{err: errTest},
+			expectedErr: errTest,
+		},
+	}
+	for desc, c := range cases {
+		rp, err := checkAuditLog(c.c, &api.VerifyAuditLogRequest{})
+		assert.Equal(t, c.expectedErr, err, desc)
+		assert.Equal(t, c.expectedRp, rp, desc)
+	}
+}
+
+type fixedAuditClient struct {
+	api.KeyClient
+	rp  *api.VerifyAuditLogResponse
+	err error
+}
+
+func (f *fixedAuditClient) VerifyAuditLog(
+	ctx context.Context, rq *api.VerifyAuditLogRequest, opts ...grpc.CallOption,
+) (*api.VerifyAuditLogResponse, error) {
+	return f.rp, f.err
+}
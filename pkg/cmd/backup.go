@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/drausin/libri/libri/common/logging"
+	"github.com/elixirhealth/key/pkg/client"
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	addressFlag = "address"
+	fileFlag    = "file"
+	formatFlag  = "format"
+	tlsCAFlag   = "tlsCA"
+	tokenFlag   = "authTokenFile"
+
+	logFile       = "file"
+	logNRecords   = "n_records"
+	logNPut       = "n_put"
+	logNDuplicate = "n_duplicate"
+)
+
+var (
+	exportCmd = &cobra.Command{
+		Use:     "export",
+		Short:   "export all public key details from a Key server to a file",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportKeys()
+		},
+	}
+
+	importCmd = &cobra.Command{
+		Use:     "import",
+		Short:   "import public key details from an exported file into a storage backend",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importKeys()
+		},
+	}
+)
+
+func init() {
+	exportCmd.Flags().String(addressFlag, "", "address of the Key server to export from")
+	exportCmd.Flags().String(tlsCAFlag, "",
+		"PEM file of the CA certificates for verifying the server's TLS certificate, if it "+
+			"uses TLS")
+	addClientAuthFlags(exportCmd)
+	exportCmd.Flags().String(fileFlag, "", "file to write the public key records to")
+	exportCmd.Flags().String(formatFlag, delimitedFormat,
+		"file format, either \""+delimitedFormat+"\" protobuf or \""+jsonlFormat+"\"")
+
+	importCmd.Flags().String(fileFlag, "", "file to read the public key records from")
+	importCmd.Flags().String(formatFlag, delimitedFormat,
+		"file format, either \""+delimitedFormat+"\" protobuf or \""+jsonlFormat+"\"")
+	importCmd.Flags().Bool(storageMemoryFlag, false, "import into in-memory storage")
+	importCmd.Flags().Bool(storagePostgresFlag, false, "import into Postgres DB storage")
+	importCmd.Flags().Bool(storageDataStoreFlag, false, "import into GCP DataStore storage")
+	importCmd.Flags().Bool(storageBoltFlag, false, "import into embedded Bolt DB file storage")
+	importCmd.Flags().String(dbURLFlag, "", "Postgres DB URL, including username")
+	importCmd.Flags().String(dbPasswordFlag, "", "DB user's password")
+	importCmd.Flags().String(gcpProjectIDFlag, "", "GCP project ID of the DataStore instance")
+	importCmd.Flags().String(emulatorHostFlag, "",
+		"host of a local DataStore emulator to use instead of GCP DataStore")
+	importCmd.Flags().String(boltDBPathFlag, "", "path of the Bolt DB file, created if missing")
+
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
+
+// bindFlags binds the running command's flags to viper, so they don't override the bindings of
+// other commands' flags with the same names.
+func bindFlags(cmd *cobra.Command, args []string) error {
+	return viper.BindPFlags(cmd.Flags())
+}
+
+func exportKeys() error {
+	logger := logging.NewDevLogger(logging.GetLogLevel(viper.GetString(logLevelFlag)))
+	c, err := getClient()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(viper.GetString(fileFlag))
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	rw, err := newRecordWriter(bw, viper.GetString(formatFlag))
+	if err != nil {
+		return err
+	}
+	stream, err := c.ExportPublicKeys(context.Background(), &api.ExportPublicKeysRequest{})
+	if err != nil {
+		return err
+	}
+	n, err := writeRecords(stream, rw)
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	logger.Info("exported public keys",
+		zap.String(logFile, f.Name()),
+		zap.Int(logNRecords, n),
+	)
+	return nil
+}
+
+// addClientAuthFlags adds the flags for authenticating to the Key server to a command that calls
+// it.
+func addClientAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().String(tlsCertFlag, "",
+		"PEM file of the client certificate to authenticate to the server with over mutual TLS")
+	cmd.Flags().String(tlsKeyFlag, "", "PEM file of the client certificate's private key")
+	cmd.Flags().String(tokenFlag, "",
+		"file of the signed bearer token to authenticate to the server with over TLS")
+}
+
+// getClient returns a client of the Key server at the address flag. It authenticates with the
+// token in the token file flag or the client certificate flags, if given, and uses TLS if either
+// is given or the TLS CA flag is.
+func getClient() (api.KeyClient, error) {
+	address, caFile := viper.GetString(addressFlag), viper.GetString(tlsCAFlag)
+	if tokenFile := viper.GetString(tokenFlag); tokenFile != "" {
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		return client.NewTLSWithToken(address, caFile, string(bytes.TrimSpace(token)))
+	}
+	if certFile := viper.GetString(tlsCertFlag); certFile != "" {
+		return client.NewMutualTLS(address, caFile, certFile, viper.GetString(tlsKeyFlag))
+	}
+	if caFile != "" {
+		return client.NewTLS(address, caFile)
+	}
+	return client.NewInsecure(address)
+}
+
+// writeRecords writes each public key record received from the export stream, returning how many
+// it wrote.
+func writeRecords(stream api.Key_ExportPublicKeysClient, rw recordWriter) (int, error) {
+	n := 0
+	for {
+		rp, err := stream.Recv()
+		if err == io.EOF {
+			return n, nil
+		} else if err != nil {
+			return n, err
+		}
+		for _, r := range rp.PublicKeyRecords {
+			if err := rw.Write(r); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+}
+
+func importKeys() error {
+	logger := logging.NewDevLogger(logging.GetLogLevel(viper.GetString(logLevelFlag)))
+	config := server.NewDefaultConfig()
+	if err := setStorageConfig(config); err != nil {
+		return err
+	}
+	storer, err := server.NewStorer(config, logger)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(viper.GetString(fileFlag))
+	if err != nil {
+		return err
+	}
+	rr, err := newRecordReader(f, viper.GetString(formatFlag))
+	if err != nil {
+		return err
+	}
+	nPut, nDup, err := putRecords(storer, rr, config.Storage.MaxBatchSize)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := storer.Close(); err != nil {
+		return err
+	}
+	logger.Info("imported public keys",
+		zap.String(logFile, f.Name()),
+		zap.Int(logNPut, nPut),
+		zap.Int(logNDuplicate, nDup),
+	)
+	return nil
+}
+
+// putRecords puts the public key records read from the file into the storer in batches of at
+// most batchSize, returning how many it put and how many already existed.
+func putRecords(storer storage.Storer, rr recordReader, batchSize uint) (int, int, error) {
+	nPut, nDup := 0, 0
+	batch := make([]*api.PublicKeyRecord, 0, batchSize)
+	for {
+		r, err := rr.Read()
+		if err != nil && err != io.EOF {
+			return nPut, nDup, err
+		}
+		if r != nil {
+			batch = append(batch, r)
+		}
+		if uint(len(batch)) == batchSize || (err == io.EOF && len(batch) > 0) {
+			bPut, bDup, err := putBatch(context.Background(), storer, batch)
+			nPut, nDup = nPut+bPut, nDup+bDup
+			if err != nil {
+				return nPut, nDup, err
+			}
+			batch = batch[:0]
+		}
+		if err == io.EOF {
+			return nPut, nDup, nil
+		}
+	}
+}
+
+// putBatch puts the batch of public key records into the storer along with the audit records of
+// importing them, putting them one at a time if some already exist so the rest are still put.
+func putBatch(
+	ctx context.Context, storer storage.Storer, batch []*api.PublicKeyRecord,
+) (int, int, error) {
+	err := storer.PutPublicKeyRecords(ctx, batch, importAuditRecords(batch))
+	if err == nil {
+		return len(batch), 0, nil
+	} else if err != storage.ErrPublicKeyExists {
+		return 0, 0, err
+	}
+	nPut, nDup := 0, 0
+	for _, r := range batch {
+		records := []*api.PublicKeyRecord{r}
+		switch err := storer.PutPublicKeyRecords(ctx, records, importAuditRecords(records)); err {
+		case nil:
+			nPut++
+		case storage.ErrPublicKeyExists:
+			nDup++
+		default:
+			return nPut, nDup, err
+		}
+	}
+	return nPut, nDup, nil
+}
+
+// importAuditRecords returns the audit records of importing the public key records, which add
+// each of their public keys and disable those that are disabled. The command line has no
+// authenticated caller to attribute them to.
+func importAuditRecords(records []*api.PublicKeyRecord) []*api.AuditRecord {
+	added := make([]*api.PublicKeyDetail, len(records))
+	disabled := make([]*api.PublicKeyDetail, 0, len(records))
+	for i, r := range records {
+		added[i] = r.PublicKeyDetail
+		if r.PublicKeyDetail.Disabled {
+			disabled = append(disabled, r.PublicKeyDetail)
+		}
+	}
+	return storage.NewAuditRecords(api.AuditAction_IMPORT, "", time.Now(), added, disabled)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+var errTest = errors.New("some test error")
+
+func TestWriteRecords(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := newTestPublicKeyRecords(rng, 5)
+	stream := &fixedExportClient{
+		rps: []*api.ExportPublicKeysResponse{
+			{PublicKeyRecords: records[:3]},
+			{PublicKeyRecords: records[3:]},
+		},
+	}
+	rw := &collectingRecordWriter{}
+	n, err := writeRecords(stream, rw)
+	assert.Nil(t, err)
+	assert.Equal(t, len(records), n)
+	assert.Equal(t, records, rw.records)
+
+	n, err = writeRecords(&fixedExportClient{recvErr: errTest}, rw)
+	assert.Equal(t, errTest, err)
+	assert.Zero(t, n)
+}
+
+func TestPutRecords(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := newTestPublicKeyRecords(rng, 10)
+	st := memory.New(storage.NewDefaultParameters(), zap.NewNop())
+	err := st.PutPublicKeyRecords(context.Background(), records[4:5], nil)
+	assert.Nil(t, err)
+
+	// the batch with the existing record is put one at a time
+	nPut, nDup, err := putRecords(st, &fixedRecordReader{records: records}, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, 9, nPut)
+	assert.Equal(t, 1, nDup)
+
+	stored, err := st.ListPublicKeyRecords(context.Background(), nil, 20)
+	assert.Nil(t, err)
+	assert.Len(t, stored, len(records))
+
+	// the put records are audited, but not the duplicate
+	audit, err := st.ListAuditRecords(context.Background(), 0, 20)
+	assert.Nil(t, err)
+	nAdded, nDisabled := 0, 0
+	for _, r := range audit {
+		assert.Equal(t, api.AuditAction_IMPORT, r.Action)
+		assert.NotContains(t, r.AddedPublicKeyHashes,
+			api.PublicKeyHashes([][]byte{records[4].PublicKeyDetail.PublicKey})[0])
+		nAdded += len(r.AddedPublicKeyHashes)
+		nDisabled += len(r.DisabledPublicKeyHashes)
+	}
+	assert.Equal(t, 9, nAdded)
+	assert.Equal(t, 4, nDisabled)
+
+	nPut, nDup, err = putRecords(st, &fixedRecordReader{readErr: errTest}, 4)
+	assert.Equal(t, errTest, err)
+	assert.Zero(t, nPut)
+	assert.Zero(t, nDup)
+}
+
+func TestImportAuditRecords(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := newTestPublicKeyRecords(rng, 2)
+	records[1].PublicKeyDetail.EntityId = records[0].PublicKeyDetail.EntityId
+	records[1].PublicKeyDetail.KeyType = records[0].PublicKeyDetail.KeyType
+
+	// both records are of one entity and key type, and only the first is disabled
+	audit := importAuditRecords(records)
+	assert.Len(t, audit, 1)
+	assert.Equal(t, api.AuditAction_IMPORT, audit[0].Action)
+	assert.Empty(t, audit[0].CallerEntityId)
+	assert.Equal(t, records[0].PublicKeyDetail.EntityId, audit[0].EntityId)
+	pks := [][]byte{records[0].PublicKeyDetail.PublicKey, records[1].PublicKeyDetail.PublicKey}
+	assert.Equal(t, api.PublicKeyHashes(pks), audit[0].AddedPublicKeyHashes)
+	assert.Equal(t, api.PublicKeyHashes(pks[:1]), audit[0].DisabledPublicKeyHashes)
+}
+
+type fixedExportClient struct {
+	grpc.ClientStream
+	rps     []*api.ExportPublicKeysResponse
+	recvErr error
+}
+
+func (f *fixedExportClient) Recv() (*api.ExportPublicKeysResponse, error) {
+	if f.recvErr != nil {
+		return nil, f.recvErr
+	}
+	if len(f.rps) == 0 {
+		return nil, io.EOF
+	}
+	rp := f.rps[0]
+	f.rps = f.rps[1:]
+	return rp, nil
+}
+
+type collectingRecordWriter struct {
+	records []*api.PublicKeyRecord
+}
+
+func (c *collectingRecordWriter) Write(r *api.PublicKeyRecord) error {
+	c.records = append(c.records, r)
+	return nil
+}
+
+type fixedRecordReader struct {
+	records []*api.PublicKeyRecord
+	readErr error
+}
+
+func (f *fixedRecordReader) Read() (*api.PublicKeyRecord, error) {
+	if f.readErr != nil {
+		return nil, f.readErr
+	}
+	if len(f.records) == 0 {
+		return nil, io.EOF
+	}
+	r := f.records[0]
+	f.records = f.records[1:]
+	return r, nil
+}
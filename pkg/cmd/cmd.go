@@ -1,36 +1,68 @@
 package cmd
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"strings"
 
 	errors2 "github.com/drausin/libri/libri/common/errors"
 	"github.com/drausin/libri/libri/common/logging"
 	"github.com/elixirhealth/key/pkg/server"
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/elixirhealth/key/pkg/server/events"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/cache"
+	"github.com/elixirhealth/key/pkg/server/storage/memory"
+	"github.com/elixirhealth/key/pkg/server/storage/postgres"
 	"github.com/elixirhealth/key/version"
 	"github.com/elixirhealth/service-base/pkg/cmd"
 	bserver "github.com/elixirhealth/service-base/pkg/server"
-	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 const (
-	serviceNameLower    = "key"
-	serviceNameCamel    = "Key"
-	envVarPrefix        = "KEY"
-	logLevelFlag        = "logLevel"
-	storageMemoryFlag   = "storageMemory"
-	dbURLFlag           = "dbURL"
-	dbPasswordFlag      = "dbPassword"
-	storagePostgresFlag = "storagePostgres"
+	serviceNameLower     = "key"
+	serviceNameCamel     = "Key"
+	envVarPrefix         = "KEY"
+	logLevelFlag         = "logLevel"
+	storageMemoryFlag    = "storageMemory"
+	dbURLFlag            = "dbURL"
+	dbPasswordFlag       = "dbPassword"
+	storagePostgresFlag  = "storagePostgres"
+	storageDataStoreFlag = "storageDataStore"
+	storageBoltFlag      = "storageBolt"
+	boltDBPathFlag       = "boltDBPath"
+	snapshotPathFlag     = "memorySnapshotPath"
+	snapshotIntervalFlag = "memorySnapshotInterval"
+	cacheSizeFlag        = "storageCacheSize"
+	cacheTTLFlag         = "storageCacheTTL"
+	cacheEntityTTLFlag   = "storageCacheEntityTTL"
+	eventsFileFlag       = "eventsFile"
+	relayIntervalFlag    = "eventRelayInterval"
+	relayBatchSizeFlag   = "eventRelayBatchSize"
+	gcpProjectIDFlag     = "gcpProjectID"
+	emulatorHostFlag     = "datastoreEmulatorHost"
+	requireProofsFlag    = "requireProofOfPossession"
+	tlsCertFlag          = "tlsCert"
+	tlsKeyFlag           = "tlsKey"
+	tlsClientCAFlag      = "tlsClientCA"
+	signingKeyFlag       = "signingKey"
+	tokenSecretFileFlag  = "authTokenSecretFile"
+	delegatesFlag        = "authDelegates"
 )
 
 var (
 	errMultipleStorageTypes = errors.New("multiple storage types specified")
 	errNoStorageType        = errors.New("no storage type specified")
+	errInvalidDelegate      = errors.New("delegate grant must be an entity ID and an admin " +
+		"entity ID separated by a colon")
+	errDelegatesWithoutAuth = errors.New("delegate grants need client CA or token secret " +
+		"authentication")
 
 	rootCmd = &cobra.Command{
 		Short: "operate a Key server",
@@ -45,8 +77,46 @@ func init() {
 		func(flags *pflag.FlagSet) {
 			flags.Bool(storageMemoryFlag, false, "use in-memory storage")
 			flags.Bool(storagePostgresFlag, false, "use Postgres DB storage")
+			flags.Bool(storageDataStoreFlag, false, "use GCP DataStore storage")
+			flags.Bool(storageBoltFlag, false, "use embedded Bolt DB file storage")
 			flags.String(dbURLFlag, "", "Postgres DB URL, including username")
 			flags.String(dbPasswordFlag, "", "DB user's password")
+			flags.String(gcpProjectIDFlag, "", "GCP project ID of the DataStore instance")
+			flags.String(emulatorHostFlag, "",
+				"host of a local DataStore emulator to use instead of GCP DataStore")
+			flags.String(boltDBPathFlag, "", "path of the Bolt DB file, created if missing")
+			flags.String(snapshotPathFlag, "",
+				"file to restore in-memory storage from and periodically snapshot it to")
+			flags.Duration(snapshotIntervalFlag, memory.DefaultSnapshotInterval,
+				"interval between snapshots of in-memory storage")
+			flags.Int(cacheSizeFlag, 0,
+				"number of public keys to cache storage lookups of, or 0 to not cache them "+
+					"(ignored for shared Postgres or DataStore storage)")
+			flags.Duration(cacheTTLFlag, cache.DefaultPublicKeyTTL,
+				"time to cache public key lookups for")
+			flags.Duration(cacheEntityTTLFlag, cache.DefaultEntityPublicKeysTTL,
+				"time to cache the public keys of each entity and key type for")
+			flags.String(eventsFileFlag, "",
+				"JSONL file to append Postgres storage's public key events to")
+			flags.Duration(relayIntervalFlag, postgres.DefaultRelayInterval,
+				"interval between publishing batches of public key events")
+			flags.Uint(relayBatchSizeFlag, postgres.DefaultRelayBatchSize,
+				"maximum number of public key events to publish at once")
+			flags.Bool(requireProofsFlag, false,
+				"require signatures proving possession of added public keys")
+			flags.String(tlsCertFlag, "", "PEM file of the server's TLS certificate")
+			flags.String(tlsKeyFlag, "", "PEM file of the server's TLS private key")
+			flags.String(tlsClientCAFlag, "",
+				"PEM file of the CA certificates that must sign client certificates, "+
+					"whose common names authorize requests for their entities")
+			flags.String(signingKeyFlag, "",
+				"PEM file of the Ed25519 private key to sign public key lookup responses with")
+			flags.String(tokenSecretFileFlag, "",
+				"file of the secret that verifies bearer tokens, whose subjects authorize "+
+					"requests for their entities")
+			flags.StringSlice(delegatesFlag, nil,
+				"delegated admin grants, each an entity ID and the entity ID of one of its "+
+					"admins separated by a colon")
 		})
 
 	testCmd := cmd.Test(serviceNameLower, rootCmd)
@@ -85,13 +155,121 @@ func getKeyConfig() (*server.Config, error) {
 		WithProfilerPort(uint(viper.GetInt(cmd.ProfilerPortFlag))).
 		WithLogLevel(logging.GetLogLevel(viper.GetString(logLevelFlag))).
 		WithProfile(viper.GetBool(cmd.ProfileFlag))
+	if err := setStorageConfig(c); err != nil {
+		return nil, err
+	}
+	c.WithMemorySnapshots(
+		viper.GetString(snapshotPathFlag),
+		viper.GetDuration(snapshotIntervalFlag),
+	)
+	c.WithStorageCache(getStorageCacheParams())
+	c.WithEventRelay(&postgres.RelayParameters{
+		Interval:  viper.GetDuration(relayIntervalFlag),
+		BatchSize: uint(viper.GetInt(relayBatchSizeFlag)),
+	})
+	c.WithRequireProofOfPossession(viper.GetBool(requireProofsFlag))
+	c.WithTLS(
+		viper.GetString(tlsCertFlag),
+		viper.GetString(tlsKeyFlag),
+		viper.GetString(tlsClientCAFlag),
+	)
+	if err := setAuthConfig(c); err != nil {
+		return nil, err
+	}
+	if signingKeyFile := viper.GetString(signingKeyFlag); signingKeyFile != "" {
+		signingKey, err := server.LoadSigningKey(signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.WithSigningKey(signingKey)
+	}
+	if eventsFile := viper.GetString(eventsFileFlag); eventsFile != "" {
+		publisher, err := events.NewFilePublisher(eventsFile)
+		if err != nil {
+			return nil, err
+		}
+		c.WithEventPublisher(publisher)
+	}
+	return c, nil
+}
+
+// setAuthConfig sets the config's Identifier from the client CA and token secret flags, if either
+// is given, and its Authorizer with the delegate grants from the delegates flag.
+func setAuthConfig(c *server.Config) error {
+	var identifiers auth.Identifiers
+	if c.TLSClientCAFile != "" {
+		clientCAs, err := server.LoadCertPool(c.TLSClientCAFile)
+		if err != nil {
+			return err
+		}
+		identifiers = append(identifiers, auth.NewCertIdentifier(clientCAs))
+	}
+	if secretFile := viper.GetString(tokenSecretFileFlag); secretFile != "" {
+		secret, err := ioutil.ReadFile(secretFile)
+		if err != nil {
+			return err
+		}
+		ti, err := auth.NewTokenIdentifier(bytes.TrimSpace(secret))
+		if err != nil {
+			return err
+		}
+		identifiers = append(identifiers, ti)
+	}
+	delegates, err := getDelegates()
+	if err != nil {
+		return err
+	}
+	if len(identifiers) == 0 {
+		if len(delegates) > 0 {
+			return errDelegatesWithoutAuth
+		}
+		return nil
+	}
+	c.WithAuth(identifiers, auth.NewEntityAuthorizer(delegates))
+	return nil
+}
+
+// getDelegates returns the map from entity ID to the entity IDs of its delegated admins given by
+// the delegates flag.
+func getDelegates() (map[string][]string, error) {
+	delegates := make(map[string][]string)
+	for _, grant := range viper.GetStringSlice(delegatesFlag) {
+		parts := strings.Split(grant, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errInvalidDelegate
+		}
+		delegates[parts[0]] = append(delegates[parts[0]], parts[1])
+	}
+	return delegates, nil
+}
+
+// getStorageCacheParams returns the storage cache parameters from the cache flags, or nil if
+// lookups shouldn't be cached.
+func getStorageCacheParams() *cache.Parameters {
+	size := viper.GetInt(cacheSizeFlag)
+	if size <= 0 {
+		return nil
+	}
+	p := cache.NewDefaultParameters()
+	p.PublicKeysSize = size
+	p.PublicKeyTTL = viper.GetDuration(cacheTTLFlag)
+	p.EntityPublicKeysTTL = viper.GetDuration(cacheEntityTTLFlag)
+	return p
+}
+
+// setStorageConfig sets the config's storage type and the settings for connecting to it from the
+// storage flags.
+func setStorageConfig(c *server.Config) error {
 	st, err := getStorageType()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	c.Storage.Type = st
-	c.DBUrl = getDBUrl()
-	return c, nil
+	c.WithDBUrl(getDBUrl()).
+		WithGCPProjectID(viper.GetString(gcpProjectIDFlag)).
+		WithDatastoreEmulatorHost(viper.GetString(emulatorHostFlag)).
+		WithBoltDBPath(viper.GetString(boltDBPathFlag))
+	return nil
 }
 
 func getDBUrl() string {
@@ -103,15 +281,24 @@ func getDBUrl() string {
 	return dbURL
 }
 
-func getStorageType() (bstorage.Type, error) {
-	if viper.GetBool(storageMemoryFlag) && viper.GetBool(storagePostgresFlag) {
-		return bstorage.Unspecified, errMultipleStorageTypes
-	}
-	if viper.GetBool(storageMemoryFlag) {
-		return bstorage.Memory, nil
+func getStorageType() (storage.Type, error) {
+	st := storage.Unspecified
+	for flag, flagST := range map[string]storage.Type{
+		storageMemoryFlag:    storage.Memory,
+		storagePostgresFlag:  storage.Postgres,
+		storageDataStoreFlag: storage.DataStore,
+		storageBoltFlag:      storage.Bolt,
+	} {
+		if !viper.GetBool(flag) {
+			continue
+		}
+		if st != storage.Unspecified {
+			return storage.Unspecified, errMultipleStorageTypes
+		}
+		st = flagST
 	}
-	if viper.GetBool(storagePostgresFlag) {
-		return bstorage.Postgres, nil
+	if st == storage.Unspecified {
+		return storage.Unspecified, errNoStorageType
 	}
-	return bstorage.Unspecified, errNoStorageType
+	return st, nil
 }
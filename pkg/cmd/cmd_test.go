@@ -1,10 +1,21 @@
 package cmd
 
 import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/elixirhealth/key/pkg/server/events"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/cache"
 	"github.com/elixirhealth/service-base/pkg/cmd"
-	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap/zapcore"
@@ -19,6 +30,11 @@ func TestGetKeyConfig(t *testing.T) {
 	dbURL := "some URL"
 	storageInMemory := false
 	storagePostgres := true
+	gcpProjectID := "some-project"
+	boltDBPath := "some/key.db"
+	snapshotPath, snapshotInterval := "some/key.snapshot", 30*time.Second
+	emulatorHost := "localhost:8081"
+	requireProofs := true
 
 	viper.Set(cmd.ServerPortFlag, serverPort)
 	viper.Set(cmd.MetricsPortFlag, metricsPort)
@@ -27,7 +43,18 @@ func TestGetKeyConfig(t *testing.T) {
 	viper.Set(cmd.ProfileFlag, profile)
 	viper.Set(storageMemoryFlag, storageInMemory)
 	viper.Set(storagePostgresFlag, storagePostgres)
+	viper.Set(storageDataStoreFlag, false)
+	viper.Set(storageBoltFlag, false)
 	viper.Set(dbURLFlag, dbURL)
+	viper.Set(gcpProjectIDFlag, gcpProjectID)
+	viper.Set(emulatorHostFlag, emulatorHost)
+	viper.Set(boltDBPathFlag, boltDBPath)
+	viper.Set(snapshotPathFlag, snapshotPath)
+	viper.Set(snapshotIntervalFlag, snapshotInterval)
+	viper.Set(requireProofsFlag, requireProofs)
+	viper.Set(cacheSizeFlag, 0)
+	viper.Set(relayIntervalFlag, 5*time.Second)
+	viper.Set(relayBatchSizeFlag, 10)
 
 	c, err := getKeyConfig()
 	assert.Nil(t, err)
@@ -37,6 +64,183 @@ func TestGetKeyConfig(t *testing.T) {
 	assert.Equal(t, logLevel, c.LogLevel.String())
 	assert.Equal(t, profile, c.Profile)
 	assert.Equal(t, dbURL, c.DBUrl)
-	assert.Equal(t, bstorage.Postgres, c.Storage.Type)
+	assert.Equal(t, storage.Postgres, c.Storage.Type)
+	assert.Equal(t, gcpProjectID, c.GCPProjectID)
+	assert.Equal(t, emulatorHost, c.DatastoreEmulatorHost)
+	assert.Equal(t, boltDBPath, c.BoltDBPath)
+	assert.Equal(t, snapshotPath, c.MemorySnapshotPath)
+	assert.Equal(t, snapshotInterval, c.MemorySnapshotInterval)
+	assert.Equal(t, requireProofs, c.RequireProofOfPossession)
+	assert.Nil(t, c.StorageCache)
+	assert.Equal(t, 5*time.Second, c.EventRelay.Interval)
+	assert.Equal(t, uint(10), c.EventRelay.BatchSize)
+	assert.Nil(t, c.EventPublisher)
+	assert.Nil(t, c.Identifier)
+	assert.Nil(t, c.Authorizer)
+	assert.Nil(t, c.SigningKey)
+}
+
+func TestGetStorageCacheParams(t *testing.T) {
+	viper.Set(cacheSizeFlag, 0)
+	assert.Nil(t, getStorageCacheParams())
+
+	viper.Set(cacheSizeFlag, 1024)
+	viper.Set(cacheTTLFlag, time.Minute)
+	viper.Set(cacheEntityTTLFlag, time.Second)
+	defer viper.Set(cacheSizeFlag, 0)
+	p := getStorageCacheParams()
+	assert.Equal(t, 1024, p.PublicKeysSize)
+	assert.Equal(t, time.Minute, p.PublicKeyTTL)
+	assert.Equal(t, cache.DefaultEntityPublicKeysSize, p.EntityPublicKeysSize)
+	assert.Equal(t, time.Second, p.EntityPublicKeysTTL)
+}
+
+func TestGetKeyConfig_tls(t *testing.T) {
+	viper.Set(storageMemoryFlag, true)
+	viper.Set(storagePostgresFlag, false)
+	viper.Set(storageDataStoreFlag, false)
+	viper.Set(storageBoltFlag, false)
+	viper.Set(tlsCertFlag, "server.crt")
+	viper.Set(tlsKeyFlag, "server.key")
+	viper.Set(tlsClientCAFlag, "")
+	defer viper.Set(tlsCertFlag, "")
+	defer viper.Set(tlsKeyFlag, "")
+
+	c, err := getKeyConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "server.crt", c.TLSCertFile)
+	assert.Equal(t, "server.key", c.TLSKeyFile)
+	assert.Nil(t, c.Identifier)
+
+	// missing client CA file
+	viper.Set(tlsClientCAFlag, "does-not-exist.crt")
+	defer viper.Set(tlsClientCAFlag, "")
+	c, err = getKeyConfig()
+	assert.NotNil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestGetKeyConfig_events(t *testing.T) {
+	viper.Set(storageMemoryFlag, false)
+	viper.Set(storagePostgresFlag, true)
+	viper.Set(storageDataStoreFlag, false)
+	viper.Set(storageBoltFlag, false)
+	dir, err := ioutil.TempDir("", "key-cmd-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	viper.Set(eventsFileFlag, filepath.Join(dir, "events.jsonl"))
+	defer viper.Set(eventsFileFlag, "")
+
+	c, err := getKeyConfig()
+	assert.Nil(t, err)
+	assert.IsType(t, &events.FilePublisher{}, c.EventPublisher)
+	assert.Nil(t, c.EventPublisher.Close())
+
+	// missing dir
+	viper.Set(eventsFileFlag, filepath.Join(dir, "missing-dir", "events.jsonl"))
+	c, err = getKeyConfig()
+	assert.NotNil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestGetKeyConfig_signingKey(t *testing.T) {
+	viper.Set(storageMemoryFlag, true)
+	viper.Set(storagePostgresFlag, false)
+	viper.Set(storageDataStoreFlag, false)
+	viper.Set(storageBoltFlag, false)
+	dir, err := ioutil.TempDir("", "key-cmd-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	_, key, err := ed25519.GenerateKey(rand.New(rand.NewSource(0)))
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+	keyFile := filepath.Join(dir, "signing.key")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	assert.Nil(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+	viper.Set(signingKeyFlag, keyFile)
+	defer viper.Set(signingKeyFlag, "")
+
+	c, err := getKeyConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, key, c.SigningKey)
+
+	// missing signing key file
+	viper.Set(signingKeyFlag, filepath.Join(dir, "does-not-exist.key"))
+	c, err = getKeyConfig()
+	assert.NotNil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestGetKeyConfig_auth(t *testing.T) {
+	viper.Set(storageMemoryFlag, true)
+	viper.Set(storagePostgresFlag, false)
+	viper.Set(storageDataStoreFlag, false)
+	viper.Set(storageBoltFlag, false)
+	dir, err := ioutil.TempDir("", "key-cmd-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	secretFile := filepath.Join(dir, "token.secret")
+	assert.Nil(t, ioutil.WriteFile(secretFile, []byte("some secret\n"), 0600))
+	viper.Set(tokenSecretFileFlag, secretFile)
+	viper.Set(delegatesFlag, []string{"entity 1:admin 1", "entity 1:admin 2"})
+	defer viper.Set(tokenSecretFileFlag, "")
+	defer viper.Set(delegatesFlag, []string{})
+
+	c, err := getKeyConfig()
+	assert.Nil(t, err)
+	assert.Len(t, c.Identifier, 1)
+	assert.IsType(t, &auth.TokenIdentifier{}, c.Identifier.(auth.Identifiers)[0])
+	assert.Equal(t, auth.NewEntityAuthorizer(map[string][]string{
+		"entity 1": {"admin 1", "admin 2"},
+	}), c.Authorizer)
+
+	// invalid delegate grant
+	viper.Set(delegatesFlag, []string{"entity 1"})
+	c, err = getKeyConfig()
+	assert.Equal(t, errInvalidDelegate, err)
+	assert.Nil(t, c)
+
+	// missing token secret file
+	viper.Set(delegatesFlag, []string{})
+	viper.Set(tokenSecretFileFlag, filepath.Join(dir, "does-not-exist.secret"))
+	c, err = getKeyConfig()
+	assert.NotNil(t, err)
+	assert.Nil(t, c)
+
+	// delegate grants without any authentication
+	viper.Set(delegatesFlag, []string{"entity 1:admin 1"})
+	viper.Set(tokenSecretFileFlag, "")
+	c, err = getKeyConfig()
+	assert.Equal(t, errDelegatesWithoutAuth, err)
+	assert.Nil(t, c)
+}
 
+func TestGetStorageType(t *testing.T) {
+	cases := map[string]struct {
+		memory, postgres, datastore, bolt bool
+		expected                          storage.Type
+		expectedErr                       error
+	}{
+		"memory":    {memory: true, expected: storage.Memory},
+		"postgres":  {postgres: true, expected: storage.Postgres},
+		"datastore": {datastore: true, expected: storage.DataStore},
+		"bolt":      {bolt: true, expected: storage.Bolt},
+		"none":      {expected: storage.Unspecified, expectedErr: errNoStorageType},
+		"multiple": {
+			postgres:    true,
+			datastore:   true,
+			expected:    storage.Unspecified,
+			expectedErr: errMultipleStorageTypes,
+		},
+	}
+	for desc, c := range cases {
+		viper.Set(storageMemoryFlag, c.memory)
+		viper.Set(storagePostgresFlag, c.postgres)
+		viper.Set(storageDataStoreFlag, c.datastore)
+		viper.Set(storageBoltFlag, c.bolt)
+		st, err := getStorageType()
+		assert.Equal(t, c.expectedErr, err, desc)
+		assert.Equal(t, c.expected, st, desc)
+	}
 }
@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/drausin/libri/libri/common/logging"
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	fromFlag       = "from"
+	toFlag         = "to"
+	checkpointFlag = "checkpoint"
+
+	memoryStorage    = "memory"
+	postgresStorage  = "postgres"
+	datastoreStorage = "datastore"
+	boltStorage      = "bolt"
+
+	logFrom      = "from"
+	logTo        = "to"
+	logAfter     = "after"
+	logNRead     = "n_read"
+	logNCopied   = "n_copied"
+	logNVerified = "n_verified"
+	logNAudit    = "n_audit_copied"
+	logNAuditDup = "n_audit_duplicate"
+	logCounts    = "counts"
+)
+
+var (
+	errUnknownStorageType = errors.New("unknown storage type")
+	errSameStorageTypes   = errors.New("migration source and destination storage types are " +
+		"the same")
+	errMemoryDestination = errors.New("migration destination can't be memory storage, which is " +
+		"lost when the migration exits")
+	errMigrationUnverified = errors.New("migrated public key records missing from or different " +
+		"in destination storage")
+	errAuditLogDiverged = errors.New("destination storage audit log differs from source storage " +
+		"audit log")
+
+	migrateCmd = &cobra.Command{
+		Use:     "migrate-storage",
+		Short:   "copy all public keys and the audit log from one storage backend to another",
+		PreRunE: bindFlags,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return migrateStorage()
+		},
+	}
+)
+
+func init() {
+	storageTypes := strings.Join([]string{memoryStorage, postgresStorage, datastoreStorage,
+		boltStorage}, ", ")
+	migrateCmd.Flags().String(fromFlag, "", "storage type to copy from, one of "+storageTypes)
+	migrateCmd.Flags().String(toFlag, "", "storage type to copy to, one of "+storageTypes)
+	migrateCmd.Flags().String(checkpointFlag, "",
+		"file recording the last public key copied, from which an interrupted migration resumes")
+	migrateCmd.Flags().String(dbURLFlag, "", "Postgres DB URL, including username")
+	migrateCmd.Flags().String(dbPasswordFlag, "", "DB user's password")
+	migrateCmd.Flags().String(gcpProjectIDFlag, "", "GCP project ID of the DataStore instance")
+	migrateCmd.Flags().String(emulatorHostFlag, "",
+		"host of a local DataStore emulator to use instead of GCP DataStore")
+	migrateCmd.Flags().String(boltDBPathFlag, "", "path of the Bolt DB file, created if missing")
+
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func migrateStorage() error {
+	logger := logging.NewDevLogger(logging.GetLogLevel(viper.GetString(logLevelFlag)))
+	fromType, err := parseStorageType(viper.GetString(fromFlag))
+	if err != nil {
+		return err
+	}
+	toType, err := parseStorageType(viper.GetString(toFlag))
+	if err != nil {
+		return err
+	}
+	if fromType == toType {
+		return errSameStorageTypes
+	}
+	if toType == storage.Memory {
+		return errMemoryDestination
+	}
+	from, err := server.NewStorer(getMigrateConfig(fromType), logger)
+	if err != nil {
+		return err
+	}
+	toConfig := getMigrateConfig(toType)
+	to, err := server.NewStorer(toConfig, logger)
+	if err != nil {
+		return err
+	}
+	m := &migrator{
+		from:         from,
+		to:           to,
+		batchSize:    toConfig.Storage.MaxBatchSize,
+		checkpointFn: viper.GetString(checkpointFlag),
+		logger:       logger,
+	}
+	counts, err := m.migrate(context.Background())
+	if err != nil {
+		return err
+	}
+	if err := from.Close(); err != nil {
+		return err
+	}
+	if err := to.Close(); err != nil {
+		return err
+	}
+	logger.Info("migrated storage",
+		zap.Stringer(logFrom, fromType),
+		zap.Stringer(logTo, toType),
+		zap.Object(logCounts, counts),
+	)
+	return nil
+}
+
+func getMigrateConfig(st storage.Type) *server.Config {
+	config := server.NewDefaultConfig().
+		WithDBUrl(getDBUrl()).
+		WithGCPProjectID(viper.GetString(gcpProjectIDFlag)).
+		WithDatastoreEmulatorHost(viper.GetString(emulatorHostFlag)).
+		WithBoltDBPath(viper.GetString(boltDBPathFlag))
+	config.Storage.Type = st
+	return config
+}
+
+func parseStorageType(name string) (storage.Type, error) {
+	switch name {
+	case memoryStorage:
+		return storage.Memory, nil
+	case postgresStorage:
+		return storage.Postgres, nil
+	case datastoreStorage:
+		return storage.DataStore, nil
+	case boltStorage:
+		return storage.Bolt, nil
+	default:
+		return storage.Unspecified, errUnknownStorageType
+	}
+}
+
+// migrator copies the audit log and then the public key records from one storer to another. It
+// copies the public key records in batches ordered by public key, recording the last public key of
+// each copied batch in the checkpoint file (if any) so an interrupted migration can resume after
+// it. The source storer shouldn't change during the migration.
+type migrator struct {
+	from         storage.Storer
+	to           storage.Storer
+	batchSize    uint
+	checkpointFn string
+	logger       *zap.Logger
+}
+
+// migrationCounts are the numbers of public key records read from the source storer, copied to
+// the destination storer, already in the destination storer, and verified to be in the
+// destination storer after copying, and the numbers of audit records copied to and already in the
+// destination storer.
+type migrationCounts struct {
+	nRead           int
+	nCopied         int
+	nDuplicate      int
+	nVerified       int
+	nAuditCopied    int
+	nAuditDuplicate int
+}
+
+// MarshalLogObject writes the counts to the given object encoder.
+func (c *migrationCounts) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddInt(logNRead, c.nRead)
+	oe.AddInt(logNCopied, c.nCopied)
+	oe.AddInt(logNDuplicate, c.nDuplicate)
+	oe.AddInt(logNVerified, c.nVerified)
+	oe.AddInt(logNAudit, c.nAuditCopied)
+	oe.AddInt(logNAuditDup, c.nAuditDuplicate)
+	return nil
+}
+
+func (m *migrator) migrate(ctx context.Context) (*migrationCounts, error) {
+	after, err := readCheckpoint(m.checkpointFn)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		m.logger.Info("resuming migration from checkpoint",
+			zap.String(logAfter, hex.EncodeToString(after)))
+	}
+	counts := &migrationCounts{}
+	if err := m.migrateAudit(ctx, counts); err != nil {
+		return counts, err
+	}
+	for {
+		records, err := m.from.ListPublicKeyRecords(ctx, after, m.batchSize)
+		if err != nil {
+			return counts, err
+		}
+		if len(records) == 0 {
+			break
+		}
+		counts.nRead += len(records)
+		nPut, nDup, err := putBatch(ctx, m.to, records)
+		counts.nCopied, counts.nDuplicate = counts.nCopied+nPut, counts.nDuplicate+nDup
+		if err != nil {
+			return counts, err
+		}
+		if err := m.verify(ctx, after, records); err != nil {
+			return counts, err
+		}
+		counts.nVerified += len(records)
+		after = records[len(records)-1].PublicKeyDetail.PublicKey
+		if err := writeCheckpoint(m.checkpointFn, after); err != nil {
+			return counts, err
+		}
+		m.logger.Debug("migrated public key batch", zap.Object(logCounts, counts))
+		if uint(len(records)) < m.batchSize {
+			break
+		}
+	}
+	return counts, nil
+}
+
+// migrateAudit copies the source storer's audit log to the destination storer, skipping the
+// audit records a previous migration already copied. Appending them chains them to the same
+// hashes as in the source audit log, which the destination audit log must start with.
+func (m *migrator) migrateAudit(ctx context.Context, counts *migrationCounts) error {
+	after := uint64(0)
+	for {
+		records, err := m.from.ListAuditRecords(ctx, after, m.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		copied, err := m.to.ListAuditRecords(ctx, after, uint(len(records)))
+		if err != nil {
+			return err
+		}
+		missing := make([]*api.AuditRecord, 0, len(records))
+		for i, r := range records {
+			if i < len(copied) {
+				if !bytes.Equal(copied[i].Hash, r.Hash) {
+					return errAuditLogDiverged
+				}
+				continue
+			}
+			missing = append(missing, proto.Clone(r).(*api.AuditRecord))
+		}
+		if len(missing) > 0 {
+			if err := m.to.AppendAuditRecords(ctx, missing); err != nil {
+				return err
+			}
+		}
+		for i, r := range missing {
+			if !bytes.Equal(records[len(copied)+i].Hash, r.Hash) {
+				return errAuditLogDiverged
+			}
+		}
+		counts.nAuditDuplicate += len(copied)
+		counts.nAuditCopied += len(missing)
+		after = records[len(records)-1].Sequence
+		m.logger.Debug("migrated audit record batch", zap.Object(logCounts, counts))
+	}
+}
+
+// verify checks that the destination storer has the same records as the source storer for the
+// batch of public keys after the given public key, including their entities, key types, disabled
+// states, and added and disabled times.
+func (m *migrator) verify(ctx context.Context, after []byte, records []*api.PublicKeyRecord) error {
+	unverified := make(map[string]*api.PublicKeyRecord, len(records))
+	for _, r := range records {
+		unverified[hex.EncodeToString(r.PublicKeyDetail.PublicKey)] = r
+	}
+	last := records[len(records)-1].PublicKeyDetail.PublicKey
+	for len(unverified) > 0 {
+		copied, err := m.to.ListPublicKeyRecords(ctx, after, m.batchSize)
+		if err != nil {
+			return err
+		}
+		for _, c := range copied {
+			pkHex := hex.EncodeToString(c.PublicKeyDetail.PublicKey)
+			if r, in := unverified[pkHex]; in {
+				if !proto.Equal(r, c) {
+					return errMigrationUnverified
+				}
+				delete(unverified, pkHex)
+			}
+		}
+		if uint(len(copied)) < m.batchSize ||
+			bytes.Compare(copied[len(copied)-1].PublicKeyDetail.PublicKey, last) >= 0 {
+			break
+		}
+		after = copied[len(copied)-1].PublicKeyDetail.PublicKey
+	}
+	if len(unverified) > 0 {
+		return errMigrationUnverified
+	}
+	return nil
+}
+
+// readCheckpoint returns the last public key recorded in the checkpoint file, or nil if there is
+// no checkpoint file.
+func readCheckpoint(fn string) ([]byte, error) {
+	if fn == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(b)))
+}
+
+// writeCheckpoint records the last public key copied in the checkpoint file, replacing it
+// atomically so an interruption never leaves a partial checkpoint.
+func writeCheckpoint(fn string, after []byte) error {
+	if fn == "" {
+		return nil
+	}
+	tmpFn := fn + ".tmp"
+	if err := ioutil.WriteFile(tmpFn, []byte(hex.EncodeToString(after)+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFn, fn)
+}
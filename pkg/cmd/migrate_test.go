@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/memory"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestMigrator_migrate_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	dir, err := ioutil.TempDir("", "key-migrate-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	params := storage.NewDefaultParameters()
+	records := newTestPublicKeyRecords(rng, 10)
+	from := memory.New(params, zap.NewNop())
+	err = from.PutPublicKeyRecords(context.Background(), records, importAuditRecords(records))
+	assert.Nil(t, err)
+	fromAudit, err := from.ListAuditRecords(context.Background(), 0, 20)
+	assert.Nil(t, err)
+	to := memory.New(params, zap.NewNop())
+	err = to.PutPublicKeyRecords(context.Background(), records[:1], nil)
+	assert.Nil(t, err)
+
+	m := &migrator{
+		from:         from,
+		to:           to,
+		batchSize:    4,
+		checkpointFn: filepath.Join(dir, "checkpoint"),
+		logger:       zap.NewNop(),
+	}
+	counts, err := m.migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, &migrationCounts{
+		nRead:        10,
+		nCopied:      9,
+		nDuplicate:   1,
+		nVerified:    10,
+		nAuditCopied: len(fromAudit),
+	}, counts)
+	fromRecords, err := from.ListPublicKeyRecords(context.Background(), nil, 20)
+	assert.Nil(t, err)
+	toRecords, err := to.ListPublicKeyRecords(context.Background(), nil, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, fromRecords, toRecords)
+
+	// the destination audit log starts with the source audit log, followed by the copies
+	toAudit, err := to.ListAuditRecords(context.Background(), 0, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, fromAudit, toAudit[:len(fromAudit)])
+	for _, r := range toAudit[len(fromAudit):] {
+		assert.Equal(t, api.AuditAction_IMPORT, r.Action)
+	}
+
+	// resuming from the checkpoint has nothing left to copy
+	counts, err = m.migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, &migrationCounts{nAuditDuplicate: len(fromAudit)}, counts)
+}
+
+func TestMigrator_migrate_resume(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	dir, err := ioutil.TempDir("", "key-migrate-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	params := storage.NewDefaultParameters()
+	from := memory.New(params, zap.NewNop())
+	err = from.PutPublicKeyRecords(context.Background(), newTestPublicKeyRecords(rng, 10), nil)
+	assert.Nil(t, err)
+	fromRecords, err := from.ListPublicKeyRecords(context.Background(), nil, 20)
+	assert.Nil(t, err)
+
+	checkpointFn := filepath.Join(dir, "checkpoint")
+	err = writeCheckpoint(checkpointFn, fromRecords[5].PublicKeyDetail.PublicKey)
+	assert.Nil(t, err)
+	m := &migrator{
+		from:         from,
+		to:           memory.New(params, zap.NewNop()),
+		batchSize:    4,
+		checkpointFn: checkpointFn,
+		logger:       zap.NewNop(),
+	}
+	counts, err := m.migrate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 4, counts.nCopied)
+	toRecords, err := m.to.ListPublicKeyRecords(context.Background(), nil, 20)
+	assert.Nil(t, err)
+	assert.Equal(t, fromRecords[6:], toRecords)
+}
+
+func TestMigrator_migrate_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := newTestPublicKeyRecords(rng, 2)
+	changed := *records[1]
+	changed.DisabledTime++
+	audit := importAuditRecords(records)
+	storage.ChainAuditRecords(nil, audit)
+	otherAudit := api.NewTestAuditRecords(rng, 1)
+	storage.ChainAuditRecords(nil, otherAudit)
+	cases := map[string]struct {
+		from     storage.Storer
+		to       storage.Storer
+		expected error
+	}{
+		"list error": {
+			from:     &fixedMigrateStorer{listErr: errTest},
+			to:       &fixedMigrateStorer{},
+			expected: errTest,
+		},
+		"put error": {
+			from:     &fixedMigrateStorer{listRecords: records},
+			to:       &fixedMigrateStorer{putErr: errTest},
+			expected: errTest,
+		},
+		"verify list error": {
+			from:     &fixedMigrateStorer{listRecords: records},
+			to:       &fixedMigrateStorer{listErr: errTest},
+			expected: errTest,
+		},
+		"unverified": {
+			from:     &fixedMigrateStorer{listRecords: records},
+			to:       &fixedMigrateStorer{listRecords: records[:1]},
+			expected: errMigrationUnverified,
+		},
+		"different": {
+			from: &fixedMigrateStorer{listRecords: records},
+			to: &fixedMigrateStorer{
+				listRecords: []*api.PublicKeyRecord{records[0], &changed},
+			},
+			expected: errMigrationUnverified,
+		},
+		"list audit error": {
+			from:     &fixedMigrateStorer{listAuditErr: errTest},
+			to:       &fixedMigrateStorer{},
+			expected: errTest,
+		},
+		"destination list audit error": {
+			from:     &fixedMigrateStorer{auditRecords: audit},
+			to:       &fixedMigrateStorer{listAuditErr: errTest},
+			expected: errTest,
+		},
+		"append audit error": {
+			from:     &fixedMigrateStorer{auditRecords: audit},
+			to:       &fixedMigrateStorer{appendAuditErr: errTest},
+			expected: errTest,
+		},
+		"audit diverged": {
+			from:     &fixedMigrateStorer{auditRecords: audit},
+			to:       &fixedMigrateStorer{auditRecords: otherAudit},
+			expected: errAuditLogDiverged,
+		},
+	}
+	for desc, c := range cases {
+		m := &migrator{from: c.from, to: c.to, batchSize: 4, logger: zap.NewNop()}
+		_, err := m.migrate(context.Background())
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestMigrateStorage_err(t *testing.T) {
+	cases := map[string]struct {
+		from     string
+		to       string
+		expected error
+	}{
+		"unknown source": {
+			from: "other", to: postgresStorage, expected: errUnknownStorageType,
+		},
+		"unknown destination": {
+			from: boltStorage, to: "other", expected: errUnknownStorageType,
+		},
+		"same types": {
+			from: boltStorage, to: boltStorage, expected: errSameStorageTypes,
+		},
+		"memory destination": {
+			from: boltStorage, to: memoryStorage, expected: errMemoryDestination,
+		},
+	}
+	for desc, c := range cases {
+		viper.Set(fromFlag, c.from)
+		viper.Set(toFlag, c.to)
+		assert.Equal(t, c.expected, migrateStorage(), desc)
+	}
+}
+
+func TestReadWriteCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-migrate-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	fn := filepath.Join(dir, "checkpoint")
+
+	after, err := readCheckpoint(fn)
+	assert.Nil(t, err)
+	assert.Nil(t, after)
+
+	err = writeCheckpoint(fn, []byte{1, 2, 3})
+	assert.Nil(t, err)
+	after, err = readCheckpoint(fn)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, after)
+
+	after, err = readCheckpoint("")
+	assert.Nil(t, err)
+	assert.Nil(t, after)
+	assert.Nil(t, writeCheckpoint("", []byte{1, 2, 3}))
+}
+
+func TestParseStorageType(t *testing.T) {
+	cases := map[string]storage.Type{
+		memoryStorage:    storage.Memory,
+		postgresStorage:  storage.Postgres,
+		datastoreStorage: storage.DataStore,
+		boltStorage:      storage.Bolt,
+	}
+	for name, expected := range cases {
+		st, err := parseStorageType(name)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, st, name)
+	}
+	st, err := parseStorageType("other")
+	assert.Equal(t, errUnknownStorageType, err)
+	assert.Equal(t, storage.Unspecified, st)
+}
+
+// fixedMigrateStorer is a storage.Storer with fixed results for the methods a migration uses.
+type fixedMigrateStorer struct {
+	storage.Storer
+	listRecords    []*api.PublicKeyRecord
+	listErr        error
+	putErr         error
+	auditRecords   []*api.AuditRecord
+	listAuditErr   error
+	appendAuditErr error
+}
+
+func (f *fixedMigrateStorer) ListPublicKeyRecords(
+	ctx context.Context, after []byte, limit uint,
+) ([]*api.PublicKeyRecord, error) {
+	if after != nil {
+		return nil, f.listErr
+	}
+	return f.listRecords, f.listErr
+}
+
+func (f *fixedMigrateStorer) PutPublicKeyRecords(
+	ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+) error {
+	return f.putErr
+}
+
+func (f *fixedMigrateStorer) AppendAuditRecords(
+	ctx context.Context, records []*api.AuditRecord,
+) error {
+	if f.appendAuditErr != nil {
+		return f.appendAuditErr
+	}
+	var last *api.AuditRecord
+	if len(f.auditRecords) > 0 {
+		last = f.auditRecords[len(f.auditRecords)-1]
+	}
+	storage.ChainAuditRecords(last, records)
+	f.auditRecords = append(f.auditRecords, records...)
+	return nil
+}
+
+func (f *fixedMigrateStorer) ListAuditRecords(
+	ctx context.Context, after uint64, limit uint,
+) ([]*api.AuditRecord, error) {
+	if f.listAuditErr != nil {
+		return nil, f.listAuditErr
+	}
+	if after >= uint64(len(f.auditRecords)) {
+		return []*api.AuditRecord{}, nil
+	}
+	records := f.auditRecords[after:]
+	if uint(len(records)) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
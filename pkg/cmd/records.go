@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// delimitedFormat is a file of public key records, each a protobuf preceded by its uvarint
+	// length.
+	delimitedFormat = "delimited"
+
+	// jsonlFormat is a file of public key records, each a JSON object on its own line.
+	jsonlFormat = "jsonl"
+)
+
+var (
+	errUnknownFormat = errors.New("unknown public key records file format")
+)
+
+// recordWriter writes public key records to a file in some format.
+type recordWriter interface {
+	Write(r *api.PublicKeyRecord) error
+}
+
+// recordReader reads public key records from a file in some format. Read returns io.EOF when
+// there are no more records.
+type recordReader interface {
+	Read() (*api.PublicKeyRecord, error)
+}
+
+func newRecordWriter(w io.Writer, format string) (recordWriter, error) {
+	switch format {
+	case delimitedFormat:
+		return &delimitedWriter{w: w}, nil
+	case jsonlFormat:
+		return &jsonlWriter{w: w, m: &jsonpb.Marshaler{}}, nil
+	default:
+		return nil, errUnknownFormat
+	}
+}
+
+func newRecordReader(r io.Reader, format string) (recordReader, error) {
+	switch format {
+	case delimitedFormat:
+		return &delimitedReader{r: bufio.NewReader(r)}, nil
+	case jsonlFormat:
+		return &jsonlReader{r: bufio.NewReader(r)}, nil
+	default:
+		return nil, errUnknownFormat
+	}
+}
+
+type delimitedWriter struct {
+	w io.Writer
+}
+
+func (dw *delimitedWriter) Write(r *api.PublicKeyRecord) error {
+	b, err := proto.Marshal(r)
+	if err != nil {
+		return err
+	}
+	size := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(size, uint64(len(b)))
+	if _, err := dw.w.Write(size[:n]); err != nil {
+		return err
+	}
+	_, err = dw.w.Write(b)
+	return err
+}
+
+type delimitedReader struct {
+	r *bufio.Reader
+}
+
+func (dr *delimitedReader) Read() (*api.PublicKeyRecord, error) {
+	size, err := binary.ReadUvarint(dr.r)
+	if err != nil {
+		// io.EOF only when there are no more records, since it's returned only if no bytes
+		// were read
+		return nil, err
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(dr.r, b); err != nil {
+		return nil, err
+	}
+	r := &api.PublicKeyRecord{}
+	if err := proto.Unmarshal(b, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type jsonlWriter struct {
+	w io.Writer
+	m *jsonpb.Marshaler
+}
+
+func (jw *jsonlWriter) Write(r *api.PublicKeyRecord) error {
+	if err := jw.m.Marshal(jw.w, r); err != nil {
+		return err
+	}
+	_, err := jw.w.Write([]byte{'\n'})
+	return err
+}
+
+type jsonlReader struct {
+	r *bufio.Reader
+}
+
+func (jr *jsonlReader) Read() (*api.PublicKeyRecord, error) {
+	for {
+		line, err := jr.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			// skip blank lines
+			continue
+		}
+		r := &api.PublicKeyRecord{}
+		if err := jsonpb.Unmarshal(bytes.NewReader(line), r); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordWriterReader(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := newTestPublicKeyRecords(rng, 8)
+	for _, format := range []string{delimitedFormat, jsonlFormat} {
+		buf := new(bytes.Buffer)
+		rw, err := newRecordWriter(buf, format)
+		assert.Nil(t, err, format)
+		for _, r := range records {
+			err = rw.Write(r)
+			assert.Nil(t, err, format)
+		}
+
+		rr, err := newRecordReader(buf, format)
+		assert.Nil(t, err, format)
+		for _, r1 := range records {
+			r2, err := rr.Read()
+			assert.Nil(t, err, format)
+			assert.Equal(t, r1, r2, format)
+		}
+		r, err := rr.Read()
+		assert.Equal(t, io.EOF, err, format)
+		assert.Nil(t, r, format)
+	}
+}
+
+func TestRecordWriterReader_err(t *testing.T) {
+	rw, err := newRecordWriter(new(bytes.Buffer), "other format")
+	assert.Equal(t, errUnknownFormat, err)
+	assert.Nil(t, rw)
+
+	rr, err := newRecordReader(new(bytes.Buffer), "other format")
+	assert.Equal(t, errUnknownFormat, err)
+	assert.Nil(t, rr)
+
+	// truncated record
+	rr, err = newRecordReader(bytes.NewReader([]byte{10, 1, 2}), delimitedFormat)
+	assert.Nil(t, err)
+	r, err := rr.Read()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Nil(t, r)
+
+	// not JSON
+	rr, err = newRecordReader(bytes.NewReader([]byte("not JSON\n")), jsonlFormat)
+	assert.Nil(t, err)
+	r, err = rr.Read()
+	assert.NotNil(t, err)
+	assert.Nil(t, r)
+}
+
+func newTestPublicKeyRecords(rng *rand.Rand, n int) []*api.PublicKeyRecord {
+	added := time.Unix(1520000000, 0)
+	records := make([]*api.PublicKeyRecord, n)
+	for i, pkd := range api.NewTestPublicKeyDetails(rng, n) {
+		pkd.Disabled = i%2 == 0
+		records[i] = storage.NewPublicKeyRecord(pkd, added, added.Add(time.Hour))
+	}
+	return records
+}
@@ -62,6 +62,7 @@ func testIO() error {
 			EntityId:   entityID,
 			KeyType:    api.KeyType_AUTHOR,
 			PublicKeys: authorKeys,
+			KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
 		}
 		client := clients[rng.Int31n(int32(len(clients)))]
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -75,6 +76,7 @@ func testIO() error {
 			EntityId:   entityID,
 			KeyType:    api.KeyType_READER,
 			PublicKeys: readerKeys,
+			KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
 		}
 		client = clients[rng.Int31n(int32(len(clients)))]
 		ctx, cancel = context.WithTimeout(context.Background(), timeout)
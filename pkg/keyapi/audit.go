@@ -0,0 +1,151 @@
+package keyapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math/rand"
+
+	"github.com/pkg/errors"
+)
+
+// auditRecordDomain separates audit record hashes from any other SHA-256 hashes of the same
+// fields.
+const auditRecordDomain = "elixirhealth key audit record"
+
+var (
+	// ErrEmptyAuditRecord indicates when an audit record is missing.
+	ErrEmptyAuditRecord = errors.New("empty audit record value")
+
+	// ErrAuditSequenceGap indicates when an audit record's sequence number doesn't immediately
+	// follow the previous record's, i.e., when records are missing or out of order.
+	ErrAuditSequenceGap = errors.New("audit record sequence number doesn't follow previous " +
+		"record's")
+
+	// ErrAuditChainBroken indicates when an audit record's previous hash isn't the hash of the
+	// previous record.
+	ErrAuditChainBroken = errors.New("audit record previous hash doesn't match previous " +
+		"record's hash")
+
+	// ErrAuditRecordEdited indicates when an audit record's hash doesn't match its contents.
+	ErrAuditRecordEdited = errors.New("audit record hash doesn't match its contents")
+
+	// ErrAuditCheckpointMismatch indicates when the audit record at a checkpoint's sequence
+	// number doesn't have the checkpoint's hash or is missing.
+	ErrAuditCheckpointMismatch = errors.New("audit record at checkpoint sequence number " +
+		"doesn't match checkpoint hash")
+
+	// ErrIncompleteCheckpoint indicates when a checkpoint has only one of a sequence number
+	// and a hash.
+	ErrIncompleteCheckpoint = errors.New("checkpoint needs both sequence number and hash")
+)
+
+// PublicKeyHashes returns the SHA-256 hash of each public key, which audit records have instead
+// of the public keys themselves.
+func PublicKeyHashes(pks [][]byte) [][]byte {
+	hashes := make([][]byte, len(pks))
+	for i, pk := range pks {
+		h := sha256.Sum256(pk)
+		hashes[i] = h[:]
+	}
+	return hashes
+}
+
+// AuditRecordHash returns the SHA-256 hash of the audit record's fields other than its hash,
+// including the previous record's hash. Each field is length-prefixed, so no two audit records
+// with different fields have the same encoding.
+func AuditRecordHash(r *AuditRecord) []byte {
+	h := sha256.New()
+	writeLengthPrefixed(h, []byte(auditRecordDomain))
+	writeUint64(h, r.Sequence)
+	writeUint64(h, uint64(r.Time))
+	writeLengthPrefixed(h, []byte(r.CallerEntityId))
+	writeLengthPrefixed(h, []byte(r.Action.String()))
+	writeLengthPrefixed(h, []byte(r.EntityId))
+	writeLengthPrefixed(h, []byte(r.KeyType.String()))
+	writeUint64(h, uint64(len(r.AddedPublicKeyHashes)))
+	for _, pkh := range r.AddedPublicKeyHashes {
+		writeLengthPrefixed(h, pkh)
+	}
+	writeUint64(h, uint64(len(r.DisabledPublicKeyHashes)))
+	for _, pkh := range r.DisabledPublicKeyHashes {
+		writeLengthPrefixed(h, pkh)
+	}
+	writeLengthPrefixed(h, r.PrevHash)
+	return h.Sum(nil)
+}
+
+// ChainAuditRecord sets the audit record's sequence number, previous hash, and hash so it
+// follows the previous record, or so it is the first record if the previous one is nil.
+func ChainAuditRecord(prev, r *AuditRecord) {
+	r.Sequence, r.PrevHash = 1, nil
+	if prev != nil {
+		r.Sequence, r.PrevHash = prev.Sequence+1, prev.Hash
+	}
+	r.Hash = AuditRecordHash(r)
+}
+
+// VerifyAuditRecord checks that the audit record follows the previous one, or is the first record
+// if the previous one is nil, and that its hash matches its contents.
+func VerifyAuditRecord(prev, r *AuditRecord) error {
+	expectedSeq, expectedPrevHash := uint64(1), []byte(nil)
+	if prev != nil {
+		expectedSeq, expectedPrevHash = prev.Sequence+1, prev.Hash
+	}
+	if r.Sequence != expectedSeq {
+		return ErrAuditSequenceGap
+	}
+	if !bytes.Equal(r.PrevHash, expectedPrevHash) {
+		return ErrAuditChainBroken
+	}
+	if !bytes.Equal(r.Hash, AuditRecordHash(r)) {
+		return ErrAuditRecordEdited
+	}
+	return nil
+}
+
+// ValidateAuditRecords checks that each audit record is present and has an entity ID.
+func ValidateAuditRecords(records []*AuditRecord) error {
+	for _, r := range records {
+		if r == nil {
+			return ErrEmptyAuditRecord
+		}
+		if r.EntityId == "" {
+			return ErrEmptyEntityID
+		}
+	}
+	return nil
+}
+
+// ValidateVerifyAuditLogRequest checks that the request's checkpoint, if any, has both a sequence
+// number and a hash.
+func ValidateVerifyAuditLogRequest(rq *VerifyAuditLogRequest) error {
+	if (rq.CheckpointSequence == 0) != (len(rq.CheckpointHash) == 0) {
+		return ErrIncompleteCheckpoint
+	}
+	return nil
+}
+
+// NewTestAuditRecords creates n random, unchained audit records for use in testing.
+func NewTestAuditRecords(rng *rand.Rand, n int) []*AuditRecord {
+	records := make([]*AuditRecord, n)
+	for i := range records {
+		pkd := NewTestPublicKeyDetail(rng)
+		records[i] = &AuditRecord{
+			Time:                 rng.Int63(),
+			CallerEntityId:       pkd.EntityId,
+			Action:               AuditAction_ADD,
+			EntityId:             pkd.EntityId,
+			KeyType:              pkd.KeyType,
+			AddedPublicKeyHashes: PublicKeyHashes([][]byte{pkd.PublicKey}),
+		}
+	}
+	return records
+}
+
+func writeUint64(h hash.Hash, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	_, _ = h.Write(b[:])
+}
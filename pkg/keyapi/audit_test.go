@@ -0,0 +1,127 @@
+package keyapi
+
+import (
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicKeyHashes(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pks := [][]byte{NewTestPublicKey(rng), NewTestPublicKey(rng)}
+	hashes := PublicKeyHashes(pks)
+	assert.Len(t, hashes, len(pks))
+	for i, pk := range pks {
+		h := sha256.Sum256(pk)
+		assert.Equal(t, h[:], hashes[i])
+	}
+}
+
+func TestAuditRecordHash(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	r := NewTestAuditRecords(rng, 1)[0]
+	h := AuditRecordHash(r)
+	assert.Len(t, h, sha256.Size)
+
+	// hash doesn't cover the hash itself
+	r.Hash = h
+	assert.Equal(t, h, AuditRecordHash(r))
+
+	edits := map[string]func(r *AuditRecord){
+		"sequence":  func(r *AuditRecord) { r.Sequence++ },
+		"time":      func(r *AuditRecord) { r.Time++ },
+		"caller":    func(r *AuditRecord) { r.CallerEntityId = "other caller" },
+		"action":    func(r *AuditRecord) { r.Action = AuditAction_REVOKE },
+		"entity ID": func(r *AuditRecord) { r.EntityId = "other entity ID" },
+		"key type":  func(r *AuditRecord) { r.KeyType = 1 - r.KeyType },
+		"prev hash": func(r *AuditRecord) { r.PrevHash = []byte{1} },
+		"added public key hashes": func(r *AuditRecord) {
+			r.AddedPublicKeyHashes = append(r.AddedPublicKeyHashes, []byte{1})
+		},
+		"moved public key hash": func(r *AuditRecord) {
+			r.DisabledPublicKeyHashes = r.AddedPublicKeyHashes
+			r.AddedPublicKeyHashes = nil
+		},
+	}
+	for desc, edit := range edits {
+		edited := *r
+		edit(&edited)
+		assert.NotEqual(t, h, AuditRecordHash(&edited), desc)
+	}
+}
+
+func TestChainVerifyAuditRecord_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := NewTestAuditRecords(rng, 4)
+	var prev *AuditRecord
+	for i, r := range records {
+		ChainAuditRecord(prev, r)
+		assert.Equal(t, uint64(i+1), r.Sequence)
+		assert.Nil(t, VerifyAuditRecord(prev, r))
+		prev = r
+	}
+	assert.Nil(t, records[0].PrevHash)
+	assert.Equal(t, records[2].Hash, records[3].PrevHash)
+}
+
+func TestVerifyAuditRecord_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := NewTestAuditRecords(rng, 3)
+	var prev *AuditRecord
+	for _, r := range records {
+		ChainAuditRecord(prev, r)
+		prev = r
+	}
+	edited := *records[1]
+	edited.EntityId = "other entity ID"
+	rehashed := edited
+	rehashed.Hash = AuditRecordHash(&rehashed)
+	rechained := *records[2]
+	rechained.PrevHash = rehashed.Hash
+
+	cases := map[string]struct {
+		prev, r  *AuditRecord
+		expected error
+	}{
+		"first not first":  {r: records[1], expected: ErrAuditSequenceGap},
+		"gap":              {prev: records[0], r: records[2], expected: ErrAuditSequenceGap},
+		"edited":           {prev: records[0], r: &edited, expected: ErrAuditRecordEdited},
+		"rehashed edit":    {prev: &rehashed, r: records[2], expected: ErrAuditChainBroken},
+		"rechained record": {prev: records[1], r: &rechained, expected: ErrAuditChainBroken},
+	}
+	for desc, c := range cases {
+		assert.Equal(t, c.expected, VerifyAuditRecord(c.prev, c.r), desc)
+	}
+}
+
+func TestValidateAuditRecords(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	assert.Nil(t, ValidateAuditRecords(NewTestAuditRecords(rng, 2)))
+	assert.Equal(t, ErrEmptyAuditRecord, ValidateAuditRecords([]*AuditRecord{nil}))
+	assert.Equal(t, ErrEmptyEntityID, ValidateAuditRecords([]*AuditRecord{{}}))
+}
+
+func TestValidateVerifyAuditLogRequest(t *testing.T) {
+	cases := map[string]struct {
+		rq       *VerifyAuditLogRequest
+		expected error
+	}{
+		"no checkpoint": {rq: &VerifyAuditLogRequest{}},
+		"checkpoint": {
+			rq: &VerifyAuditLogRequest{CheckpointSequence: 1, CheckpointHash: []byte{1}},
+		},
+		"missing hash": {
+			rq:       &VerifyAuditLogRequest{CheckpointSequence: 1},
+			expected: ErrIncompleteCheckpoint,
+		},
+		"missing sequence": {
+			rq:       &VerifyAuditLogRequest{CheckpointHash: []byte{1}},
+			expected: ErrIncompleteCheckpoint,
+		},
+	}
+	for desc, c := range cases {
+		assert.Equal(t, c.expected, ValidateVerifyAuditLogRequest(c.rq), desc)
+	}
+}
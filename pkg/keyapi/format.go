@@ -0,0 +1,201 @@
+package keyapi
+
+import (
+	"math/big"
+	"math/rand"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Secp256k1CompressedLength is the length of a compressed secp256k1 public key.
+	Secp256k1CompressedLength = 33
+
+	// Ed25519Length is the length of an Ed25519 public key.
+	Ed25519Length = 32
+
+	// X25519Length is the length of an X25519 public key.
+	X25519Length = 32
+)
+
+var (
+	// ErrUnknownKeyFormat indicates when a public key format has no validator.
+	ErrUnknownKeyFormat = errors.New("unknown public key format")
+
+	// ErrUnspecifiedKeyFormat indicates when a request adding public keys doesn't specify their
+	// format, so they can't be validated.
+	ErrUnspecifiedKeyFormat = errors.New("unspecified public key format")
+
+	// ErrInvalidPublicKeyLength indicates when a public key doesn't have the length of its
+	// format.
+	ErrInvalidPublicKeyLength = errors.New("invalid public key length")
+
+	// ErrInvalidPublicKeyPrefix indicates when a compressed secp256k1 public key doesn't start
+	// with 0x02 or 0x03.
+	ErrInvalidPublicKeyPrefix = errors.New("invalid compressed public key prefix")
+
+	// ErrNonCanonicalPublicKey indicates when a public key's coordinate isn't reduced modulo the
+	// field prime.
+	ErrNonCanonicalPublicKey = errors.New("public key coordinate not less than field prime")
+
+	// ErrPublicKeyNotOnCurve indicates when a public key doesn't encode a point on its curve.
+	ErrPublicKeyNotOnCurve = errors.New("public key is not a point on the curve")
+
+	// ErrLowOrderPublicKey indicates when an X25519 public key is a point of small order, which
+	// would make any shared secret computed with it predictable.
+	ErrLowOrderPublicKey = errors.New("public key is a low-order point")
+)
+
+// PublicKeyValidator checks that a public key is a valid encoding for a particular key format.
+type PublicKeyValidator interface {
+	Validate(pk []byte) error
+}
+
+// PublicKeyValidatorFunc is a PublicKeyValidator for a function.
+type PublicKeyValidatorFunc func(pk []byte) error
+
+// Validate calls the function on the public key.
+func (f PublicKeyValidatorFunc) Validate(pk []byte) error {
+	return f(pk)
+}
+
+var publicKeyValidators = map[KeyFormat]PublicKeyValidator{
+	KeyFormat_SECP256K1_COMPRESSED: PublicKeyValidatorFunc(ValidateSecp256k1Compressed),
+	KeyFormat_ED25519:              PublicKeyValidatorFunc(ValidateEd25519),
+	KeyFormat_X25519:               PublicKeyValidatorFunc(ValidateX25519),
+}
+
+// RegisterPublicKeyValidator sets the validator used for public keys of the given format,
+// replacing any existing one. It isn't safe to call concurrently with validation, so it should
+// only be called during initialization.
+func RegisterPublicKeyValidator(kf KeyFormat, v PublicKeyValidator) {
+	publicKeyValidators[kf] = v
+}
+
+// ValidatePublicKeyFormat checks that each public key is valid for the given key format. The
+// returned error identifies the first invalid public key and why it's invalid. Public keys of an
+// unspecified format are rejected rather than left unvalidated.
+func ValidatePublicKeyFormat(kf KeyFormat, pks [][]byte) error {
+	if kf == KeyFormat_UNSPECIFIED_FORMAT {
+		return ErrUnspecifiedKeyFormat
+	}
+	v, in := publicKeyValidators[kf]
+	if !in {
+		return ErrUnknownKeyFormat
+	}
+	for i, pk := range pks {
+		if err := v.Validate(pk); err != nil {
+			return errors.Wrapf(err, "%s public key %d", kf, i)
+		}
+	}
+	return nil
+}
+
+var (
+	ed25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	ed25519D = fromHex("52036cee2b6ffe738cc740797779e89800700a4d4141d8ab75eb4dca135978a3")
+
+	// x25519LowOrder contains the u-coordinates of the points of small order on Curve25519
+	x25519LowOrder = []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		fromHex("00b8495f16056286fdb1329ceb8d09da6ac49ff1fae35616aeb8413b7c7aebe0"),
+		fromHex("57119fd0dd4e22d8868e1c58c45c44045bef839c55b1d0b1248c50a3bc959c5f"),
+		new(big.Int).Sub(ed25519P, big.NewInt(1)),
+	}
+)
+
+// ValidateSecp256k1Compressed checks that the public key is a compressed secp256k1 point, i.e.,
+// a 0x02 or 0x03 prefix followed by a big-endian x-coordinate of a point on the curve.
+func ValidateSecp256k1Compressed(pk []byte) error {
+	if len(pk) != Secp256k1CompressedLength {
+		return errors.Wrapf(ErrInvalidPublicKeyLength, "got %d bytes, expected %d", len(pk),
+			Secp256k1CompressedLength)
+	}
+	if pk[0] != 0x02 && pk[0] != 0x03 {
+		return errors.Wrapf(ErrInvalidPublicKeyPrefix, "got 0x%02x", pk[0])
+	}
+	if new(big.Int).SetBytes(pk[1:]).Cmp(btcec.S256().P) >= 0 {
+		return ErrNonCanonicalPublicKey
+	}
+	if _, err := btcec.ParsePubKey(pk, btcec.S256()); err != nil {
+		return errors.Wrap(ErrPublicKeyNotOnCurve, err.Error())
+	}
+	return nil
+}
+
+// ValidateEd25519 checks that the public key is an Ed25519 point encoded as in RFC 8032, i.e., a
+// little-endian y-coordinate whose top bit holds the sign of the x-coordinate.
+func ValidateEd25519(pk []byte) error {
+	if len(pk) != Ed25519Length {
+		return errors.Wrapf(ErrInvalidPublicKeyLength, "got %d bytes, expected %d", len(pk),
+			Ed25519Length)
+	}
+	le := append([]byte{}, pk...)
+	xOdd := le[Ed25519Length-1]&0x80 != 0
+	le[Ed25519Length-1] &= 0x7f
+	y := fromLittleEndian(le)
+	if y.Cmp(ed25519P) >= 0 {
+		return ErrNonCanonicalPublicKey
+	}
+
+	// x^2 = (y^2 - 1) / (d y^2 + 1)
+	y2 := new(big.Int).Mul(y, y)
+	u := new(big.Int).Sub(y2, big.NewInt(1))
+	v := new(big.Int).Mul(ed25519D, y2)
+	v.Add(v, big.NewInt(1)).Mod(v, ed25519P)
+	x2 := new(big.Int).ModInverse(v, ed25519P)
+	x2.Mul(x2, u).Mod(x2, ed25519P)
+	if x2.Sign() == 0 {
+		if xOdd {
+			// x = 0 has no negative
+			return ErrPublicKeyNotOnCurve
+		}
+		return nil
+	}
+	if new(big.Int).ModSqrt(x2, ed25519P) == nil {
+		return ErrPublicKeyNotOnCurve
+	}
+	return nil
+}
+
+// ValidateX25519 checks that the public key is an X25519 u-coordinate as in RFC 7748 that isn't
+// a point of small order.
+func ValidateX25519(pk []byte) error {
+	if len(pk) != X25519Length {
+		return errors.Wrapf(ErrInvalidPublicKeyLength, "got %d bytes, expected %d", len(pk),
+			X25519Length)
+	}
+	le := append([]byte{}, pk...)
+	le[X25519Length-1] &= 0x7f // RFC 7748 ignores the top bit
+	u := fromLittleEndian(le)
+	u.Mod(u, ed25519P)
+	for _, lowOrder := range x25519LowOrder {
+		if u.Cmp(lowOrder) == 0 {
+			return ErrLowOrderPublicKey
+		}
+	}
+	return nil
+}
+
+// NewTestPublicKey creates a random compressed secp256k1 public key for use in testing.
+func NewTestPublicKey(rng *rand.Rand) []byte {
+	return newTestSecp256k1Key(rng).PubKey().SerializeCompressed()
+}
+
+func fromHex(h string) *big.Int {
+	i, ok := new(big.Int).SetString(h, 16)
+	if !ok {
+		panic("invalid hex: " + h)
+	}
+	return i
+}
+
+func fromLittleEndian(le []byte) *big.Int {
+	be := make([]byte, len(le))
+	for i, b := range le {
+		be[len(le)-1-i] = b
+	}
+	return new(big.Int).SetBytes(be)
+}
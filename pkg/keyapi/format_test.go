@@ -0,0 +1,160 @@
+package keyapi
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePublicKeyFormat(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pks := [][]byte{NewTestPublicKey(rng), NewTestPublicKey(rng)}
+
+	err := ValidatePublicKeyFormat(KeyFormat_SECP256K1_COMPRESSED, pks)
+	assert.Nil(t, err)
+
+	err = ValidatePublicKeyFormat(KeyFormat_ED25519, pks)
+	assert.Equal(t, ErrInvalidPublicKeyLength, errors.Cause(err))
+	assert.Contains(t, err.Error(), "ED25519 public key 0")
+
+	err = ValidatePublicKeyFormat(KeyFormat(-1), pks)
+	assert.Equal(t, ErrUnknownKeyFormat, err)
+
+	// public keys of an unspecified format are rejected
+	err = ValidatePublicKeyFormat(KeyFormat_UNSPECIFIED_FORMAT, [][]byte{{1, 2, 3}})
+	assert.Equal(t, ErrUnspecifiedKeyFormat, err)
+}
+
+func TestRegisterPublicKeyValidator(t *testing.T) {
+	kf := KeyFormat(-1)
+	defer delete(publicKeyValidators, kf)
+	RegisterPublicKeyValidator(kf, PublicKeyValidatorFunc(func(pk []byte) error {
+		return nil
+	}))
+	err := ValidatePublicKeyFormat(kf, [][]byte{{1, 2, 3}})
+	assert.Nil(t, err)
+}
+
+func TestValidateSecp256k1Compressed(t *testing.T) {
+	cases := map[string]struct {
+		pk       string
+		expected error
+	}{
+		"generator": {
+			pk:       "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			expected: nil,
+		},
+		"uncompressed": {
+			pk: "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" +
+				"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+			expected: ErrInvalidPublicKeyLength,
+		},
+		"bad prefix": {
+			pk:       "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			expected: ErrInvalidPublicKeyPrefix,
+		},
+		"x not less than p": {
+			pk:       "02fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f",
+			expected: ErrNonCanonicalPublicKey,
+		},
+		"not on curve": {
+			pk:       "020000000000000000000000000000000000000000000000000000000000000005",
+			expected: ErrPublicKeyNotOnCurve,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateSecp256k1Compressed(mustDecodeHex(c.pk))
+		assert.Equal(t, c.expected, errors.Cause(err), desc)
+	}
+}
+
+func TestValidateEd25519(t *testing.T) {
+	cases := map[string]struct {
+		pk       string
+		expected error
+	}{
+		"RFC 8032 test 1": {
+			pk:       "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			expected: nil,
+		},
+		"RFC 8032 test 2": {
+			pk:       "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			expected: nil,
+		},
+		"too short": {
+			pk:       "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f70751",
+			expected: ErrInvalidPublicKeyLength,
+		},
+		"y not less than p": {
+			pk:       "edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+			expected: ErrNonCanonicalPublicKey,
+		},
+		"not on curve": {
+			pk:       "0200000000000000000000000000000000000000000000000000000000000000",
+			expected: ErrPublicKeyNotOnCurve,
+		},
+		"negative zero x": {
+			pk:       "0100000000000000000000000000000000000000000000000000000000000080",
+			expected: ErrPublicKeyNotOnCurve,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateEd25519(mustDecodeHex(c.pk))
+		assert.Equal(t, c.expected, errors.Cause(err), desc)
+	}
+}
+
+func TestValidateX25519(t *testing.T) {
+	cases := map[string]struct {
+		pk       string
+		expected error
+	}{
+		"RFC 7748 Alice": {
+			pk:       "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a",
+			expected: nil,
+		},
+		"RFC 7748 Bob": {
+			pk:       "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f",
+			expected: nil,
+		},
+		"too long": {
+			pk:       "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a00",
+			expected: ErrInvalidPublicKeyLength,
+		},
+		"zero": {
+			pk:       "0000000000000000000000000000000000000000000000000000000000000000",
+			expected: ErrLowOrderPublicKey,
+		},
+		"order 8": {
+			pk:       "e0eb7a7c3b41b8ae1656e3faf19fc46ada098deb9c32b1fd866205165f49b800",
+			expected: ErrLowOrderPublicKey,
+		},
+		"p + 1": {
+			pk:       "eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+			expected: ErrLowOrderPublicKey,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateX25519(mustDecodeHex(c.pk))
+		assert.Equal(t, c.expected, errors.Cause(err), desc)
+	}
+}
+
+func TestNewTestPublicKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 16; i++ {
+		err := ValidateSecp256k1Compressed(NewTestPublicKey(rng))
+		assert.Nil(t, err)
+	}
+}
+
+func mustDecodeHex(h string) []byte {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"math/rand"
 
-	"github.com/elixirhealth/service-base/pkg/util"
 	"github.com/pkg/errors"
 )
 
@@ -13,6 +12,18 @@ const (
 	// MaxSamplePublicKeysSize is the maximum number of public keys that an entity can sample
 	// from another entity.
 	MaxSamplePublicKeysSize = 8
+
+	// DefaultListPageSize is the number of public key details returned in a ListPublicKeys
+	// response when the request doesn't specify a page size.
+	DefaultListPageSize = 100
+
+	// MaxListPageSize is the maximum number of public key details returned in a single
+	// ListPublicKeys response.
+	MaxListPageSize = 1000
+
+	// MaxWatchEntityIDs is the maximum number of entity IDs a WatchPublicKeys request can
+	// watch.
+	MaxWatchEntityIDs = 1000
 )
 
 var (
@@ -41,11 +52,54 @@ var (
 	ErrNPublicKeysTooLarge = fmt.Errorf("number of public keys larger than maximum value %d",
 		MaxSamplePublicKeysSize)
 
+	// ErrNegativeAsOfTime indicates when the as-of time (in epoch micros) of a request is
+	// negative.
+	ErrNegativeAsOfTime = errors.New("negative as-of time")
+
+	// ErrNegativeAddedAfter indicates when the added-after time (in epoch micros) of a
+	// ListPublicKeys request is negative.
+	ErrNegativeAddedAfter = errors.New("negative added-after time")
+
+	// ErrNegativeModifiedTime indicates when the modified-after or modified-before time (in
+	// epoch micros) of a list request is negative.
+	ErrNegativeModifiedTime = errors.New("negative modified-after or modified-before time")
+
+	// ErrEmptyModifiedRange indicates when the modified-before time of a list request isn't
+	// after its modified-after time, so no public keys could match.
+	ErrEmptyModifiedRange = errors.New("modified-before time not after modified-after time")
+
+	// ErrPageSizeTooLarge indicates when the page size of a ListPublicKeys request is larger
+	// than the maximum value.
+	ErrPageSizeTooLarge = fmt.Errorf("page size larger than maximum value %d",
+		MaxListPageSize)
+
+	// ErrInvalidPageToken indicates when the page token of a ListPublicKeys request wasn't
+	// returned as a next page token.
+	ErrInvalidPageToken = errors.New("invalid page token")
+
+	// ErrTooManyEntityIDs indicates when a WatchPublicKeys request has more than the maximum
+	// number of entity IDs.
+	ErrTooManyEntityIDs = fmt.Errorf("number of entity IDs larger than maximum value %d",
+		MaxWatchEntityIDs)
+
 	// ErrNoSuchPublicKey indicates when details for a requested public key do not exist.
 	ErrNoSuchPublicKey = errors.New("no details found for given public key")
+
+	// ErrEmptyPublicKeyRecord indicates when a public key record value is nil.
+	ErrEmptyPublicKeyRecord = errors.New("empty public key record value")
+
+	// ErrMissingAddedTime indicates when the added time (in epoch micros) of a public key record
+	// isn't positive.
+	ErrMissingAddedTime = errors.New("missing added time")
+
+	// ErrInvalidDisabledTime indicates when a public key record has a disabled time but isn't
+	// disabled, is disabled without a disabled time, or was disabled before it was added.
+	ErrInvalidDisabledTime = errors.New("disabled time inconsistent with disabled state or " +
+		"added time")
 )
 
-// ValidateAddPublicKeysRequest checks that the request has the entity ID and public keys present.
+// ValidateAddPublicKeysRequest checks that the request has the entity ID and public keys present
+// and that the public keys are valid for the request's key format.
 func ValidateAddPublicKeysRequest(rq *AddPublicKeysRequest) error {
 	if rq.EntityId == "" {
 		return ErrEmptyEntityID
@@ -53,22 +107,64 @@ func ValidateAddPublicKeysRequest(rq *AddPublicKeysRequest) error {
 	if err := ValidatePublicKeys(rq.PublicKeys); err != nil {
 		return err
 	}
-	return nil
+	return ValidatePublicKeyFormat(rq.KeyFormat, rq.PublicKeys)
 }
 
-// ValidateGetPublicKeysRequest checks that the entity ID field is not empty.
+// ValidateGetPublicKeysRequest checks that the entity ID field is not empty and the as-of time
+// is not negative.
 func ValidateGetPublicKeysRequest(rq *GetPublicKeysRequest) error {
 	if rq.EntityId == "" {
 		return ErrEmptyEntityID
 	}
+	if rq.AsOfTime < 0 {
+		return ErrNegativeAsOfTime
+	}
 	return nil
 }
 
-// ValidateGetPublicKeyDetailsRequest checks that the request has the public keys present.
+// ValidateGetPublicKeyDetailsRequest checks that the request has the public keys present and a
+// non-negative as-of time.
 func ValidateGetPublicKeyDetailsRequest(rq *GetPublicKeyDetailsRequest) error {
+	if rq.AsOfTime < 0 {
+		return ErrNegativeAsOfTime
+	}
+	return ValidatePublicKeys(rq.PublicKeys)
+}
+
+// ValidateRevokePublicKeysRequest checks that the request has the entity ID and public keys
+// present.
+func ValidateRevokePublicKeysRequest(rq *RevokePublicKeysRequest) error {
+	if rq.EntityId == "" {
+		return ErrEmptyEntityID
+	}
 	return ValidatePublicKeys(rq.PublicKeys)
 }
 
+// ValidateRotatePublicKeysRequest checks that the request has the entity ID and old and new public
+// keys present, that the new public keys are valid for the request's new key format, and that no
+// public key is in both the old and new lists.
+func ValidateRotatePublicKeysRequest(rq *RotatePublicKeysRequest) error {
+	if rq.EntityId == "" {
+		return ErrEmptyEntityID
+	}
+	if err := ValidatePublicKeys(rq.OldPublicKeys); err != nil {
+		return err
+	}
+	if err := ValidatePublicKeys(rq.NewPublicKeys); err != nil {
+		return err
+	}
+	oldPKs := map[string]struct{}{}
+	for _, pk := range rq.OldPublicKeys {
+		oldPKs[hex.EncodeToString(pk)] = struct{}{}
+	}
+	for _, pk := range rq.NewPublicKeys {
+		if _, in := oldPKs[hex.EncodeToString(pk)]; in {
+			return ErrDupPublicKeys
+		}
+	}
+	return ValidatePublicKeyFormat(rq.NewKeyFormat, rq.NewPublicKeys)
+}
+
 // ValidateSamplePublicKeysRequest checks that the request has the entity IDs and number of public
 // keys present.
 func ValidateSamplePublicKeysRequest(rq *SamplePublicKeysRequest) error {
@@ -87,6 +183,82 @@ func ValidateSamplePublicKeysRequest(rq *SamplePublicKeysRequest) error {
 	return nil
 }
 
+// ValidateListPublicKeysRequest checks that the request has a non-negative added-after time, a
+// non-empty modified time range, a page size no larger than the maximum, and a valid page token,
+// if any.
+func ValidateListPublicKeysRequest(rq *ListPublicKeysRequest) error {
+	if rq.AddedAfter < 0 {
+		return ErrNegativeAddedAfter
+	}
+	if rq.ModifiedAfter < 0 || rq.ModifiedBefore < 0 {
+		return ErrNegativeModifiedTime
+	}
+	if rq.ModifiedBefore != 0 && rq.ModifiedBefore <= rq.ModifiedAfter {
+		return ErrEmptyModifiedRange
+	}
+	if rq.PageSize > MaxListPageSize {
+		return ErrPageSizeTooLarge
+	}
+	if _, err := DecodePageToken(rq.PageToken); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidateImportPublicKeysRequest checks that the request has public key details present, a known
+// key format, and either no signatures or one for each public key detail. It doesn't validate the
+// public key details themselves, since an import rejects invalid ones individually.
+func ValidateImportPublicKeysRequest(rq *ImportPublicKeysRequest) error {
+	if len(rq.PublicKeyDetails) == 0 {
+		return ErrEmptyPublicKeys
+	}
+	if len(rq.Signatures) != 0 && len(rq.Signatures) != len(rq.PublicKeyDetails) {
+		return ErrMissingProofsOfPossession
+	}
+	return ValidatePublicKeyFormat(rq.KeyFormat, nil)
+}
+
+// ValidateExportPublicKeysRequest checks that the request's page size is no larger than the
+// maximum.
+func ValidateExportPublicKeysRequest(rq *ExportPublicKeysRequest) error {
+	if rq.PageSize > MaxListPageSize {
+		return ErrPageSizeTooLarge
+	}
+	return nil
+}
+
+// ValidateWatchPublicKeysRequest checks that the request has no more than the maximum number of
+// entity IDs and that none of them are empty.
+func ValidateWatchPublicKeysRequest(rq *WatchPublicKeysRequest) error {
+	if len(rq.EntityIds) > MaxWatchEntityIDs {
+		return ErrTooManyEntityIDs
+	}
+	for _, entityID := range rq.EntityIds {
+		if entityID == "" {
+			return ErrEmptyEntityID
+		}
+	}
+	return nil
+}
+
+// EncodePageToken returns the page token for the page starting after the given public key.
+func EncodePageToken(lastPK []byte) string {
+	return hex.EncodeToString(lastPK)
+}
+
+// DecodePageToken returns the public key after which the page given by the token starts, or nil
+// if the token is empty.
+func DecodePageToken(token string) ([]byte, error) {
+	if token == "" {
+		return nil, nil
+	}
+	lastPK, err := hex.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	return lastPK, nil
+}
+
 // ValidatePublicKeyDetails checks that the list of public key details isn't empty, has no dups,
 // and has valid public key detail elements.
 func ValidatePublicKeyDetails(pkds []*PublicKeyDetail) error {
@@ -122,6 +294,45 @@ func ValidatePublicKeyDetail(pkd *PublicKeyDetail) error {
 	return nil
 }
 
+// ValidatePublicKeyRecords checks that the list of public key records isn't empty, has no dups,
+// and has valid public key record elements.
+func ValidatePublicKeyRecords(records []*PublicKeyRecord) error {
+	if len(records) == 0 {
+		return ErrEmptyPublicKeys
+	}
+	pks := map[string]struct{}{}
+	for _, r := range records {
+		if err := ValidatePublicKeyRecord(r); err != nil {
+			return err
+		}
+		pkHex := hex.EncodeToString(r.PublicKeyDetail.PublicKey)
+		if _, in := pks[pkHex]; in {
+			return ErrDupPublicKeys
+		}
+		pks[pkHex] = struct{}{}
+	}
+	return nil
+}
+
+// ValidatePublicKeyRecord checks that a public key record has a valid public key detail, an added
+// time, and a disabled time if and only if it is disabled, which isn't before the added time.
+func ValidatePublicKeyRecord(r *PublicKeyRecord) error {
+	if r == nil {
+		return ErrEmptyPublicKeyRecord
+	}
+	if err := ValidatePublicKeyDetail(r.PublicKeyDetail); err != nil {
+		return err
+	}
+	if r.AddedTime <= 0 {
+		return ErrMissingAddedTime
+	}
+	if r.PublicKeyDetail.Disabled != (r.DisabledTime != 0) || (r.DisabledTime != 0 &&
+		r.DisabledTime < r.AddedTime) {
+		return ErrInvalidDisabledTime
+	}
+	return nil
+}
+
 // ValidatePublicKeys checks that a list of public keys is not empty, has no dups, and has
 // non-empty elements.
 func ValidatePublicKeys(pks [][]byte) error {
@@ -150,10 +361,11 @@ func ValidatePublicKey(pk []byte) error {
 	return nil
 }
 
-// NewTestPublicKeyDetail creates a random *PublicKeyDetail for use in testing.
+// NewTestPublicKeyDetail creates a random *PublicKeyDetail with a compressed secp256k1 public key
+// for use in testing.
 func NewTestPublicKeyDetail(rng *rand.Rand) *PublicKeyDetail {
 	return &PublicKeyDetail{
-		PublicKey: util.RandBytes(rng, 33),
+		PublicKey: NewTestPublicKey(rng),
 		EntityId:  fmt.Sprintf("EntityID-%d", rng.Intn(4)),
 		KeyType:   KeyType(rng.Intn(2)),
 	}
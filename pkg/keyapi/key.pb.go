@@ -12,10 +12,37 @@ It has these top-level messages:
 	AddPublicKeysResponse
 	GetPublicKeyDetailsRequest
 	GetPublicKeyDetailsResponse
+	PublicKeyResult
 	GetPublicKeysRequest
 	GetPublicKeysResponse
 	SamplePublicKeysRequest
 	SamplePublicKeysResponse
+	RevokePublicKeysRequest
+	RevokePublicKeysResponse
+	RotatePublicKeysRequest
+	RotatePublicKeysResponse
+	ListPublicKeysRequest
+	ListPublicKeysResponse
+	ImportPublicKeysRequest
+	ImportPublicKeysResponse
+	ExportPublicKeysRequest
+	ExportPublicKeysResponse
+	PublicKeyRecord
+	WatchPublicKeysRequest
+	WatchPublicKeysResponse
+	PublicKeyEvent
+	VerifyAuditLogRequest
+	VerifyAuditLogResponse
+	AuditRecord
+	GetSigningKeyRequest
+	GetSigningKeyResponse
+	ResponseSignature
+	GetTreeHeadRequest
+	GetTreeHeadResponse
+	GetConsistencyProofRequest
+	GetConsistencyProofResponse
+	TreeHead
+	InclusionProof
 	PublicKeyDetail
 */
 package keyapi
@@ -61,10 +88,111 @@ func (x KeyType) String() string {
 }
 func (KeyType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+type KeyStatus int32
+
+const (
+	KeyStatus_ANY_STATUS KeyStatus = 0
+	KeyStatus_ACTIVE     KeyStatus = 1
+	KeyStatus_REVOKED    KeyStatus = 2
+)
+
+var KeyStatus_name = map[int32]string{
+	0: "ANY_STATUS",
+	1: "ACTIVE",
+	2: "REVOKED",
+}
+var KeyStatus_value = map[string]int32{
+	"ANY_STATUS": 0,
+	"ACTIVE":     1,
+	"REVOKED":    2,
+}
+
+func (x KeyStatus) String() string {
+	return proto.EnumName(KeyStatus_name, int32(x))
+}
+func (KeyStatus) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type PublicKeyEventType int32
+
+const (
+	PublicKeyEventType_ADDED    PublicKeyEventType = 0
+	PublicKeyEventType_DISABLED PublicKeyEventType = 1
+)
+
+var PublicKeyEventType_name = map[int32]string{
+	0: "ADDED",
+	1: "DISABLED",
+}
+var PublicKeyEventType_value = map[string]int32{
+	"ADDED":    0,
+	"DISABLED": 1,
+}
+
+func (x PublicKeyEventType) String() string {
+	return proto.EnumName(PublicKeyEventType_name, int32(x))
+}
+func (PublicKeyEventType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type KeyFormat int32
+
+const (
+	KeyFormat_UNSPECIFIED_FORMAT   KeyFormat = 0
+	KeyFormat_ED25519              KeyFormat = 1
+	KeyFormat_X25519               KeyFormat = 2
+	KeyFormat_SECP256K1_COMPRESSED KeyFormat = 3
+)
+
+var KeyFormat_name = map[int32]string{
+	0: "UNSPECIFIED_FORMAT",
+	1: "ED25519",
+	2: "X25519",
+	3: "SECP256K1_COMPRESSED",
+}
+var KeyFormat_value = map[string]int32{
+	"UNSPECIFIED_FORMAT":   0,
+	"ED25519":              1,
+	"X25519":               2,
+	"SECP256K1_COMPRESSED": 3,
+}
+
+func (x KeyFormat) String() string {
+	return proto.EnumName(KeyFormat_name, int32(x))
+}
+func (KeyFormat) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type AuditAction int32
+
+const (
+	AuditAction_ADD    AuditAction = 0
+	AuditAction_REVOKE AuditAction = 1
+	AuditAction_ROTATE AuditAction = 2
+	AuditAction_IMPORT AuditAction = 3
+)
+
+var AuditAction_name = map[int32]string{
+	0: "ADD",
+	1: "REVOKE",
+	2: "ROTATE",
+	3: "IMPORT",
+}
+var AuditAction_value = map[string]int32{
+	"ADD":    0,
+	"REVOKE": 1,
+	"ROTATE": 2,
+	"IMPORT": 3,
+}
+
+func (x AuditAction) String() string {
+	return proto.EnumName(AuditAction_name, int32(x))
+}
+func (AuditAction) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
 type AddPublicKeysRequest struct {
-	EntityId   string   `protobuf:"bytes,1,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
-	KeyType    KeyType  `protobuf:"varint,2,opt,name=key_type,json=keyType,enum=keyapi.KeyType" json:"key_type,omitempty"`
-	PublicKeys [][]byte `protobuf:"bytes,3,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+	EntityId   string    `protobuf:"bytes,1,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
+	KeyType    KeyType   `protobuf:"varint,2,opt,name=key_type,json=keyType,enum=keyapi.KeyType" json:"key_type,omitempty"`
+	PublicKeys [][]byte  `protobuf:"bytes,3,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+	KeyFormat  KeyFormat `protobuf:"varint,4,opt,name=key_format,json=keyFormat,enum=keyapi.KeyFormat" json:"key_format,omitempty"`
+	Signatures [][]byte  `protobuf:"bytes,5,rep,name=signatures,proto3" json:"signatures,omitempty"`
 }
 
 func (m *AddPublicKeysRequest) Reset()                    { *m = AddPublicKeysRequest{} }
@@ -93,6 +221,20 @@ func (m *AddPublicKeysRequest) GetPublicKeys() [][]byte {
 	return nil
 }
 
+func (m *AddPublicKeysRequest) GetKeyFormat() KeyFormat {
+	if m != nil {
+		return m.KeyFormat
+	}
+	return KeyFormat_UNSPECIFIED_FORMAT
+}
+
+func (m *AddPublicKeysRequest) GetSignatures() [][]byte {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
 type AddPublicKeysResponse struct {
 }
 
@@ -102,7 +244,10 @@ func (*AddPublicKeysResponse) ProtoMessage()               {}
 func (*AddPublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
 
 type GetPublicKeyDetailsRequest struct {
-	PublicKeys [][]byte `protobuf:"bytes,3,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+	PublicKeys      [][]byte `protobuf:"bytes,3,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+	AsOfTime        int64    `protobuf:"varint,4,opt,name=as_of_time,json=asOfTime" json:"as_of_time,omitempty"`
+	Partial         bool     `protobuf:"varint,5,opt,name=partial" json:"partial,omitempty"`
+	InclusionProofs bool     `protobuf:"varint,6,opt,name=inclusion_proofs,json=inclusionProofs" json:"inclusion_proofs,omitempty"`
 }
 
 func (m *GetPublicKeyDetailsRequest) Reset()                    { *m = GetPublicKeyDetailsRequest{} }
@@ -117,8 +262,33 @@ func (m *GetPublicKeyDetailsRequest) GetPublicKeys() [][]byte {
 	return nil
 }
 
+func (m *GetPublicKeyDetailsRequest) GetAsOfTime() int64 {
+	if m != nil {
+		return m.AsOfTime
+	}
+	return 0
+}
+
+func (m *GetPublicKeyDetailsRequest) GetPartial() bool {
+	if m != nil {
+		return m.Partial
+	}
+	return false
+}
+
+func (m *GetPublicKeyDetailsRequest) GetInclusionProofs() bool {
+	if m != nil {
+		return m.InclusionProofs
+	}
+	return false
+}
+
 type GetPublicKeyDetailsResponse struct {
 	PublicKeyDetails []*PublicKeyDetail `protobuf:"bytes,1,rep,name=public_key_details,json=publicKeyDetails" json:"public_key_details,omitempty"`
+	Results          []*PublicKeyResult `protobuf:"bytes,2,rep,name=results" json:"results,omitempty"`
+	Signature        *ResponseSignature `protobuf:"bytes,3,opt,name=signature" json:"signature,omitempty"`
+	TreeHead         *TreeHead          `protobuf:"bytes,4,opt,name=tree_head,json=treeHead" json:"tree_head,omitempty"`
+	InclusionProofs  []*InclusionProof  `protobuf:"bytes,5,rep,name=inclusion_proofs,json=inclusionProofs" json:"inclusion_proofs,omitempty"`
 }
 
 func (m *GetPublicKeyDetailsResponse) Reset()                    { *m = GetPublicKeyDetailsResponse{} }
@@ -133,15 +303,76 @@ func (m *GetPublicKeyDetailsResponse) GetPublicKeyDetails() []*PublicKeyDetail {
 	return nil
 }
 
+func (m *GetPublicKeyDetailsResponse) GetResults() []*PublicKeyResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+func (m *GetPublicKeyDetailsResponse) GetSignature() *ResponseSignature {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *GetPublicKeyDetailsResponse) GetTreeHead() *TreeHead {
+	if m != nil {
+		return m.TreeHead
+	}
+	return nil
+}
+
+func (m *GetPublicKeyDetailsResponse) GetInclusionProofs() []*InclusionProof {
+	if m != nil {
+		return m.InclusionProofs
+	}
+	return nil
+}
+
+type PublicKeyResult struct {
+	PublicKey       []byte           `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Found           bool             `protobuf:"varint,2,opt,name=found" json:"found,omitempty"`
+	PublicKeyDetail *PublicKeyDetail `protobuf:"bytes,3,opt,name=public_key_detail,json=publicKeyDetail" json:"public_key_detail,omitempty"`
+}
+
+func (m *PublicKeyResult) Reset()                    { *m = PublicKeyResult{} }
+func (m *PublicKeyResult) String() string            { return proto.CompactTextString(m) }
+func (*PublicKeyResult) ProtoMessage()               {}
+func (*PublicKeyResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *PublicKeyResult) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+func (m *PublicKeyResult) GetFound() bool {
+	if m != nil {
+		return m.Found
+	}
+	return false
+}
+
+func (m *PublicKeyResult) GetPublicKeyDetail() *PublicKeyDetail {
+	if m != nil {
+		return m.PublicKeyDetail
+	}
+	return nil
+}
+
 type GetPublicKeysRequest struct {
 	EntityId string  `protobuf:"bytes,1,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
 	KeyType  KeyType `protobuf:"varint,2,opt,name=key_type,json=keyType,enum=keyapi.KeyType" json:"key_type,omitempty"`
+	AsOfTime int64   `protobuf:"varint,3,opt,name=as_of_time,json=asOfTime" json:"as_of_time,omitempty"`
 }
 
 func (m *GetPublicKeysRequest) Reset()                    { *m = GetPublicKeysRequest{} }
 func (m *GetPublicKeysRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetPublicKeysRequest) ProtoMessage()               {}
-func (*GetPublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*GetPublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 func (m *GetPublicKeysRequest) GetEntityId() string {
 	if m != nil {
@@ -157,14 +388,22 @@ func (m *GetPublicKeysRequest) GetKeyType() KeyType {
 	return KeyType_AUTHOR
 }
 
+func (m *GetPublicKeysRequest) GetAsOfTime() int64 {
+	if m != nil {
+		return m.AsOfTime
+	}
+	return 0
+}
+
 type GetPublicKeysResponse struct {
-	PublicKeys [][]byte `protobuf:"bytes,3,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+	PublicKeys [][]byte           `protobuf:"bytes,3,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+	Signature  *ResponseSignature `protobuf:"bytes,4,opt,name=signature" json:"signature,omitempty"`
 }
 
 func (m *GetPublicKeysResponse) Reset()                    { *m = GetPublicKeysResponse{} }
 func (m *GetPublicKeysResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetPublicKeysResponse) ProtoMessage()               {}
-func (*GetPublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*GetPublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *GetPublicKeysResponse) GetPublicKeys() [][]byte {
 	if m != nil {
@@ -173,6 +412,13 @@ func (m *GetPublicKeysResponse) GetPublicKeys() [][]byte {
 	return nil
 }
 
+func (m *GetPublicKeysResponse) GetSignature() *ResponseSignature {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
 type SamplePublicKeysRequest struct {
 	OfEntityId        string `protobuf:"bytes,1,opt,name=of_entity_id,json=ofEntityId" json:"of_entity_id,omitempty"`
 	RequesterEntityId string `protobuf:"bytes,2,opt,name=requester_entity_id,json=requesterEntityId" json:"requester_entity_id,omitempty"`
@@ -182,7 +428,7 @@ type SamplePublicKeysRequest struct {
 func (m *SamplePublicKeysRequest) Reset()                    { *m = SamplePublicKeysRequest{} }
 func (m *SamplePublicKeysRequest) String() string            { return proto.CompactTextString(m) }
 func (*SamplePublicKeysRequest) ProtoMessage()               {}
-func (*SamplePublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*SamplePublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *SamplePublicKeysRequest) GetOfEntityId() string {
 	if m != nil {
@@ -191,32 +437,808 @@ func (m *SamplePublicKeysRequest) GetOfEntityId() string {
 	return ""
 }
 
-func (m *SamplePublicKeysRequest) GetRequesterEntityId() string {
+func (m *SamplePublicKeysRequest) GetRequesterEntityId() string {
+	if m != nil {
+		return m.RequesterEntityId
+	}
+	return ""
+}
+
+func (m *SamplePublicKeysRequest) GetNPublicKeys() uint32 {
+	if m != nil {
+		return m.NPublicKeys
+	}
+	return 0
+}
+
+type SamplePublicKeysResponse struct {
+	PublicKeyDetails []*PublicKeyDetail `protobuf:"bytes,1,rep,name=public_key_details,json=publicKeyDetails" json:"public_key_details,omitempty"`
+	Signature        *ResponseSignature `protobuf:"bytes,2,opt,name=signature" json:"signature,omitempty"`
+}
+
+func (m *SamplePublicKeysResponse) Reset()                    { *m = SamplePublicKeysResponse{} }
+func (m *SamplePublicKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*SamplePublicKeysResponse) ProtoMessage()               {}
+func (*SamplePublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *SamplePublicKeysResponse) GetPublicKeyDetails() []*PublicKeyDetail {
+	if m != nil {
+		return m.PublicKeyDetails
+	}
+	return nil
+}
+
+func (m *SamplePublicKeysResponse) GetSignature() *ResponseSignature {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type RevokePublicKeysRequest struct {
+	EntityId   string   `protobuf:"bytes,1,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
+	PublicKeys [][]byte `protobuf:"bytes,2,rep,name=public_keys,json=publicKeys,proto3" json:"public_keys,omitempty"`
+}
+
+func (m *RevokePublicKeysRequest) Reset()                    { *m = RevokePublicKeysRequest{} }
+func (m *RevokePublicKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*RevokePublicKeysRequest) ProtoMessage()               {}
+func (*RevokePublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *RevokePublicKeysRequest) GetEntityId() string {
+	if m != nil {
+		return m.EntityId
+	}
+	return ""
+}
+
+func (m *RevokePublicKeysRequest) GetPublicKeys() [][]byte {
+	if m != nil {
+		return m.PublicKeys
+	}
+	return nil
+}
+
+type RevokePublicKeysResponse struct {
+}
+
+func (m *RevokePublicKeysResponse) Reset()                    { *m = RevokePublicKeysResponse{} }
+func (m *RevokePublicKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*RevokePublicKeysResponse) ProtoMessage()               {}
+func (*RevokePublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+type RotatePublicKeysRequest struct {
+	EntityId      string    `protobuf:"bytes,1,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
+	KeyType       KeyType   `protobuf:"varint,2,opt,name=key_type,json=keyType,enum=keyapi.KeyType" json:"key_type,omitempty"`
+	OldPublicKeys [][]byte  `protobuf:"bytes,3,rep,name=old_public_keys,json=oldPublicKeys,proto3" json:"old_public_keys,omitempty"`
+	NewPublicKeys [][]byte  `protobuf:"bytes,4,rep,name=new_public_keys,json=newPublicKeys,proto3" json:"new_public_keys,omitempty"`
+	NewKeyFormat  KeyFormat `protobuf:"varint,5,opt,name=new_key_format,json=newKeyFormat,enum=keyapi.KeyFormat" json:"new_key_format,omitempty"`
+	NewSignatures [][]byte  `protobuf:"bytes,6,rep,name=new_signatures,json=newSignatures,proto3" json:"new_signatures,omitempty"`
+}
+
+func (m *RotatePublicKeysRequest) Reset()                    { *m = RotatePublicKeysRequest{} }
+func (m *RotatePublicKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*RotatePublicKeysRequest) ProtoMessage()               {}
+func (*RotatePublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *RotatePublicKeysRequest) GetEntityId() string {
+	if m != nil {
+		return m.EntityId
+	}
+	return ""
+}
+
+func (m *RotatePublicKeysRequest) GetKeyType() KeyType {
+	if m != nil {
+		return m.KeyType
+	}
+	return KeyType_AUTHOR
+}
+
+func (m *RotatePublicKeysRequest) GetOldPublicKeys() [][]byte {
+	if m != nil {
+		return m.OldPublicKeys
+	}
+	return nil
+}
+
+func (m *RotatePublicKeysRequest) GetNewPublicKeys() [][]byte {
+	if m != nil {
+		return m.NewPublicKeys
+	}
+	return nil
+}
+
+func (m *RotatePublicKeysRequest) GetNewKeyFormat() KeyFormat {
+	if m != nil {
+		return m.NewKeyFormat
+	}
+	return KeyFormat_UNSPECIFIED_FORMAT
+}
+
+func (m *RotatePublicKeysRequest) GetNewSignatures() [][]byte {
+	if m != nil {
+		return m.NewSignatures
+	}
+	return nil
+}
+
+type RotatePublicKeysResponse struct {
+}
+
+func (m *RotatePublicKeysResponse) Reset()                    { *m = RotatePublicKeysResponse{} }
+func (m *RotatePublicKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*RotatePublicKeysResponse) ProtoMessage()               {}
+func (*RotatePublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+type ListPublicKeysRequest struct {
+	EntityId       string    `protobuf:"bytes,1,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
+	KeyTypes       []KeyType `protobuf:"varint,2,rep,name=key_types,json=keyTypes,enum=keyapi.KeyType,packed" json:"key_types,omitempty"`
+	AddedAfter     int64     `protobuf:"varint,3,opt,name=added_after,json=addedAfter" json:"added_after,omitempty"`
+	Status         KeyStatus `protobuf:"varint,4,opt,name=status,enum=keyapi.KeyStatus" json:"status,omitempty"`
+	PageSize       uint32    `protobuf:"varint,5,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	PageToken      string    `protobuf:"bytes,6,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
+	ModifiedAfter  int64     `protobuf:"varint,7,opt,name=modified_after,json=modifiedAfter" json:"modified_after,omitempty"`
+	ModifiedBefore int64     `protobuf:"varint,8,opt,name=modified_before,json=modifiedBefore" json:"modified_before,omitempty"`
+}
+
+func (m *ListPublicKeysRequest) Reset()                    { *m = ListPublicKeysRequest{} }
+func (m *ListPublicKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListPublicKeysRequest) ProtoMessage()               {}
+func (*ListPublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *ListPublicKeysRequest) GetEntityId() string {
+	if m != nil {
+		return m.EntityId
+	}
+	return ""
+}
+
+func (m *ListPublicKeysRequest) GetKeyTypes() []KeyType {
+	if m != nil {
+		return m.KeyTypes
+	}
+	return nil
+}
+
+func (m *ListPublicKeysRequest) GetAddedAfter() int64 {
+	if m != nil {
+		return m.AddedAfter
+	}
+	return 0
+}
+
+func (m *ListPublicKeysRequest) GetStatus() KeyStatus {
+	if m != nil {
+		return m.Status
+	}
+	return KeyStatus_ANY_STATUS
+}
+
+func (m *ListPublicKeysRequest) GetPageSize() uint32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListPublicKeysRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+func (m *ListPublicKeysRequest) GetModifiedAfter() int64 {
+	if m != nil {
+		return m.ModifiedAfter
+	}
+	return 0
+}
+
+func (m *ListPublicKeysRequest) GetModifiedBefore() int64 {
+	if m != nil {
+		return m.ModifiedBefore
+	}
+	return 0
+}
+
+type ListPublicKeysResponse struct {
+	PublicKeyDetails []*PublicKeyDetail `protobuf:"bytes,1,rep,name=public_key_details,json=publicKeyDetails" json:"public_key_details,omitempty"`
+	NextPageToken    string             `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
+}
+
+func (m *ListPublicKeysResponse) Reset()                    { *m = ListPublicKeysResponse{} }
+func (m *ListPublicKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListPublicKeysResponse) ProtoMessage()               {}
+func (*ListPublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *ListPublicKeysResponse) GetPublicKeyDetails() []*PublicKeyDetail {
+	if m != nil {
+		return m.PublicKeyDetails
+	}
+	return nil
+}
+
+func (m *ListPublicKeysResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type ImportPublicKeysRequest struct {
+	PublicKeyDetails []*PublicKeyDetail `protobuf:"bytes,1,rep,name=public_key_details,json=publicKeyDetails" json:"public_key_details,omitempty"`
+	KeyFormat        KeyFormat          `protobuf:"varint,2,opt,name=key_format,json=keyFormat,enum=keyapi.KeyFormat" json:"key_format,omitempty"`
+	Signatures       [][]byte           `protobuf:"bytes,3,rep,name=signatures,proto3" json:"signatures,omitempty"`
+}
+
+func (m *ImportPublicKeysRequest) Reset()                    { *m = ImportPublicKeysRequest{} }
+func (m *ImportPublicKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*ImportPublicKeysRequest) ProtoMessage()               {}
+func (*ImportPublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *ImportPublicKeysRequest) GetPublicKeyDetails() []*PublicKeyDetail {
+	if m != nil {
+		return m.PublicKeyDetails
+	}
+	return nil
+}
+
+func (m *ImportPublicKeysRequest) GetKeyFormat() KeyFormat {
+	if m != nil {
+		return m.KeyFormat
+	}
+	return KeyFormat_UNSPECIFIED_FORMAT
+}
+
+func (m *ImportPublicKeysRequest) GetSignatures() [][]byte {
+	if m != nil {
+		return m.Signatures
+	}
+	return nil
+}
+
+type ImportPublicKeysResponse struct {
+	NInserted          uint32   `protobuf:"varint,1,opt,name=n_inserted,json=nInserted" json:"n_inserted,omitempty"`
+	NDuplicate         uint32   `protobuf:"varint,2,opt,name=n_duplicate,json=nDuplicate" json:"n_duplicate,omitempty"`
+	NRejected          uint32   `protobuf:"varint,3,opt,name=n_rejected,json=nRejected" json:"n_rejected,omitempty"`
+	RejectedPublicKeys [][]byte `protobuf:"bytes,4,rep,name=rejected_public_keys,json=rejectedPublicKeys,proto3" json:"rejected_public_keys,omitempty"`
+}
+
+func (m *ImportPublicKeysResponse) Reset()                    { *m = ImportPublicKeysResponse{} }
+func (m *ImportPublicKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*ImportPublicKeysResponse) ProtoMessage()               {}
+func (*ImportPublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *ImportPublicKeysResponse) GetNInserted() uint32 {
+	if m != nil {
+		return m.NInserted
+	}
+	return 0
+}
+
+func (m *ImportPublicKeysResponse) GetNDuplicate() uint32 {
+	if m != nil {
+		return m.NDuplicate
+	}
+	return 0
+}
+
+func (m *ImportPublicKeysResponse) GetNRejected() uint32 {
+	if m != nil {
+		return m.NRejected
+	}
+	return 0
+}
+
+func (m *ImportPublicKeysResponse) GetRejectedPublicKeys() [][]byte {
+	if m != nil {
+		return m.RejectedPublicKeys
+	}
+	return nil
+}
+
+type ExportPublicKeysRequest struct {
+	PageSize uint32 `protobuf:"varint,1,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+}
+
+func (m *ExportPublicKeysRequest) Reset()                    { *m = ExportPublicKeysRequest{} }
+func (m *ExportPublicKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExportPublicKeysRequest) ProtoMessage()               {}
+func (*ExportPublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *ExportPublicKeysRequest) GetPageSize() uint32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+type ExportPublicKeysResponse struct {
+	PublicKeyRecords []*PublicKeyRecord `protobuf:"bytes,1,rep,name=public_key_records,json=publicKeyRecords" json:"public_key_records,omitempty"`
+}
+
+func (m *ExportPublicKeysResponse) Reset()                    { *m = ExportPublicKeysResponse{} }
+func (m *ExportPublicKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*ExportPublicKeysResponse) ProtoMessage()               {}
+func (*ExportPublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *ExportPublicKeysResponse) GetPublicKeyRecords() []*PublicKeyRecord {
+	if m != nil {
+		return m.PublicKeyRecords
+	}
+	return nil
+}
+
+type PublicKeyRecord struct {
+	PublicKeyDetail *PublicKeyDetail `protobuf:"bytes,1,opt,name=public_key_detail,json=publicKeyDetail" json:"public_key_detail,omitempty"`
+	AddedTime       int64            `protobuf:"varint,2,opt,name=added_time,json=addedTime" json:"added_time,omitempty"`
+	DisabledTime    int64            `protobuf:"varint,3,opt,name=disabled_time,json=disabledTime" json:"disabled_time,omitempty"`
+}
+
+func (m *PublicKeyRecord) Reset()                    { *m = PublicKeyRecord{} }
+func (m *PublicKeyRecord) String() string            { return proto.CompactTextString(m) }
+func (*PublicKeyRecord) ProtoMessage()               {}
+func (*PublicKeyRecord) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *PublicKeyRecord) GetPublicKeyDetail() *PublicKeyDetail {
+	if m != nil {
+		return m.PublicKeyDetail
+	}
+	return nil
+}
+
+func (m *PublicKeyRecord) GetAddedTime() int64 {
+	if m != nil {
+		return m.AddedTime
+	}
+	return 0
+}
+
+func (m *PublicKeyRecord) GetDisabledTime() int64 {
+	if m != nil {
+		return m.DisabledTime
+	}
+	return 0
+}
+
+type WatchPublicKeysRequest struct {
+	EntityIds     []string `protobuf:"bytes,1,rep,name=entity_ids,json=entityIds" json:"entity_ids,omitempty"`
+	AfterSequence uint64   `protobuf:"varint,2,opt,name=after_sequence,json=afterSequence" json:"after_sequence,omitempty"`
+}
+
+func (m *WatchPublicKeysRequest) Reset()                    { *m = WatchPublicKeysRequest{} }
+func (m *WatchPublicKeysRequest) String() string            { return proto.CompactTextString(m) }
+func (*WatchPublicKeysRequest) ProtoMessage()               {}
+func (*WatchPublicKeysRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *WatchPublicKeysRequest) GetEntityIds() []string {
+	if m != nil {
+		return m.EntityIds
+	}
+	return nil
+}
+
+func (m *WatchPublicKeysRequest) GetAfterSequence() uint64 {
+	if m != nil {
+		return m.AfterSequence
+	}
+	return 0
+}
+
+type WatchPublicKeysResponse struct {
+	Events []*PublicKeyEvent `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+}
+
+func (m *WatchPublicKeysResponse) Reset()                    { *m = WatchPublicKeysResponse{} }
+func (m *WatchPublicKeysResponse) String() string            { return proto.CompactTextString(m) }
+func (*WatchPublicKeysResponse) ProtoMessage()               {}
+func (*WatchPublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *WatchPublicKeysResponse) GetEvents() []*PublicKeyEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type PublicKeyEvent struct {
+	Sequence        uint64             `protobuf:"varint,1,opt,name=sequence" json:"sequence,omitempty"`
+	Type            PublicKeyEventType `protobuf:"varint,2,opt,name=type,enum=keyapi.PublicKeyEventType" json:"type,omitempty"`
+	PublicKeyDetail *PublicKeyDetail   `protobuf:"bytes,3,opt,name=public_key_detail,json=publicKeyDetail" json:"public_key_detail,omitempty"`
+	Time            int64              `protobuf:"varint,4,opt,name=time" json:"time,omitempty"`
+}
+
+func (m *PublicKeyEvent) Reset()                    { *m = PublicKeyEvent{} }
+func (m *PublicKeyEvent) String() string            { return proto.CompactTextString(m) }
+func (*PublicKeyEvent) ProtoMessage()               {}
+func (*PublicKeyEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *PublicKeyEvent) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *PublicKeyEvent) GetType() PublicKeyEventType {
+	if m != nil {
+		return m.Type
+	}
+	return PublicKeyEventType_ADDED
+}
+
+func (m *PublicKeyEvent) GetPublicKeyDetail() *PublicKeyDetail {
+	if m != nil {
+		return m.PublicKeyDetail
+	}
+	return nil
+}
+
+func (m *PublicKeyEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type VerifyAuditLogRequest struct {
+	CheckpointSequence uint64 `protobuf:"varint,1,opt,name=checkpoint_sequence,json=checkpointSequence" json:"checkpoint_sequence,omitempty"`
+	CheckpointHash     []byte `protobuf:"bytes,2,opt,name=checkpoint_hash,json=checkpointHash,proto3" json:"checkpoint_hash,omitempty"`
+}
+
+func (m *VerifyAuditLogRequest) Reset()                    { *m = VerifyAuditLogRequest{} }
+func (m *VerifyAuditLogRequest) String() string            { return proto.CompactTextString(m) }
+func (*VerifyAuditLogRequest) ProtoMessage()               {}
+func (*VerifyAuditLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{23} }
+
+func (m *VerifyAuditLogRequest) GetCheckpointSequence() uint64 {
+	if m != nil {
+		return m.CheckpointSequence
+	}
+	return 0
+}
+
+func (m *VerifyAuditLogRequest) GetCheckpointHash() []byte {
+	if m != nil {
+		return m.CheckpointHash
+	}
+	return nil
+}
+
+type VerifyAuditLogResponse struct {
+	Valid           bool   `protobuf:"varint,1,opt,name=valid" json:"valid,omitempty"`
+	NRecords        uint64 `protobuf:"varint,2,opt,name=n_records,json=nRecords" json:"n_records,omitempty"`
+	HeadSequence    uint64 `protobuf:"varint,3,opt,name=head_sequence,json=headSequence" json:"head_sequence,omitempty"`
+	HeadHash        []byte `protobuf:"bytes,4,opt,name=head_hash,json=headHash,proto3" json:"head_hash,omitempty"`
+	InvalidSequence uint64 `protobuf:"varint,5,opt,name=invalid_sequence,json=invalidSequence" json:"invalid_sequence,omitempty"`
+	InvalidReason   string `protobuf:"bytes,6,opt,name=invalid_reason,json=invalidReason" json:"invalid_reason,omitempty"`
+}
+
+func (m *VerifyAuditLogResponse) Reset()                    { *m = VerifyAuditLogResponse{} }
+func (m *VerifyAuditLogResponse) String() string            { return proto.CompactTextString(m) }
+func (*VerifyAuditLogResponse) ProtoMessage()               {}
+func (*VerifyAuditLogResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{24} }
+
+func (m *VerifyAuditLogResponse) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func (m *VerifyAuditLogResponse) GetNRecords() uint64 {
+	if m != nil {
+		return m.NRecords
+	}
+	return 0
+}
+
+func (m *VerifyAuditLogResponse) GetHeadSequence() uint64 {
+	if m != nil {
+		return m.HeadSequence
+	}
+	return 0
+}
+
+func (m *VerifyAuditLogResponse) GetHeadHash() []byte {
+	if m != nil {
+		return m.HeadHash
+	}
+	return nil
+}
+
+func (m *VerifyAuditLogResponse) GetInvalidSequence() uint64 {
+	if m != nil {
+		return m.InvalidSequence
+	}
+	return 0
+}
+
+func (m *VerifyAuditLogResponse) GetInvalidReason() string {
+	if m != nil {
+		return m.InvalidReason
+	}
+	return ""
+}
+
+type AuditRecord struct {
+	Sequence                uint64      `protobuf:"varint,1,opt,name=sequence" json:"sequence,omitempty"`
+	Time                    int64       `protobuf:"varint,2,opt,name=time" json:"time,omitempty"`
+	CallerEntityId          string      `protobuf:"bytes,3,opt,name=caller_entity_id,json=callerEntityId" json:"caller_entity_id,omitempty"`
+	Action                  AuditAction `protobuf:"varint,4,opt,name=action,enum=keyapi.AuditAction" json:"action,omitempty"`
+	EntityId                string      `protobuf:"bytes,5,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
+	KeyType                 KeyType     `protobuf:"varint,6,opt,name=key_type,json=keyType,enum=keyapi.KeyType" json:"key_type,omitempty"`
+	AddedPublicKeyHashes    [][]byte    `protobuf:"bytes,7,rep,name=added_public_key_hashes,json=addedPublicKeyHashes,proto3" json:"added_public_key_hashes,omitempty"`
+	DisabledPublicKeyHashes [][]byte    `protobuf:"bytes,8,rep,name=disabled_public_key_hashes,json=disabledPublicKeyHashes,proto3" json:"disabled_public_key_hashes,omitempty"`
+	PrevHash                []byte      `protobuf:"bytes,9,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	Hash                    []byte      `protobuf:"bytes,10,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *AuditRecord) Reset()                    { *m = AuditRecord{} }
+func (m *AuditRecord) String() string            { return proto.CompactTextString(m) }
+func (*AuditRecord) ProtoMessage()               {}
+func (*AuditRecord) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{25} }
+
+func (m *AuditRecord) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *AuditRecord) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AuditRecord) GetCallerEntityId() string {
+	if m != nil {
+		return m.CallerEntityId
+	}
+	return ""
+}
+
+func (m *AuditRecord) GetAction() AuditAction {
+	if m != nil {
+		return m.Action
+	}
+	return AuditAction_ADD
+}
+
+func (m *AuditRecord) GetEntityId() string {
+	if m != nil {
+		return m.EntityId
+	}
+	return ""
+}
+
+func (m *AuditRecord) GetKeyType() KeyType {
+	if m != nil {
+		return m.KeyType
+	}
+	return KeyType_AUTHOR
+}
+
+func (m *AuditRecord) GetAddedPublicKeyHashes() [][]byte {
+	if m != nil {
+		return m.AddedPublicKeyHashes
+	}
+	return nil
+}
+
+func (m *AuditRecord) GetDisabledPublicKeyHashes() [][]byte {
+	if m != nil {
+		return m.DisabledPublicKeyHashes
+	}
+	return nil
+}
+
+func (m *AuditRecord) GetPrevHash() []byte {
+	if m != nil {
+		return m.PrevHash
+	}
+	return nil
+}
+
+func (m *AuditRecord) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+type GetSigningKeyRequest struct {
+}
+
+func (m *GetSigningKeyRequest) Reset()                    { *m = GetSigningKeyRequest{} }
+func (m *GetSigningKeyRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetSigningKeyRequest) ProtoMessage()               {}
+func (*GetSigningKeyRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+type GetSigningKeyResponse struct {
+	SigningKey []byte    `protobuf:"bytes,1,opt,name=signing_key,json=signingKey,proto3" json:"signing_key,omitempty"`
+	KeyFormat  KeyFormat `protobuf:"varint,2,opt,name=key_format,json=keyFormat,enum=keyapi.KeyFormat" json:"key_format,omitempty"`
+}
+
+func (m *GetSigningKeyResponse) Reset()                    { *m = GetSigningKeyResponse{} }
+func (m *GetSigningKeyResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetSigningKeyResponse) ProtoMessage()               {}
+func (*GetSigningKeyResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+
+func (m *GetSigningKeyResponse) GetSigningKey() []byte {
+	if m != nil {
+		return m.SigningKey
+	}
+	return nil
+}
+
+func (m *GetSigningKeyResponse) GetKeyFormat() KeyFormat {
+	if m != nil {
+		return m.KeyFormat
+	}
+	return KeyFormat_UNSPECIFIED_FORMAT
+}
+
+type ResponseSignature struct {
+	Time      int64  `protobuf:"varint,1,opt,name=time" json:"time,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *ResponseSignature) Reset()                    { *m = ResponseSignature{} }
+func (m *ResponseSignature) String() string            { return proto.CompactTextString(m) }
+func (*ResponseSignature) ProtoMessage()               {}
+func (*ResponseSignature) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+
+func (m *ResponseSignature) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *ResponseSignature) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type GetTreeHeadRequest struct {
+}
+
+func (m *GetTreeHeadRequest) Reset()                    { *m = GetTreeHeadRequest{} }
+func (m *GetTreeHeadRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTreeHeadRequest) ProtoMessage()               {}
+func (*GetTreeHeadRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
+
+type GetTreeHeadResponse struct {
+	TreeHead *TreeHead `protobuf:"bytes,1,opt,name=tree_head,json=treeHead" json:"tree_head,omitempty"`
+}
+
+func (m *GetTreeHeadResponse) Reset()                    { *m = GetTreeHeadResponse{} }
+func (m *GetTreeHeadResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetTreeHeadResponse) ProtoMessage()               {}
+func (*GetTreeHeadResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+
+func (m *GetTreeHeadResponse) GetTreeHead() *TreeHead {
+	if m != nil {
+		return m.TreeHead
+	}
+	return nil
+}
+
+type GetConsistencyProofRequest struct {
+	FirstTreeSize  uint64 `protobuf:"varint,1,opt,name=first_tree_size,json=firstTreeSize" json:"first_tree_size,omitempty"`
+	SecondTreeSize uint64 `protobuf:"varint,2,opt,name=second_tree_size,json=secondTreeSize" json:"second_tree_size,omitempty"`
+}
+
+func (m *GetConsistencyProofRequest) Reset()                    { *m = GetConsistencyProofRequest{} }
+func (m *GetConsistencyProofRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetConsistencyProofRequest) ProtoMessage()               {}
+func (*GetConsistencyProofRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+
+func (m *GetConsistencyProofRequest) GetFirstTreeSize() uint64 {
+	if m != nil {
+		return m.FirstTreeSize
+	}
+	return 0
+}
+
+func (m *GetConsistencyProofRequest) GetSecondTreeSize() uint64 {
+	if m != nil {
+		return m.SecondTreeSize
+	}
+	return 0
+}
+
+type GetConsistencyProofResponse struct {
+	Hashes [][]byte `protobuf:"bytes,1,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (m *GetConsistencyProofResponse) Reset()                    { *m = GetConsistencyProofResponse{} }
+func (m *GetConsistencyProofResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetConsistencyProofResponse) ProtoMessage()               {}
+func (*GetConsistencyProofResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+
+func (m *GetConsistencyProofResponse) GetHashes() [][]byte {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+type TreeHead struct {
+	TreeSize  uint64 `protobuf:"varint,1,opt,name=tree_size,json=treeSize" json:"tree_size,omitempty"`
+	RootHash  []byte `protobuf:"bytes,2,opt,name=root_hash,json=rootHash,proto3" json:"root_hash,omitempty"`
+	Time      int64  `protobuf:"varint,3,opt,name=time" json:"time,omitempty"`
+	Signature []byte `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *TreeHead) Reset()                    { *m = TreeHead{} }
+func (m *TreeHead) String() string            { return proto.CompactTextString(m) }
+func (*TreeHead) ProtoMessage()               {}
+func (*TreeHead) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *TreeHead) GetTreeSize() uint64 {
+	if m != nil {
+		return m.TreeSize
+	}
+	return 0
+}
+
+func (m *TreeHead) GetRootHash() []byte {
+	if m != nil {
+		return m.RootHash
+	}
+	return nil
+}
+
+func (m *TreeHead) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *TreeHead) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type InclusionProof struct {
+	Found     bool     `protobuf:"varint,1,opt,name=found" json:"found,omitempty"`
+	LeafIndex uint64   `protobuf:"varint,2,opt,name=leaf_index,json=leafIndex" json:"leaf_index,omitempty"`
+	Hashes    [][]byte `protobuf:"bytes,3,rep,name=hashes,proto3" json:"hashes,omitempty"`
+}
+
+func (m *InclusionProof) Reset()                    { *m = InclusionProof{} }
+func (m *InclusionProof) String() string            { return proto.CompactTextString(m) }
+func (*InclusionProof) ProtoMessage()               {}
+func (*InclusionProof) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
+
+func (m *InclusionProof) GetFound() bool {
 	if m != nil {
-		return m.RequesterEntityId
+		return m.Found
 	}
-	return ""
+	return false
 }
 
-func (m *SamplePublicKeysRequest) GetNPublicKeys() uint32 {
+func (m *InclusionProof) GetLeafIndex() uint64 {
 	if m != nil {
-		return m.NPublicKeys
+		return m.LeafIndex
 	}
 	return 0
 }
 
-type SamplePublicKeysResponse struct {
-	PublicKeyDetails []*PublicKeyDetail `protobuf:"bytes,1,rep,name=public_key_details,json=publicKeyDetails" json:"public_key_details,omitempty"`
-}
-
-func (m *SamplePublicKeysResponse) Reset()                    { *m = SamplePublicKeysResponse{} }
-func (m *SamplePublicKeysResponse) String() string            { return proto.CompactTextString(m) }
-func (*SamplePublicKeysResponse) ProtoMessage()               {}
-func (*SamplePublicKeysResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
-
-func (m *SamplePublicKeysResponse) GetPublicKeyDetails() []*PublicKeyDetail {
+func (m *InclusionProof) GetHashes() [][]byte {
 	if m != nil {
-		return m.PublicKeyDetails
+		return m.Hashes
 	}
 	return nil
 }
@@ -225,12 +1247,13 @@ type PublicKeyDetail struct {
 	PublicKey []byte  `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
 	EntityId  string  `protobuf:"bytes,2,opt,name=entity_id,json=entityId" json:"entity_id,omitempty"`
 	KeyType   KeyType `protobuf:"varint,3,opt,name=key_type,json=keyType,enum=keyapi.KeyType" json:"key_type,omitempty"`
+	Disabled  bool    `protobuf:"varint,4,opt,name=disabled" json:"disabled,omitempty"`
 }
 
 func (m *PublicKeyDetail) Reset()                    { *m = PublicKeyDetail{} }
 func (m *PublicKeyDetail) String() string            { return proto.CompactTextString(m) }
 func (*PublicKeyDetail) ProtoMessage()               {}
-func (*PublicKeyDetail) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*PublicKeyDetail) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
 
 func (m *PublicKeyDetail) GetPublicKey() []byte {
 	if m != nil {
@@ -253,17 +1276,55 @@ func (m *PublicKeyDetail) GetKeyType() KeyType {
 	return KeyType_AUTHOR
 }
 
+func (m *PublicKeyDetail) GetDisabled() bool {
+	if m != nil {
+		return m.Disabled
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterType((*AddPublicKeysRequest)(nil), "keyapi.AddPublicKeysRequest")
 	proto.RegisterType((*AddPublicKeysResponse)(nil), "keyapi.AddPublicKeysResponse")
 	proto.RegisterType((*GetPublicKeyDetailsRequest)(nil), "keyapi.GetPublicKeyDetailsRequest")
 	proto.RegisterType((*GetPublicKeyDetailsResponse)(nil), "keyapi.GetPublicKeyDetailsResponse")
+	proto.RegisterType((*PublicKeyResult)(nil), "keyapi.PublicKeyResult")
 	proto.RegisterType((*GetPublicKeysRequest)(nil), "keyapi.GetPublicKeysRequest")
 	proto.RegisterType((*GetPublicKeysResponse)(nil), "keyapi.GetPublicKeysResponse")
 	proto.RegisterType((*SamplePublicKeysRequest)(nil), "keyapi.SamplePublicKeysRequest")
 	proto.RegisterType((*SamplePublicKeysResponse)(nil), "keyapi.SamplePublicKeysResponse")
+	proto.RegisterType((*RevokePublicKeysRequest)(nil), "keyapi.RevokePublicKeysRequest")
+	proto.RegisterType((*RevokePublicKeysResponse)(nil), "keyapi.RevokePublicKeysResponse")
+	proto.RegisterType((*RotatePublicKeysRequest)(nil), "keyapi.RotatePublicKeysRequest")
+	proto.RegisterType((*RotatePublicKeysResponse)(nil), "keyapi.RotatePublicKeysResponse")
+	proto.RegisterType((*ListPublicKeysRequest)(nil), "keyapi.ListPublicKeysRequest")
+	proto.RegisterType((*ListPublicKeysResponse)(nil), "keyapi.ListPublicKeysResponse")
+	proto.RegisterType((*ImportPublicKeysRequest)(nil), "keyapi.ImportPublicKeysRequest")
+	proto.RegisterType((*ImportPublicKeysResponse)(nil), "keyapi.ImportPublicKeysResponse")
+	proto.RegisterType((*ExportPublicKeysRequest)(nil), "keyapi.ExportPublicKeysRequest")
+	proto.RegisterType((*ExportPublicKeysResponse)(nil), "keyapi.ExportPublicKeysResponse")
+	proto.RegisterType((*PublicKeyRecord)(nil), "keyapi.PublicKeyRecord")
+	proto.RegisterType((*WatchPublicKeysRequest)(nil), "keyapi.WatchPublicKeysRequest")
+	proto.RegisterType((*WatchPublicKeysResponse)(nil), "keyapi.WatchPublicKeysResponse")
+	proto.RegisterType((*PublicKeyEvent)(nil), "keyapi.PublicKeyEvent")
+	proto.RegisterType((*VerifyAuditLogRequest)(nil), "keyapi.VerifyAuditLogRequest")
+	proto.RegisterType((*VerifyAuditLogResponse)(nil), "keyapi.VerifyAuditLogResponse")
+	proto.RegisterType((*AuditRecord)(nil), "keyapi.AuditRecord")
+	proto.RegisterType((*GetSigningKeyRequest)(nil), "keyapi.GetSigningKeyRequest")
+	proto.RegisterType((*GetSigningKeyResponse)(nil), "keyapi.GetSigningKeyResponse")
+	proto.RegisterType((*ResponseSignature)(nil), "keyapi.ResponseSignature")
+	proto.RegisterType((*GetTreeHeadRequest)(nil), "keyapi.GetTreeHeadRequest")
+	proto.RegisterType((*GetTreeHeadResponse)(nil), "keyapi.GetTreeHeadResponse")
+	proto.RegisterType((*GetConsistencyProofRequest)(nil), "keyapi.GetConsistencyProofRequest")
+	proto.RegisterType((*GetConsistencyProofResponse)(nil), "keyapi.GetConsistencyProofResponse")
+	proto.RegisterType((*TreeHead)(nil), "keyapi.TreeHead")
+	proto.RegisterType((*InclusionProof)(nil), "keyapi.InclusionProof")
 	proto.RegisterType((*PublicKeyDetail)(nil), "keyapi.PublicKeyDetail")
 	proto.RegisterEnum("keyapi.KeyType", KeyType_name, KeyType_value)
+	proto.RegisterEnum("keyapi.KeyStatus", KeyStatus_name, KeyStatus_value)
+	proto.RegisterEnum("keyapi.PublicKeyEventType", PublicKeyEventType_name, PublicKeyEventType_value)
+	proto.RegisterEnum("keyapi.KeyFormat", KeyFormat_name, KeyFormat_value)
+	proto.RegisterEnum("keyapi.AuditAction", AuditAction_name, AuditAction_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -281,6 +1342,16 @@ type KeyClient interface {
 	GetPublicKeys(ctx context.Context, in *GetPublicKeysRequest, opts ...grpc.CallOption) (*GetPublicKeysResponse, error)
 	SamplePublicKeys(ctx context.Context, in *SamplePublicKeysRequest, opts ...grpc.CallOption) (*SamplePublicKeysResponse, error)
 	GetPublicKeyDetails(ctx context.Context, in *GetPublicKeyDetailsRequest, opts ...grpc.CallOption) (*GetPublicKeyDetailsResponse, error)
+	RevokePublicKeys(ctx context.Context, in *RevokePublicKeysRequest, opts ...grpc.CallOption) (*RevokePublicKeysResponse, error)
+	RotatePublicKeys(ctx context.Context, in *RotatePublicKeysRequest, opts ...grpc.CallOption) (*RotatePublicKeysResponse, error)
+	ListPublicKeys(ctx context.Context, in *ListPublicKeysRequest, opts ...grpc.CallOption) (*ListPublicKeysResponse, error)
+	ImportPublicKeys(ctx context.Context, opts ...grpc.CallOption) (Key_ImportPublicKeysClient, error)
+	ExportPublicKeys(ctx context.Context, in *ExportPublicKeysRequest, opts ...grpc.CallOption) (Key_ExportPublicKeysClient, error)
+	WatchPublicKeys(ctx context.Context, in *WatchPublicKeysRequest, opts ...grpc.CallOption) (Key_WatchPublicKeysClient, error)
+	VerifyAuditLog(ctx context.Context, in *VerifyAuditLogRequest, opts ...grpc.CallOption) (*VerifyAuditLogResponse, error)
+	GetSigningKey(ctx context.Context, in *GetSigningKeyRequest, opts ...grpc.CallOption) (*GetSigningKeyResponse, error)
+	GetTreeHead(ctx context.Context, in *GetTreeHeadRequest, opts ...grpc.CallOption) (*GetTreeHeadResponse, error)
+	GetConsistencyProof(ctx context.Context, in *GetConsistencyProofRequest, opts ...grpc.CallOption) (*GetConsistencyProofResponse, error)
 }
 
 type keyClient struct {
@@ -327,6 +1398,167 @@ func (c *keyClient) GetPublicKeyDetails(ctx context.Context, in *GetPublicKeyDet
 	return out, nil
 }
 
+func (c *keyClient) RevokePublicKeys(ctx context.Context, in *RevokePublicKeysRequest, opts ...grpc.CallOption) (*RevokePublicKeysResponse, error) {
+	out := new(RevokePublicKeysResponse)
+	err := grpc.Invoke(ctx, "/keyapi.Key/RevokePublicKeys", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyClient) RotatePublicKeys(ctx context.Context, in *RotatePublicKeysRequest, opts ...grpc.CallOption) (*RotatePublicKeysResponse, error) {
+	out := new(RotatePublicKeysResponse)
+	err := grpc.Invoke(ctx, "/keyapi.Key/RotatePublicKeys", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyClient) ListPublicKeys(ctx context.Context, in *ListPublicKeysRequest, opts ...grpc.CallOption) (*ListPublicKeysResponse, error) {
+	out := new(ListPublicKeysResponse)
+	err := grpc.Invoke(ctx, "/keyapi.Key/ListPublicKeys", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyClient) ImportPublicKeys(ctx context.Context, opts ...grpc.CallOption) (Key_ImportPublicKeysClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Key_serviceDesc.Streams[0], c.cc, "/keyapi.Key/ImportPublicKeys", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &keyImportPublicKeysClient{stream}
+	return x, nil
+}
+
+type Key_ImportPublicKeysClient interface {
+	Send(*ImportPublicKeysRequest) error
+	CloseAndRecv() (*ImportPublicKeysResponse, error)
+	grpc.ClientStream
+}
+
+type keyImportPublicKeysClient struct {
+	grpc.ClientStream
+}
+
+func (x *keyImportPublicKeysClient) Send(m *ImportPublicKeysRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *keyImportPublicKeysClient) CloseAndRecv() (*ImportPublicKeysResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportPublicKeysResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *keyClient) ExportPublicKeys(ctx context.Context, in *ExportPublicKeysRequest, opts ...grpc.CallOption) (Key_ExportPublicKeysClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Key_serviceDesc.Streams[1], c.cc, "/keyapi.Key/ExportPublicKeys", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &keyExportPublicKeysClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Key_ExportPublicKeysClient interface {
+	Recv() (*ExportPublicKeysResponse, error)
+	grpc.ClientStream
+}
+
+type keyExportPublicKeysClient struct {
+	grpc.ClientStream
+}
+
+func (x *keyExportPublicKeysClient) Recv() (*ExportPublicKeysResponse, error) {
+	m := new(ExportPublicKeysResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *keyClient) WatchPublicKeys(ctx context.Context, in *WatchPublicKeysRequest, opts ...grpc.CallOption) (Key_WatchPublicKeysClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Key_serviceDesc.Streams[2], c.cc, "/keyapi.Key/WatchPublicKeys", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &keyWatchPublicKeysClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Key_WatchPublicKeysClient interface {
+	Recv() (*WatchPublicKeysResponse, error)
+	grpc.ClientStream
+}
+
+type keyWatchPublicKeysClient struct {
+	grpc.ClientStream
+}
+
+func (x *keyWatchPublicKeysClient) Recv() (*WatchPublicKeysResponse, error) {
+	m := new(WatchPublicKeysResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *keyClient) VerifyAuditLog(ctx context.Context, in *VerifyAuditLogRequest, opts ...grpc.CallOption) (*VerifyAuditLogResponse, error) {
+	out := new(VerifyAuditLogResponse)
+	err := grpc.Invoke(ctx, "/keyapi.Key/VerifyAuditLog", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyClient) GetSigningKey(ctx context.Context, in *GetSigningKeyRequest, opts ...grpc.CallOption) (*GetSigningKeyResponse, error) {
+	out := new(GetSigningKeyResponse)
+	err := grpc.Invoke(ctx, "/keyapi.Key/GetSigningKey", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyClient) GetTreeHead(ctx context.Context, in *GetTreeHeadRequest, opts ...grpc.CallOption) (*GetTreeHeadResponse, error) {
+	out := new(GetTreeHeadResponse)
+	err := grpc.Invoke(ctx, "/keyapi.Key/GetTreeHead", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyClient) GetConsistencyProof(ctx context.Context, in *GetConsistencyProofRequest, opts ...grpc.CallOption) (*GetConsistencyProofResponse, error) {
+	out := new(GetConsistencyProofResponse)
+	err := grpc.Invoke(ctx, "/keyapi.Key/GetConsistencyProof", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Key service
 
 type KeyServer interface {
@@ -334,6 +1566,16 @@ type KeyServer interface {
 	GetPublicKeys(context.Context, *GetPublicKeysRequest) (*GetPublicKeysResponse, error)
 	SamplePublicKeys(context.Context, *SamplePublicKeysRequest) (*SamplePublicKeysResponse, error)
 	GetPublicKeyDetails(context.Context, *GetPublicKeyDetailsRequest) (*GetPublicKeyDetailsResponse, error)
+	RevokePublicKeys(context.Context, *RevokePublicKeysRequest) (*RevokePublicKeysResponse, error)
+	RotatePublicKeys(context.Context, *RotatePublicKeysRequest) (*RotatePublicKeysResponse, error)
+	ListPublicKeys(context.Context, *ListPublicKeysRequest) (*ListPublicKeysResponse, error)
+	ImportPublicKeys(Key_ImportPublicKeysServer) error
+	ExportPublicKeys(*ExportPublicKeysRequest, Key_ExportPublicKeysServer) error
+	WatchPublicKeys(*WatchPublicKeysRequest, Key_WatchPublicKeysServer) error
+	VerifyAuditLog(context.Context, *VerifyAuditLogRequest) (*VerifyAuditLogResponse, error)
+	GetSigningKey(context.Context, *GetSigningKeyRequest) (*GetSigningKeyResponse, error)
+	GetTreeHead(context.Context, *GetTreeHeadRequest) (*GetTreeHeadResponse, error)
+	GetConsistencyProof(context.Context, *GetConsistencyProofRequest) (*GetConsistencyProofResponse, error)
 }
 
 func RegisterKeyServer(s *grpc.Server, srv KeyServer) {
@@ -412,6 +1654,200 @@ func _Key_GetPublicKeyDetails_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Key_RevokePublicKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokePublicKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServer).RevokePublicKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/keyapi.Key/RevokePublicKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServer).RevokePublicKeys(ctx, req.(*RevokePublicKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Key_RotatePublicKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotatePublicKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServer).RotatePublicKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/keyapi.Key/RotatePublicKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServer).RotatePublicKeys(ctx, req.(*RotatePublicKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Key_ListPublicKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPublicKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServer).ListPublicKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/keyapi.Key/ListPublicKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServer).ListPublicKeys(ctx, req.(*ListPublicKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Key_ImportPublicKeys_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(KeyServer).ImportPublicKeys(&keyImportPublicKeysServer{stream})
+}
+
+type Key_ImportPublicKeysServer interface {
+	SendAndClose(*ImportPublicKeysResponse) error
+	Recv() (*ImportPublicKeysRequest, error)
+	grpc.ServerStream
+}
+
+type keyImportPublicKeysServer struct {
+	grpc.ServerStream
+}
+
+func (x *keyImportPublicKeysServer) SendAndClose(m *ImportPublicKeysResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *keyImportPublicKeysServer) Recv() (*ImportPublicKeysRequest, error) {
+	m := new(ImportPublicKeysRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Key_ExportPublicKeys_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportPublicKeysRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KeyServer).ExportPublicKeys(m, &keyExportPublicKeysServer{stream})
+}
+
+type Key_ExportPublicKeysServer interface {
+	Send(*ExportPublicKeysResponse) error
+	grpc.ServerStream
+}
+
+type keyExportPublicKeysServer struct {
+	grpc.ServerStream
+}
+
+func (x *keyExportPublicKeysServer) Send(m *ExportPublicKeysResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Key_WatchPublicKeys_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPublicKeysRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KeyServer).WatchPublicKeys(m, &keyWatchPublicKeysServer{stream})
+}
+
+type Key_WatchPublicKeysServer interface {
+	Send(*WatchPublicKeysResponse) error
+	grpc.ServerStream
+}
+
+type keyWatchPublicKeysServer struct {
+	grpc.ServerStream
+}
+
+func (x *keyWatchPublicKeysServer) Send(m *WatchPublicKeysResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Key_VerifyAuditLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyAuditLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServer).VerifyAuditLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/keyapi.Key/VerifyAuditLog",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServer).VerifyAuditLog(ctx, req.(*VerifyAuditLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Key_GetSigningKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSigningKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServer).GetSigningKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/keyapi.Key/GetSigningKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServer).GetSigningKey(ctx, req.(*GetSigningKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Key_GetTreeHead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTreeHeadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServer).GetTreeHead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/keyapi.Key/GetTreeHead",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServer).GetTreeHead(ctx, req.(*GetTreeHeadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Key_GetConsistencyProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConsistencyProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServer).GetConsistencyProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/keyapi.Key/GetConsistencyProof",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServer).GetConsistencyProof(ctx, req.(*GetConsistencyProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Key_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "keyapi.Key",
 	HandlerType: (*KeyServer)(nil),
@@ -432,41 +1868,184 @@ var _Key_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetPublicKeyDetails",
 			Handler:    _Key_GetPublicKeyDetails_Handler,
 		},
+		{
+			MethodName: "RevokePublicKeys",
+			Handler:    _Key_RevokePublicKeys_Handler,
+		},
+		{
+			MethodName: "RotatePublicKeys",
+			Handler:    _Key_RotatePublicKeys_Handler,
+		},
+		{
+			MethodName: "ListPublicKeys",
+			Handler:    _Key_ListPublicKeys_Handler,
+		},
+		{
+			MethodName: "VerifyAuditLog",
+			Handler:    _Key_VerifyAuditLog_Handler,
+		},
+		{
+			MethodName: "GetSigningKey",
+			Handler:    _Key_GetSigningKey_Handler,
+		},
+		{
+			MethodName: "GetTreeHead",
+			Handler:    _Key_GetTreeHead_Handler,
+		},
+		{
+			MethodName: "GetConsistencyProof",
+			Handler:    _Key_GetConsistencyProof_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ImportPublicKeys",
+			Handler:       _Key_ImportPublicKeys_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ExportPublicKeys",
+			Handler:       _Key_ExportPublicKeys_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchPublicKeys",
+			Handler:       _Key_WatchPublicKeys_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/keyapi/key.proto",
 }
 
 func init() { proto.RegisterFile("pkg/keyapi/key.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 441 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x54, 0x41, 0x8f, 0x9a, 0x40,
-	0x14, 0x76, 0x20, 0x71, 0xd7, 0xa7, 0x76, 0xe9, 0xac, 0x1b, 0x09, 0x5b, 0x23, 0x9d, 0x5e, 0x88,
-	0x07, 0x9a, 0xd8, 0x4b, 0x2f, 0x3d, 0x98, 0x48, 0xda, 0xc6, 0xa4, 0x6d, 0xa6, 0x36, 0x3d, 0x52,
-	0x2c, 0x63, 0x43, 0xb0, 0x30, 0x15, 0x3c, 0xcc, 0xad, 0xff, 0xa0, 0x7f, 0xb1, 0x3f, 0x65, 0x03,
-	0x08, 0x08, 0xa2, 0x5e, 0x3c, 0x31, 0x99, 0xf7, 0xbd, 0xef, 0xfb, 0xde, 0xbc, 0xf7, 0x80, 0x01,
-	0xf7, 0x7f, 0xbd, 0xf6, 0x99, 0x70, 0xb8, 0x97, 0x7c, 0x4c, 0xbe, 0x0d, 0xe3, 0x10, 0xb7, 0xb3,
-	0x1b, 0xf2, 0x17, 0xc1, 0x60, 0xe6, 0xba, 0x5f, 0x76, 0xab, 0x8d, 0xf7, 0x73, 0xc1, 0x44, 0x44,
-	0xd9, 0x9f, 0x1d, 0x8b, 0x62, 0xfc, 0x08, 0x1d, 0x16, 0xc4, 0x5e, 0x2c, 0x6c, 0xcf, 0x55, 0x91,
-	0x8e, 0x8c, 0x0e, 0xbd, 0xcd, 0x2e, 0x3e, 0xba, 0x78, 0x02, 0xb7, 0x3e, 0x13, 0x76, 0x2c, 0x38,
-	0x53, 0x25, 0x1d, 0x19, 0xcf, 0xa6, 0x77, 0x66, 0x46, 0x68, 0x2e, 0x98, 0x58, 0x0a, 0xce, 0xe8,
-	0x8d, 0x9f, 0x1d, 0xf0, 0x18, 0xba, 0x3c, 0x65, 0xb7, 0x7d, 0x26, 0x22, 0x55, 0xd6, 0x65, 0xa3,
-	0x47, 0x81, 0x17, 0x82, 0x64, 0x08, 0x0f, 0x35, 0x07, 0x11, 0x0f, 0x83, 0x88, 0x91, 0x77, 0xa0,
-	0xbd, 0x67, 0x71, 0x11, 0x98, 0xb3, 0xd8, 0xf1, 0x36, 0x85, 0xc1, 0x8b, 0xbc, 0x2e, 0x3c, 0x36,
-	0xa6, 0x67, 0xec, 0xd8, 0x02, 0x5c, 0xe6, 0xdb, 0x6e, 0x16, 0x55, 0x91, 0x2e, 0x1b, 0xdd, 0xe9,
-	0x30, 0xaf, 0xa6, 0x96, 0x4d, 0x15, 0x5e, 0xa3, 0x23, 0x36, 0x0c, 0x0e, 0x55, 0xae, 0xfe, 0x7e,
-	0xe4, 0x2d, 0x3c, 0xd4, 0x04, 0xf6, 0x05, 0x5c, 0x7c, 0x80, 0x7f, 0x08, 0x86, 0x5f, 0x9d, 0xdf,
-	0x7c, 0xc3, 0x8e, 0xed, 0xe9, 0xd0, 0x0b, 0xd7, 0x76, 0xdd, 0x21, 0x84, 0x6b, 0x2b, 0xf7, 0x68,
-	0xc2, 0xfd, 0x36, 0x03, 0xb3, 0xed, 0x01, 0x50, 0x4a, 0x81, 0xcf, 0x8b, 0x50, 0x81, 0x27, 0xd0,
-	0x0f, 0xec, 0xaa, 0x21, 0x64, 0xf4, 0x69, 0x37, 0x28, 0xc5, 0x89, 0x03, 0xea, 0xb1, 0xa1, 0xeb,
-	0xf6, 0x43, 0xc0, 0x5d, 0x0d, 0x84, 0x47, 0x00, 0x25, 0x73, 0x5a, 0x69, 0x8f, 0x76, 0x8a, 0xc4,
-	0x6a, 0xa7, 0xa4, 0x33, 0x9d, 0x92, 0xcf, 0x77, 0x6a, 0xf2, 0x12, 0x6e, 0xf6, 0x77, 0x18, 0xa0,
-	0x3d, 0xfb, 0xb6, 0xfc, 0xf0, 0x99, 0x2a, 0xad, 0xe4, 0x4c, 0xad, 0xd9, 0xdc, 0xa2, 0x0a, 0x9a,
-	0xfe, 0x97, 0x40, 0x4e, 0x34, 0x3f, 0x41, 0xbf, 0x32, 0xf3, 0xf8, 0x45, 0xce, 0xda, 0xb4, 0x8c,
-	0xda, 0xe8, 0x44, 0x74, 0xbf, 0x28, 0xad, 0x84, 0xaf, 0x32, 0x24, 0x25, 0x5f, 0xd3, 0x70, 0x96,
-	0x7c, 0x8d, 0x93, 0x45, 0x5a, 0xf8, 0x3b, 0x28, 0xf5, 0x46, 0xe1, 0x71, 0x9e, 0x74, 0x62, 0xa6,
-	0x34, 0xfd, 0x34, 0xa0, 0x20, 0xfe, 0x01, 0xf7, 0x0d, 0x4b, 0x89, 0x49, 0x93, 0xa1, 0xea, 0xc2,
-	0x6b, 0xaf, 0xce, 0x62, 0x72, 0x85, 0x55, 0x3b, 0xfd, 0xc1, 0xbd, 0x79, 0x0a, 0x00, 0x00, 0xff,
-	0xff, 0x26, 0xe6, 0xb7, 0x17, 0xf8, 0x04, 0x00, 0x00,
+	// 2023 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x59, 0xcd, 0x72, 0xdb, 0xc8,
+	0x11, 0x16, 0x48, 0x8a, 0x02, 0x5b, 0xe2, 0x8f, 0xc7, 0xb2, 0x88, 0x40, 0x96, 0xcd, 0xc0, 0xb5,
+	0x1b, 0xae, 0x92, 0xd5, 0xda, 0x4a, 0xb4, 0xa9, 0x24, 0x27, 0xae, 0x08, 0xaf, 0x58, 0xfe, 0x91,
+	0x76, 0x48, 0xcb, 0xd9, 0x43, 0x82, 0xc0, 0xc4, 0x50, 0xc2, 0x8a, 0x02, 0xb8, 0x00, 0x24, 0x8b,
+	0x5b, 0x95, 0x4b, 0x2e, 0x39, 0xe4, 0x90, 0xaa, 0x54, 0xe5, 0x92, 0x53, 0xee, 0xb9, 0xa5, 0x2a,
+	0x97, 0x1c, 0xfd, 0x08, 0x79, 0x81, 0xbc, 0x4a, 0x6a, 0x66, 0x80, 0xc1, 0x0f, 0x01, 0x29, 0xaa,
+	0xd2, 0x9e, 0x44, 0x74, 0x7f, 0xd3, 0xd3, 0xdd, 0xd3, 0x3d, 0xdd, 0x3d, 0x82, 0xf5, 0xd9, 0xd9,
+	0xc9, 0x67, 0x67, 0x64, 0x6e, 0xce, 0x6c, 0xfa, 0x67, 0x67, 0xe6, 0xb9, 0x81, 0x8b, 0xaa, 0x9c,
+	0xa2, 0xfd, 0x47, 0x82, 0xf5, 0x9e, 0x65, 0x1d, 0x5d, 0xbc, 0x9b, 0xda, 0xe3, 0x17, 0x64, 0xee,
+	0x63, 0xf2, 0xed, 0x05, 0xf1, 0x03, 0xb4, 0x09, 0x35, 0xe2, 0x04, 0x76, 0x30, 0x37, 0x6c, 0x4b,
+	0x91, 0x3a, 0x52, 0xb7, 0x86, 0x65, 0x4e, 0x18, 0x58, 0x68, 0x1b, 0xe4, 0x33, 0x32, 0x37, 0x82,
+	0xf9, 0x8c, 0x28, 0xa5, 0x8e, 0xd4, 0x6d, 0xec, 0x36, 0x77, 0xb8, 0xc0, 0x9d, 0x17, 0x64, 0x3e,
+	0x9a, 0xcf, 0x08, 0x5e, 0x39, 0xe3, 0x3f, 0xd0, 0x63, 0x58, 0x9d, 0x31, 0xe9, 0xc6, 0x19, 0x99,
+	0xfb, 0x4a, 0xb9, 0x53, 0xee, 0xae, 0x61, 0x98, 0x89, 0x0d, 0xd1, 0x53, 0x00, 0x2a, 0x6c, 0xe2,
+	0x7a, 0xe7, 0x66, 0xa0, 0x54, 0x98, 0xb8, 0x7b, 0x09, 0x71, 0xcf, 0x19, 0x03, 0xd7, 0xce, 0xa2,
+	0x9f, 0xe8, 0x11, 0x80, 0x6f, 0x9f, 0x38, 0x66, 0x70, 0xe1, 0x11, 0x5f, 0x59, 0xe6, 0x12, 0x63,
+	0x8a, 0xd6, 0x86, 0x07, 0x19, 0x9b, 0xfc, 0x99, 0xeb, 0xf8, 0x44, 0xfb, 0xbb, 0x04, 0xea, 0x97,
+	0x24, 0x10, 0x9c, 0x3e, 0x09, 0x4c, 0x7b, 0x2a, 0x6c, 0xbe, 0x51, 0xd5, 0x87, 0x00, 0xa6, 0x6f,
+	0xb8, 0x13, 0x23, 0xb0, 0xcf, 0x09, 0x53, 0xb5, 0x8c, 0x65, 0xd3, 0x3f, 0x9c, 0x8c, 0xec, 0x73,
+	0x82, 0x14, 0x58, 0x99, 0x99, 0x5e, 0x60, 0x9b, 0x53, 0x65, 0xb9, 0x23, 0x75, 0x65, 0x1c, 0x7d,
+	0xa2, 0x4f, 0xa0, 0x65, 0x3b, 0xe3, 0xe9, 0x85, 0x6f, 0xbb, 0x8e, 0x31, 0xf3, 0x5c, 0x77, 0xe2,
+	0x2b, 0x55, 0x06, 0x69, 0x0a, 0xfa, 0x11, 0x23, 0x6b, 0x1f, 0x4a, 0xb0, 0x99, 0xab, 0x22, 0x37,
+	0x01, 0xe9, 0x80, 0x62, 0x1d, 0x0d, 0x8b, 0x73, 0x15, 0xa9, 0x53, 0xee, 0xae, 0xee, 0xb6, 0x23,
+	0xaf, 0x65, 0x56, 0xe3, 0xd6, 0x2c, 0x23, 0x0e, 0x3d, 0x83, 0x15, 0x8f, 0xf8, 0x17, 0xd3, 0xc0,
+	0x57, 0x4a, 0x05, 0x6b, 0x31, 0xe3, 0xe3, 0x08, 0x87, 0x7e, 0x0e, 0x35, 0xe1, 0x63, 0xa5, 0xdc,
+	0x91, 0xba, 0xab, 0xbb, 0x3f, 0x88, 0x16, 0x45, 0xea, 0x0d, 0x23, 0x00, 0x8e, 0xb1, 0xe8, 0x53,
+	0xa8, 0x05, 0x1e, 0x21, 0xc6, 0x29, 0x31, 0x2d, 0xe6, 0xb4, 0xd5, 0xdd, 0x56, 0xb4, 0x70, 0xe4,
+	0x11, 0x72, 0x40, 0x4c, 0x0b, 0xcb, 0x41, 0xf8, 0x0b, 0xf5, 0x72, 0x9c, 0xb5, 0xcc, 0x74, 0xdc,
+	0x88, 0x56, 0x0d, 0x52, 0x4e, 0x5b, 0x74, 0xe2, 0x9f, 0x24, 0x68, 0x66, 0xec, 0x40, 0x5b, 0x00,
+	0xb1, 0xe3, 0x58, 0x44, 0xaf, 0xe1, 0x9a, 0xf0, 0x0b, 0x5a, 0x87, 0xe5, 0x89, 0x7b, 0xe1, 0x58,
+	0x2c, 0x9e, 0x65, 0xcc, 0x3f, 0xd0, 0x3e, 0xdc, 0x5b, 0xf0, 0x76, 0x68, 0x7b, 0xa1, 0xb3, 0x9b,
+	0x19, 0x67, 0x6b, 0xbf, 0x87, 0xf5, 0xe4, 0x89, 0xde, 0x7d, 0x8a, 0xa5, 0xc3, 0xb2, 0x9c, 0x0e,
+	0x4b, 0xed, 0x5b, 0x78, 0x90, 0xd9, 0x3e, 0x0c, 0xa5, 0x1b, 0xc3, 0x3d, 0x75, 0xe2, 0x95, 0xff,
+	0xff, 0xc4, 0xb5, 0x3f, 0x4b, 0xd0, 0x1e, 0x9a, 0xe7, 0xb3, 0x29, 0x59, 0xb4, 0xba, 0x03, 0x6b,
+	0xee, 0xc4, 0xc8, 0x1a, 0x0e, 0xee, 0x44, 0x8f, 0x4c, 0xdf, 0x81, 0xfb, 0x1e, 0x07, 0x13, 0x2f,
+	0x01, 0x2c, 0x31, 0xe0, 0x3d, 0xc1, 0x12, 0x78, 0x0d, 0xea, 0x8e, 0x91, 0xb6, 0x44, 0xea, 0xd6,
+	0xf1, 0xaa, 0x13, 0x6f, 0xae, 0xfd, 0x4d, 0x02, 0x65, 0x51, 0xa3, 0xbb, 0xcd, 0xa9, 0x94, 0xbb,
+	0x4a, 0xb7, 0x70, 0xd7, 0x5b, 0x68, 0x63, 0x72, 0xe9, 0x9e, 0x91, 0x5b, 0xc6, 0x48, 0xe6, 0x00,
+	0x4b, 0xd9, 0x03, 0xd4, 0x54, 0x50, 0x16, 0x05, 0x87, 0x77, 0xe1, 0x5f, 0x4a, 0xd0, 0xc6, 0x6e,
+	0x60, 0x06, 0xe4, 0x7b, 0x8c, 0xcc, 0x8f, 0xa1, 0xe9, 0x4e, 0x2d, 0x63, 0x31, 0xcc, 0xea, 0xee,
+	0x34, 0x71, 0x41, 0x53, 0x9c, 0x43, 0xde, 0xa7, 0x70, 0x15, 0x8e, 0x73, 0xc8, 0xfb, 0xa3, 0x64,
+	0x44, 0x36, 0x28, 0x2e, 0x51, 0x2f, 0x96, 0x8b, 0xea, 0xc5, 0x9a, 0x43, 0xde, 0x8b, 0x2f, 0xf4,
+	0x11, 0x5f, 0x98, 0x28, 0x1b, 0x55, 0x21, 0x7f, 0x18, 0x57, 0x0e, 0xea, 0xb0, 0x05, 0x9f, 0x84,
+	0x0e, 0xfb, 0x77, 0x09, 0x1e, 0xbc, 0xb4, 0xfd, 0xdb, 0x26, 0xf2, 0x4f, 0xa0, 0x16, 0xb9, 0x8b,
+	0x1f, 0x51, 0x8e, 0xbf, 0xe4, 0xd0, 0x5f, 0x3e, 0x3d, 0x52, 0xd3, 0xb2, 0x88, 0x65, 0x98, 0x93,
+	0x80, 0x78, 0x61, 0x2e, 0x03, 0x23, 0xf5, 0x28, 0x05, 0x7d, 0x02, 0x55, 0x3f, 0x30, 0x83, 0x0b,
+	0x3f, 0xa7, 0x52, 0x0e, 0x19, 0x03, 0x87, 0x00, 0xaa, 0xd6, 0xcc, 0x3c, 0x21, 0x86, 0x6f, 0x7f,
+	0x47, 0x98, 0x9f, 0xea, 0x58, 0xa6, 0x84, 0xa1, 0xfd, 0x1d, 0x61, 0xd7, 0x21, 0x65, 0x06, 0xee,
+	0x19, 0x71, 0x58, 0x31, 0xaa, 0x61, 0x06, 0x1f, 0x51, 0x02, 0xf5, 0xd7, 0xb9, 0x6b, 0xd9, 0x13,
+	0x5b, 0xa8, 0xb2, 0xc2, 0x54, 0xa9, 0x47, 0x54, 0xae, 0xcd, 0x8f, 0xa0, 0x29, 0x60, 0xef, 0xc8,
+	0xc4, 0xf5, 0x88, 0x22, 0x33, 0x9c, 0x58, 0xfd, 0x05, 0xa3, 0x6a, 0x7f, 0x94, 0x60, 0x23, 0xeb,
+	0xbc, 0xbb, 0xcd, 0x3e, 0x16, 0x42, 0x57, 0x81, 0x91, 0xb0, 0x8a, 0xdf, 0x18, 0x75, 0x4a, 0x3e,
+	0x8a, 0x2c, 0xd3, 0xfe, 0x29, 0x41, 0x7b, 0x70, 0x3e, 0x73, 0xbd, 0x9c, 0x83, 0xbc, 0x23, 0x55,
+	0xd2, 0x1d, 0x4d, 0xe9, 0xd6, 0x1d, 0x4d, 0x79, 0xa1, 0xa3, 0xf9, 0x87, 0x04, 0xca, 0xa2, 0xd2,
+	0xa1, 0x03, 0xb7, 0x00, 0x1c, 0xc3, 0x76, 0x7c, 0xe2, 0x05, 0x84, 0xc7, 0x5f, 0x1d, 0xd7, 0x9c,
+	0x41, 0x48, 0xa0, 0x21, 0xe5, 0x18, 0xd6, 0xc5, 0x6c, 0x6a, 0x8f, 0xcd, 0x80, 0xa7, 0x6c, 0x1d,
+	0x83, 0xd3, 0x8f, 0x28, 0x7c, 0xbd, 0x47, 0xbe, 0x21, 0x63, 0xba, 0xbe, 0x1c, 0xae, 0xc7, 0x21,
+	0x01, 0x3d, 0x85, 0xf5, 0x88, 0x99, 0x93, 0xa0, 0x28, 0xe2, 0x25, 0x2e, 0xdb, 0xcf, 0xa1, 0xad,
+	0x5f, 0xe5, 0x7b, 0x38, 0x15, 0x93, 0x52, 0x3a, 0x26, 0x35, 0x13, 0x14, 0xfd, 0xaa, 0xc0, 0xc8,
+	0xf4, 0xd1, 0x78, 0x64, 0xec, 0x7a, 0x56, 0xf1, 0xd1, 0x60, 0xc6, 0x4f, 0x1c, 0x0d, 0x27, 0xb0,
+	0x3a, 0xd0, 0xcc, 0xa0, 0xf2, 0x8b, 0xbc, 0x74, 0xbb, 0x22, 0x4f, 0x9d, 0xc8, 0x13, 0x97, 0xd5,
+	0xe0, 0x12, 0x4b, 0x82, 0x1a, 0xa3, 0xb0, 0xde, 0xf0, 0x09, 0xd4, 0x2d, 0xdb, 0x37, 0xdf, 0x4d,
+	0x23, 0x04, 0xcf, 0xec, 0xb5, 0x88, 0xc8, 0x2a, 0xf5, 0x6f, 0x61, 0xe3, 0xad, 0x19, 0x8c, 0x4f,
+	0x17, 0xdd, 0xb6, 0x05, 0x20, 0x6e, 0x18, 0x6e, 0x75, 0x0d, 0xd7, 0xa2, 0x2b, 0xc6, 0xa7, 0xd9,
+	0xca, 0x92, 0xd4, 0xf0, 0x29, 0xde, 0x19, 0x73, 0x05, 0x2a, 0xb8, 0xce, 0xa8, 0xc3, 0x90, 0xa8,
+	0x0d, 0xa0, 0xbd, 0x20, 0x3f, 0x74, 0xef, 0x0e, 0x54, 0xc9, 0x25, 0x71, 0x82, 0xc8, 0xa5, 0x1b,
+	0x0b, 0x86, 0xeb, 0x94, 0x8d, 0x43, 0x94, 0xf6, 0x2f, 0x09, 0x1a, 0x69, 0x16, 0x52, 0x41, 0x16,
+	0xdb, 0x4b, 0x6c, 0x7b, 0xf1, 0x8d, 0x76, 0xa0, 0x92, 0xa8, 0x17, 0x6a, 0xbe, 0x70, 0x76, 0x15,
+	0x32, 0xdc, 0x9d, 0xf4, 0x5d, 0x08, 0x41, 0x25, 0xd1, 0xa7, 0x57, 0x82, 0xb0, 0x19, 0x3a, 0x26,
+	0x9e, 0x3d, 0x99, 0xf7, 0x2e, 0x2c, 0x3b, 0x78, 0xe9, 0x9e, 0x44, 0x1e, 0xfe, 0x0c, 0xee, 0x8f,
+	0x4f, 0xc9, 0xf8, 0x6c, 0xe6, 0xda, 0x4e, 0x60, 0x64, 0x0c, 0x41, 0x31, 0x2b, 0x72, 0x26, 0xbd,
+	0xfa, 0x12, 0x0b, 0x4e, 0x4d, 0xff, 0x94, 0x59, 0xb7, 0x86, 0x1b, 0x31, 0xf9, 0xc0, 0xf4, 0x4f,
+	0xb5, 0xff, 0x4a, 0xb0, 0x91, 0xdd, 0x33, 0xf4, 0xfa, 0x3a, 0x2c, 0x5f, 0x9a, 0xd3, 0xb0, 0x68,
+	0xc8, 0x98, 0x7f, 0xd0, 0x1c, 0x71, 0x44, 0x84, 0xf3, 0x83, 0x94, 0x9d, 0x30, 0x80, 0x69, 0x20,
+	0xd1, 0x3e, 0x3a, 0xd6, 0xb0, 0xcc, 0x00, 0x6b, 0x94, 0x28, 0x74, 0xdb, 0x84, 0x1a, 0x03, 0x31,
+	0xad, 0x2a, 0x4c, 0x2b, 0x99, 0x12, 0xa8, 0x3e, 0x7c, 0x18, 0x61, 0x3b, 0xc5, 0x42, 0x96, 0x99,
+	0x90, 0x66, 0x48, 0x17, 0x72, 0x3e, 0x82, 0x46, 0x04, 0xf5, 0x88, 0xe9, 0xbb, 0x51, 0xa1, 0xa8,
+	0x87, 0x54, 0xcc, 0x88, 0xda, 0x1f, 0xca, 0xb0, 0xca, 0x6c, 0x0b, 0x13, 0xea, 0xba, 0x48, 0x88,
+	0x0e, 0xa5, 0x14, 0x1f, 0x0a, 0xea, 0x42, 0x6b, 0x6c, 0x4e, 0xa7, 0xa9, 0x6e, 0xaf, 0xcc, 0x36,
+	0x6a, 0x70, 0xba, 0x68, 0xf5, 0x7e, 0x0c, 0x55, 0x73, 0x1c, 0xd8, 0xae, 0x13, 0x56, 0xbf, 0xfb,
+	0x51, 0x30, 0xb0, 0xed, 0x7b, 0x8c, 0x85, 0x43, 0x48, 0xba, 0x2c, 0x2f, 0x5f, 0xd3, 0xc5, 0x54,
+	0x6f, 0xe8, 0x62, 0xf6, 0xa0, 0xcd, 0x73, 0x3b, 0x11, 0x93, 0xd4, 0xb5, 0xc4, 0x57, 0x56, 0xd8,
+	0x25, 0xb8, 0xce, 0xd8, 0x22, 0x20, 0x0f, 0x18, 0x0f, 0xfd, 0x0a, 0x54, 0x91, 0xf3, 0x8b, 0x2b,
+	0x65, 0xb6, 0xb2, 0x1d, 0x21, 0xb2, 0x8b, 0xe9, 0x45, 0xe9, 0x91, 0x4b, 0x7e, 0x84, 0x35, 0x7e,
+	0x84, 0x94, 0xc0, 0x8e, 0x10, 0x41, 0x85, 0xd1, 0x81, 0xd1, 0xd9, 0x6f, 0x6d, 0x83, 0x4d, 0x19,
+	0xb4, 0x97, 0xb1, 0x9d, 0x13, 0x76, 0xb9, 0xb1, 0xc0, 0xd6, 0xbe, 0x81, 0x07, 0x19, 0x7a, 0xdc,
+	0xfe, 0xfb, 0x9c, 0x9a, 0x98, 0x88, 0xc0, 0x17, 0xc0, 0xdb, 0x97, 0x31, 0x4d, 0x87, 0x7b, 0x0b,
+	0x8d, 0xae, 0x38, 0x71, 0x29, 0x71, 0xe2, 0x0f, 0xb3, 0xad, 0xf2, 0x5a, 0xb2, 0x1f, 0x5e, 0x07,
+	0xf4, 0x25, 0x09, 0xc4, 0x68, 0x18, 0x1a, 0xd2, 0x87, 0xfb, 0x29, 0x6a, 0x68, 0x46, 0x6a, 0xba,
+	0x94, 0x6e, 0x9a, 0x2e, 0x35, 0x87, 0xbd, 0x00, 0xec, 0xbb, 0x8e, 0x6f, 0xfb, 0x01, 0x71, 0xc6,
+	0x73, 0x3e, 0x42, 0x86, 0xb7, 0xc0, 0xc7, 0xd0, 0x9c, 0xd8, 0x9e, 0x1f, 0x18, 0x4c, 0xa4, 0x28,
+	0x52, 0x15, 0x5c, 0x67, 0x64, 0x2a, 0x8f, 0x75, 0x4f, 0x5d, 0x68, 0xf9, 0x64, 0xec, 0x3a, 0x56,
+	0x02, 0xc8, 0x33, 0xb5, 0xc1, 0xe9, 0x11, 0x52, 0xdb, 0x83, 0xcd, 0xdc, 0xfd, 0x42, 0xed, 0x37,
+	0xa0, 0x1a, 0xc6, 0x83, 0xc4, 0xe2, 0x21, 0xfc, 0xd2, 0x2e, 0x41, 0x8e, 0x94, 0xa7, 0xa1, 0x90,
+	0x55, 0x47, 0x0e, 0x42, 0xf9, 0x94, 0xe9, 0xb9, 0x6e, 0xea, 0x02, 0x92, 0x29, 0x21, 0x8a, 0x93,
+	0x44, 0xb1, 0xc9, 0x71, 0x7d, 0x25, 0xeb, 0xfa, 0xdf, 0x40, 0x23, 0x3d, 0x5c, 0xc7, 0x83, 0xb1,
+	0x94, 0x1c, 0x8c, 0xb7, 0x00, 0xa6, 0xc4, 0x9c, 0x18, 0xb6, 0x63, 0x91, 0xab, 0xd0, 0xf4, 0x1a,
+	0xa5, 0x0c, 0x28, 0x21, 0x61, 0x56, 0x39, 0x65, 0xd6, 0x5f, 0x93, 0xe5, 0x37, 0xae, 0x9c, 0xd7,
+	0x0d, 0xe6, 0xa9, 0x2c, 0x2e, 0x5d, 0x93, 0xc5, 0xe5, 0x1b, 0xb2, 0x58, 0x05, 0x39, 0x4a, 0x36,
+	0x66, 0xb7, 0x8c, 0xc5, 0xf7, 0xf6, 0x0f, 0x61, 0x25, 0xc4, 0x23, 0x80, 0x6a, 0xef, 0xcd, 0xe8,
+	0xe0, 0x10, 0xb7, 0x96, 0xe8, 0x6f, 0xac, 0xf7, 0xfa, 0x3a, 0x6e, 0x49, 0xdb, 0x3f, 0x83, 0x9a,
+	0x68, 0xb1, 0x51, 0x03, 0xa0, 0xf7, 0xfa, 0x6b, 0x63, 0x38, 0xea, 0x8d, 0xde, 0x0c, 0x39, 0xb0,
+	0xb7, 0x3f, 0x1a, 0x1c, 0xeb, 0x2d, 0x09, 0xad, 0xc2, 0x0a, 0xd6, 0x8f, 0x0f, 0x5f, 0xe8, 0xfd,
+	0x56, 0x69, 0xfb, 0x53, 0x40, 0x8b, 0x45, 0x0e, 0xd5, 0x60, 0xb9, 0xd7, 0xef, 0xeb, 0xfd, 0xd6,
+	0x12, 0x5a, 0x03, 0xb9, 0x3f, 0x18, 0xf6, 0xbe, 0x78, 0xa9, 0xf7, 0x5b, 0xd2, 0xf6, 0x31, 0xdb,
+	0x24, 0x6c, 0x0a, 0x37, 0x00, 0xbd, 0x79, 0x3d, 0x3c, 0xd2, 0xf7, 0x07, 0xcf, 0x07, 0x7a, 0xdf,
+	0x78, 0x7e, 0x88, 0x5f, 0xf5, 0x46, 0xad, 0x25, 0xba, 0x81, 0xde, 0xdf, 0xdd, 0xdb, 0x7b, 0xf6,
+	0x8b, 0x96, 0x44, 0x77, 0xfe, 0x35, 0xff, 0x5d, 0x42, 0x0a, 0xac, 0x0f, 0xf5, 0xfd, 0xa3, 0xdd,
+	0xbd, 0xcf, 0x5f, 0x3c, 0x33, 0xf6, 0x0f, 0x5f, 0x1d, 0x61, 0x7d, 0x38, 0xd4, 0xfb, 0xad, 0xf2,
+	0xf6, 0x2f, 0x61, 0x35, 0x71, 0x43, 0xa2, 0x15, 0x28, 0xf7, 0xfa, 0xfd, 0xc8, 0x40, 0xaa, 0x2b,
+	0x97, 0x84, 0x0f, 0x47, 0xbd, 0x91, 0xde, 0x2a, 0xd1, 0xdf, 0x83, 0x57, 0x47, 0x87, 0x78, 0xd4,
+	0x2a, 0xef, 0x7e, 0xa8, 0x41, 0x99, 0x1e, 0xc4, 0x6b, 0xa8, 0xa7, 0x5e, 0xd5, 0xd0, 0x43, 0x71,
+	0xf9, 0xe6, 0x3c, 0x20, 0xaa, 0x5b, 0x05, 0xdc, 0x70, 0x9a, 0x5a, 0xa2, 0xf2, 0x52, 0xef, 0x12,
+	0xb1, 0xbc, 0xbc, 0xd7, 0x12, 0x75, 0xab, 0x80, 0x2b, 0xe4, 0xbd, 0x85, 0x56, 0x76, 0xc2, 0x47,
+	0x8f, 0xa3, 0x45, 0x05, 0xaf, 0x11, 0x6a, 0xa7, 0x18, 0x20, 0x04, 0xff, 0x8e, 0x5d, 0x3c, 0xd9,
+	0x17, 0x39, 0xa4, 0xe5, 0x29, 0x94, 0x7e, 0x51, 0x54, 0x9f, 0x5c, 0x8b, 0x49, 0xaa, 0x9e, 0x9d,
+	0xd3, 0x63, 0xd5, 0x0b, 0x9e, 0x06, 0xd4, 0x4e, 0x31, 0x20, 0x25, 0x38, 0x33, 0xcf, 0x26, 0x04,
+	0xe7, 0x4f, 0xff, 0x6a, 0xa7, 0x18, 0x20, 0x04, 0x7f, 0x05, 0x8d, 0xf4, 0x38, 0x87, 0xc4, 0xf9,
+	0xe4, 0xce, 0xc8, 0xea, 0xa3, 0x22, 0xb6, 0x10, 0xf9, 0x35, 0xb4, 0xb2, 0x23, 0x4e, 0xac, 0x6b,
+	0xc1, 0xc4, 0xa6, 0x76, 0x8a, 0x01, 0x91, 0xe0, 0xae, 0x44, 0x45, 0xeb, 0x57, 0x45, 0xa2, 0xf5,
+	0xab, 0x1b, 0x44, 0x17, 0xcd, 0x24, 0xda, 0xd2, 0x53, 0x09, 0x1d, 0x43, 0x33, 0xd3, 0x53, 0x23,
+	0x61, 0x6a, 0x7e, 0x33, 0xaf, 0x3e, 0x2e, 0xe4, 0x27, 0xe4, 0x7e, 0x05, 0x8d, 0x74, 0xd3, 0x18,
+	0x3b, 0x38, 0xb7, 0x81, 0x55, 0x1f, 0x15, 0xb1, 0x33, 0x09, 0x17, 0x77, 0x02, 0xa9, 0x84, 0x5b,
+	0x68, 0x1c, 0xd4, 0xad, 0x02, 0xae, 0x90, 0x77, 0x00, 0xab, 0x89, 0x82, 0x8c, 0xd4, 0x04, 0x3e,
+	0x53, 0xbb, 0xd5, 0xcd, 0x5c, 0x5e, 0x26, 0xc3, 0xb2, 0x45, 0x32, 0x95, 0x61, 0x05, 0x15, 0x5b,
+	0x7d, 0x72, 0x2d, 0x26, 0xda, 0xe1, 0x5d, 0x95, 0xfd, 0xdb, 0xe3, 0xa7, 0xff, 0x1b, 0x00, 0x2f,
+	0xe7, 0x91, 0x1a, 0x0e, 0x19, 0x00, 0x00,
 }
@@ -4,10 +4,12 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestValidateAddPublicKeysRequest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
 	cases := map[string]struct {
 		rq       *AddPublicKeysRequest
 		expected error
@@ -15,13 +17,14 @@ func TestValidateAddPublicKeysRequest(t *testing.T) {
 		"ok": {
 			rq: &AddPublicKeysRequest{
 				EntityId:   "some entity ID",
-				PublicKeys: [][]byte{{1, 2, 3}},
+				PublicKeys: [][]byte{NewTestPublicKey(rng)},
+				KeyFormat:  KeyFormat_SECP256K1_COMPRESSED,
 			},
 			expected: nil,
 		},
 		"missing entity ID": {
 			rq: &AddPublicKeysRequest{
-				PublicKeys: [][]byte{{1, 2, 3}},
+				PublicKeys: [][]byte{NewTestPublicKey(rng)},
 			},
 			expected: ErrEmptyEntityID,
 		},
@@ -31,10 +34,33 @@ func TestValidateAddPublicKeysRequest(t *testing.T) {
 			},
 			expected: ErrEmptyPublicKeys,
 		},
+		"invalid public key format": {
+			rq: &AddPublicKeysRequest{
+				EntityId:   "some entity ID",
+				PublicKeys: [][]byte{NewTestPublicKey(rng), {1, 2, 3}},
+				KeyFormat:  KeyFormat_SECP256K1_COMPRESSED,
+			},
+			expected: ErrInvalidPublicKeyLength,
+		},
+		"unspecified public key format": {
+			rq: &AddPublicKeysRequest{
+				EntityId:   "some entity ID",
+				PublicKeys: [][]byte{NewTestPublicKey(rng), {1, 2, 3}},
+			},
+			expected: ErrUnspecifiedKeyFormat,
+		},
+		"unknown key format": {
+			rq: &AddPublicKeysRequest{
+				EntityId:   "some entity ID",
+				PublicKeys: [][]byte{NewTestPublicKey(rng)},
+				KeyFormat:  KeyFormat(-1),
+			},
+			expected: ErrUnknownKeyFormat,
+		},
 	}
-	for _, c := range cases {
+	for desc, c := range cases {
 		err := ValidateAddPublicKeysRequest(c.rq)
-		assert.Equal(t, c.expected, err)
+		assert.Equal(t, c.expected, errors.Cause(err), desc)
 	}
 }
 
@@ -49,10 +75,24 @@ func TestValidateGetPublicKeysRequest(t *testing.T) {
 			},
 			expected: nil,
 		},
+		"ok as-of": {
+			rq: &GetPublicKeysRequest{
+				EntityId: "some entity ID",
+				AsOfTime: 1520000000000000,
+			},
+			expected: nil,
+		},
 		"missing entity ID": {
 			rq:       &GetPublicKeysRequest{},
 			expected: ErrEmptyEntityID,
 		},
+		"negative as-of time": {
+			rq: &GetPublicKeysRequest{
+				EntityId: "some entity ID",
+				AsOfTime: -1,
+			},
+			expected: ErrNegativeAsOfTime,
+		},
 	}
 	for _, c := range cases {
 		err := ValidateGetPublicKeysRequest(c.rq)
@@ -71,10 +111,24 @@ func TestValidateGetPublicKeyDetailsRequest(t *testing.T) {
 			},
 			expected: nil,
 		},
+		"ok as-of": {
+			rq: &GetPublicKeyDetailsRequest{
+				PublicKeys: [][]byte{{1, 2, 3}},
+				AsOfTime:   1520000000000000,
+			},
+			expected: nil,
+		},
 		"missing public keys": {
 			rq:       &GetPublicKeyDetailsRequest{},
 			expected: ErrEmptyPublicKeys,
 		},
+		"negative as-of time": {
+			rq: &GetPublicKeyDetailsRequest{
+				PublicKeys: [][]byte{{1, 2, 3}},
+				AsOfTime:   -1,
+			},
+			expected: ErrNegativeAsOfTime,
+		},
 	}
 	for _, c := range cases {
 		err := ValidateGetPublicKeyDetailsRequest(c.rq)
@@ -82,6 +136,106 @@ func TestValidateGetPublicKeyDetailsRequest(t *testing.T) {
 	}
 }
 
+func TestValidateRevokePublicKeysRequest(t *testing.T) {
+	cases := map[string]struct {
+		rq       *RevokePublicKeysRequest
+		expected error
+	}{
+		"ok": {
+			rq: &RevokePublicKeysRequest{
+				EntityId:   "some entity ID",
+				PublicKeys: [][]byte{{1, 2, 3}},
+			},
+			expected: nil,
+		},
+		"missing entity ID": {
+			rq: &RevokePublicKeysRequest{
+				PublicKeys: [][]byte{{1, 2, 3}},
+			},
+			expected: ErrEmptyEntityID,
+		},
+		"missing public keys": {
+			rq: &RevokePublicKeysRequest{
+				EntityId: "some entity ID",
+			},
+			expected: ErrEmptyPublicKeys,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateRevokePublicKeysRequest(c.rq)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestValidateRotatePublicKeysRequest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	oldPK, newPK := NewTestPublicKey(rng), NewTestPublicKey(rng)
+	cases := map[string]struct {
+		rq       *RotatePublicKeysRequest
+		expected error
+	}{
+		"ok": {
+			rq: &RotatePublicKeysRequest{
+				EntityId:      "some entity ID",
+				OldPublicKeys: [][]byte{oldPK},
+				NewPublicKeys: [][]byte{newPK},
+				NewKeyFormat:  KeyFormat_SECP256K1_COMPRESSED,
+			},
+			expected: nil,
+		},
+		"missing entity ID": {
+			rq: &RotatePublicKeysRequest{
+				OldPublicKeys: [][]byte{oldPK},
+				NewPublicKeys: [][]byte{newPK},
+			},
+			expected: ErrEmptyEntityID,
+		},
+		"missing old public keys": {
+			rq: &RotatePublicKeysRequest{
+				EntityId:      "some entity ID",
+				NewPublicKeys: [][]byte{newPK},
+			},
+			expected: ErrEmptyPublicKeys,
+		},
+		"missing new public keys": {
+			rq: &RotatePublicKeysRequest{
+				EntityId:      "some entity ID",
+				OldPublicKeys: [][]byte{oldPK},
+			},
+			expected: ErrEmptyPublicKeys,
+		},
+		"public key in old and new": {
+			rq: &RotatePublicKeysRequest{
+				EntityId:      "some entity ID",
+				OldPublicKeys: [][]byte{oldPK},
+				NewPublicKeys: [][]byte{newPK, oldPK},
+			},
+			expected: ErrDupPublicKeys,
+		},
+		"invalid new public key format": {
+			rq: &RotatePublicKeysRequest{
+				EntityId:      "some entity ID",
+				OldPublicKeys: [][]byte{oldPK},
+				NewPublicKeys: [][]byte{newPK},
+				NewKeyFormat:  KeyFormat_ED25519,
+			},
+			expected: ErrInvalidPublicKeyLength,
+		},
+		"unspecified new public key format": {
+			rq: &RotatePublicKeysRequest{
+				EntityId:      "some entity ID",
+				OldPublicKeys: [][]byte{oldPK},
+				NewPublicKeys: [][]byte{{1, 2, 3}},
+			},
+			expected: ErrUnspecifiedKeyFormat,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateRotatePublicKeysRequest(c.rq)
+		assert.Equal(t, c.expected, errors.Cause(err), desc)
+	}
+}
+
 func TestValidateSamplePublicKeysRequest(t *testing.T) {
 	cases := map[string]struct {
 		rq       *SamplePublicKeysRequest
@@ -131,6 +285,203 @@ func TestValidateSamplePublicKeysRequest(t *testing.T) {
 	}
 }
 
+func TestValidateListPublicKeysRequest(t *testing.T) {
+	cases := map[string]struct {
+		rq       *ListPublicKeysRequest
+		expected error
+	}{
+		"ok": {
+			rq:       &ListPublicKeysRequest{},
+			expected: nil,
+		},
+		"ok filtered": {
+			rq: &ListPublicKeysRequest{
+				EntityId:   "some entity ID",
+				KeyTypes:   []KeyType{KeyType_READER},
+				AddedAfter: 1520000000000000,
+				Status:     KeyStatus_ACTIVE,
+				PageSize:   MaxListPageSize,
+				PageToken:  EncodePageToken([]byte{1, 2, 3}),
+			},
+			expected: nil,
+		},
+		"ok modified range": {
+			rq: &ListPublicKeysRequest{
+				ModifiedAfter:  1520000000000000,
+				ModifiedBefore: 1520000000000001,
+			},
+			expected: nil,
+		},
+		"negative added-after time": {
+			rq: &ListPublicKeysRequest{
+				AddedAfter: -1,
+			},
+			expected: ErrNegativeAddedAfter,
+		},
+		"negative modified-after time": {
+			rq: &ListPublicKeysRequest{
+				ModifiedAfter: -1,
+			},
+			expected: ErrNegativeModifiedTime,
+		},
+		"negative modified-before time": {
+			rq: &ListPublicKeysRequest{
+				ModifiedBefore: -1,
+			},
+			expected: ErrNegativeModifiedTime,
+		},
+		"empty modified range": {
+			rq: &ListPublicKeysRequest{
+				ModifiedAfter:  1520000000000000,
+				ModifiedBefore: 1520000000000000,
+			},
+			expected: ErrEmptyModifiedRange,
+		},
+		"page size too large": {
+			rq: &ListPublicKeysRequest{
+				PageSize: MaxListPageSize + 1,
+			},
+			expected: ErrPageSizeTooLarge,
+		},
+		"invalid page token": {
+			rq: &ListPublicKeysRequest{
+				PageToken: "not a page token",
+			},
+			expected: ErrInvalidPageToken,
+		},
+	}
+	for _, c := range cases {
+		err := ValidateListPublicKeysRequest(c.rq)
+		assert.Equal(t, c.expected, err)
+	}
+}
+
+func TestValidateImportPublicKeysRequest(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkds := NewTestPublicKeyDetails(rng, 2)
+	cases := map[string]struct {
+		rq       *ImportPublicKeysRequest
+		expected error
+	}{
+		"ok": {
+			rq: &ImportPublicKeysRequest{
+				PublicKeyDetails: pkds,
+				KeyFormat:        KeyFormat_SECP256K1_COMPRESSED,
+			},
+			expected: nil,
+		},
+		"ok with signatures": {
+			rq: &ImportPublicKeysRequest{
+				PublicKeyDetails: pkds,
+				KeyFormat:        KeyFormat_ED25519,
+				Signatures:       [][]byte{{1, 2, 3}, {4, 5, 6}},
+			},
+			expected: nil,
+		},
+		"ok with invalid public key detail": {
+			rq: &ImportPublicKeysRequest{
+				PublicKeyDetails: []*PublicKeyDetail{{}},
+				KeyFormat:        KeyFormat_SECP256K1_COMPRESSED,
+			},
+			expected: nil,
+		},
+		"empty public key details": {
+			rq:       &ImportPublicKeysRequest{},
+			expected: ErrEmptyPublicKeys,
+		},
+		"missing signature": {
+			rq: &ImportPublicKeysRequest{
+				PublicKeyDetails: pkds,
+				Signatures:       [][]byte{{1, 2, 3}},
+			},
+			expected: ErrMissingProofsOfPossession,
+		},
+		"unknown key format": {
+			rq: &ImportPublicKeysRequest{
+				PublicKeyDetails: pkds,
+				KeyFormat:        KeyFormat(-1),
+			},
+			expected: ErrUnknownKeyFormat,
+		},
+		"unspecified key format": {
+			rq:       &ImportPublicKeysRequest{PublicKeyDetails: pkds},
+			expected: ErrUnspecifiedKeyFormat,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateImportPublicKeysRequest(c.rq)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestValidateExportPublicKeysRequest(t *testing.T) {
+	cases := map[string]struct {
+		rq       *ExportPublicKeysRequest
+		expected error
+	}{
+		"ok": {
+			rq:       &ExportPublicKeysRequest{PageSize: MaxListPageSize},
+			expected: nil,
+		},
+		"ok default page size": {
+			rq:       &ExportPublicKeysRequest{},
+			expected: nil,
+		},
+		"page size too large": {
+			rq:       &ExportPublicKeysRequest{PageSize: MaxListPageSize + 1},
+			expected: ErrPageSizeTooLarge,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateExportPublicKeysRequest(c.rq)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestValidateWatchPublicKeysRequest(t *testing.T) {
+	cases := map[string]struct {
+		rq       *WatchPublicKeysRequest
+		expected error
+	}{
+		"ok": {
+			rq: &WatchPublicKeysRequest{
+				EntityIds:     []string{"entity 1", "entity 2"},
+				AfterSequence: 1,
+			},
+			expected: nil,
+		},
+		"ok all entities": {
+			rq:       &WatchPublicKeysRequest{},
+			expected: nil,
+		},
+		"empty entity ID": {
+			rq:       &WatchPublicKeysRequest{EntityIds: []string{"entity 1", ""}},
+			expected: ErrEmptyEntityID,
+		},
+		"too many entity IDs": {
+			rq: &WatchPublicKeysRequest{
+				EntityIds: make([]string, MaxWatchEntityIDs+1),
+			},
+			expected: ErrTooManyEntityIDs,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidateWatchPublicKeysRequest(c.rq)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestEncodeDecodePageToken(t *testing.T) {
+	lastPK := []byte{1, 2, 3}
+	decoded, err := DecodePageToken(EncodePageToken(lastPK))
+	assert.Nil(t, err)
+	assert.Equal(t, lastPK, decoded)
+
+	decoded, err = DecodePageToken("")
+	assert.Nil(t, err)
+	assert.Nil(t, decoded)
+}
+
 func TestValidatePublicKeyDetails(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	okPKD := NewTestPublicKeyDetail(rng)
@@ -198,6 +549,100 @@ func TestValidatePublicKeyDetail(t *testing.T) {
 	}
 }
 
+func TestValidatePublicKeyRecords(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	okRecord := &PublicKeyRecord{
+		PublicKeyDetail: NewTestPublicKeyDetail(rng),
+		AddedTime:       1520000000000000,
+	}
+	cases := map[string]struct {
+		records  []*PublicKeyRecord
+		expected error
+	}{
+		"ok": {
+			records:  []*PublicKeyRecord{okRecord},
+			expected: nil,
+		},
+		"nil value": {
+			records:  nil,
+			expected: ErrEmptyPublicKeys,
+		},
+		"record missing required fields": {
+			records:  []*PublicKeyRecord{{}},
+			expected: ErrEmptyPublicKeyDetail,
+		},
+		"duplicate record": {
+			records:  []*PublicKeyRecord{okRecord, okRecord},
+			expected: ErrDupPublicKeys,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidatePublicKeyRecords(c.records)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestValidatePublicKeyRecord(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkd := NewTestPublicKeyDetail(rng)
+	disabledPKD := NewTestPublicKeyDetail(rng)
+	disabledPKD.Disabled = true
+	added := int64(1520000000000000)
+	cases := map[string]struct {
+		record   *PublicKeyRecord
+		expected error
+	}{
+		"ok": {
+			record:   &PublicKeyRecord{PublicKeyDetail: pkd, AddedTime: added},
+			expected: nil,
+		},
+		"ok disabled": {
+			record: &PublicKeyRecord{
+				PublicKeyDetail: disabledPKD,
+				AddedTime:       added,
+				DisabledTime:    added + 1,
+			},
+			expected: nil,
+		},
+		"nil value": {
+			record:   nil,
+			expected: ErrEmptyPublicKeyRecord,
+		},
+		"invalid public key detail": {
+			record:   &PublicKeyRecord{PublicKeyDetail: &PublicKeyDetail{}, AddedTime: added},
+			expected: ErrEmptyPublicKey,
+		},
+		"missing added time": {
+			record:   &PublicKeyRecord{PublicKeyDetail: pkd},
+			expected: ErrMissingAddedTime,
+		},
+		"disabled time without disabled": {
+			record: &PublicKeyRecord{
+				PublicKeyDetail: pkd,
+				AddedTime:       added,
+				DisabledTime:    added + 1,
+			},
+			expected: ErrInvalidDisabledTime,
+		},
+		"disabled without disabled time": {
+			record:   &PublicKeyRecord{PublicKeyDetail: disabledPKD, AddedTime: added},
+			expected: ErrInvalidDisabledTime,
+		},
+		"disabled before added": {
+			record: &PublicKeyRecord{
+				PublicKeyDetail: disabledPKD,
+				AddedTime:       added,
+				DisabledTime:    added - 1,
+			},
+			expected: ErrInvalidDisabledTime,
+		},
+	}
+	for desc, c := range cases {
+		err := ValidatePublicKeyRecord(c.record)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
 func TestValidatePublicKeys(t *testing.T) {
 	cases := map[string]struct {
 		pks      [][]byte
@@ -0,0 +1,166 @@
+package keyapi
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math/big"
+	"math/rand"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+)
+
+// proofOfPossessionDomain separates proof-of-possession digests from any other data an entity
+// might sign with the same key.
+const proofOfPossessionDomain = "elixirhealth key proof of possession"
+
+// secp256k1SignatureLength is the length of a secp256k1 proof-of-possession signature, its
+// 32-byte big-endian r and s values concatenated.
+const secp256k1SignatureLength = 64
+
+var (
+	// ErrMissingProofsOfPossession indicates when a request doesn't have exactly one
+	// proof-of-possession signature per public key.
+	ErrMissingProofsOfPossession = errors.New("number of proof-of-possession signatures " +
+		"doesn't match number of public keys")
+
+	// ErrInvalidProofOfPossession indicates when a proof-of-possession signature doesn't verify
+	// for its public key.
+	ErrInvalidProofOfPossession = errors.New("invalid proof-of-possession signature")
+
+	// ErrProofOfPossessionUnsupported indicates when a key format can't sign, so its public keys
+	// can't be proven.
+	ErrProofOfPossessionUnsupported = errors.New("key format doesn't support proof of " +
+		"possession")
+)
+
+// ProofVerifier checks that a signature of a digest was made with the private key of a public key.
+type ProofVerifier func(pk, digest, sig []byte) bool
+
+var proofVerifiers = map[KeyFormat]ProofVerifier{
+	KeyFormat_SECP256K1_COMPRESSED: verifySecp256k1,
+	KeyFormat_ED25519: func(pk, digest, sig []byte) bool {
+		return len(pk) == ed25519.PublicKeySize && ed25519.Verify(pk, digest, sig)
+	},
+}
+
+// RegisterProofVerifier sets the verifier used for proofs of possession of public keys of the
+// given format, replacing any existing one. Like RegisterPublicKeyValidator, it should only be
+// called during initialization.
+func RegisterProofVerifier(kf KeyFormat, v ProofVerifier) {
+	proofVerifiers[kf] = v
+}
+
+// ProofOfPossessionDigest returns the SHA-256 digest that an entity signs with the private key of
+// a public key to prove that it holds it. Binding the entity ID and key type to the digest stops
+// a proof for one entity or key type from being replayed for another.
+func ProofOfPossessionDigest(entityID string, kt KeyType, pk []byte) []byte {
+	h := sha256.New()
+	writeLengthPrefixed(h, []byte(proofOfPossessionDomain))
+	writeLengthPrefixed(h, []byte(entityID))
+	writeLengthPrefixed(h, []byte(kt.String()))
+	writeLengthPrefixed(h, pk)
+	return h.Sum(nil)
+}
+
+// VerifyProofsOfPossession checks that each signature is a valid signature of the
+// proof-of-possession digest for the entity, key type, and corresponding public key.
+func VerifyProofsOfPossession(
+	kf KeyFormat, entityID string, kt KeyType, pks, sigs [][]byte,
+) error {
+	if len(sigs) != len(pks) {
+		return ErrMissingProofsOfPossession
+	}
+	verify, in := proofVerifiers[kf]
+	if !in {
+		return ErrProofOfPossessionUnsupported
+	}
+	for i, pk := range pks {
+		if !verify(pk, ProofOfPossessionDigest(entityID, kt, pk), sigs[i]) {
+			return errors.Wrapf(ErrInvalidProofOfPossession, "%s public key %d", kf, i)
+		}
+	}
+	return nil
+}
+
+// VerifyAddPublicKeysProofs checks that the request has a valid proof of possession for each of
+// its public keys.
+func VerifyAddPublicKeysProofs(rq *AddPublicKeysRequest) error {
+	return VerifyProofsOfPossession(rq.KeyFormat, rq.EntityId, rq.KeyType, rq.PublicKeys,
+		rq.Signatures)
+}
+
+// VerifyRotatePublicKeysProofs checks that the request has a valid proof of possession for each
+// of its new public keys.
+func VerifyRotatePublicKeysProofs(rq *RotatePublicKeysRequest) error {
+	return VerifyProofsOfPossession(rq.NewKeyFormat, rq.EntityId, rq.KeyType,
+		rq.NewPublicKeys, rq.NewSignatures)
+}
+
+// NewTestProvenPublicKeys creates n random compressed secp256k1 public keys and their
+// proof-of-possession signatures for the entity and key type, for use in testing.
+func NewTestProvenPublicKeys(
+	rng *rand.Rand, entityID string, kt KeyType, n int,
+) ([][]byte, [][]byte) {
+	pks, sigs := make([][]byte, n), make([][]byte, n)
+	for i := range pks {
+		priv := newTestSecp256k1Key(rng)
+		pk := priv.PubKey().SerializeCompressed()
+		digest := ProofOfPossessionDigest(entityID, kt, pk)
+		pks[i], sigs[i] = pk, signSecp256k1(priv, digest)
+	}
+	return pks, sigs
+}
+
+// verifySecp256k1 checks that the signature is a valid ECDSA signature of the digest by the
+// compressed secp256k1 public key.
+func verifySecp256k1(pk, digest, sig []byte) bool {
+	if ValidateSecp256k1Compressed(pk) != nil || len(sig) != secp256k1SignatureLength {
+		return false
+	}
+	pub, err := btcec.ParsePubKey(pk, btcec.S256())
+	if err != nil {
+		return false
+	}
+	btcSig := &btcec.Signature{
+		R: new(big.Int).SetBytes(sig[:32]),
+		S: new(big.Int).SetBytes(sig[32:]),
+	}
+	return btcSig.Verify(digest, pub)
+}
+
+// signSecp256k1 returns an ECDSA signature of the digest by the private key in the format
+// verifySecp256k1 expects.
+func signSecp256k1(priv *btcec.PrivateKey, digest []byte) []byte {
+	btcSig, err := priv.Sign(digest)
+	if err != nil {
+		panic(err) // only fails for invalid private keys
+	}
+	sig := make([]byte, secp256k1SignatureLength)
+	rBytes, sBytes := btcSig.R.Bytes(), btcSig.S.Bytes()
+	copy(sig[32-len(rBytes):32], rBytes)
+	copy(sig[64-len(sBytes):], sBytes)
+	return sig
+}
+
+// newTestSecp256k1Key returns a random secp256k1 private key for use in testing.
+func newTestSecp256k1Key(rng *rand.Rand) *btcec.PrivateKey {
+	n := btcec.S256().N
+	for {
+		d := make([]byte, 32)
+		_, _ = rng.Read(d)
+		if k := new(big.Int).SetBytes(d); k.Sign() > 0 && k.Cmp(n) < 0 {
+			priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), d)
+			return priv
+		}
+	}
+}
+
+func writeLengthPrefixed(h hash.Hash, b []byte) {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+	_, _ = h.Write(l[:])
+	_, _ = h.Write(b)
+}
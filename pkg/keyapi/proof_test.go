@@ -0,0 +1,99 @@
+package keyapi
+
+import (
+	"crypto/ed25519"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyProofsOfPossession_secp256k1(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", KeyType_READER
+	pks, sigs := NewTestProvenPublicKeys(rng, entityID, kt, 4)
+
+	err := VerifyProofsOfPossession(KeyFormat_SECP256K1_COMPRESSED, entityID, kt, pks, sigs)
+	assert.Nil(t, err)
+
+	// proofs for another entity or key type aren't valid
+	err = VerifyProofsOfPossession(KeyFormat_SECP256K1_COMPRESSED, "other entity ID", kt,
+		pks, sigs)
+	assert.Equal(t, ErrInvalidProofOfPossession, errors.Cause(err))
+	err = VerifyProofsOfPossession(KeyFormat_SECP256K1_COMPRESSED, entityID,
+		KeyType_AUTHOR, pks, sigs)
+	assert.Equal(t, ErrInvalidProofOfPossession, errors.Cause(err))
+
+	// nor are proofs for other public keys
+	swapped := [][]byte{sigs[1], sigs[0], sigs[2], sigs[3]}
+	err = VerifyProofsOfPossession(KeyFormat_SECP256K1_COMPRESSED, entityID, kt, pks, swapped)
+	assert.Equal(t, ErrInvalidProofOfPossession, errors.Cause(err))
+	assert.Contains(t, err.Error(), "public key 0")
+}
+
+func TestVerifyProofsOfPossession_ed25519(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", KeyType_AUTHOR
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	sig := ed25519.Sign(sk, ProofOfPossessionDigest(entityID, kt, pk))
+
+	err = VerifyProofsOfPossession(KeyFormat_ED25519, entityID, kt, [][]byte{pk},
+		[][]byte{sig})
+	assert.Nil(t, err)
+
+	sig[0] ^= 1
+	err = VerifyProofsOfPossession(KeyFormat_ED25519, entityID, kt, [][]byte{pk},
+		[][]byte{sig})
+	assert.Equal(t, ErrInvalidProofOfPossession, errors.Cause(err))
+}
+
+func TestVerifyProofsOfPossession_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", KeyType_READER
+	pks, sigs := NewTestProvenPublicKeys(rng, entityID, kt, 2)
+
+	err := VerifyProofsOfPossession(KeyFormat_SECP256K1_COMPRESSED, entityID, kt, pks,
+		sigs[:1])
+	assert.Equal(t, ErrMissingProofsOfPossession, err)
+
+	err = VerifyProofsOfPossession(KeyFormat_X25519, entityID, kt, pks, sigs)
+	assert.Equal(t, ErrProofOfPossessionUnsupported, err)
+
+	err = VerifyProofsOfPossession(KeyFormat_UNSPECIFIED_FORMAT, entityID, kt, pks, sigs)
+	assert.Equal(t, ErrProofOfPossessionUnsupported, err)
+
+	badSigs := [][]byte{{1, 2, 3}, make([]byte, secp256k1SignatureLength)}
+	err = VerifyProofsOfPossession(KeyFormat_SECP256K1_COMPRESSED, entityID, kt, pks,
+		badSigs)
+	assert.Equal(t, ErrInvalidProofOfPossession, errors.Cause(err))
+}
+
+func TestVerifyAddRotatePublicKeysProofs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", KeyType_READER
+	pks, sigs := NewTestProvenPublicKeys(rng, entityID, kt, 2)
+
+	addRq := &AddPublicKeysRequest{
+		EntityId:   entityID,
+		KeyType:    kt,
+		PublicKeys: pks,
+		KeyFormat:  KeyFormat_SECP256K1_COMPRESSED,
+		Signatures: sigs,
+	}
+	assert.Nil(t, VerifyAddPublicKeysProofs(addRq))
+
+	rotateRq := &RotatePublicKeysRequest{
+		EntityId:      entityID,
+		KeyType:       kt,
+		OldPublicKeys: [][]byte{NewTestPublicKey(rng)},
+		NewPublicKeys: pks,
+		NewKeyFormat:  KeyFormat_SECP256K1_COMPRESSED,
+		NewSignatures: sigs,
+	}
+	assert.Nil(t, VerifyRotatePublicKeysProofs(rotateRq))
+
+	rotateRq.NewSignatures = nil
+	assert.Equal(t, ErrMissingProofsOfPossession, VerifyRotatePublicKeysProofs(rotateRq))
+}
@@ -0,0 +1,191 @@
+package keyapi
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"hash"
+
+	"github.com/pkg/errors"
+)
+
+// responseSignatureDomain separates response signature digests from any other data the server
+// might sign with the same key.
+const responseSignatureDomain = "elixirhealth key response signature"
+
+var (
+	// ErrMissingResponseSignature indicates when a response that should be signed isn't.
+	ErrMissingResponseSignature = errors.New("missing response signature")
+
+	// ErrInvalidResponseSignature indicates when a response signature doesn't verify for the
+	// request and response with the server's signing key.
+	ErrInvalidResponseSignature = errors.New("invalid response signature")
+)
+
+// SignGetPublicKeysResponse sets the response's signature of it and its request, made with the
+// signing key at the given time, in epoch micros.
+func SignGetPublicKeysResponse(
+	key ed25519.PrivateKey, rq *GetPublicKeysRequest, rp *GetPublicKeysResponse, signedTime int64,
+) {
+	digest := getPublicKeysResponseDigest(rq, rp, signedTime)
+	rp.Signature = signResponse(key, digest, signedTime)
+}
+
+// VerifyGetPublicKeysResponse checks that the response has a valid signature of it and its
+// request for the signing key.
+func VerifyGetPublicKeysResponse(
+	key ed25519.PublicKey, rq *GetPublicKeysRequest, rp *GetPublicKeysResponse,
+) error {
+	if rp.Signature == nil {
+		return ErrMissingResponseSignature
+	}
+	digest := getPublicKeysResponseDigest(rq, rp, rp.Signature.Time)
+	return verifyResponse(key, digest, rp.Signature)
+}
+
+// SignGetPublicKeyDetailsResponse sets the response's signature of it and its request, made with
+// the signing key at the given time, in epoch micros.
+func SignGetPublicKeyDetailsResponse(
+	key ed25519.PrivateKey,
+	rq *GetPublicKeyDetailsRequest,
+	rp *GetPublicKeyDetailsResponse,
+	signedTime int64,
+) {
+	digest := getPublicKeyDetailsResponseDigest(rq, rp, signedTime)
+	rp.Signature = signResponse(key, digest, signedTime)
+}
+
+// VerifyGetPublicKeyDetailsResponse checks that the response has a valid signature of it and its
+// request for the signing key.
+func VerifyGetPublicKeyDetailsResponse(
+	key ed25519.PublicKey, rq *GetPublicKeyDetailsRequest, rp *GetPublicKeyDetailsResponse,
+) error {
+	if rp.Signature == nil {
+		return ErrMissingResponseSignature
+	}
+	digest := getPublicKeyDetailsResponseDigest(rq, rp, rp.Signature.Time)
+	return verifyResponse(key, digest, rp.Signature)
+}
+
+// SignSamplePublicKeysResponse sets the response's signature of it and its request, made with the
+// signing key at the given time, in epoch micros.
+func SignSamplePublicKeysResponse(
+	key ed25519.PrivateKey,
+	rq *SamplePublicKeysRequest,
+	rp *SamplePublicKeysResponse,
+	signedTime int64,
+) {
+	digest := samplePublicKeysResponseDigest(rq, rp, signedTime)
+	rp.Signature = signResponse(key, digest, signedTime)
+}
+
+// VerifySamplePublicKeysResponse checks that the response has a valid signature of it and its
+// request for the signing key.
+func VerifySamplePublicKeysResponse(
+	key ed25519.PublicKey, rq *SamplePublicKeysRequest, rp *SamplePublicKeysResponse,
+) error {
+	if rp.Signature == nil {
+		return ErrMissingResponseSignature
+	}
+	digest := samplePublicKeysResponseDigest(rq, rp, rp.Signature.Time)
+	return verifyResponse(key, digest, rp.Signature)
+}
+
+func signResponse(key ed25519.PrivateKey, digest []byte, signedTime int64) *ResponseSignature {
+	return &ResponseSignature{
+		Time:      signedTime,
+		Signature: ed25519.Sign(key, digest),
+	}
+}
+
+func verifyResponse(key ed25519.PublicKey, digest []byte, sig *ResponseSignature) error {
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, digest, sig.Signature) {
+		return ErrInvalidResponseSignature
+	}
+	return nil
+}
+
+// newResponseDigest returns a SHA-256 hash of the response signature domain, the RPC method, and
+// the signed time, to which the request and response fields are then written. Like audit record
+// hashes, each field is length-prefixed so no two requests and responses have the same encoding.
+func newResponseDigest(method string, signedTime int64) hash.Hash {
+	h := sha256.New()
+	writeLengthPrefixed(h, []byte(responseSignatureDomain))
+	writeLengthPrefixed(h, []byte(method))
+	writeUint64(h, uint64(signedTime))
+	return h
+}
+
+func getPublicKeysResponseDigest(
+	rq *GetPublicKeysRequest, rp *GetPublicKeysResponse, signedTime int64,
+) []byte {
+	h := newResponseDigest("GetPublicKeys", signedTime)
+	writeLengthPrefixed(h, []byte(rq.EntityId))
+	writeLengthPrefixed(h, []byte(rq.KeyType.String()))
+	writeUint64(h, uint64(rq.AsOfTime))
+	writeByteSlices(h, rp.PublicKeys)
+	return h.Sum(nil)
+}
+
+func getPublicKeyDetailsResponseDigest(
+	rq *GetPublicKeyDetailsRequest, rp *GetPublicKeyDetailsResponse, signedTime int64,
+) []byte {
+	h := newResponseDigest("GetPublicKeyDetails", signedTime)
+	writeByteSlices(h, rq.PublicKeys)
+	writeUint64(h, uint64(rq.AsOfTime))
+	writeBool(h, rq.Partial)
+	writePublicKeyDetails(h, rp.PublicKeyDetails)
+	writeUint64(h, uint64(len(rp.Results)))
+	for _, r := range rp.Results {
+		writeBool(h, r != nil)
+		if r != nil {
+			writeLengthPrefixed(h, r.PublicKey)
+			writeBool(h, r.Found)
+			writePublicKeyDetail(h, r.PublicKeyDetail)
+		}
+	}
+	return h.Sum(nil)
+}
+
+func samplePublicKeysResponseDigest(
+	rq *SamplePublicKeysRequest, rp *SamplePublicKeysResponse, signedTime int64,
+) []byte {
+	h := newResponseDigest("SamplePublicKeys", signedTime)
+	writeLengthPrefixed(h, []byte(rq.OfEntityId))
+	writeLengthPrefixed(h, []byte(rq.RequesterEntityId))
+	writeUint64(h, uint64(rq.NPublicKeys))
+	writePublicKeyDetails(h, rp.PublicKeyDetails)
+	return h.Sum(nil)
+}
+
+func writePublicKeyDetails(h hash.Hash, pkds []*PublicKeyDetail) {
+	writeUint64(h, uint64(len(pkds)))
+	for _, pkd := range pkds {
+		writePublicKeyDetail(h, pkd)
+	}
+}
+
+func writePublicKeyDetail(h hash.Hash, pkd *PublicKeyDetail) {
+	writeBool(h, pkd != nil)
+	if pkd == nil {
+		return
+	}
+	writeLengthPrefixed(h, pkd.PublicKey)
+	writeLengthPrefixed(h, []byte(pkd.EntityId))
+	writeLengthPrefixed(h, []byte(pkd.KeyType.String()))
+	writeBool(h, pkd.Disabled)
+}
+
+func writeByteSlices(h hash.Hash, bs [][]byte) {
+	writeUint64(h, uint64(len(bs)))
+	for _, b := range bs {
+		writeLengthPrefixed(h, b)
+	}
+}
+
+func writeBool(h hash.Hash, v bool) {
+	b := byte(0)
+	if v {
+		b = 1
+	}
+	_, _ = h.Write([]byte{b})
+}
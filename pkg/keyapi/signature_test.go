@@ -0,0 +1,138 @@
+package keyapi
+
+import (
+	"crypto/ed25519"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerifyGetPublicKeysResponse(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	otherPK, _, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	pkds := NewTestPublicKeyDetails(rng, 2)
+	rq := &GetPublicKeysRequest{EntityId: pkds[0].EntityId, KeyType: pkds[0].KeyType}
+	rp := &GetPublicKeysResponse{
+		PublicKeys: [][]byte{pkds[0].PublicKey, pkds[1].PublicKey},
+	}
+
+	SignGetPublicKeysResponse(sk, rq, rp, 123)
+	assert.Equal(t, int64(123), rp.Signature.Time)
+	assert.Nil(t, VerifyGetPublicKeysResponse(pk, rq, rp))
+
+	otherEntityRq := *rq
+	otherEntityRq.EntityId = "other entity ID"
+	asOfRq := *rq
+	asOfRq.AsOfTime = 1
+	fewerRp := *rp
+	fewerRp.PublicKeys = rp.PublicKeys[:1]
+	laterRp := *rp
+	laterRp.Signature = &ResponseSignature{Time: 124, Signature: rp.Signature.Signature}
+	cases := map[string]struct {
+		key ed25519.PublicKey
+		rq  *GetPublicKeysRequest
+		rp  *GetPublicKeysResponse
+	}{
+		"other entity": {key: pk, rq: &otherEntityRq, rp: rp},
+		"as of time":   {key: pk, rq: &asOfRq, rp: rp},
+		"fewer keys":   {key: pk, rq: rq, rp: &fewerRp},
+		"later time":   {key: pk, rq: rq, rp: &laterRp},
+		"other key":    {key: otherPK, rq: rq, rp: rp},
+		"short key":    {key: pk[:16], rq: rq, rp: rp},
+	}
+	for desc, c := range cases {
+		err := VerifyGetPublicKeysResponse(c.key, c.rq, c.rp)
+		assert.Equal(t, ErrInvalidResponseSignature, err, desc)
+	}
+
+	err = VerifyGetPublicKeysResponse(pk, rq, &GetPublicKeysResponse{})
+	assert.Equal(t, ErrMissingResponseSignature, err)
+}
+
+func TestSignVerifyGetPublicKeyDetailsResponse(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	pkds := NewTestPublicKeyDetails(rng, 2)
+	rq := &GetPublicKeyDetailsRequest{
+		PublicKeys: [][]byte{pkds[0].PublicKey, pkds[1].PublicKey},
+	}
+	rp := &GetPublicKeyDetailsResponse{PublicKeyDetails: pkds}
+	SignGetPublicKeyDetailsResponse(sk, rq, rp, 123)
+	assert.Nil(t, VerifyGetPublicKeyDetailsResponse(pk, rq, rp))
+
+	partialRq := &GetPublicKeyDetailsRequest{PublicKeys: rq.PublicKeys, Partial: true}
+	partialRp := &GetPublicKeyDetailsResponse{
+		Results: []*PublicKeyResult{
+			{PublicKey: pkds[0].PublicKey, Found: true, PublicKeyDetail: pkds[0]},
+			{PublicKey: pkds[1].PublicKey},
+		},
+	}
+	SignGetPublicKeyDetailsResponse(sk, partialRq, partialRp, 123)
+	assert.Nil(t, VerifyGetPublicKeyDetailsResponse(pk, partialRq, partialRp))
+
+	disabledPKD := *pkds[1]
+	disabledPKD.Disabled = true
+	disabledRp := *rp
+	disabledRp.PublicKeyDetails = []*PublicKeyDetail{pkds[0], &disabledPKD}
+	notFoundRp := *partialRp
+	notFoundRp.Results = []*PublicKeyResult{partialRp.Results[1], partialRp.Results[1]}
+	cases := map[string]struct {
+		rq *GetPublicKeyDetailsRequest
+		rp *GetPublicKeyDetailsResponse
+	}{
+		"disabled detail": {rq: rq, rp: &disabledRp},
+		"not partial":     {rq: rq, rp: partialRp},
+		"partial":         {rq: partialRq, rp: rp},
+		"not found":       {rq: partialRq, rp: &notFoundRp},
+		"other public keys": {
+			rq: &GetPublicKeyDetailsRequest{PublicKeys: rq.PublicKeys[:1]},
+			rp: rp,
+		},
+	}
+	for desc, c := range cases {
+		err := VerifyGetPublicKeyDetailsResponse(pk, c.rq, c.rp)
+		assert.Equal(t, ErrInvalidResponseSignature, err, desc)
+	}
+
+	err = VerifyGetPublicKeyDetailsResponse(pk, rq, &GetPublicKeyDetailsResponse{})
+	assert.Equal(t, ErrMissingResponseSignature, err)
+}
+
+func TestSignVerifySamplePublicKeysResponse(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	pkds := NewTestPublicKeyDetails(rng, 2)
+	rq := &SamplePublicKeysRequest{
+		OfEntityId:        pkds[0].EntityId,
+		RequesterEntityId: "requester entity ID",
+		NPublicKeys:       2,
+	}
+	rp := &SamplePublicKeysResponse{PublicKeyDetails: pkds}
+	SignSamplePublicKeysResponse(sk, rq, rp, 123)
+	assert.Nil(t, VerifySamplePublicKeysResponse(pk, rq, rp))
+
+	otherRequesterRq := *rq
+	otherRequesterRq.RequesterEntityId = "other requester entity ID"
+	reorderedRp := *rp
+	reorderedRp.PublicKeyDetails = []*PublicKeyDetail{pkds[1], pkds[0]}
+	cases := map[string]struct {
+		rq *SamplePublicKeysRequest
+		rp *SamplePublicKeysResponse
+	}{
+		"other requester": {rq: &otherRequesterRq, rp: rp},
+		"reordered":       {rq: rq, rp: &reorderedRp},
+	}
+	for desc, c := range cases {
+		err := VerifySamplePublicKeysResponse(pk, c.rq, c.rp)
+		assert.Equal(t, ErrInvalidResponseSignature, err, desc)
+	}
+
+	err = VerifySamplePublicKeysResponse(pk, rq, &SamplePublicKeysResponse{})
+	assert.Equal(t, ErrMissingResponseSignature, err)
+}
@@ -0,0 +1,218 @@
+package keyapi
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// treeHeadDomain separates tree head signature digests from any other data the server might
+	// sign with the same key.
+	treeHeadDomain = "elixirhealth key tree head"
+
+	// leafHashPrefix and nodeHashPrefix separate the hashes of transparency log leaves from those
+	// of its interior nodes, as in RFC 6962, so no leaf can pass as a subtree.
+	leafHashPrefix = 0x00
+	nodeHashPrefix = 0x01
+)
+
+var (
+	// ErrInvalidInclusionProof indicates when an inclusion proof doesn't prove that a leaf is in
+	// a tree with the tree head's root hash.
+	ErrInvalidInclusionProof = errors.New("invalid inclusion proof")
+
+	// ErrInvalidConsistencyProof indicates when a consistency proof doesn't prove that a tree is
+	// a prefix of a later one.
+	ErrInvalidConsistencyProof = errors.New("invalid consistency proof")
+
+	// ErrMissingInclusionProofs indicates when a response doesn't have a tree head and an
+	// inclusion proof for each public key detail.
+	ErrMissingInclusionProofs = errors.New("missing tree head or inclusion proofs")
+
+	// ErrUnsignedTreeHead indicates when a tree head that should be signed isn't.
+	ErrUnsignedTreeHead = errors.New("unsigned tree head")
+
+	// ErrInvalidTreeHeadSignature indicates when a tree head's signature doesn't verify with the
+	// server's signing key.
+	ErrInvalidTreeHeadSignature = errors.New("invalid tree head signature")
+
+	// ErrInvalidTreeSizes indicates when a consistency proof request's first tree size is zero
+	// or larger than its second tree size.
+	ErrInvalidTreeSizes = errors.New("first tree size must be positive and no larger than " +
+		"second tree size")
+)
+
+// LogLeafHash returns the transparency log leaf hash of an entity's public key of a key type,
+// given the SHA-256 hash of the public key, as audit records have.
+func LogLeafHash(entityID string, kt KeyType, pkHash []byte) []byte {
+	h := sha256.New()
+	_, _ = h.Write([]byte{leafHashPrefix})
+	writeLengthPrefixed(h, []byte(entityID))
+	writeLengthPrefixed(h, []byte(kt.String()))
+	writeLengthPrefixed(h, pkHash)
+	return h.Sum(nil)
+}
+
+// PublicKeyDetailLeafHash returns the transparency log leaf hash of the public key detail, which
+// ignores whether it has been disabled.
+func PublicKeyDetailLeafHash(pkd *PublicKeyDetail) []byte {
+	pkHash := sha256.Sum256(pkd.PublicKey)
+	return LogLeafHash(pkd.EntityId, pkd.KeyType, pkHash[:])
+}
+
+// LogNodeHash returns the hash of a transparency log interior node with the given child hashes.
+func LogNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	_, _ = h.Write([]byte{nodeHashPrefix})
+	_, _ = h.Write(left)
+	_, _ = h.Write(right)
+	return h.Sum(nil)
+}
+
+// EmptyLogRootHash returns the root hash of an empty transparency log.
+func EmptyLogRootHash() []byte {
+	h := sha256.Sum256(nil)
+	return h[:]
+}
+
+// VerifyInclusionProof checks that the proof shows that the leaf hash is at the index of the tree
+// of the given size and root hash, following RFC 9162.
+func VerifyInclusionProof(leafHash []byte, index, size uint64, proof [][]byte, root []byte) error {
+	if index >= size {
+		return ErrInvalidInclusionProof
+	}
+	fn, sn, r := index, size-1, leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return ErrInvalidInclusionProof
+		}
+		if fn&1 == 1 || fn == sn {
+			r = LogNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn, sn = fn>>1, sn>>1
+			}
+		} else {
+			r = LogNodeHash(r, p)
+		}
+		fn, sn = fn>>1, sn>>1
+	}
+	if sn != 0 || !bytes.Equal(r, root) {
+		return ErrInvalidInclusionProof
+	}
+	return nil
+}
+
+// VerifyConsistencyProof checks that the proof shows that the tree of the first size and root hash
+// is a prefix of the tree of the second size and root hash, following RFC 9162.
+func VerifyConsistencyProof(
+	firstSize, secondSize uint64, firstRoot, secondRoot []byte, proof [][]byte,
+) error {
+	if firstSize == 0 || firstSize > secondSize {
+		return ErrInvalidConsistencyProof
+	}
+	if firstSize == secondSize {
+		if len(proof) != 0 || !bytes.Equal(firstRoot, secondRoot) {
+			return ErrInvalidConsistencyProof
+		}
+		return nil
+	}
+	if firstSize&(firstSize-1) == 0 {
+		// the first tree is a complete subtree of the second, whose root the proof omits
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return ErrInvalidConsistencyProof
+	}
+	fn, sn := firstSize-1, secondSize-1
+	for fn&1 == 1 {
+		fn, sn = fn>>1, sn>>1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return ErrInvalidConsistencyProof
+		}
+		if fn&1 == 1 || fn == sn {
+			fr, sr = LogNodeHash(c, fr), LogNodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn, sn = fn>>1, sn>>1
+			}
+		} else {
+			sr = LogNodeHash(sr, c)
+		}
+		fn, sn = fn>>1, sn>>1
+	}
+	if sn != 0 || !bytes.Equal(fr, firstRoot) || !bytes.Equal(sr, secondRoot) {
+		return ErrInvalidConsistencyProof
+	}
+	return nil
+}
+
+// VerifyPublicKeyDetailsInclusion checks that the response has an inclusion proof in its tree
+// head's tree for each public key detail, or for each found result of a partial request. It
+// doesn't check the tree head's signature.
+func VerifyPublicKeyDetailsInclusion(rp *GetPublicKeyDetailsResponse) error {
+	pkds := rp.PublicKeyDetails
+	if len(rp.Results) > 0 {
+		pkds = make([]*PublicKeyDetail, 0, len(rp.Results))
+		for _, r := range rp.Results {
+			pkds = append(pkds, r.PublicKeyDetail)
+		}
+	}
+	if rp.TreeHead == nil || len(rp.InclusionProofs) != len(pkds) {
+		return ErrMissingInclusionProofs
+	}
+	for i, pkd := range pkds {
+		p := rp.InclusionProofs[i]
+		if pkd == nil {
+			continue
+		}
+		if !p.Found {
+			return errors.Wrapf(ErrInvalidInclusionProof, "public key detail %d not found", i)
+		}
+		err := VerifyInclusionProof(PublicKeyDetailLeafHash(pkd), p.LeafIndex,
+			rp.TreeHead.TreeSize, p.Hashes, rp.TreeHead.RootHash)
+		if err != nil {
+			return errors.Wrapf(err, "public key detail %d", i)
+		}
+	}
+	return nil
+}
+
+// SignTreeHead sets the tree head's signature of its size, root hash, and time, made with the
+// signing key.
+func SignTreeHead(key ed25519.PrivateKey, th *TreeHead) {
+	th.Signature = ed25519.Sign(key, treeHeadDigest(th))
+}
+
+// VerifyTreeHead checks that the tree head has a valid signature for the signing key.
+func VerifyTreeHead(key ed25519.PublicKey, th *TreeHead) error {
+	if len(th.Signature) == 0 {
+		return ErrUnsignedTreeHead
+	}
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, treeHeadDigest(th), th.Signature) {
+		return ErrInvalidTreeHeadSignature
+	}
+	return nil
+}
+
+// ValidateGetConsistencyProofRequest checks that the request's first tree size is positive and no
+// larger than its second tree size.
+func ValidateGetConsistencyProofRequest(rq *GetConsistencyProofRequest) error {
+	if rq.FirstTreeSize == 0 || rq.FirstTreeSize > rq.SecondTreeSize {
+		return ErrInvalidTreeSizes
+	}
+	return nil
+}
+
+func treeHeadDigest(th *TreeHead) []byte {
+	h := sha256.New()
+	writeLengthPrefixed(h, []byte(treeHeadDomain))
+	writeUint64(h, th.TreeSize)
+	writeLengthPrefixed(h, th.RootHash)
+	writeUint64(h, uint64(th.Time))
+	return h.Sum(nil)
+}
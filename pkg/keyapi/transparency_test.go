@@ -0,0 +1,204 @@
+package keyapi
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicKeyDetailLeafHash(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkd := NewTestPublicKeyDetail(rng)
+	pkHash := sha256.Sum256(pkd.PublicKey)
+	leaf := PublicKeyDetailLeafHash(pkd)
+	assert.Equal(t, LogLeafHash(pkd.EntityId, pkd.KeyType, pkHash[:]), leaf)
+
+	// disabling doesn't change the leaf hash, but the entity ID and key type do
+	disabled := *pkd
+	disabled.Disabled = true
+	assert.Equal(t, leaf, PublicKeyDetailLeafHash(&disabled))
+	otherEntity := *pkd
+	otherEntity.EntityId = "other entity ID"
+	assert.NotEqual(t, leaf, PublicKeyDetailLeafHash(&otherEntity))
+	otherKeyType := *pkd
+	otherKeyType.KeyType = (pkd.KeyType + 1) % 2
+	assert.NotEqual(t, leaf, PublicKeyDetailLeafHash(&otherKeyType))
+}
+
+func TestVerifyInclusionProof(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a, b, c := newTestLeafHashes(rng)
+	ab := LogNodeHash(a, b)
+	root := LogNodeHash(ab, c)
+
+	assert.Nil(t, VerifyInclusionProof(a, 0, 3, [][]byte{b, c}, root))
+	assert.Nil(t, VerifyInclusionProof(b, 1, 3, [][]byte{a, c}, root))
+	assert.Nil(t, VerifyInclusionProof(c, 2, 3, [][]byte{ab}, root))
+	assert.Nil(t, VerifyInclusionProof(a, 0, 1, nil, a))
+
+	cases := map[string]struct {
+		leaf        []byte
+		index, size uint64
+		proof       [][]byte
+		root        []byte
+	}{
+		"other leaf":         {leaf: b, index: 0, size: 3, proof: [][]byte{b, c}, root: root},
+		"other index":        {leaf: a, index: 1, size: 3, proof: [][]byte{b, c}, root: root},
+		"other size":         {leaf: a, index: 0, size: 5, proof: [][]byte{b, c}, root: root},
+		"index out of range": {leaf: c, index: 3, size: 3, proof: [][]byte{ab}, root: root},
+		"short proof":        {leaf: a, index: 0, size: 3, proof: [][]byte{b}, root: root},
+		"long proof":         {leaf: c, index: 2, size: 3, proof: [][]byte{ab, a}, root: root},
+		"other root":         {leaf: a, index: 0, size: 3, proof: [][]byte{b, c}, root: ab},
+	}
+	for desc, c := range cases {
+		err := VerifyInclusionProof(c.leaf, c.index, c.size, c.proof, c.root)
+		assert.Equal(t, ErrInvalidInclusionProof, err, desc)
+	}
+}
+
+func TestVerifyConsistencyProof(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	a, b, c := newTestLeafHashes(rng)
+	ab := LogNodeHash(a, b)
+	abc := LogNodeHash(ab, c)
+
+	assert.Nil(t, VerifyConsistencyProof(1, 3, a, abc, [][]byte{b, c}))
+	assert.Nil(t, VerifyConsistencyProof(2, 3, ab, abc, [][]byte{c}))
+	assert.Nil(t, VerifyConsistencyProof(3, 3, abc, abc, nil))
+
+	cases := map[string]struct {
+		first, second         uint64
+		firstRoot, secondRoot []byte
+		proof                 [][]byte
+	}{
+		"zero first size": {first: 0, second: 3, firstRoot: a, secondRoot: abc},
+		"first larger than second": {
+			first: 3, second: 2, firstRoot: abc, secondRoot: ab, proof: [][]byte{c},
+		},
+		"equal sizes with proof": {
+			first: 3, second: 3, firstRoot: abc, secondRoot: abc, proof: [][]byte{c},
+		},
+		"equal sizes, other roots": {first: 3, second: 3, firstRoot: abc, secondRoot: ab},
+		"other first root": {
+			first: 2, second: 3, firstRoot: a, secondRoot: abc, proof: [][]byte{c},
+		},
+		"other second root": {
+			first: 2, second: 3, firstRoot: ab, secondRoot: ab, proof: [][]byte{c},
+		},
+		"missing proof": {first: 1, second: 3, firstRoot: a, secondRoot: abc},
+		"short proof": {
+			first: 1, second: 3, firstRoot: a, secondRoot: abc, proof: [][]byte{b},
+		},
+	}
+	for desc, c := range cases {
+		err := VerifyConsistencyProof(c.first, c.second, c.firstRoot, c.secondRoot, c.proof)
+		assert.Equal(t, ErrInvalidConsistencyProof, err, desc)
+	}
+}
+
+func TestVerifyPublicKeyDetailsInclusion(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkds := NewTestPublicKeyDetails(rng, 2)
+	a, b := PublicKeyDetailLeafHash(pkds[0]), PublicKeyDetailLeafHash(pkds[1])
+	th := &TreeHead{TreeSize: 2, RootHash: LogNodeHash(a, b)}
+	rp := &GetPublicKeyDetailsResponse{
+		PublicKeyDetails: []*PublicKeyDetail{pkds[1], pkds[0]},
+		TreeHead:         th,
+		InclusionProofs: []*InclusionProof{
+			{Found: true, LeafIndex: 1, Hashes: [][]byte{a}},
+			{Found: true, LeafIndex: 0, Hashes: [][]byte{b}},
+		},
+	}
+	assert.Nil(t, VerifyPublicKeyDetailsInclusion(rp))
+
+	partialRp := &GetPublicKeyDetailsResponse{
+		Results: []*PublicKeyResult{
+			{PublicKey: pkds[0].PublicKey, Found: true, PublicKeyDetail: pkds[0]},
+			{PublicKey: NewTestPublicKey(rng)},
+		},
+		TreeHead:        th,
+		InclusionProofs: []*InclusionProof{rp.InclusionProofs[1], {}},
+	}
+	assert.Nil(t, VerifyPublicKeyDetailsInclusion(partialRp))
+
+	noTreeHead := *rp
+	noTreeHead.TreeHead = nil
+	missingProof := *rp
+	missingProof.InclusionProofs = rp.InclusionProofs[:1]
+	notFound := *rp
+	notFound.InclusionProofs = []*InclusionProof{rp.InclusionProofs[0], {}}
+	swapped := *rp
+	swapped.InclusionProofs = []*InclusionProof{rp.InclusionProofs[1], rp.InclusionProofs[0]}
+	cases := map[string]struct {
+		rp       *GetPublicKeyDetailsResponse
+		expected error
+	}{
+		"no tree head":  {rp: &noTreeHead, expected: ErrMissingInclusionProofs},
+		"missing proof": {rp: &missingProof, expected: ErrMissingInclusionProofs},
+		"not found":     {rp: &notFound, expected: ErrInvalidInclusionProof},
+		"swapped":       {rp: &swapped, expected: ErrInvalidInclusionProof},
+	}
+	for desc, c := range cases {
+		err := VerifyPublicKeyDetailsInclusion(c.rp)
+		assert.Equal(t, c.expected, errors.Cause(err), desc)
+	}
+}
+
+func TestSignVerifyTreeHead(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	a, _, _ := newTestLeafHashes(rng)
+	th := &TreeHead{TreeSize: 1, RootHash: a, Time: 123}
+	assert.Equal(t, ErrUnsignedTreeHead, VerifyTreeHead(pk, th))
+
+	SignTreeHead(sk, th)
+	assert.Nil(t, VerifyTreeHead(pk, th))
+
+	larger := *th
+	larger.TreeSize = 2
+	later := *th
+	later.Time = 124
+	cases := map[string]struct {
+		key ed25519.PublicKey
+		th  *TreeHead
+	}{
+		"larger tree": {key: pk, th: &larger},
+		"later time":  {key: pk, th: &later},
+		"short key":   {key: pk[:16], th: th},
+	}
+	for desc, c := range cases {
+		assert.Equal(t, ErrInvalidTreeHeadSignature, VerifyTreeHead(c.key, c.th), desc)
+	}
+}
+
+func TestValidateGetConsistencyProofRequest(t *testing.T) {
+	cases := map[string]struct {
+		rq       *GetConsistencyProofRequest
+		expected error
+	}{
+		"ok":          {rq: &GetConsistencyProofRequest{FirstTreeSize: 1, SecondTreeSize: 2}},
+		"equal sizes": {rq: &GetConsistencyProofRequest{FirstTreeSize: 2, SecondTreeSize: 2}},
+		"zero first size": {
+			rq:       &GetConsistencyProofRequest{SecondTreeSize: 2},
+			expected: ErrInvalidTreeSizes,
+		},
+		"first larger than second": {
+			rq:       &GetConsistencyProofRequest{FirstTreeSize: 3, SecondTreeSize: 2},
+			expected: ErrInvalidTreeSizes,
+		},
+	}
+	for desc, c := range cases {
+		assert.Equal(t, c.expected, ValidateGetConsistencyProofRequest(c.rq), desc)
+	}
+}
+
+func newTestLeafHashes(rng *rand.Rand) ([]byte, []byte, []byte) {
+	pkds := NewTestPublicKeyDetails(rng, 3)
+	return PublicKeyDetailLeafHash(pkds[0]), PublicKeyDetailLeafHash(pkds[1]),
+		PublicKeyDetailLeafHash(pkds[2])
+}
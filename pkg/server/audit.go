@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VerifyAuditLog checks that each audit record follows the previous one and that its hash matches
+// its contents, and that the audit log still has the request's checkpoint, if any. An invalid
+// audit log isn't an error: the response says which record is the first invalid one and why.
+func (k *Key) VerifyAuditLog(
+	ctx context.Context, rq *api.VerifyAuditLogRequest,
+) (*api.VerifyAuditLogResponse, error) {
+	k.Logger.Debug("received verify audit log request", logVerifyAuditLogRq(rq)...)
+	if err := api.ValidateVerifyAuditLogRequest(rq); err != nil {
+		k.Logger.Info("verify audit log request invalid", zap.String(logErr, err.Error()))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	rp := &api.VerifyAuditLogResponse{Valid: true}
+	checkpointFound := rq.CheckpointSequence == 0
+	var prev *api.AuditRecord
+	for {
+		records, err := k.storer.ListAuditRecords(ctx, rp.HeadSequence, api.DefaultListPageSize)
+		if err != nil {
+			k.Logger.Error("storer list audit records error", zap.Error(err))
+			return nil, ErrInternal
+		}
+		for _, r := range records {
+			if err := api.VerifyAuditRecord(prev, r); err != nil {
+				return k.invalidAuditLog(rp, r.Sequence, err), nil
+			}
+			if r.Sequence == rq.CheckpointSequence {
+				if !bytes.Equal(r.Hash, rq.CheckpointHash) {
+					return k.invalidAuditLog(rp, r.Sequence, api.ErrAuditCheckpointMismatch), nil
+				}
+				checkpointFound = true
+			}
+			rp.NRecords++
+			rp.HeadSequence, rp.HeadHash = r.Sequence, r.Hash
+			prev = r
+		}
+		if len(records) < api.DefaultListPageSize {
+			break
+		}
+	}
+	if !checkpointFound {
+		// the audit log was truncated before the checkpoint
+		return k.invalidAuditLog(rp, rq.CheckpointSequence, api.ErrAuditCheckpointMismatch), nil
+	}
+	k.Logger.Info("verified audit log", logVerifyAuditLogRp(rp)...)
+	return rp, nil
+}
+
+func (k *Key) invalidAuditLog(
+	rp *api.VerifyAuditLogResponse, seq uint64, err error,
+) *api.VerifyAuditLogResponse {
+	rp.Valid = false
+	rp.InvalidSequence, rp.InvalidReason = seq, err.Error()
+	k.Logger.Warn("audit log invalid", logVerifyAuditLogRp(rp)...)
+	return rp
+}
+
+// newAuditRecords returns the audit records of the mutation for each entity and key type of its
+// added and disabled public key details, attributed to the authenticated caller, if any, for the
+// storer to append atomically with the mutation.
+func newAuditRecords(
+	ctx context.Context, action api.AuditAction, added, disabled []*api.PublicKeyDetail,
+) []*api.AuditRecord {
+	var callerEntityID string
+	if caller, ok := auth.FromContext(ctx); ok {
+		callerEntityID = caller.EntityID
+	}
+	return storage.NewAuditRecords(action, callerEntityID, time.Now(), added, disabled)
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	bserver "github.com/elixirhealth/service-base/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKey_VerifyAuditLog_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := newTestAuditLog(rng, api.DefaultListPageSize+2)
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		storer:     &fixedStorer{auditRecords: records},
+	}
+	head := records[len(records)-1]
+	checkpoint := records[api.DefaultListPageSize/2]
+
+	cases := map[string]*api.VerifyAuditLogRequest{
+		"no checkpoint": {},
+		"checkpoint": {
+			CheckpointSequence: checkpoint.Sequence,
+			CheckpointHash:     checkpoint.Hash,
+		},
+		"head checkpoint": {
+			CheckpointSequence: head.Sequence,
+			CheckpointHash:     head.Hash,
+		},
+	}
+	for desc, rq := range cases {
+		rp, err := k.VerifyAuditLog(context.Background(), rq)
+		assert.Nil(t, err, desc)
+		assert.Equal(t, &api.VerifyAuditLogResponse{
+			Valid:        true,
+			NRecords:     uint64(len(records)),
+			HeadSequence: head.Sequence,
+			HeadHash:     head.Hash,
+		}, rp, desc)
+	}
+
+	// empty audit log is valid
+	k.storer = &fixedStorer{}
+	rp, err := k.VerifyAuditLog(context.Background(), &api.VerifyAuditLogRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, &api.VerifyAuditLogResponse{Valid: true}, rp)
+}
+
+func TestKey_VerifyAuditLog_invalid(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	newRecords := func() []*api.AuditRecord { return newTestAuditLog(rng, 4) }
+
+	edited := newRecords()
+	edited[2].EntityId = "other entity ID"
+	rehashed := newRecords()
+	rehashed[1].KeyType = api.KeyType_AUTHOR
+	rehashed[1].Hash = api.AuditRecordHash(rehashed[1])
+	gap := newRecords()
+	gap = append(gap[:1], gap[2:]...)
+	truncated := newRecords()
+
+	cases := map[string]struct {
+		records         []*api.AuditRecord
+		rq              *api.VerifyAuditLogRequest
+		invalidSequence uint64
+		invalidReason   error
+	}{
+		"edited": {
+			records:         edited,
+			rq:              &api.VerifyAuditLogRequest{},
+			invalidSequence: 3,
+			invalidReason:   api.ErrAuditRecordEdited,
+		},
+		"rehashed": {
+			records:         rehashed,
+			rq:              &api.VerifyAuditLogRequest{},
+			invalidSequence: 3,
+			invalidReason:   api.ErrAuditChainBroken,
+		},
+		"gap": {
+			records:         gap,
+			rq:              &api.VerifyAuditLogRequest{},
+			invalidSequence: 3,
+			invalidReason:   api.ErrAuditSequenceGap,
+		},
+		"checkpoint mismatch": {
+			records: newRecords(),
+			rq: &api.VerifyAuditLogRequest{
+				CheckpointSequence: 2,
+				CheckpointHash:     []byte("some other hash"),
+			},
+			invalidSequence: 2,
+			invalidReason:   api.ErrAuditCheckpointMismatch,
+		},
+		"truncated before checkpoint": {
+			records: truncated[:2],
+			rq: &api.VerifyAuditLogRequest{
+				CheckpointSequence: truncated[3].Sequence,
+				CheckpointHash:     truncated[3].Hash,
+			},
+			invalidSequence: 4,
+			invalidReason:   api.ErrAuditCheckpointMismatch,
+		},
+	}
+	for desc, c := range cases {
+		k := &Key{
+			BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+			storer:     &fixedStorer{auditRecords: c.records},
+		}
+		rp, err := k.VerifyAuditLog(context.Background(), c.rq)
+		assert.Nil(t, err, desc)
+		assert.False(t, rp.Valid, desc)
+		assert.Equal(t, c.invalidSequence, rp.InvalidSequence, desc)
+		assert.Equal(t, c.invalidReason.Error(), rp.InvalidReason, desc)
+	}
+}
+
+func TestKey_VerifyAuditLog_err(t *testing.T) {
+	baseServer := bserver.NewBaseServer(bserver.NewDefaultBaseConfig())
+	cases := map[string]struct {
+		k        *Key
+		rq       *api.VerifyAuditLogRequest
+		expected error
+	}{
+		"bad request": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{},
+			},
+			rq:       &api.VerifyAuditLogRequest{CheckpointSequence: 1},
+			expected: status.Error(codes.InvalidArgument, api.ErrIncompleteCheckpoint.Error()),
+		},
+		"storer list audit records error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{listAuditErr: errTest},
+			},
+			rq:       &api.VerifyAuditLogRequest{},
+			expected: ErrInternal,
+		},
+	}
+	for desc, c := range cases {
+		rp, err := c.k.VerifyAuditLog(context.Background(), c.rq)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, rp, desc)
+	}
+}
+
+func newTestAuditLog(rng *rand.Rand, n int) []*api.AuditRecord {
+	records := api.NewTestAuditRecords(rng, n)
+	storage.ChainAuditRecords(nil, records)
+	return records
+}
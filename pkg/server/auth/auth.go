@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+)
+
+var (
+	// ErrNoCredentials indicates when a request doesn't carry any credentials an Identifier
+	// recognizes.
+	ErrNoCredentials = errors.New("no caller credentials")
+
+	// ErrUnauthenticated indicates when a request needs an authenticated caller but doesn't have
+	// one.
+	ErrUnauthenticated = errors.New("request requires an authenticated caller")
+
+	// ErrNotEntityOrDelegate indicates when the caller is neither the entity a request acts on
+	// nor one of its delegated admins.
+	ErrNotEntityOrDelegate = errors.New("caller is not the entity or one of its delegated " +
+		"admins")
+
+	// ErrRequesterNotCaller indicates when a request's requester entity ID isn't the caller's.
+	ErrRequesterNotCaller = errors.New("requester entity ID does not match caller")
+)
+
+// Identity is the authenticated identity of the caller of a request.
+type Identity struct {
+	// EntityID is the ID of the entity making the request.
+	EntityID string
+}
+
+// Identifier derives the caller's identity from a request's context.
+type Identifier interface {
+	// Identify returns the identity of the caller. It returns ErrNoCredentials when the context
+	// has no credentials of the kind the Identifier handles and another error when it has
+	// credentials that are invalid.
+	Identify(ctx context.Context) (*Identity, error)
+}
+
+// Identifiers is an Identifier that tries each of its Identifiers in order, returning the identity
+// from the first whose credentials are present.
+type Identifiers []Identifier
+
+// Identify returns the identity from the first Identifier that finds credentials in the context.
+func (ids Identifiers) Identify(ctx context.Context) (*Identity, error) {
+	for _, id := range ids {
+		caller, err := id.Identify(ctx)
+		if err != ErrNoCredentials {
+			return caller, err
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+// Authorizer decides whether a caller may make a request.
+type Authorizer interface {
+	// Authorize returns nil if the caller may make the request and an error otherwise. The
+	// caller is nil when the request has no credentials, in which case the Authorizer should
+	// return ErrUnauthenticated for requests that need them.
+	Authorize(caller *Identity, rq interface{}) error
+}
+
+// EntityAuthorizer is an Authorizer that lets only an entity or its delegated admins add, revoke,
+// rotate, or import the entity's public keys and requires the requester of sampled public keys to
+// be the caller. Requests that enumerate public keys across entities, i.e., listing, exporting,
+// and watching them, and verifying the audit log need an authenticated caller. It allows all other
+// requests.
+type EntityAuthorizer struct {
+	delegates map[string]map[string]struct{}
+}
+
+// NewEntityAuthorizer returns an EntityAuthorizer with the given map from entity ID to the entity
+// IDs of its delegated admins.
+func NewEntityAuthorizer(delegates map[string][]string) *EntityAuthorizer {
+	a := &EntityAuthorizer{delegates: make(map[string]map[string]struct{})}
+	for entityID, admins := range delegates {
+		a.delegates[entityID] = make(map[string]struct{})
+		for _, admin := range admins {
+			a.delegates[entityID][admin] = struct{}{}
+		}
+	}
+	return a
+}
+
+// Authorize returns nil if the caller may make the request.
+func (a *EntityAuthorizer) Authorize(caller *Identity, rq interface{}) error {
+	switch rq := rq.(type) {
+	case *api.AddPublicKeysRequest:
+		return a.authorizeEntity(caller, rq.EntityId)
+	case *api.RevokePublicKeysRequest:
+		return a.authorizeEntity(caller, rq.EntityId)
+	case *api.RotatePublicKeysRequest:
+		return a.authorizeEntity(caller, rq.EntityId)
+	case *api.ImportPublicKeysRequest:
+		return a.authorizeImport(caller, rq)
+	case *api.SamplePublicKeysRequest:
+		if caller == nil {
+			return ErrUnauthenticated
+		}
+		if caller.EntityID != rq.RequesterEntityId {
+			return ErrRequesterNotCaller
+		}
+	case *api.ListPublicKeysRequest, *api.ExportPublicKeysRequest,
+		*api.WatchPublicKeysRequest, *api.VerifyAuditLogRequest:
+		if caller == nil {
+			return ErrUnauthenticated
+		}
+	}
+	return nil
+}
+
+// authorizeImport checks that the caller may add public keys for each entity in the import
+// request. Public key details without an entity ID are left for the import to reject.
+func (a *EntityAuthorizer) authorizeImport(
+	caller *Identity, rq *api.ImportPublicKeysRequest,
+) error {
+	authorized := make(map[string]struct{})
+	for _, pkd := range rq.PublicKeyDetails {
+		entityID := pkd.GetEntityId()
+		if _, in := authorized[entityID]; in || entityID == "" {
+			continue
+		}
+		if err := a.authorizeEntity(caller, entityID); err != nil {
+			return err
+		}
+		authorized[entityID] = struct{}{}
+	}
+	return nil
+}
+
+func (a *EntityAuthorizer) authorizeEntity(caller *Identity, entityID string) error {
+	if caller == nil {
+		return ErrUnauthenticated
+	}
+	if caller.EntityID == entityID {
+		return nil
+	}
+	if _, in := a.delegates[entityID][caller.EntityID]; in {
+		return nil
+	}
+	return ErrNotEntityOrDelegate
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of the context carrying the caller's identity.
+func NewContext(ctx context.Context, caller *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, caller)
+}
+
+// FromContext returns the caller's identity stored in the context, if any.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	caller, ok := ctx.Value(identityKey{}).(*Identity)
+	return caller, ok
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentifiers_Identify(t *testing.T) {
+	errTest := errors.New("some identify error")
+	caller := &Identity{EntityID: "entity 1"}
+	cases := map[string]struct {
+		ids            Identifiers
+		expectedCaller *Identity
+		expectedErr    error
+	}{
+		"none": {
+			ids:         Identifiers{},
+			expectedErr: ErrNoCredentials,
+		},
+		"no credentials": {
+			ids: Identifiers{
+				&fixedIdentifier{err: ErrNoCredentials},
+				&fixedIdentifier{err: ErrNoCredentials},
+			},
+			expectedErr: ErrNoCredentials,
+		},
+		"second": {
+			ids: Identifiers{
+				&fixedIdentifier{err: ErrNoCredentials},
+				&fixedIdentifier{caller: caller},
+			},
+			expectedCaller: caller,
+		},
+		"first err": {
+			ids: Identifiers{
+				&fixedIdentifier{err: errTest},
+				&fixedIdentifier{caller: caller},
+			},
+			expectedErr: errTest,
+		},
+	}
+	for desc, c := range cases {
+		caller, err := c.ids.Identify(context.Background())
+		assert.Equal(t, c.expectedCaller, caller, desc)
+		assert.Equal(t, c.expectedErr, err, desc)
+	}
+}
+
+func TestEntityAuthorizer_Authorize(t *testing.T) {
+	a := NewEntityAuthorizer(map[string][]string{
+		"entity 1": {"admin 1"},
+	})
+	entity1 := &Identity{EntityID: "entity 1"}
+	admin1 := &Identity{EntityID: "admin 1"}
+	entity2 := &Identity{EntityID: "entity 2"}
+	cases := map[string]struct {
+		caller   *Identity
+		rq       interface{}
+		expected error
+	}{
+		"add by entity": {
+			caller:   entity1,
+			rq:       &api.AddPublicKeysRequest{EntityId: "entity 1"},
+			expected: nil,
+		},
+		"add by delegate": {
+			caller:   admin1,
+			rq:       &api.AddPublicKeysRequest{EntityId: "entity 1"},
+			expected: nil,
+		},
+		"add by other": {
+			caller:   entity2,
+			rq:       &api.AddPublicKeysRequest{EntityId: "entity 1"},
+			expected: ErrNotEntityOrDelegate,
+		},
+		"add unauthenticated": {
+			rq:       &api.AddPublicKeysRequest{EntityId: "entity 1"},
+			expected: ErrUnauthenticated,
+		},
+		"revoke by delegate": {
+			caller:   admin1,
+			rq:       &api.RevokePublicKeysRequest{EntityId: "entity 1"},
+			expected: nil,
+		},
+		"revoke by delegate of other": {
+			caller:   admin1,
+			rq:       &api.RevokePublicKeysRequest{EntityId: "entity 2"},
+			expected: ErrNotEntityOrDelegate,
+		},
+		"rotate by other": {
+			caller:   entity2,
+			rq:       &api.RotatePublicKeysRequest{EntityId: "entity 1"},
+			expected: ErrNotEntityOrDelegate,
+		},
+		"import by delegate": {
+			caller: admin1,
+			rq: &api.ImportPublicKeysRequest{
+				PublicKeyDetails: []*api.PublicKeyDetail{
+					{EntityId: "entity 1"},
+					{EntityId: "entity 1"},
+					{},
+				},
+			},
+			expected: nil,
+		},
+		"import by delegate with other entity": {
+			caller: admin1,
+			rq: &api.ImportPublicKeysRequest{
+				PublicKeyDetails: []*api.PublicKeyDetail{
+					{EntityId: "entity 1"},
+					{EntityId: "entity 2"},
+				},
+			},
+			expected: ErrNotEntityOrDelegate,
+		},
+		"import unauthenticated": {
+			rq: &api.ImportPublicKeysRequest{
+				PublicKeyDetails: []*api.PublicKeyDetail{{EntityId: "entity 1"}},
+			},
+			expected: ErrUnauthenticated,
+		},
+		"sample by requester": {
+			caller:   entity2,
+			rq:       &api.SamplePublicKeysRequest{RequesterEntityId: "entity 2"},
+			expected: nil,
+		},
+		"sample by delegate of requester": {
+			caller:   admin1,
+			rq:       &api.SamplePublicKeysRequest{RequesterEntityId: "entity 1"},
+			expected: ErrRequesterNotCaller,
+		},
+		"sample unauthenticated": {
+			rq:       &api.SamplePublicKeysRequest{RequesterEntityId: "entity 2"},
+			expected: ErrUnauthenticated,
+		},
+		"get unauthenticated": {
+			rq:       &api.GetPublicKeysRequest{EntityId: "entity 1"},
+			expected: nil,
+		},
+		"list by other": {
+			caller:   entity2,
+			rq:       &api.ListPublicKeysRequest{EntityId: "entity 1"},
+			expected: nil,
+		},
+		"list unauthenticated": {
+			rq:       &api.ListPublicKeysRequest{},
+			expected: ErrUnauthenticated,
+		},
+		"export unauthenticated": {
+			rq:       &api.ExportPublicKeysRequest{},
+			expected: ErrUnauthenticated,
+		},
+		"watch unauthenticated": {
+			rq:       &api.WatchPublicKeysRequest{},
+			expected: ErrUnauthenticated,
+		},
+		"verify audit log by entity": {
+			caller:   entity1,
+			rq:       &api.VerifyAuditLogRequest{},
+			expected: nil,
+		},
+		"verify audit log unauthenticated": {
+			rq:       &api.VerifyAuditLogRequest{},
+			expected: ErrUnauthenticated,
+		},
+	}
+	for desc, c := range cases {
+		err := a.Authorize(c.caller, c.rq)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestNewContext_FromContext(t *testing.T) {
+	ctx := context.Background()
+	_, ok := FromContext(ctx)
+	assert.False(t, ok)
+
+	caller := &Identity{EntityID: "entity 1"}
+	caller2, ok := FromContext(NewContext(ctx, caller))
+	assert.True(t, ok)
+	assert.Equal(t, caller, caller2)
+}
+
+type fixedIdentifier struct {
+	caller *Identity
+	err    error
+}
+
+func (f *fixedIdentifier) Identify(ctx context.Context) (*Identity, error) {
+	return f.caller, f.err
+}
+
+type fixedAuthorizer struct {
+	err    error
+	caller *Identity
+}
+
+func (f *fixedAuthorizer) Authorize(caller *Identity, rq interface{}) error {
+	f.caller = caller
+	return f.err
+}
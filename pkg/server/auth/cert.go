@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+var (
+	// ErrUntrustedCertificate indicates when a client certificate doesn't chain to a trusted
+	// root CA.
+	ErrUntrustedCertificate = errors.New("client certificate not signed by a trusted CA")
+
+	// ErrMissingCommonName indicates when a client certificate has no subject common name to
+	// use as the caller's entity ID.
+	ErrMissingCommonName = errors.New("client certificate has no subject common name")
+)
+
+// CertIdentifier is an Identifier that takes the caller's entity ID from the subject common name
+// of the client certificate of an mTLS connection.
+type CertIdentifier struct {
+	roots *x509.CertPool
+}
+
+// NewCertIdentifier returns a CertIdentifier that only trusts client certificates chaining to the
+// given root CAs.
+func NewCertIdentifier(roots *x509.CertPool) *CertIdentifier {
+	return &CertIdentifier{roots: roots}
+}
+
+// Identify returns the identity in the verified client certificate of the request's connection.
+func (ci *CertIdentifier) Identify(ctx context.Context) (*Identity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil, ErrNoCredentials
+	}
+	return ci.identifyCert(tlsInfo.State.PeerCertificates)
+}
+
+// identifyCert verifies the leaf certificate against the root CAs, using the rest of the chain as
+// intermediates, even if the TLS handshake already did so, so that a misconfigured server can't
+// accept identities from an untrusted CA.
+func (ci *CertIdentifier) identifyCert(chain []*x509.Certificate) (*Identity, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	leaf := chain[0]
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         ci.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, ErrUntrustedCertificate
+	}
+	if leaf.Subject.CommonName == "" {
+		return nil, ErrMissingCommonName
+	}
+	return &Identity{EntityID: leaf.Subject.CommonName}, nil
+}
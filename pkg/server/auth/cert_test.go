@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestCertIdentifier_Identify_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ca, caKey := newTestCA(t, rng)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	ci := NewCertIdentifier(roots)
+
+	cert := newTestClientCert(t, rng, "entity 1", ca, caKey, x509.ExtKeyUsageClientAuth)
+	caller, err := ci.Identify(newTLSContext(cert))
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{EntityID: "entity 1"}, caller)
+}
+
+func TestCertIdentifier_Identify_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ca, caKey := newTestCA(t, rng)
+	otherCA, otherCAKey := newTestCA(t, rng)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	ci := NewCertIdentifier(roots)
+
+	cases := map[string]struct {
+		ctx      context.Context
+		expected error
+	}{
+		"no peer": {
+			ctx:      context.Background(),
+			expected: ErrNoCredentials,
+		},
+		"no TLS": {
+			ctx:      peer.NewContext(context.Background(), &peer.Peer{}),
+			expected: ErrNoCredentials,
+		},
+		"no client cert": {
+			ctx:      newTLSContext(),
+			expected: ErrNoCredentials,
+		},
+		"untrusted CA": {
+			ctx: newTLSContext(newTestClientCert(t, rng, "entity 1", otherCA,
+				otherCAKey, x509.ExtKeyUsageClientAuth)),
+			expected: ErrUntrustedCertificate,
+		},
+		"server cert": {
+			ctx: newTLSContext(newTestClientCert(t, rng, "entity 1", ca, caKey,
+				x509.ExtKeyUsageServerAuth)),
+			expected: ErrUntrustedCertificate,
+		},
+		"no common name": {
+			ctx: newTLSContext(newTestClientCert(t, rng, "", ca, caKey,
+				x509.ExtKeyUsageClientAuth)),
+			expected: ErrMissingCommonName,
+		},
+	}
+	for desc, c := range cases {
+		caller, err := ci.Identify(c.ctx)
+		assert.Nil(t, caller, desc)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func newTLSContext(certs ...*x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 10100},
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{PeerCertificates: certs},
+		},
+	})
+}
+
+// newTestCA creates a self-signed CA certificate and its private key.
+func newTestCA(t *testing.T, rng *rand.Rand) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(rng.Int63()),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rng, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return cert, key
+}
+
+// newTestClientCert creates a certificate for the given common name signed by the CA.
+func newTestClientCert(
+	t *testing.T,
+	rng *rand.Rand,
+	commonName string,
+	ca *x509.Certificate,
+	caKey *ecdsa.PrivateKey,
+	extKeyUsage x509.ExtKeyUsage,
+) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(rng.Int63()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+	}
+	der, err := x509.CreateCertificate(rng, template, ca, &key.PublicKey, caKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return cert
+}
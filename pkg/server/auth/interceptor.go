@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor that identifies the caller of each request
+// and checks that the authorizer allows it before calling the handler with the caller's identity
+// in the context. Requests without credentials reach the authorizer with a nil caller.
+func UnaryServerInterceptor(
+	identifier Identifier, authorizer Authorizer,
+) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		rq interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		caller, err := identifier.Identify(ctx)
+		if err != nil && err != ErrNoCredentials {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err := authorizer.Authorize(caller, rq); err != nil {
+			return nil, authorizationError(err)
+		}
+		if caller != nil {
+			ctx = NewContext(ctx, caller)
+		}
+		return handler(ctx, rq)
+	}
+}
+
+func authorizationError(err error) error {
+	if err == ErrUnauthenticated {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return status.Error(codes.PermissionDenied, err.Error())
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor_ok(t *testing.T) {
+	caller := &Identity{EntityID: "entity 1"}
+	rq := &api.AddPublicKeysRequest{EntityId: "entity 1"}
+	rp := &api.AddPublicKeysResponse{}
+	cases := map[string]struct {
+		identifier Identifier
+		expected   *Identity
+	}{
+		"caller": {
+			identifier: &fixedIdentifier{caller: caller},
+			expected:   caller,
+		},
+		"no credentials": {
+			identifier: &fixedIdentifier{err: ErrNoCredentials},
+		},
+	}
+	for desc, c := range cases {
+		authorizer := &fixedAuthorizer{}
+		interceptor := UnaryServerInterceptor(c.identifier, authorizer)
+		var handlerCaller *Identity
+		handler := func(ctx context.Context, rq interface{}) (interface{}, error) {
+			handlerCaller, _ = FromContext(ctx)
+			return rp, nil
+		}
+		rp2, err := interceptor(context.Background(), rq, &grpc.UnaryServerInfo{}, handler)
+		assert.Nil(t, err, desc)
+		assert.Equal(t, rp, rp2, desc)
+		assert.Equal(t, c.expected, authorizer.caller, desc)
+		assert.Equal(t, c.expected, handlerCaller, desc)
+	}
+}
+
+func TestUnaryServerInterceptor_err(t *testing.T) {
+	rq := &api.AddPublicKeysRequest{EntityId: "entity 1"}
+	caller := &Identity{EntityID: "entity 2"}
+	cases := map[string]struct {
+		identifier Identifier
+		authorizer Authorizer
+		expected   codes.Code
+	}{
+		"invalid credentials": {
+			identifier: &fixedIdentifier{err: ErrInvalidToken},
+			authorizer: &fixedAuthorizer{},
+			expected:   codes.Unauthenticated,
+		},
+		"unauthenticated": {
+			identifier: &fixedIdentifier{err: ErrNoCredentials},
+			authorizer: &fixedAuthorizer{err: ErrUnauthenticated},
+			expected:   codes.Unauthenticated,
+		},
+		"denied": {
+			identifier: &fixedIdentifier{caller: caller},
+			authorizer: &fixedAuthorizer{err: ErrNotEntityOrDelegate},
+			expected:   codes.PermissionDenied,
+		},
+		"other authorizer error": {
+			identifier: &fixedIdentifier{caller: caller},
+			authorizer: &fixedAuthorizer{err: errors.New("some authorize error")},
+			expected:   codes.PermissionDenied,
+		},
+	}
+	for desc, c := range cases {
+		interceptor := UnaryServerInterceptor(c.identifier, c.authorizer)
+		handler := func(ctx context.Context, rq interface{}) (interface{}, error) {
+			assert.Fail(t, "handler should not be called", desc)
+			return nil, nil
+		}
+		rp, err := interceptor(context.Background(), rq, &grpc.UnaryServerInfo{}, handler)
+		assert.Nil(t, rp, desc)
+		assert.Equal(t, c.expected, status.Convert(err).Code(), desc)
+	}
+}
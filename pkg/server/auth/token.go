@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// AuthorizationKey is the request metadata key holding a signed token.
+	AuthorizationKey = "authorization"
+
+	bearerPrefix = "Bearer "
+)
+
+var (
+	// ErrInvalidToken indicates when a token is malformed or its signature doesn't verify.
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrExpiredToken indicates when a token's expiration time has passed.
+	ErrExpiredToken = errors.New("expired token")
+
+	// ErrEmptySecret indicates when a token secret is empty.
+	ErrEmptySecret = errors.New("token secret is empty")
+)
+
+// tokenClaims are the contents of a signed token.
+type tokenClaims struct {
+	EntityID string `json:"sub"`
+	Expires  int64  `json:"exp"`
+}
+
+// NewToken returns a token for the entity that expires at the given time, signed with an
+// HMAC-SHA256 of the secret. The token is the base64url encodings of its claims and signature,
+// separated by a period.
+func NewToken(secret []byte, entityID string, expires time.Time) (string, error) {
+	if len(secret) == 0 {
+		return "", ErrEmptySecret
+	}
+	claims, err := json.Marshal(&tokenClaims{EntityID: entityID, Expires: expires.Unix()})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	sig := base64.RawURLEncoding.EncodeToString(signToken(secret, payload))
+	return payload + "." + sig, nil
+}
+
+func signToken(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// TokenIdentifier is an Identifier that takes the caller's entity ID from a signed bearer token in
+// the request's authorization metadata.
+type TokenIdentifier struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewTokenIdentifier returns a TokenIdentifier that verifies tokens with the given secret.
+func NewTokenIdentifier(secret []byte) (*TokenIdentifier, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+	return &TokenIdentifier{secret: secret, now: time.Now}, nil
+}
+
+// Identify returns the identity in the request's bearer token.
+func (ti *TokenIdentifier) Identify(ctx context.Context) (*Identity, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md[AuthorizationKey]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	value := md[AuthorizationKey][0]
+	if !strings.HasPrefix(value, bearerPrefix) {
+		return nil, ErrNoCredentials
+	}
+	return ti.identifyToken(strings.TrimPrefix(value, bearerPrefix))
+}
+
+func (ti *TokenIdentifier) identifyToken(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, signToken(ti.secret, parts[0])) {
+		return nil, ErrInvalidToken
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &tokenClaims{}
+	if err := json.Unmarshal(claimsJSON, claims); err != nil || claims.EntityID == "" {
+		return nil, ErrInvalidToken
+	}
+	if !ti.now().Before(time.Unix(claims.Expires, 0)) {
+		return nil, ErrExpiredToken
+	}
+	return &Identity{EntityID: claims.EntityID}, nil
+}
+
+// TokenCredentials are gRPC per-RPC credentials that send a signed token in each request's
+// authorization metadata.
+type TokenCredentials struct {
+	token  string
+	secure bool
+}
+
+// NewTokenCredentials returns TokenCredentials sending the given token. When secure is true, gRPC
+// refuses to send the token over a connection without transport security.
+func NewTokenCredentials(token string, secure bool) *TokenCredentials {
+	return &TokenCredentials{token: token, secure: secure}
+}
+
+// GetRequestMetadata returns the authorization metadata for a request.
+func (tc *TokenCredentials) GetRequestMetadata(
+	ctx context.Context, uri ...string,
+) (map[string]string, error) {
+	return map[string]string{AuthorizationKey: bearerPrefix + tc.token}, nil
+}
+
+// RequireTransportSecurity indicates whether the token requires transport security.
+func (tc *TokenCredentials) RequireTransportSecurity() bool {
+	return tc.secure
+}
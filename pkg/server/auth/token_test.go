@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNewToken_err(t *testing.T) {
+	token, err := NewToken(nil, "entity 1", time.Now())
+	assert.Equal(t, ErrEmptySecret, err)
+	assert.Empty(t, token)
+
+	ti, err := NewTokenIdentifier(nil)
+	assert.Equal(t, ErrEmptySecret, err)
+	assert.Nil(t, ti)
+}
+
+func TestTokenIdentifier_Identify_ok(t *testing.T) {
+	secret := []byte("some secret")
+	ti, err := NewTokenIdentifier(secret)
+	assert.Nil(t, err)
+	token, err := NewToken(secret, "entity 1", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+
+	caller, err := ti.Identify(newTokenContext(bearerPrefix + token))
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{EntityID: "entity 1"}, caller)
+}
+
+func TestTokenIdentifier_Identify_err(t *testing.T) {
+	secret := []byte("some secret")
+	now := time.Now()
+	ti, err := NewTokenIdentifier(secret)
+	assert.Nil(t, err)
+	ti.now = func() time.Time { return now }
+
+	okToken, err := NewToken(secret, "entity 1", now.Add(time.Hour))
+	assert.Nil(t, err)
+	otherToken, err := NewToken([]byte("other secret"), "entity 1", now.Add(time.Hour))
+	assert.Nil(t, err)
+	expiredToken, err := NewToken(secret, "entity 1", now)
+	assert.Nil(t, err)
+	noEntityToken, err := NewToken(secret, "", now.Add(time.Hour))
+	assert.Nil(t, err)
+
+	cases := map[string]struct {
+		ctx      context.Context
+		expected error
+	}{
+		"no metadata": {
+			ctx:      context.Background(),
+			expected: ErrNoCredentials,
+		},
+		"no authorization": {
+			ctx:      metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+			expected: ErrNoCredentials,
+		},
+		"not bearer": {
+			ctx:      newTokenContext("Basic " + okToken),
+			expected: ErrNoCredentials,
+		},
+		"malformed": {
+			ctx:      newTokenContext(bearerPrefix + "not a token"),
+			expected: ErrInvalidToken,
+		},
+		"other secret": {
+			ctx:      newTokenContext(bearerPrefix + otherToken),
+			expected: ErrInvalidToken,
+		},
+		"expired": {
+			ctx:      newTokenContext(bearerPrefix + expiredToken),
+			expected: ErrExpiredToken,
+		},
+		"no entity": {
+			ctx:      newTokenContext(bearerPrefix + noEntityToken),
+			expected: ErrInvalidToken,
+		},
+	}
+	for desc, c := range cases {
+		caller, err := ti.Identify(c.ctx)
+		assert.Nil(t, caller, desc)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestTokenCredentials(t *testing.T) {
+	tc := NewTokenCredentials("some token", true)
+	md, err := tc.GetRequestMetadata(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{AuthorizationKey: "Bearer some token"}, md)
+	assert.True(t, tc.RequireTransportSecurity())
+}
+
+func newTokenContext(value string) context.Context {
+	md := metadata.Pairs(AuthorizationKey, value)
+	return metadata.NewIncomingContext(context.Background(), md)
+}
@@ -1,18 +1,86 @@
 package server
 
 import (
-	"github.com/drausin/libri/libri/common/errors"
+	"crypto/ed25519"
+	"errors"
+	"time"
+
+	errors2 "github.com/drausin/libri/libri/common/errors"
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/elixirhealth/key/pkg/server/events"
 	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/cache"
+	"github.com/elixirhealth/key/pkg/server/storage/postgres"
+	"github.com/elixirhealth/key/pkg/server/watch"
 	"github.com/elixirhealth/service-base/pkg/server"
 	"go.uber.org/zap/zapcore"
 )
 
+var (
+	// ErrEventsWithoutPostgres indicates when the config has an EventPublisher but not Postgres
+	// storage, whose outbox is the only source of the events it publishes.
+	ErrEventsWithoutPostgres = errors.New("event publisher requires Postgres storage")
+)
+
 // Config is the config for a Key instance.
 type Config struct {
 	*server.BaseConfig
 	Storage      *storage.Parameters
 	GCPProjectID string
 	DBUrl        string
+
+	// BoltDBPath is the path of the embedded Bolt DB file used for Bolt storage, which is created
+	// if it doesn't exist.
+	BoltDBPath string
+
+	// MemorySnapshotPath is the file the in-memory storer restores its public keys from on start
+	// and writes snapshots of them to every MemorySnapshotInterval and on stop. The in-memory
+	// storer only snapshots when it is set.
+	MemorySnapshotPath     string
+	MemorySnapshotInterval time.Duration
+
+	// StorageCache defines the cache of public key lookups in front of the storer. Lookups
+	// aren't cached when it is nil or the storage is shared with other instances.
+	StorageCache *cache.Parameters
+
+	// Watch defines how many public key events are retained for resuming WatchPublicKeys
+	// streams and buffered for each stream. Shared storage isn't watched.
+	Watch *watch.Parameters
+
+	// EventPublisher publishes the public key events the Postgres storer writes to its outbox.
+	// Events are only published when it is set, which requires Postgres storage.
+	EventPublisher events.EventPublisher
+
+	// EventRelay defines how often and in what batch sizes the outbox events are published.
+	EventRelay *postgres.RelayParameters
+
+	// DatastoreEmulatorHost is the host of a local DataStore emulator to use instead of GCP
+	// DataStore, for development and testing.
+	DatastoreEmulatorHost string
+
+	// RequireProofOfPossession requires requests adding public keys to include a signature for
+	// each key with its private key, proving that the entity holds it.
+	RequireProofOfPossession bool
+
+	// Identifier derives the caller's identity for each request. Requests are only authorized
+	// when both it and Authorizer are set.
+	Identifier auth.Identifier
+
+	// Authorizer decides whether each request's caller may make it.
+	Authorizer auth.Authorizer
+
+	// TLSCertFile and TLSKeyFile are the PEM files of the server's TLS certificate and private
+	// key. The server only serves over TLS when they are set.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSClientCAFile is the PEM file of the CA certificates that must sign client certificates.
+	// Clients only need certificates when it is set.
+	TLSClientCAFile string
+
+	// SigningKey signs the responses of public key lookups, so clients can check that they came
+	// from the server. Responses are only signed when it is set.
+	SigningKey ed25519.PrivateKey
 }
 
 // NewDefaultConfig create a new config instance with default values.
@@ -21,15 +89,33 @@ func NewDefaultConfig() *Config {
 		BaseConfig: server.NewDefaultBaseConfig(),
 	}
 	return config.
-		WithDefaultStorage()
+		WithDefaultStorage().
+		WithDefaultWatch().
+		WithDefaultEventRelay()
 }
 
 // MarshalLogObject writes the config to the given object encoder.
 func (c *Config) MarshalLogObject(oe zapcore.ObjectEncoder) error {
 	err := c.BaseConfig.MarshalLogObject(oe)
-	errors.MaybePanic(err) // should never happen
+	errors2.MaybePanic(err) // should never happen
 	err = oe.AddObject(logStorage, c.Storage)
-	errors.MaybePanic(err) // should never happen
+	errors2.MaybePanic(err) // should never happen
+	oe.AddString(logMemorySnapshotPath, c.MemorySnapshotPath)
+	if c.StorageCache != nil {
+		err = oe.AddObject(logStorageCache, c.StorageCache)
+		errors2.MaybePanic(err) // should never happen
+	}
+	err = oe.AddObject(logWatch, c.Watch)
+	errors2.MaybePanic(err) // should never happen
+	if c.EventPublisher != nil {
+		err = oe.AddObject(logEventRelay, c.EventRelay)
+		errors2.MaybePanic(err) // should never happen
+	}
+	oe.AddBool(logRequireProofOfPossession, c.RequireProofOfPossession)
+	oe.AddBool(logAuthorize, c.authorize())
+	oe.AddString(logTLSCertFile, c.TLSCertFile)
+	oe.AddString(logTLSClientCAFile, c.TLSClientCAFile)
+	oe.AddBool(logSignResponses, c.SigningKey != nil)
 	return nil
 }
 
@@ -48,14 +134,122 @@ func (c *Config) WithDefaultStorage() *Config {
 	return c
 }
 
+// WithWatch sets the watch parameters to the given value or the defaults if it is nil.
+func (c *Config) WithWatch(p *watch.Parameters) *Config {
+	if p == nil {
+		return c.WithDefaultWatch()
+	}
+	c.Watch = p
+	return c
+}
+
+// WithDefaultWatch sets the watch parameters to their default values.
+func (c *Config) WithDefaultWatch() *Config {
+	c.Watch = watch.NewDefaultParameters()
+	return c
+}
+
+// WithEventPublisher sets the publisher of the Postgres storer's outbox events to the given
+// value, or disables publishing them if it is nil.
+func (c *Config) WithEventPublisher(p events.EventPublisher) *Config {
+	c.EventPublisher = p
+	return c
+}
+
+// WithEventRelay sets the event relay parameters to the given value or the defaults if it is nil.
+func (c *Config) WithEventRelay(p *postgres.RelayParameters) *Config {
+	if p == nil {
+		return c.WithDefaultEventRelay()
+	}
+	c.EventRelay = p
+	return c
+}
+
+// WithDefaultEventRelay sets the event relay parameters to their default values.
+func (c *Config) WithDefaultEventRelay() *Config {
+	c.EventRelay = postgres.NewDefaultRelayParameters()
+	return c
+}
+
 // WithGCPProjectID sets the GCP ProjectID to the given value.
 func (c *Config) WithGCPProjectID(id string) *Config {
 	c.GCPProjectID = id
 	return c
 }
 
+// WithDatastoreEmulatorHost sets the host of the local DataStore emulator to the given value.
+func (c *Config) WithDatastoreEmulatorHost(host string) *Config {
+	c.DatastoreEmulatorHost = host
+	return c
+}
+
 // WithDBUrl sets the DB URL to the given value.
 func (c *Config) WithDBUrl(dbURL string) *Config {
 	c.DBUrl = dbURL
 	return c
 }
+
+// WithBoltDBPath sets the Bolt DB file path to the given value.
+func (c *Config) WithBoltDBPath(dbPath string) *Config {
+	c.BoltDBPath = dbPath
+	return c
+}
+
+// WithMemorySnapshots sets the in-memory storer's snapshot file and the interval between
+// snapshots.
+func (c *Config) WithMemorySnapshots(snapshotPath string, interval time.Duration) *Config {
+	c.MemorySnapshotPath = snapshotPath
+	c.MemorySnapshotInterval = interval
+	return c
+}
+
+// WithStorageCache sets the storer's cache parameters to the given value, or disables the cache
+// if it is nil.
+func (c *Config) WithStorageCache(p *cache.Parameters) *Config {
+	c.StorageCache = p
+	return c
+}
+
+// WithRequireProofOfPossession sets whether requests adding public keys must prove possession of
+// their private keys.
+func (c *Config) WithRequireProofOfPossession(require bool) *Config {
+	c.RequireProofOfPossession = require
+	return c
+}
+
+// WithAuth sets the Identifier and Authorizer used to authorize requests.
+func (c *Config) WithAuth(identifier auth.Identifier, authorizer auth.Authorizer) *Config {
+	c.Identifier = identifier
+	c.Authorizer = authorizer
+	return c
+}
+
+// WithTLS sets the TLS certificate, key, and client CA files.
+func (c *Config) WithTLS(certFile, keyFile, clientCAFile string) *Config {
+	c.TLSCertFile = certFile
+	c.TLSKeyFile = keyFile
+	c.TLSClientCAFile = clientCAFile
+	return c
+}
+
+// WithSigningKey sets the key that signs public key lookup responses, or disables signing them if
+// it is nil.
+func (c *Config) WithSigningKey(key ed25519.PrivateKey) *Config {
+	c.SigningKey = key
+	return c
+}
+
+func (c *Config) tls() bool {
+	return c.TLSCertFile != ""
+}
+
+func (c *Config) authorize() bool {
+	return c.Identifier != nil && c.Authorizer != nil
+}
+
+func (c *Config) validateEvents() error {
+	if c.EventPublisher != nil && c.Storage.Type != storage.Postgres {
+		return ErrEventsWithoutPostgres
+	}
+	return nil
+}
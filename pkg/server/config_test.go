@@ -2,9 +2,14 @@ package server
 
 import (
 	"testing"
+	"time"
 
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/elixirhealth/key/pkg/server/events"
 	"github.com/elixirhealth/key/pkg/server/storage"
-	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/cache"
+	"github.com/elixirhealth/key/pkg/server/storage/postgres"
+	"github.com/elixirhealth/key/pkg/server/watch"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -12,6 +17,8 @@ func TestNewDefaultConfig(t *testing.T) {
 	c := NewDefaultConfig()
 	assert.NotNil(t, c)
 	assert.NotNil(t, c.Storage)
+	assert.NotNil(t, c.Watch)
+	assert.NotNil(t, c.EventRelay)
 }
 
 func TestConfig_WithStorage(t *testing.T) {
@@ -21,11 +28,45 @@ func TestConfig_WithStorage(t *testing.T) {
 	assert.NotEqual(t,
 		c1.Storage.Type,
 		c3.WithStorage(
-			&storage.Parameters{Type: bstorage.DataStore},
+			&storage.Parameters{Type: storage.DataStore},
 		).Storage.Type,
 	)
 }
 
+func TestConfig_WithWatch(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultWatch()
+	assert.Equal(t, c1.Watch, c2.WithWatch(nil).Watch)
+	p := &watch.Parameters{RetainedEvents: 16}
+	assert.Equal(t, p, c3.WithWatch(p).Watch)
+}
+
+func TestConfig_WithEventPublisher(t *testing.T) {
+	c1 := &Config{}
+	p := events.NewMemoryPublisher()
+	c1.WithEventPublisher(p)
+	assert.Equal(t, p, c1.EventPublisher)
+}
+
+func TestConfig_WithEventRelay(t *testing.T) {
+	c1, c2, c3 := &Config{}, &Config{}, &Config{}
+	c1.WithDefaultEventRelay()
+	assert.Equal(t, c1.EventRelay, c2.WithEventRelay(nil).EventRelay)
+	p := &postgres.RelayParameters{Interval: time.Minute, BatchSize: 10}
+	assert.Equal(t, p, c3.WithEventRelay(p).EventRelay)
+}
+
+func TestConfig_validateEvents(t *testing.T) {
+	c := NewDefaultConfig()
+	assert.Nil(t, c.validateEvents())
+
+	c.WithEventPublisher(events.NewMemoryPublisher())
+	assert.Equal(t, ErrEventsWithoutPostgres, c.validateEvents())
+
+	c.Storage.Type = storage.Postgres
+	assert.Nil(t, c.validateEvents())
+}
+
 func TestConfig_WithGCPProjectID(t *testing.T) {
 	c1 := &Config{}
 	p := "project-ID"
@@ -33,9 +74,65 @@ func TestConfig_WithGCPProjectID(t *testing.T) {
 	assert.Equal(t, p, c1.GCPProjectID)
 }
 
+func TestConfig_WithDatastoreEmulatorHost(t *testing.T) {
+	c1 := &Config{}
+	host := "localhost:8081"
+	c1.WithDatastoreEmulatorHost(host)
+	assert.Equal(t, host, c1.DatastoreEmulatorHost)
+}
+
+func TestConfig_WithBoltDBPath(t *testing.T) {
+	c1 := &Config{}
+	dbPath := "some/key.db"
+	c1.WithBoltDBPath(dbPath)
+	assert.Equal(t, dbPath, c1.BoltDBPath)
+}
+
+func TestConfig_WithMemorySnapshots(t *testing.T) {
+	c1 := &Config{}
+	snapshotPath, interval := "some/key.snapshot", 30*time.Second
+	c1.WithMemorySnapshots(snapshotPath, interval)
+	assert.Equal(t, snapshotPath, c1.MemorySnapshotPath)
+	assert.Equal(t, interval, c1.MemorySnapshotInterval)
+}
+
+func TestConfig_WithStorageCache(t *testing.T) {
+	c1 := &Config{}
+	p := cache.NewDefaultParameters()
+	c1.WithStorageCache(p)
+	assert.Equal(t, p, c1.StorageCache)
+}
+
 func TestConfig_WithDBUrl(t *testing.T) {
 	c1 := &Config{}
 	dbURL := "some DB URL"
 	c1.WithDBUrl(dbURL)
 	assert.Equal(t, dbURL, c1.DBUrl)
 }
+
+func TestConfig_WithRequireProofOfPossession(t *testing.T) {
+	c1 := &Config{}
+	c1.WithRequireProofOfPossession(true)
+	assert.True(t, c1.RequireProofOfPossession)
+}
+
+func TestConfig_WithAuth(t *testing.T) {
+	c1 := &Config{}
+	assert.False(t, c1.authorize())
+	identifier := auth.Identifiers{}
+	authorizer := auth.NewEntityAuthorizer(nil)
+	c1.WithAuth(identifier, authorizer)
+	assert.Equal(t, identifier, c1.Identifier)
+	assert.Equal(t, authorizer, c1.Authorizer)
+	assert.True(t, c1.authorize())
+}
+
+func TestConfig_WithTLS(t *testing.T) {
+	c1 := &Config{}
+	assert.False(t, c1.tls())
+	c1.WithTLS("server.crt", "server.key", "ca.crt")
+	assert.Equal(t, "server.crt", c1.TLSCertFile)
+	assert.Equal(t, "server.key", c1.TLSKeyFile)
+	assert.Equal(t, "ca.crt", c1.TLSClientCAFile)
+	assert.True(t, c1.tls())
+}
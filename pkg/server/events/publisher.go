@@ -0,0 +1,126 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/golang/protobuf/jsonpb"
+)
+
+var (
+	// ErrPublisherClosed indicates when events are published after the publisher has been
+	// closed.
+	ErrPublisherClosed = errors.New("event publisher closed")
+)
+
+// EventPublisher publishes public key events to some downstream consumer, e.g., an audit
+// pipeline.
+//
+// Events are delivered at least once: a batch whose Publish succeeds may be published again if
+// the relay fails to record that it was, so consumers should ignore events with sequence numbers
+// they have already seen. Sequence numbers increase within and across batches in the order the
+// events were committed, but may have gaps.
+type EventPublisher interface {
+	// Publish publishes the events, in order.
+	Publish(ctx context.Context, events []*api.PublicKeyEvent) error
+
+	// Close releases the publisher's resources.
+	Close() error
+}
+
+// MemoryPublisher is an EventPublisher that keeps the published events in memory, mostly for
+// testing.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []*api.PublicKeyEvent
+	closed bool
+}
+
+// NewMemoryPublisher creates a new MemoryPublisher.
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish appends the events to those already published.
+func (p *MemoryPublisher) Publish(ctx context.Context, events []*api.PublicKeyEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPublisherClosed
+	}
+	p.events = append(p.events, events...)
+	return nil
+}
+
+// Events returns the events published so far.
+func (p *MemoryPublisher) Events() []*api.PublicKeyEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := make([]*api.PublicKeyEvent, len(p.events))
+	copy(events, p.events)
+	return events
+}
+
+// Close stops the publisher from publishing more events.
+func (p *MemoryPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+// FilePublisher is an EventPublisher that appends the events to a local file, each a JSON object
+// on its own line.
+type FilePublisher struct {
+	mu sync.Mutex
+	f  *os.File
+	m  *jsonpb.Marshaler
+}
+
+// NewFilePublisher creates a new FilePublisher appending to the file at the given path, which is
+// created if it doesn't exist.
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{f: f, m: &jsonpb.Marshaler{}}, nil
+}
+
+// Publish writes a line for each of the events and syncs the file, so the events have been
+// persisted once it returns. The batch is written at once so a failed write doesn't leave only
+// some of its events in the file.
+func (p *FilePublisher) Publish(ctx context.Context, events []*api.PublicKeyEvent) error {
+	buf := new(bytes.Buffer)
+	for _, e := range events {
+		if err := p.m.Marshal(buf, e); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.f == nil {
+		return ErrPublisherClosed
+	}
+	if _, err := p.f.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return p.f.Sync()
+}
+
+// Close closes the file.
+func (p *FilePublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.f == nil {
+		return nil
+	}
+	err := p.f.Close()
+	p.f = nil
+	return err
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPublisher(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	events := newTestEvents(rng, 4)
+	p := NewMemoryPublisher()
+
+	err := p.Publish(context.Background(), events[:1])
+	assert.Nil(t, err)
+	err = p.Publish(context.Background(), events[1:])
+	assert.Nil(t, err)
+	assert.Equal(t, events, p.Events())
+
+	err = p.Close()
+	assert.Nil(t, err)
+	err = p.Publish(context.Background(), events)
+	assert.Equal(t, ErrPublisherClosed, err)
+	assert.Equal(t, events, p.Events())
+}
+
+func TestFilePublisher_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	events := newTestEvents(rng, 4)
+	dir, err := ioutil.TempDir("", "events-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	path := filepath.Join(dir, "events.jsonl")
+
+	p, err := NewFilePublisher(path)
+	assert.Nil(t, err)
+	err = p.Publish(context.Background(), events[:2])
+	assert.Nil(t, err)
+	err = p.Close()
+	assert.Nil(t, err)
+
+	// reopening appends to the existing events
+	p, err = NewFilePublisher(path)
+	assert.Nil(t, err)
+	err = p.Publish(context.Background(), events[2:])
+	assert.Nil(t, err)
+	err = p.Close()
+	assert.Nil(t, err)
+	err = p.Close()
+	assert.Nil(t, err)
+
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, f.Close()) }()
+	read := make([]*api.PublicKeyEvent, 0, len(events))
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := &api.PublicKeyEvent{}
+		assert.Nil(t, jsonpb.UnmarshalString(scanner.Text(), e))
+		read = append(read, e)
+	}
+	assert.Nil(t, scanner.Err())
+	assert.Equal(t, events, read)
+}
+
+func TestFilePublisher_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	// missing dir
+	p, err := NewFilePublisher(filepath.Join("missing-dir", "events.jsonl"))
+	assert.NotNil(t, err)
+	assert.Nil(t, p)
+
+	dir, err := ioutil.TempDir("", "events-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	p, err = NewFilePublisher(filepath.Join(dir, "events.jsonl"))
+	assert.Nil(t, err)
+	assert.Nil(t, p.Close())
+	err = p.Publish(context.Background(), newTestEvents(rng, 1))
+	assert.Equal(t, ErrPublisherClosed, err)
+}
+
+func newTestEvents(rng *rand.Rand, n int) []*api.PublicKeyEvent {
+	pkds := api.NewTestPublicKeyDetails(rng, n)
+	events := make([]*api.PublicKeyEvent, n)
+	for i, pkd := range pkds {
+		events[i] = &api.PublicKeyEvent{
+			Sequence:        uint64(i + 1),
+			Type:            api.PublicKeyEventType(i % 2),
+			PublicKeyDetail: pkd,
+			Time:            int64(1e15 + i),
+		}
+	}
+	return events
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExportPublicKeys streams every public key record, with the times each public key was added and
+// disabled, in pages ordered by public key. Unlike ListPublicKeys, it includes the times needed to
+// restore the public keys into another storer with their history intact.
+func (k *Key) ExportPublicKeys(
+	rq *api.ExportPublicKeysRequest, stream api.Key_ExportPublicKeysServer,
+) error {
+	ctx := stream.Context()
+	k.Logger.Debug("received export public keys request", logExportPublicKeysRq(rq)...)
+	if err := api.ValidateExportPublicKeysRequest(rq); err != nil {
+		k.Logger.Info("export public keys request invalid", zap.String(logErr, err.Error()))
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	pageSize := uint(rq.PageSize)
+	if pageSize == 0 {
+		pageSize = api.DefaultListPageSize
+	}
+	var after []byte
+	nRecords := 0
+	for {
+		records, err := k.storer.ListPublicKeyRecords(ctx, after, pageSize)
+		if err != nil {
+			k.Logger.Error("storer list public key records error", zap.Error(err))
+			return ErrInternal
+		}
+		if len(records) > 0 {
+			rp := &api.ExportPublicKeysResponse{PublicKeyRecords: records}
+			if err := stream.Send(rp); err != nil {
+				return err
+			}
+			nRecords += len(records)
+		}
+		if uint(len(records)) < pageSize {
+			break
+		}
+		after = records[len(records)-1].PublicKeyDetail.PublicKey
+	}
+	k.Logger.Info("exported public keys", zap.Int(logNPublicKeys, nRecords))
+	return nil
+}
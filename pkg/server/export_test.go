@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/memory"
+	bserver "github.com/elixirhealth/service-base/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKey_ExportPublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	config := NewDefaultConfig()
+	st := memory.New(config.Storage, zap.NewNop())
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     config,
+		storer:     st,
+	}
+	pkds := api.NewTestPublicKeyDetails(rng, 25)
+	err := st.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	err = st.DisablePublicKeys(context.Background(), pkds[0].EntityId,
+		[][]byte{pkds[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	stream := &fixedExportStream{}
+	err = k.ExportPublicKeys(&api.ExportPublicKeysRequest{PageSize: 10}, stream)
+	assert.Nil(t, err)
+	assert.Len(t, stream.rps, 3)
+	records := make([]*api.PublicKeyRecord, 0, len(pkds))
+	for _, rp := range stream.rps {
+		assert.True(t, len(rp.PublicKeyRecords) <= 10)
+		records = append(records, rp.PublicKeyRecords...)
+	}
+	assert.Len(t, records, len(pkds))
+	assert.True(t, sort.SliceIsSorted(records, func(i, j int) bool {
+		pkI, pkJ := records[i].PublicKeyDetail.PublicKey, records[j].PublicKeyDetail.PublicKey
+		return bytes.Compare(pkI, pkJ) < 0
+	}))
+	for _, r := range records {
+		assert.NotZero(t, r.AddedTime)
+		if bytes.Equal(pkds[0].PublicKey, r.PublicKeyDetail.PublicKey) {
+			assert.True(t, r.PublicKeyDetail.Disabled)
+			assert.NotZero(t, r.DisabledTime)
+		} else {
+			assert.Zero(t, r.DisabledTime)
+		}
+	}
+
+	// a full last page is followed by an empty one, which isn't sent
+	stream = &fixedExportStream{}
+	err = k.ExportPublicKeys(&api.ExportPublicKeysRequest{PageSize: 5}, stream)
+	assert.Nil(t, err)
+	assert.Len(t, stream.rps, 5)
+}
+
+func TestKey_ExportPublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := []*api.PublicKeyRecord{
+		storage.NewPublicKeyRecord(api.NewTestPublicKeyDetail(rng), time.Now(), time.Time{}),
+	}
+	cases := map[string]struct {
+		rq       *api.ExportPublicKeysRequest
+		storer   storage.Storer
+		stream   *fixedExportStream
+		expected error
+	}{
+		"bad request": {
+			rq:     &api.ExportPublicKeysRequest{PageSize: api.MaxListPageSize + 1},
+			storer: &fixedStorer{},
+			stream: &fixedExportStream{},
+			expected: status.Error(codes.InvalidArgument,
+				api.ErrPageSizeTooLarge.Error()),
+		},
+		"storer list error": {
+			rq:       &api.ExportPublicKeysRequest{},
+			storer:   &fixedStorer{listRecordsErr: errTest},
+			stream:   &fixedExportStream{},
+			expected: ErrInternal,
+		},
+		"send error": {
+			rq:       &api.ExportPublicKeysRequest{},
+			storer:   &fixedStorer{listRecords: records},
+			stream:   &fixedExportStream{sendErr: errTest},
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		k := &Key{
+			BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+			config:     NewDefaultConfig(),
+			storer:     c.storer,
+		}
+		err := k.ExportPublicKeys(c.rq, c.stream)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Empty(t, c.stream.rps, desc)
+	}
+}
+
+type fixedExportStream struct {
+	grpc.ServerStream
+	sendErr error
+	rps     []*api.ExportPublicKeysResponse
+}
+
+func (f *fixedExportStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fixedExportStream) Send(rp *api.ExportPublicKeysResponse) error {
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+	f.rps = append(f.rps, rp)
+	return nil
+}
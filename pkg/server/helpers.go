@@ -1,15 +1,22 @@
 package server
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/bolt"
+	"github.com/elixirhealth/key/pkg/server/storage/cache"
 	"github.com/elixirhealth/key/pkg/server/storage/datastore"
 	"github.com/elixirhealth/key/pkg/server/storage/memory"
 	"github.com/elixirhealth/key/pkg/server/storage/postgres"
-	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
+	errors2 "github.com/pkg/errors"
 	"go.uber.org/zap"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
@@ -17,19 +24,98 @@ var (
 	ErrInvalidStorageType = errors.New("invalid storage type")
 )
 
-func getStorer(config *Config, logger *zap.Logger) (storage.Storer, error) {
+// NewStorer returns a new Storer of the config's storage type, caching its lookups if the config
+// has a storage cache. Shared storage isn't cached, since the cache is only invalidated by writes
+// made through this instance.
+func NewStorer(config *Config, logger *zap.Logger) (storage.Storer, error) {
+	storer, err := newBackendStorer(config, logger)
+	if err != nil || config.StorageCache == nil {
+		return storer, err
+	}
+	if config.Storage.Type.Shared() {
+		logger.Warn("not caching shared storage lookups",
+			zap.Stringer(logStorageType, config.Storage.Type))
+		return storer, nil
+	}
+	return cache.New(storer, config.Storage, config.StorageCache, logger), nil
+}
+
+func newBackendStorer(config *Config, logger *zap.Logger) (storage.Storer, error) {
 	switch config.Storage.Type {
-	case bstorage.Memory:
+	case storage.Memory:
+		if config.MemorySnapshotPath != "" {
+			return memory.NewWithSnapshots(config.Storage, logger, config.MemorySnapshotPath,
+				config.MemorySnapshotInterval)
+		}
 		return memory.New(config.Storage, logger), nil
-	case bstorage.Postgres:
+	case storage.Postgres:
 		return postgres.New(config.DBUrl, config.Storage, logger)
-	case bstorage.DataStore:
-		return datastore.New(config.GCPProjectID, config.Storage, logger)
+	case storage.DataStore:
+		var opts []option.ClientOption
+		if config.DatastoreEmulatorHost != "" {
+			opts = datastore.EmulatorOptions(config.DatastoreEmulatorHost)
+		}
+		return datastore.New(config.GCPProjectID, config.Storage, logger, opts...)
+	case storage.Bolt:
+		return bolt.New(config.BoltDBPath, config.Storage, logger)
 	default:
 		return nil, ErrInvalidStorageType
 	}
 }
 
+// publish sends events of the given type for the public key details to WatchPublicKeys streams.
+func (k *Key) publish(typ api.PublicKeyEventType, pkds []*api.PublicKeyDetail) {
+	if k.watcher != nil {
+		k.watcher.Publish(typ, pkds)
+	}
+}
+
+// getRevoked returns disabled copies of the stored public key details of the public keys to revoke
+// or rotate out, so the revocation can be audited and published with them. Public keys that are
+// already disabled are left out, since disabling them doesn't change them.
+func (k *Key) getRevoked(ctx context.Context, pks [][]byte) ([]*api.PublicKeyDetail, error) {
+	found, err := k.storer.GetPublicKeys(ctx, pks)
+	if err != nil {
+		k.Logger.Error("storer get revoked public keys error", zap.Error(err))
+		return nil, ErrInternal
+	}
+	pkds := make([]*api.PublicKeyDetail, 0, len(found))
+	for _, pkd := range found {
+		if pkd != nil && !pkd.Disabled {
+			disabled := *pkd
+			disabled.Disabled = true
+			pkds = append(pkds, &disabled)
+		}
+	}
+	return pkds, nil
+}
+
+// getNewPublicKeyDetails returns the public key details of the rotation's new public keys.
+func getNewPublicKeyDetails(rq *api.RotatePublicKeysRequest) []*api.PublicKeyDetail {
+	newPKDs := make([]*api.PublicKeyDetail, len(rq.NewPublicKeys))
+	for i, pk := range rq.NewPublicKeys {
+		newPKDs[i] = &api.PublicKeyDetail{
+			PublicKey: pk,
+			EntityId:  rq.EntityId,
+			KeyType:   rq.KeyType,
+		}
+	}
+	return newPKDs
+}
+
+func (k *Key) requireProofs() bool {
+	return k.config != nil && k.config.RequireProofOfPossession
+}
+
+// proofError returns the gRPC error for a proof-of-possession verification error. Signatures that
+// don't verify are denied, while missing signatures or unsupported key formats are invalid.
+func proofError(err error) error {
+	if errors2.Cause(err) == api.ErrInvalidProofOfPossession {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
 func getPublicKeyDetails(rq *api.AddPublicKeysRequest) []*api.PublicKeyDetail {
 	pkds := make([]*api.PublicKeyDetail, len(rq.PublicKeys))
 	for i, pk := range rq.PublicKeys {
@@ -41,3 +127,26 @@ func getPublicKeyDetails(rq *api.AddPublicKeysRequest) []*api.PublicKeyDetail {
 	}
 	return pkds
 }
+
+func getListFilter(rq *api.ListPublicKeysRequest) *storage.ListFilter {
+	filter := &storage.ListFilter{
+		EntityID: rq.EntityId,
+		KeyTypes: rq.KeyTypes,
+		Status:   rq.Status,
+	}
+	if rq.AddedAfter != 0 {
+		filter.AddedAfter = fromEpochMicros(rq.AddedAfter)
+	}
+	if rq.ModifiedAfter != 0 {
+		filter.ModifiedAfter = fromEpochMicros(rq.ModifiedAfter)
+	}
+	if rq.ModifiedBefore != 0 {
+		filter.ModifiedBefore = fromEpochMicros(rq.ModifiedBefore)
+	}
+	return filter
+}
+
+// fromEpochMicros returns the time for the given number of microseconds since the epoch.
+func fromEpochMicros(micros int64) time.Time {
+	return time.Unix(0, micros*int64(time.Microsecond))
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ImportPublicKeys adds the public key details in each request of the stream, possibly for many
+// entities, in chunks of at most the storage max batch size. Invalid public key details and those
+// that would exceed the maximum number of active public keys for their entity and key type are
+// rejected individually rather than failing the import, and public keys that already exist are
+// counted as duplicates. It responds with a summary once the client closes the stream.
+func (k *Key) ImportPublicKeys(stream api.Key_ImportPublicKeysServer) error {
+	im := newImporter(k.storer, k.config.Storage.MaxBatchSize, k.requireProofs(), k.Logger)
+	im.onAdded = func(pkds []*api.PublicKeyDetail) {
+		k.publish(api.PublicKeyEventType_ADDED, pkds)
+	}
+	for {
+		rq, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		k.Logger.Debug("received import public keys request", logImportPublicKeysRq(rq)...)
+		if err := api.ValidateImportPublicKeysRequest(rq); err != nil {
+			k.Logger.Info("import public keys request invalid", zap.String(logErr, err.Error()))
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		// the stream's context has the caller's identity once a request has been intercepted
+		if err := im.add(stream.Context(), rq); err != nil {
+			k.Logger.Error("storer import public keys error", zap.Error(err))
+			return ErrInternal
+		}
+	}
+	if err := im.flush(stream.Context(), im.pending); err != nil {
+		k.Logger.Error("storer import public keys error", zap.Error(err))
+		return ErrInternal
+	}
+	k.Logger.Info("imported public keys", logImportPublicKeysRp(im.rp)...)
+	return stream.SendAndClose(im.rp)
+}
+
+// importer accumulates the valid public key details of an import and adds them to the storer in
+// chunks, keeping count of how each public key was handled.
+type importer struct {
+	storer        storage.Storer
+	maxBatchSize  int
+	requireProofs bool
+	logger        *zap.Logger
+
+	// onAdded is called with the public key details added to the storer, if it is set.
+	onAdded func(pkds []*api.PublicKeyDetail)
+
+	seen    map[string]struct{}
+	pending []*api.PublicKeyDetail
+	rp      *api.ImportPublicKeysResponse
+}
+
+func newImporter(
+	storer storage.Storer, maxBatchSize uint, requireProofs bool, logger *zap.Logger,
+) *importer {
+	if maxBatchSize == 0 {
+		maxBatchSize = storage.DefaultMaxBatchSize
+	}
+	return &importer{
+		storer:        storer,
+		maxBatchSize:  int(maxBatchSize),
+		requireProofs: requireProofs,
+		logger:        logger,
+		seen:          make(map[string]struct{}),
+		rp:            &api.ImportPublicKeysResponse{},
+	}
+}
+
+// add validates each of the request's public key details and adds the valid ones to the storer
+// once there are enough pending to fill a chunk.
+func (im *importer) add(ctx context.Context, rq *api.ImportPublicKeysRequest) error {
+	for i, pkd := range rq.PublicKeyDetails {
+		if err := im.validate(rq, i); err != nil {
+			im.reject(pkd.GetPublicKey(), err)
+			continue
+		}
+		pkHex := hex.EncodeToString(pkd.PublicKey)
+		if _, in := im.seen[pkHex]; in {
+			im.rp.NDuplicate++
+			continue
+		}
+		im.seen[pkHex] = struct{}{}
+		im.pending = append(im.pending, &api.PublicKeyDetail{
+			PublicKey: pkd.PublicKey,
+			EntityId:  pkd.EntityId,
+			KeyType:   pkd.KeyType,
+		})
+		if len(im.pending) >= im.maxBatchSize {
+			chunk := im.pending[:im.maxBatchSize]
+			im.pending = im.pending[im.maxBatchSize:]
+			if err := im.flush(ctx, chunk); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks that the request's ith public key detail is valid for its key format and, if
+// proofs are required, that it has a valid proof of possession.
+func (im *importer) validate(rq *api.ImportPublicKeysRequest, i int) error {
+	pkd := rq.PublicKeyDetails[i]
+	if err := api.ValidatePublicKeyDetail(pkd); err != nil {
+		return err
+	}
+	pks := [][]byte{pkd.PublicKey}
+	if err := api.ValidatePublicKeyFormat(rq.KeyFormat, pks); err != nil {
+		return err
+	}
+	if !im.requireProofs {
+		return nil
+	}
+	var sigs [][]byte
+	if len(rq.Signatures) != 0 {
+		sigs = rq.Signatures[i : i+1]
+	}
+	return api.VerifyProofsOfPossession(rq.KeyFormat, pkd.EntityId, pkd.KeyType, pks, sigs)
+}
+
+// flush adds the chunk's public key details that don't already exist to the storer. If they
+// can't all be added together, it adds them one at a time so only those that can't be added are
+// rejected.
+func (im *importer) flush(ctx context.Context, chunk []*api.PublicKeyDetail) error {
+	if len(chunk) == 0 {
+		return nil
+	}
+	pks := make([][]byte, len(chunk))
+	for i, pkd := range chunk {
+		pks[i] = pkd.PublicKey
+	}
+	existing, err := im.storer.GetPublicKeys(ctx, pks)
+	if err != nil {
+		return err
+	}
+	newPKDs := make([]*api.PublicKeyDetail, 0, len(chunk))
+	for i, pkd := range chunk {
+		if existing[i] != nil {
+			im.rp.NDuplicate++
+			continue
+		}
+		newPKDs = append(newPKDs, pkd)
+	}
+	if len(newPKDs) == 0 {
+		return nil
+	}
+	audit := newAuditRecords(ctx, api.AuditAction_IMPORT, newPKDs, nil)
+	switch err := im.storer.AddPublicKeys(ctx, newPKDs, audit); err {
+	case nil:
+		im.rp.NInserted += uint32(len(newPKDs))
+		im.added(newPKDs)
+		return nil
+	case storage.ErrTooManyActivePublicKeys, storage.ErrPublicKeyExists:
+		return im.addEach(ctx, newPKDs)
+	default:
+		return err
+	}
+}
+
+func (im *importer) addEach(ctx context.Context, pkds []*api.PublicKeyDetail) error {
+	for _, pkd := range pkds {
+		pkds := []*api.PublicKeyDetail{pkd}
+		audit := newAuditRecords(ctx, api.AuditAction_IMPORT, pkds, nil)
+		switch err := im.storer.AddPublicKeys(ctx, pkds, audit); err {
+		case nil:
+			im.rp.NInserted++
+			im.added(pkds)
+		case storage.ErrTooManyActivePublicKeys:
+			im.reject(pkd.PublicKey, err)
+		case storage.ErrPublicKeyExists:
+			im.rp.NDuplicate++
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *importer) added(pkds []*api.PublicKeyDetail) {
+	if im.onAdded != nil {
+		im.onAdded(pkds)
+	}
+}
+
+func (im *importer) reject(pk []byte, err error) {
+	im.logger.Debug("rejected imported public key", logRejectedPublicKey(pk, err)...)
+	im.rp.NRejected++
+	im.rp.RejectedPublicKeys = append(im.rp.RejectedPublicKeys, pk)
+}
@@ -0,0 +1,292 @@
+package server
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/memory"
+	"github.com/elixirhealth/key/pkg/server/watch"
+	bserver "github.com/elixirhealth/service-base/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKey_ImportPublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	config := NewDefaultConfig()
+	config.Storage.MaxBatchSize = 4
+	st := memory.New(config.Storage, zap.NewNop())
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     config,
+		storer:     st,
+		watcher:    watch.New(config.Watch, zap.NewNop()),
+	}
+	existing := api.NewTestPublicKeyDetails(rng, 2)
+	err := st.AddPublicKeys(context.Background(), existing, nil)
+	assert.Nil(t, err)
+	_, sub, err := k.watcher.Subscribe(nil, 0)
+	assert.Nil(t, err)
+
+	pkds1 := api.NewTestPublicKeyDetails(rng, 6)
+	pkds2 := api.NewTestPublicKeyDetails(rng, 3)
+	invalid := &api.PublicKeyDetail{PublicKey: []byte{1, 2, 3}, EntityId: "some entity ID"}
+	stream := &fixedImportStream{
+		rqs: []*api.ImportPublicKeysRequest{
+			{
+				PublicKeyDetails: append(pkds1, existing[0], invalid),
+				KeyFormat:        api.KeyFormat_SECP256K1_COMPRESSED,
+			},
+			{
+				PublicKeyDetails: append(pkds2, pkds1[0], existing[1]),
+				KeyFormat:        api.KeyFormat_SECP256K1_COMPRESSED,
+			},
+		},
+	}
+	err = k.ImportPublicKeys(stream)
+	assert.Nil(t, err)
+	assert.Equal(t, &api.ImportPublicKeysResponse{
+		NInserted:          9,
+		NDuplicate:         3,
+		NRejected:          1,
+		RejectedPublicKeys: [][]byte{invalid.PublicKey},
+	}, stream.rp)
+
+	pks := make([][]byte, 0, len(pkds1)+len(pkds2))
+	for _, pkd := range append(pkds1, pkds2...) {
+		pks = append(pks, pkd.PublicKey)
+	}
+	stored, err := st.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.Equal(t, append(pkds1, pkds2...), stored)
+
+	// only the inserted public keys are published
+	for range stored {
+		e := <-sub.Events()
+		assert.Equal(t, api.PublicKeyEventType_ADDED, e.Type)
+	}
+	assert.Len(t, sub.Events(), 0)
+
+	// and audited, with a record per entity and key type
+	records, err := st.ListAuditRecords(context.Background(), 0, 64)
+	assert.Nil(t, err)
+	nAudited := 0
+	for _, r := range records {
+		assert.Equal(t, api.AuditAction_IMPORT, r.Action)
+		nAudited += len(r.AddedPublicKeyHashes)
+	}
+	assert.Equal(t, len(stored), nAudited)
+}
+
+func TestKey_ImportPublicKeys_authenticated(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	config := NewDefaultConfig()
+	config.Storage.MaxBatchSize = 4
+	st := memory.New(config.Storage, zap.NewNop())
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     config,
+		storer:     st,
+	}
+	interceptor := func(
+		ctx context.Context,
+		rq interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(auth.NewContext(ctx, &auth.Identity{EntityID: "some caller"}), rq)
+	}
+	stream := &fixedImportStream{
+		rqs: []*api.ImportPublicKeysRequest{
+			{
+				PublicKeyDetails: api.NewTestPublicKeyDetails(rng, 6),
+				KeyFormat:        api.KeyFormat_SECP256K1_COMPRESSED,
+			},
+		},
+	}
+	err := newInterceptedKey(k, interceptor).ImportPublicKeys(stream)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(6), stream.rp.NInserted)
+
+	// both the full chunk and the rest flushed at the end are attributed to the caller
+	records, err := st.ListAuditRecords(context.Background(), 0, 64)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, records)
+	nAudited := 0
+	for _, r := range records {
+		assert.Equal(t, "some caller", r.CallerEntityId)
+		nAudited += len(r.AddedPublicKeyHashes)
+	}
+	assert.Equal(t, 6, nAudited)
+}
+
+func TestKey_ImportPublicKeys_tooManyActive(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	config := NewDefaultConfig()
+	st := memory.New(config.Storage, zap.NewNop())
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     config,
+		storer:     st,
+	}
+	entityID, kt := "some entity ID", api.KeyType_READER
+	pkds := make([]*api.PublicKeyDetail, storage.MaxEntityKeyTypeKeys+1)
+	for i := range pkds {
+		pkds[i] = &api.PublicKeyDetail{
+			PublicKey: api.NewTestPublicKey(rng),
+			EntityId:  entityID,
+			KeyType:   kt,
+		}
+	}
+	err := st.AddPublicKeys(context.Background(), pkds[:storage.MaxEntityKeyTypeKeys-1], nil)
+	assert.Nil(t, err)
+
+	// only the first of the last two keys fits within the limit
+	stream := &fixedImportStream{
+		rqs: []*api.ImportPublicKeysRequest{
+			{
+				PublicKeyDetails: pkds[storage.MaxEntityKeyTypeKeys-1:],
+				KeyFormat:        api.KeyFormat_SECP256K1_COMPRESSED,
+			},
+		},
+	}
+	err = k.ImportPublicKeys(stream)
+	assert.Nil(t, err)
+	assert.Equal(t, &api.ImportPublicKeysResponse{
+		NInserted:          1,
+		NRejected:          1,
+		RejectedPublicKeys: [][]byte{pkds[storage.MaxEntityKeyTypeKeys].PublicKey},
+	}, stream.rp)
+}
+
+func TestKey_ImportPublicKeys_proofs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	config := NewDefaultConfig().WithRequireProofOfPossession(true)
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     config,
+		storer:     memory.New(config.Storage, zap.NewNop()),
+	}
+	entityID, kt := "some entity ID", api.KeyType_READER
+	pks, sigs := api.NewTestProvenPublicKeys(rng, entityID, kt, 3)
+	sigs[1] = sigs[0]
+	pkds := make([]*api.PublicKeyDetail, len(pks))
+	for i, pk := range pks {
+		pkds[i] = &api.PublicKeyDetail{PublicKey: pk, EntityId: entityID, KeyType: kt}
+	}
+	unproven := api.NewTestPublicKeyDetail(rng)
+
+	stream := &fixedImportStream{
+		rqs: []*api.ImportPublicKeysRequest{
+			{
+				PublicKeyDetails: pkds,
+				KeyFormat:        api.KeyFormat_SECP256K1_COMPRESSED,
+				Signatures:       sigs,
+			},
+			{
+				PublicKeyDetails: []*api.PublicKeyDetail{unproven},
+				KeyFormat:        api.KeyFormat_SECP256K1_COMPRESSED,
+			},
+		},
+	}
+	err := k.ImportPublicKeys(stream)
+	assert.Nil(t, err)
+	assert.Equal(t, &api.ImportPublicKeysResponse{
+		NInserted:          2,
+		NRejected:          2,
+		RejectedPublicKeys: [][]byte{pks[1], unproven.PublicKey},
+	}, stream.rp)
+}
+
+func TestKey_ImportPublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 2)
+	okRqs := []*api.ImportPublicKeysRequest{
+		{PublicKeyDetails: pkds, KeyFormat: api.KeyFormat_SECP256K1_COMPRESSED},
+	}
+	cases := map[string]struct {
+		storer   storage.Storer
+		stream   *fixedImportStream
+		expected error
+	}{
+		"recv error": {
+			storer:   &fixedStorer{},
+			stream:   &fixedImportStream{recvErr: errTest},
+			expected: errTest,
+		},
+		"bad request": {
+			storer: &fixedStorer{},
+			stream: &fixedImportStream{
+				rqs: []*api.ImportPublicKeysRequest{{}},
+			},
+			expected: status.Error(codes.InvalidArgument, api.ErrEmptyPublicKeys.Error()),
+		},
+		"storer get error": {
+			storer:   &fixedStorer{getErr: errTest},
+			stream:   &fixedImportStream{rqs: okRqs},
+			expected: ErrInternal,
+		},
+		"storer add error": {
+			storer: &fixedStorer{
+				getPKDs: make([]*api.PublicKeyDetail, len(pkds)),
+				addErr:  errTest,
+			},
+			stream:   &fixedImportStream{rqs: okRqs},
+			expected: ErrInternal,
+		},
+		"storer append audit records error": {
+			storer: &fixedStorer{
+				getPKDs:        make([]*api.PublicKeyDetail, len(pkds)),
+				appendAuditErr: errTest,
+			},
+			stream:   &fixedImportStream{rqs: okRqs},
+			expected: ErrInternal,
+		},
+	}
+	for desc, c := range cases {
+		k := &Key{
+			BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+			config:     NewDefaultConfig(),
+			storer:     c.storer,
+		}
+		err := k.ImportPublicKeys(c.stream)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, c.stream.rp, desc)
+	}
+}
+
+type fixedImportStream struct {
+	grpc.ServerStream
+	rqs     []*api.ImportPublicKeysRequest
+	recvErr error
+	rp      *api.ImportPublicKeysResponse
+}
+
+func (f *fixedImportStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fixedImportStream) Recv() (*api.ImportPublicKeysRequest, error) {
+	if f.recvErr != nil {
+		return nil, f.recvErr
+	}
+	if len(f.rqs) == 0 {
+		return nil, io.EOF
+	}
+	rq := f.rqs[0]
+	f.rqs = f.rqs[1:]
+	return rq, nil
+}
+
+func (f *fixedImportStream) SendAndClose(rp *api.ImportPublicKeysResponse) error {
+	f.rp = rp
+	return nil
+}
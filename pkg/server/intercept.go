@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"google.golang.org/grpc"
+)
+
+const keyServiceName = "/keyapi.Key/"
+
+// interceptedKey is a KeyServer that passes each request through a unary interceptor before the
+// wrapped KeyServer handles it. The base server creates the gRPC server itself, so interceptors
+// can't be given to it as server options.
+type interceptedKey struct {
+	api.KeyServer
+	interceptor grpc.UnaryServerInterceptor
+}
+
+func newInterceptedKey(
+	ks api.KeyServer, interceptor grpc.UnaryServerInterceptor,
+) api.KeyServer {
+	return &interceptedKey{KeyServer: ks, interceptor: interceptor}
+}
+
+func (k *interceptedKey) intercept(
+	ctx context.Context, method string, rq interface{}, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	info := &grpc.UnaryServerInfo{
+		Server:     k.KeyServer,
+		FullMethod: keyServiceName + method,
+	}
+	return k.interceptor(ctx, rq, info, handler)
+}
+
+func (k *interceptedKey) AddPublicKeys(
+	ctx context.Context, rq *api.AddPublicKeysRequest,
+) (*api.AddPublicKeysResponse, error) {
+	rp, err := k.intercept(ctx, "AddPublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.AddPublicKeys(ctx, rq.(*api.AddPublicKeysRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.AddPublicKeysResponse), nil
+}
+
+func (k *interceptedKey) GetPublicKeys(
+	ctx context.Context, rq *api.GetPublicKeysRequest,
+) (*api.GetPublicKeysResponse, error) {
+	rp, err := k.intercept(ctx, "GetPublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.GetPublicKeys(ctx, rq.(*api.GetPublicKeysRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.GetPublicKeysResponse), nil
+}
+
+func (k *interceptedKey) SamplePublicKeys(
+	ctx context.Context, rq *api.SamplePublicKeysRequest,
+) (*api.SamplePublicKeysResponse, error) {
+	rp, err := k.intercept(ctx, "SamplePublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.SamplePublicKeys(ctx, rq.(*api.SamplePublicKeysRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.SamplePublicKeysResponse), nil
+}
+
+func (k *interceptedKey) GetPublicKeyDetails(
+	ctx context.Context, rq *api.GetPublicKeyDetailsRequest,
+) (*api.GetPublicKeyDetailsResponse, error) {
+	rp, err := k.intercept(ctx, "GetPublicKeyDetails", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.GetPublicKeyDetails(ctx, rq.(*api.GetPublicKeyDetailsRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.GetPublicKeyDetailsResponse), nil
+}
+
+func (k *interceptedKey) RevokePublicKeys(
+	ctx context.Context, rq *api.RevokePublicKeysRequest,
+) (*api.RevokePublicKeysResponse, error) {
+	rp, err := k.intercept(ctx, "RevokePublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.RevokePublicKeys(ctx, rq.(*api.RevokePublicKeysRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.RevokePublicKeysResponse), nil
+}
+
+func (k *interceptedKey) RotatePublicKeys(
+	ctx context.Context, rq *api.RotatePublicKeysRequest,
+) (*api.RotatePublicKeysResponse, error) {
+	rp, err := k.intercept(ctx, "RotatePublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.RotatePublicKeys(ctx, rq.(*api.RotatePublicKeysRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.RotatePublicKeysResponse), nil
+}
+
+func (k *interceptedKey) ListPublicKeys(
+	ctx context.Context, rq *api.ListPublicKeysRequest,
+) (*api.ListPublicKeysResponse, error) {
+	rp, err := k.intercept(ctx, "ListPublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.ListPublicKeys(ctx, rq.(*api.ListPublicKeysRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.ListPublicKeysResponse), nil
+}
+
+// ImportPublicKeys passes each request received on the stream through the interceptor, so the
+// caller must be authorized for every request of the import.
+func (k *interceptedKey) ImportPublicKeys(stream api.Key_ImportPublicKeysServer) error {
+	return k.KeyServer.ImportPublicKeys(&interceptedImportStream{
+		Key_ImportPublicKeysServer: stream,
+		k:                          k,
+	})
+}
+
+type interceptedImportStream struct {
+	api.Key_ImportPublicKeysServer
+	k *interceptedKey
+
+	// ctx is the context the interceptor passed on with the last request, which has the
+	// caller's identity when requests are authenticated
+	ctx context.Context
+}
+
+func (s *interceptedImportStream) Context() context.Context {
+	if s.ctx != nil {
+		return s.ctx
+	}
+	return s.Key_ImportPublicKeysServer.Context()
+}
+
+func (s *interceptedImportStream) Recv() (*api.ImportPublicKeysRequest, error) {
+	rq, err := s.Key_ImportPublicKeysServer.Recv()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.k.intercept(s.Key_ImportPublicKeysServer.Context(), "ImportPublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			s.ctx = ctx
+			return rq, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rq, nil
+}
+
+// ExportPublicKeys passes the request through the interceptor before streaming the export.
+func (k *interceptedKey) ExportPublicKeys(
+	rq *api.ExportPublicKeysRequest, stream api.Key_ExportPublicKeysServer,
+) error {
+	_, err := k.intercept(stream.Context(), "ExportPublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return nil, k.KeyServer.ExportPublicKeys(rq.(*api.ExportPublicKeysRequest), stream)
+		})
+	return err
+}
+
+// WatchPublicKeys passes the request through the interceptor before streaming the events.
+func (k *interceptedKey) WatchPublicKeys(
+	rq *api.WatchPublicKeysRequest, stream api.Key_WatchPublicKeysServer,
+) error {
+	_, err := k.intercept(stream.Context(), "WatchPublicKeys", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return nil, k.KeyServer.WatchPublicKeys(rq.(*api.WatchPublicKeysRequest), stream)
+		})
+	return err
+}
+
+func (k *interceptedKey) VerifyAuditLog(
+	ctx context.Context, rq *api.VerifyAuditLogRequest,
+) (*api.VerifyAuditLogResponse, error) {
+	rp, err := k.intercept(ctx, "VerifyAuditLog", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.VerifyAuditLog(ctx, rq.(*api.VerifyAuditLogRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.VerifyAuditLogResponse), nil
+}
+
+func (k *interceptedKey) GetSigningKey(
+	ctx context.Context, rq *api.GetSigningKeyRequest,
+) (*api.GetSigningKeyResponse, error) {
+	rp, err := k.intercept(ctx, "GetSigningKey", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.GetSigningKey(ctx, rq.(*api.GetSigningKeyRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.GetSigningKeyResponse), nil
+}
+
+func (k *interceptedKey) GetTreeHead(
+	ctx context.Context, rq *api.GetTreeHeadRequest,
+) (*api.GetTreeHeadResponse, error) {
+	rp, err := k.intercept(ctx, "GetTreeHead", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.GetTreeHead(ctx, rq.(*api.GetTreeHeadRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.GetTreeHeadResponse), nil
+}
+
+func (k *interceptedKey) GetConsistencyProof(
+	ctx context.Context, rq *api.GetConsistencyProofRequest,
+) (*api.GetConsistencyProofResponse, error) {
+	rp, err := k.intercept(ctx, "GetConsistencyProof", rq,
+		func(ctx context.Context, rq interface{}) (interface{}, error) {
+			return k.KeyServer.GetConsistencyProof(ctx, rq.(*api.GetConsistencyProofRequest))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return rp.(*api.GetConsistencyProofResponse), nil
+}
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+func TestInterceptedKey_ok(t *testing.T) {
+	ks := &fixedKeyServer{}
+	var methods []string
+	interceptor := func(
+		ctx context.Context,
+		rq interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		assert.Equal(t, ks, info.Server)
+		methods = append(methods, info.FullMethod)
+		return handler(ctx, rq)
+	}
+	k := newInterceptedKey(ks, interceptor)
+	ctx := context.Background()
+
+	rp1, err := k.AddPublicKeys(ctx, &api.AddPublicKeysRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp1)
+	rp2, err := k.GetPublicKeys(ctx, &api.GetPublicKeysRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp2)
+	rp3, err := k.SamplePublicKeys(ctx, &api.SamplePublicKeysRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp3)
+	rp4, err := k.GetPublicKeyDetails(ctx, &api.GetPublicKeyDetailsRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp4)
+	rp5, err := k.RevokePublicKeys(ctx, &api.RevokePublicKeysRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp5)
+	rp6, err := k.RotatePublicKeys(ctx, &api.RotatePublicKeysRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp6)
+	rp7, err := k.ListPublicKeys(ctx, &api.ListPublicKeysRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp7)
+	rp8, err := k.VerifyAuditLog(ctx, &api.VerifyAuditLogRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp8)
+	rp9, err := k.GetSigningKey(ctx, &api.GetSigningKeyRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp9)
+	rp10, err := k.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp10)
+	rp11, err := k.GetConsistencyProof(ctx, &api.GetConsistencyProofRequest{})
+	assert.Nil(t, err)
+	assert.NotNil(t, rp11)
+
+	assert.Equal(t, []string{
+		"/keyapi.Key/AddPublicKeys",
+		"/keyapi.Key/GetPublicKeys",
+		"/keyapi.Key/SamplePublicKeys",
+		"/keyapi.Key/GetPublicKeyDetails",
+		"/keyapi.Key/RevokePublicKeys",
+		"/keyapi.Key/RotatePublicKeys",
+		"/keyapi.Key/ListPublicKeys",
+		"/keyapi.Key/VerifyAuditLog",
+		"/keyapi.Key/GetSigningKey",
+		"/keyapi.Key/GetTreeHead",
+		"/keyapi.Key/GetConsistencyProof",
+	}, methods)
+	assert.Equal(t, 11, ks.nCalls)
+}
+
+func TestInterceptedKey_err(t *testing.T) {
+	ks := &fixedKeyServer{}
+	errTest := errors.New("some interceptor error")
+	interceptor := func(
+		ctx context.Context,
+		rq interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return nil, errTest
+	}
+	k := newInterceptedKey(ks, interceptor)
+	ctx := context.Background()
+
+	rp1, err := k.AddPublicKeys(ctx, &api.AddPublicKeysRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp1)
+	rp2, err := k.GetPublicKeys(ctx, &api.GetPublicKeysRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp2)
+	rp3, err := k.SamplePublicKeys(ctx, &api.SamplePublicKeysRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp3)
+	rp4, err := k.GetPublicKeyDetails(ctx, &api.GetPublicKeyDetailsRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp4)
+	rp5, err := k.RevokePublicKeys(ctx, &api.RevokePublicKeysRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp5)
+	rp6, err := k.RotatePublicKeys(ctx, &api.RotatePublicKeysRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp6)
+	rp7, err := k.ListPublicKeys(ctx, &api.ListPublicKeysRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp7)
+	rp8, err := k.VerifyAuditLog(ctx, &api.VerifyAuditLogRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp8)
+	rp9, err := k.GetSigningKey(ctx, &api.GetSigningKeyRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp9)
+	rp10, err := k.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp10)
+	rp11, err := k.GetConsistencyProof(ctx, &api.GetConsistencyProofRequest{})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, rp11)
+
+	assert.Zero(t, ks.nCalls)
+}
+
+func TestInterceptedKey_ImportPublicKeys(t *testing.T) {
+	ks := &fixedKeyServer{}
+	errTest := errors.New("some interceptor error")
+	var rqs []interface{}
+	interceptor := func(
+		ctx context.Context,
+		rq interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		assert.Equal(t, "/keyapi.Key/ImportPublicKeys", info.FullMethod)
+		rqs = append(rqs, rq)
+		if len(rqs) > 1 {
+			return nil, errTest
+		}
+		return handler(auth.NewContext(ctx, &auth.Identity{EntityID: "some entity ID"}), rq)
+	}
+	k := newInterceptedKey(ks, interceptor)
+	stream := &fixedImportStream{
+		rqs: []*api.ImportPublicKeysRequest{
+			{KeyFormat: api.KeyFormat_ED25519},
+			{KeyFormat: api.KeyFormat_X25519},
+			{KeyFormat: api.KeyFormat_ED25519},
+		},
+	}
+
+	// the second request isn't authorized, so the import stops there
+	err := k.ImportPublicKeys(stream)
+	assert.Equal(t, errTest, err)
+	assert.Len(t, rqs, 2)
+	assert.Len(t, stream.rqs, 1)
+	assert.Equal(t, 1, ks.nCalls)
+
+	// the stream's context has the caller's identity from the intercepted request
+	caller, ok := auth.FromContext(ks.importCtx)
+	assert.True(t, ok)
+	assert.Equal(t, "some entity ID", caller.EntityID)
+}
+
+func TestInterceptedKey_ExportPublicKeys(t *testing.T) {
+	ks := &fixedKeyServer{}
+	errTest := errors.New("some interceptor error")
+	var interceptorErr error
+	interceptor := func(
+		ctx context.Context,
+		rq interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		assert.Equal(t, "/keyapi.Key/ExportPublicKeys", info.FullMethod)
+		if interceptorErr != nil {
+			return nil, interceptorErr
+		}
+		return handler(ctx, rq)
+	}
+	k := newInterceptedKey(ks, interceptor)
+
+	err := k.ExportPublicKeys(&api.ExportPublicKeysRequest{}, &fixedExportStream{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, ks.nCalls)
+
+	interceptorErr = errTest
+	err = k.ExportPublicKeys(&api.ExportPublicKeysRequest{}, &fixedExportStream{})
+	assert.Equal(t, errTest, err)
+	assert.Equal(t, 1, ks.nCalls)
+}
+
+func TestInterceptedKey_WatchPublicKeys(t *testing.T) {
+	ks := &fixedKeyServer{}
+	errTest := errors.New("some interceptor error")
+	var interceptorErr error
+	interceptor := func(
+		ctx context.Context,
+		rq interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		assert.Equal(t, "/keyapi.Key/WatchPublicKeys", info.FullMethod)
+		if interceptorErr != nil {
+			return nil, interceptorErr
+		}
+		return handler(ctx, rq)
+	}
+	k := newInterceptedKey(ks, interceptor)
+	stream := newFixedWatchStream(context.Background())
+
+	err := k.WatchPublicKeys(&api.WatchPublicKeysRequest{}, stream)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, ks.nCalls)
+
+	interceptorErr = errTest
+	err = k.WatchPublicKeys(&api.WatchPublicKeysRequest{}, stream)
+	assert.Equal(t, errTest, err)
+	assert.Equal(t, 1, ks.nCalls)
+}
+
+func TestKey_keyServer(t *testing.T) {
+	k := &Key{config: NewDefaultConfig()}
+	assert.Equal(t, k, k.keyServer())
+
+	k.config.WithAuth(auth.Identifiers{}, auth.NewEntityAuthorizer(nil))
+	ks := k.keyServer()
+	assert.IsType(t, &interceptedKey{}, ks)
+	assert.Equal(t, k, ks.(*interceptedKey).KeyServer)
+}
+
+type fixedKeyServer struct {
+	nCalls    int
+	importCtx context.Context
+}
+
+func (f *fixedKeyServer) AddPublicKeys(
+	ctx context.Context, rq *api.AddPublicKeysRequest,
+) (*api.AddPublicKeysResponse, error) {
+	f.nCalls++
+	return &api.AddPublicKeysResponse{}, nil
+}
+
+func (f *fixedKeyServer) GetPublicKeys(
+	ctx context.Context, rq *api.GetPublicKeysRequest,
+) (*api.GetPublicKeysResponse, error) {
+	f.nCalls++
+	return &api.GetPublicKeysResponse{}, nil
+}
+
+func (f *fixedKeyServer) SamplePublicKeys(
+	ctx context.Context, rq *api.SamplePublicKeysRequest,
+) (*api.SamplePublicKeysResponse, error) {
+	f.nCalls++
+	return &api.SamplePublicKeysResponse{}, nil
+}
+
+func (f *fixedKeyServer) GetPublicKeyDetails(
+	ctx context.Context, rq *api.GetPublicKeyDetailsRequest,
+) (*api.GetPublicKeyDetailsResponse, error) {
+	f.nCalls++
+	return &api.GetPublicKeyDetailsResponse{}, nil
+}
+
+func (f *fixedKeyServer) RevokePublicKeys(
+	ctx context.Context, rq *api.RevokePublicKeysRequest,
+) (*api.RevokePublicKeysResponse, error) {
+	f.nCalls++
+	return &api.RevokePublicKeysResponse{}, nil
+}
+
+func (f *fixedKeyServer) RotatePublicKeys(
+	ctx context.Context, rq *api.RotatePublicKeysRequest,
+) (*api.RotatePublicKeysResponse, error) {
+	f.nCalls++
+	return &api.RotatePublicKeysResponse{}, nil
+}
+
+func (f *fixedKeyServer) ListPublicKeys(
+	ctx context.Context, rq *api.ListPublicKeysRequest,
+) (*api.ListPublicKeysResponse, error) {
+	f.nCalls++
+	return &api.ListPublicKeysResponse{}, nil
+}
+
+func (f *fixedKeyServer) ImportPublicKeys(stream api.Key_ImportPublicKeysServer) error {
+	f.nCalls++
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+		f.importCtx = stream.Context()
+	}
+}
+
+func (f *fixedKeyServer) ExportPublicKeys(
+	rq *api.ExportPublicKeysRequest, stream api.Key_ExportPublicKeysServer,
+) error {
+	f.nCalls++
+	return nil
+}
+
+func (f *fixedKeyServer) WatchPublicKeys(
+	rq *api.WatchPublicKeysRequest, stream api.Key_WatchPublicKeysServer,
+) error {
+	f.nCalls++
+	return nil
+}
+
+func (f *fixedKeyServer) VerifyAuditLog(
+	ctx context.Context, rq *api.VerifyAuditLogRequest,
+) (*api.VerifyAuditLogResponse, error) {
+	f.nCalls++
+	return &api.VerifyAuditLogResponse{}, nil
+}
+
+func (f *fixedKeyServer) GetSigningKey(
+	ctx context.Context, rq *api.GetSigningKeyRequest,
+) (*api.GetSigningKeyResponse, error) {
+	f.nCalls++
+	return &api.GetSigningKeyResponse{}, nil
+}
+
+func (f *fixedKeyServer) GetTreeHead(
+	ctx context.Context, rq *api.GetTreeHeadRequest,
+) (*api.GetTreeHeadResponse, error) {
+	f.nCalls++
+	return &api.GetTreeHeadResponse{}, nil
+}
+
+func (f *fixedKeyServer) GetConsistencyProof(
+	ctx context.Context, rq *api.GetConsistencyProofRequest,
+) (*api.GetConsistencyProofResponse, error) {
+	f.nCalls++
+	return &api.GetConsistencyProofResponse{}, nil
+}
@@ -1,8 +1,13 @@
 package server
 
 import (
+	"context"
+
 	"github.com/drausin/libri/libri/common/errors"
 	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/auth"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/postgres"
 	"github.com/elixirhealth/key/pkg/server/storage/postgres/migrations"
 	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
 	"github.com/mattes/migrate/source/go-bindata"
@@ -11,6 +16,12 @@ import (
 
 // Start starts the server and eviction routines.
 func Start(config *Config, up chan *Key) error {
+	if err := config.validateTLS(); err != nil {
+		return err
+	}
+	if err := config.validateEvents(); err != nil {
+		return err
+	}
 	c, err := newKey(config)
 	if err != nil {
 		return err
@@ -19,20 +30,55 @@ func Start(config *Config, up chan *Key) error {
 	if err := c.maybeMigrateDB(); err != nil {
 		return err
 	}
+	if err := c.transparencyLog.Backfill(context.Background(), c.storer); err != nil {
+		return err
+	}
+	if err := c.maybeStartEventRelay(); err != nil {
+		return err
+	}
 
-	registerServer := func(s *grpc.Server) { api.RegisterKeyServer(s, c) }
+	registerServer := func(s *grpc.Server) { api.RegisterKeyServer(s, c.keyServer()) }
+	if config.tls() {
+		return c.serveTLS(registerServer, func() { up <- c })
+	}
 	return c.Serve(registerServer, func() { up <- c })
 }
 
 // StopServer handles cleanup involved in closing down the server.
 func (k *Key) StopServer() {
-	k.BaseServer.StopServer()
+	if k.watcher != nil {
+		// end WatchPublicKeys streams so the gRPC server can stop gracefully
+		k.watcher.Close()
+	}
+	if k.tlsServer != nil {
+		k.tlsServer.GracefulStop()
+	} else {
+		k.BaseServer.StopServer()
+	}
+	if k.eventRelay != nil {
+		// stop after the server so the events of the last requests are in the outbox, though
+		// any not yet published are left for the next start
+		err := k.eventRelay.Stop()
+		errors.MaybePanic(err)
+		err = k.config.EventPublisher.Close()
+		errors.MaybePanic(err)
+	}
 	err := k.storer.Close()
 	errors.MaybePanic(err)
 }
 
+// keyServer returns the KeyServer to register, which authorizes each request first when the
+// config has an Identifier and Authorizer.
+func (k *Key) keyServer() api.KeyServer {
+	if !k.config.authorize() {
+		return k
+	}
+	interceptor := auth.UnaryServerInterceptor(k.config.Identifier, k.config.Authorizer)
+	return newInterceptedKey(k, interceptor)
+}
+
 func (k *Key) maybeMigrateDB() error {
-	if k.config.Storage.Type != bstorage.Postgres {
+	if k.config.Storage.Type != storage.Postgres {
 		return nil
 	}
 
@@ -43,3 +89,19 @@ func (k *Key) maybeMigrateDB() error {
 	)
 	return m.Up()
 }
+
+// maybeStartEventRelay starts relaying the Postgres storer's outbox events to the config's
+// EventPublisher, if it has one.
+func (k *Key) maybeStartEventRelay() error {
+	if k.config.EventPublisher == nil {
+		return nil
+	}
+	relay, err := postgres.NewRelay(k.config.DBUrl, k.config.EventPublisher, k.config.EventRelay,
+		k.Logger)
+	if err != nil {
+		return err
+	}
+	k.eventRelay = relay
+	k.eventRelay.Start()
+	return nil
+}
@@ -1,20 +1,65 @@
 package server
 
 import (
+	"encoding/hex"
+
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 const (
-	logStorage            = "storage"
-	logEntityID           = "entity_id"
-	logKeyType            = "key_type"
-	logNKeys              = "n_keys"
-	logOfEntityID         = "of_entity_id"
-	logRequersterEntityID = "requester_entity_id"
-	logNPublicKeys        = "n_public_keys"
-	logErr                = "err"
+	logStorage                  = "storage"
+	logStorageType              = "storage_type"
+	logRequireProofOfPossession = "require_proof_of_possession"
+	logMemorySnapshotPath       = "memory_snapshot_path"
+	logStorageCache             = "storage_cache"
+	logWatch                    = "watch"
+	logEventRelay               = "event_relay"
+	logAuthorize                = "authorize"
+	logTLSCertFile              = "tls_cert_file"
+	logTLSClientCAFile          = "tls_client_ca_file"
+	logServerPort               = "server_port"
+	logMutualTLS                = "mutual_tls"
+	logSignResponses            = "sign_responses"
+	logEntityID                 = "entity_id"
+	logKeyType                  = "key_type"
+	logNKeys                    = "n_keys"
+	logNFoundKeys               = "n_found_keys"
+	logPartial                  = "partial"
+	logOfEntityID               = "of_entity_id"
+	logRequersterEntityID       = "requester_entity_id"
+	logNPublicKeys              = "n_public_keys"
+	logAsOfTime                 = "as_of_time"
+	logNOldKeys                 = "n_old_keys"
+	logNNewKeys                 = "n_new_keys"
+	logNKeyTypes                = "n_key_types"
+	logAddedAfter               = "added_after"
+	logModifiedAfter            = "modified_after"
+	logModifiedBefore           = "modified_before"
+	logStatus                   = "status"
+	logPageSize                 = "page_size"
+	logPageToken                = "page_token"
+	logNextPageToken            = "next_page_token"
+	logKeyFormat                = "key_format"
+	logNInserted                = "n_inserted"
+	logNDuplicate               = "n_duplicate"
+	logNRejected                = "n_rejected"
+	logPublicKey                = "public_key"
+	logNEntityIDs               = "n_entity_ids"
+	logAfterSequence            = "after_sequence"
+	logNEvents                  = "n_events"
+	logCheckpointSequence       = "checkpoint_sequence"
+	logValid                    = "valid"
+	logNRecords                 = "n_records"
+	logHeadSequence             = "head_sequence"
+	logInvalidSequence          = "invalid_sequence"
+	logInvalidReason            = "invalid_reason"
+	logTreeSize                 = "tree_size"
+	logSigned                   = "signed"
+	logFirstTreeSize            = "first_tree_size"
+	logSecondTreeSize           = "second_tree_size"
+	logErr                      = "err"
 )
 
 func logAddPublicKeysRq(rq *api.AddPublicKeysRequest) []zapcore.Field {
@@ -25,10 +70,50 @@ func logAddPublicKeysRq(rq *api.AddPublicKeysRequest) []zapcore.Field {
 	}
 }
 
+func logRevokePublicKeysRq(rq *api.RevokePublicKeysRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, rq.EntityId),
+		zap.Int(logNKeys, len(rq.PublicKeys)),
+	}
+}
+
+func logRotatePublicKeysRq(rq *api.RotatePublicKeysRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, rq.EntityId),
+		zap.Stringer(logKeyType, rq.KeyType),
+		zap.Int(logNOldKeys, len(rq.OldPublicKeys)),
+		zap.Int(logNNewKeys, len(rq.NewPublicKeys)),
+	}
+}
+
+func logListPublicKeysRq(rq *api.ListPublicKeysRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, rq.EntityId),
+		zap.Int(logNKeyTypes, len(rq.KeyTypes)),
+		zap.Int64(logAddedAfter, rq.AddedAfter),
+		zap.Int64(logModifiedAfter, rq.ModifiedAfter),
+		zap.Int64(logModifiedBefore, rq.ModifiedBefore),
+		zap.Stringer(logStatus, rq.Status),
+		zap.Uint32(logPageSize, rq.PageSize),
+		zap.String(logPageToken, rq.PageToken),
+	}
+}
+
+func logListPublicKeysRp(
+	rq *api.ListPublicKeysRequest, rp *api.ListPublicKeysResponse,
+) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, rq.EntityId),
+		zap.Int(logNPublicKeys, len(rp.PublicKeyDetails)),
+		zap.String(logNextPageToken, rp.NextPageToken),
+	}
+}
+
 func logGetPublicKeysRq(rq *api.GetPublicKeysRequest) []zapcore.Field {
 	return []zapcore.Field{
 		zap.String(logEntityID, rq.EntityId),
 		zap.Stringer(logKeyType, rq.KeyType),
+		zap.Int64(logAsOfTime, rq.AsOfTime),
 	}
 }
 
@@ -59,3 +144,73 @@ func logSamplePublicKeysRp(
 		zap.Int(logNPublicKeys, len(rp.PublicKeyDetails)),
 	}
 }
+
+func logImportPublicKeysRq(rq *api.ImportPublicKeysRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNKeys, len(rq.PublicKeyDetails)),
+		zap.Stringer(logKeyFormat, rq.KeyFormat),
+	}
+}
+
+func logImportPublicKeysRp(rp *api.ImportPublicKeysResponse) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Uint32(logNInserted, rp.NInserted),
+		zap.Uint32(logNDuplicate, rp.NDuplicate),
+		zap.Uint32(logNRejected, rp.NRejected),
+	}
+}
+
+func logExportPublicKeysRq(rq *api.ExportPublicKeysRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Uint32(logPageSize, rq.PageSize),
+	}
+}
+
+func logWatchPublicKeysRq(rq *api.WatchPublicKeysRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNEntityIDs, len(rq.EntityIds)),
+		zap.Uint64(logAfterSequence, rq.AfterSequence),
+	}
+}
+
+func logVerifyAuditLogRq(rq *api.VerifyAuditLogRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Uint64(logCheckpointSequence, rq.CheckpointSequence),
+	}
+}
+
+func logVerifyAuditLogRp(rp *api.VerifyAuditLogResponse) []zapcore.Field {
+	fields := []zapcore.Field{
+		zap.Bool(logValid, rp.Valid),
+		zap.Uint64(logNRecords, rp.NRecords),
+		zap.Uint64(logHeadSequence, rp.HeadSequence),
+	}
+	if !rp.Valid {
+		fields = append(fields,
+			zap.Uint64(logInvalidSequence, rp.InvalidSequence),
+			zap.String(logInvalidReason, rp.InvalidReason),
+		)
+	}
+	return fields
+}
+
+func logRejectedPublicKey(pk []byte, err error) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logPublicKey, hex.EncodeToString(pk)),
+		zap.String(logErr, err.Error()),
+	}
+}
+
+func logTreeHead(th *api.TreeHead) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Uint64(logTreeSize, th.TreeSize),
+		zap.Bool(logSigned, len(th.Signature) > 0),
+	}
+}
+
+func logGetConsistencyProofRq(rq *api.GetConsistencyProofRequest) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Uint64(logFirstTreeSize, rq.FirstTreeSize),
+		zap.Uint64(logSecondTreeSize, rq.SecondTreeSize),
+	}
+}
@@ -1,14 +1,18 @@
 package server
 
 import (
+	"context"
 	"math/rand"
 	"time"
 
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/postgres"
+	"github.com/elixirhealth/key/pkg/server/transparency"
+	"github.com/elixirhealth/key/pkg/server/watch"
 	"github.com/elixirhealth/service-base/pkg/server"
 	"go.uber.org/zap"
-	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -29,23 +33,47 @@ type Key struct {
 	config *Config
 
 	storer storage.Storer
+
+	// watcher broadcasts the public keys added and disabled through this instance to
+	// WatchPublicKeys streams. It's nil when the storage is shared, since it wouldn't see the
+	// public keys added and disabled through the other instances.
+	watcher *watch.Broadcaster
+
+	// transparencyLog is the Merkle tree of the public key details added, backfilled from the
+	// stored public key records at startup and synced from the audit log when tree heads and
+	// proofs are requested.
+	transparencyLog *transparency.Log
+
+	// eventRelay publishes the Postgres storer's outbox events, if the config has an
+	// EventPublisher.
+	eventRelay *postgres.Relay
+
+	// tlsServer is the gRPC server serving over TLS, if the config has TLS.
+	tlsServer *grpc.Server
 }
 
 // newKey creates a new KeyServer from the given config.
 func newKey(config *Config) (*Key, error) {
 	baseServer := server.NewBaseServer(config.BaseConfig)
-	storer, err := getStorer(config, baseServer.Logger)
+	storer, err := NewStorer(config, baseServer.Logger)
 	if err != nil {
 		return nil, err
 	}
-	return &Key{
+	k := &Key{
 		BaseServer: baseServer,
 		config:     config,
 		storer:     storer,
-	}, nil
+
+		transparencyLog: transparency.New(baseServer.Logger),
+	}
+	if !config.Storage.Type.Shared() {
+		k.watcher = watch.New(config.Watch, baseServer.Logger)
+	}
+	return k, nil
 }
 
-// AddPublicKeys adds a set of public keys associated with a given entity.
+// AddPublicKeys adds a set of public keys associated with a given entity. When the config requires
+// proof of possession, each public key must have a valid signature by its private key.
 func (k *Key) AddPublicKeys(
 	ctx context.Context, rq *api.AddPublicKeysRequest,
 ) (*api.AddPublicKeysResponse, error) {
@@ -54,22 +82,32 @@ func (k *Key) AddPublicKeys(
 		k.Logger.Info("add public keys request invalid", zap.String(logErr, err.Error()))
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	if n, err := k.storer.CountEntityPublicKeys(rq.EntityId, rq.KeyType); err != nil {
-		k.Logger.Error("storer count entity public keys error", zap.Error(err))
-		return nil, ErrInternal
-	} else if n+len(rq.PublicKeys) > storage.MaxEntityKeyTypeKeys {
-		return nil, ErrTooManyActivePublicKeys
+	if k.requireProofs() {
+		if err := api.VerifyAddPublicKeysProofs(rq); err != nil {
+			k.Logger.Info("add public keys proofs invalid", zap.String(logErr, err.Error()))
+			return nil, proofError(err)
+		}
 	}
 	pkds := getPublicKeyDetails(rq)
-	if err := k.storer.AddPublicKeys(pkds); err != nil {
+	audit := newAuditRecords(ctx, api.AuditAction_ADD, pkds, nil)
+	switch err := k.storer.AddPublicKeys(ctx, pkds, audit); err {
+	case nil:
+	case storage.ErrPublicKeyExists:
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case storage.ErrTooManyActivePublicKeys:
+		return nil, ErrTooManyActivePublicKeys
+	default:
 		k.Logger.Error("storer add public keys error", zap.Error(err))
 		return nil, ErrInternal
 	}
+	k.publish(api.PublicKeyEventType_ADDED, pkds)
 	k.Logger.Info("added public keys", logAddPublicKeysRq(rq)...)
 	return &api.AddPublicKeysResponse{}, nil
 }
 
-// GetPublicKeys returns the public keys of a given type for a given entity ID.
+// GetPublicKeys returns the public keys of a given type for a given entity ID. If the request has
+// an as-of time, it returns the public keys that were active at that time instead. The response is
+// signed when the config has a signing key.
 func (k *Key) GetPublicKeys(
 	ctx context.Context, rq *api.GetPublicKeysRequest,
 ) (*api.GetPublicKeysResponse, error) {
@@ -78,7 +116,14 @@ func (k *Key) GetPublicKeys(
 		k.Logger.Info("get public keys request invalid", zap.String(logErr, err.Error()))
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	pkds, err := k.storer.GetEntityPublicKeys(rq.EntityId, rq.KeyType)
+	var pkds []*api.PublicKeyDetail
+	var err error
+	if rq.AsOfTime == 0 {
+		pkds, err = k.storer.GetEntityPublicKeys(ctx, rq.EntityId, rq.KeyType)
+	} else {
+		asOf := fromEpochMicros(rq.AsOfTime)
+		pkds, err = k.storer.GetEntityPublicKeysAsOf(ctx, rq.EntityId, rq.KeyType, asOf)
+	}
 	if err != nil {
 		k.Logger.Error("storer get entity public keys error", zap.Error(err))
 		return nil, ErrInternal
@@ -88,36 +133,89 @@ func (k *Key) GetPublicKeys(
 		pks[i] = pkd.PublicKey
 	}
 	rp := &api.GetPublicKeysResponse{PublicKeys: pks}
+	if k.signResponses() {
+		api.SignGetPublicKeysResponse(k.config.SigningKey, rq, rp, signedTime())
+	}
 	k.Logger.Info("got public keys", logGetPublicKeysRp(rq, rp)...)
 	return rp, nil
 }
 
 // GetPublicKeyDetails gets the details (including their associated entity IDs) for a given set of
-// public keys.
+// public keys, either currently or as of the request's as-of time. If any public key is not
+// found, the request fails with NotFound unless it is partial, in which case the response has a
+// result for each public key in request order marking whether it was found. When the request asks
+// for them, the response has a proof that each public key detail found is in the transparency
+// log. The response is signed when the config has a signing key.
 func (k *Key) GetPublicKeyDetails(
 	ctx context.Context, rq *api.GetPublicKeyDetailsRequest,
 ) (*api.GetPublicKeyDetailsResponse, error) {
 	k.Logger.Debug("received get public key details request",
-		zap.Int(logNKeys, len(rq.PublicKeys)))
+		zap.Int(logNKeys, len(rq.PublicKeys)), zap.Bool(logPartial, rq.Partial))
 	if err := api.ValidateGetPublicKeyDetailsRequest(rq); err != nil {
 		k.Logger.Info("get public key details request invalid",
 			zap.String(logErr, err.Error()))
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	pkds, err := k.storer.GetPublicKeys(rq.PublicKeys)
-	if err != nil && err == api.ErrNoSuchPublicKey {
-		return nil, status.Error(codes.NotFound, err.Error())
-	} else if err != nil {
+	var pkds []*api.PublicKeyDetail
+	var err error
+	if rq.AsOfTime == 0 {
+		pkds, err = k.storer.GetPublicKeys(ctx, rq.PublicKeys)
+	} else {
+		pkds, err = k.storer.GetPublicKeysAsOf(ctx, rq.PublicKeys, fromEpochMicros(rq.AsOfTime))
+	}
+	if err != nil {
 		k.Logger.Error("storer get public keys error", zap.Error(err))
 		return nil, ErrInternal
 	}
-	k.Logger.Info("got public key details", zap.Int(logNKeys, len(pkds)))
-	return &api.GetPublicKeyDetailsResponse{
-		PublicKeyDetails: pkds,
-	}, nil
+	rp := &api.GetPublicKeyDetailsResponse{}
+	nFound := len(pkds)
+	if rq.Partial {
+		rp.Results, nFound = getPublicKeyResults(rq.PublicKeys, pkds)
+	} else {
+		for _, pkd := range pkds {
+			if pkd == nil {
+				return nil, status.Error(codes.NotFound, api.ErrNoSuchPublicKey.Error())
+			}
+		}
+		rp.PublicKeyDetails = pkds
+	}
+	if rq.InclusionProofs {
+		if rp.TreeHead, rp.InclusionProofs, err = k.getInclusionProofs(ctx, pkds); err != nil {
+			return nil, err
+		}
+	}
+	if k.signResponses() {
+		api.SignGetPublicKeyDetailsResponse(k.config.SigningKey, rq, rp, signedTime())
+	}
+	if rq.Partial {
+		k.Logger.Info("got partial public key details",
+			zap.Int(logNKeys, len(rp.Results)), zap.Int(logNFoundKeys, nFound))
+	} else {
+		k.Logger.Info("got public key details", zap.Int(logNKeys, len(pkds)))
+	}
+	return rp, nil
 }
 
-// SamplePublicKeys returns a sample of public keys of the given entity.
+// getPublicKeyResults returns a result for each public key from its detail, which is nil if the
+// public key wasn't found, along with the number of public keys found.
+func getPublicKeyResults(
+	pks [][]byte, pkds []*api.PublicKeyDetail,
+) ([]*api.PublicKeyResult, int) {
+	results := make([]*api.PublicKeyResult, len(pks))
+	nFound := 0
+	for i, pk := range pks {
+		results[i] = &api.PublicKeyResult{PublicKey: pk}
+		if pkds[i] != nil {
+			results[i].Found = true
+			results[i].PublicKeyDetail = pkds[i]
+			nFound++
+		}
+	}
+	return results, nFound
+}
+
+// SamplePublicKeys returns a sample of public keys of the given entity. The response is signed
+// when the config has a signing key.
 func (k *Key) SamplePublicKeys(
 	ctx context.Context, rq *api.SamplePublicKeysRequest,
 ) (*api.SamplePublicKeysResponse, error) {
@@ -126,7 +224,7 @@ func (k *Key) SamplePublicKeys(
 		k.Logger.Info("sample public keys request invalid", zap.String(logErr, err.Error()))
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	allPKDs, err := k.storer.GetEntityPublicKeys(rq.OfEntityId, api.KeyType_READER)
+	allPKDs, err := k.storer.GetEntityPublicKeys(ctx, rq.OfEntityId, api.KeyType_READER)
 	if err != nil {
 		k.Logger.Error("storer get entity public keys error", zap.Error(err))
 		return nil, ErrInternal
@@ -138,6 +236,110 @@ func (k *Key) SamplePublicKeys(
 	rp := &api.SamplePublicKeysResponse{
 		PublicKeyDetails: topSampled,
 	}
+	if k.signResponses() {
+		api.SignSamplePublicKeysResponse(k.config.SigningKey, rq, rp, signedTime())
+	}
 	k.Logger.Info("sampled public keys", logSamplePublicKeysRp(rq, rp)...)
 	return rp, nil
 }
+
+// RevokePublicKeys revokes a set of public keys associated with a given entity. Revoked keys are
+// still returned by GetPublicKeyDetails but are no longer returned by GetPublicKeys or
+// SamplePublicKeys.
+func (k *Key) RevokePublicKeys(
+	ctx context.Context, rq *api.RevokePublicKeysRequest,
+) (*api.RevokePublicKeysResponse, error) {
+	k.Logger.Debug("received revoke public keys request", logRevokePublicKeysRq(rq)...)
+	if err := api.ValidateRevokePublicKeysRequest(rq); err != nil {
+		k.Logger.Info("revoke public keys request invalid", zap.String(logErr, err.Error()))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	revoked, err := k.getRevoked(ctx, rq.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	audit := newAuditRecords(ctx, api.AuditAction_REVOKE, nil, revoked)
+	err = k.storer.DisablePublicKeys(ctx, rq.EntityId, rq.PublicKeys, audit)
+	if err != nil && err == api.ErrNoSuchPublicKey {
+		return nil, status.Error(codes.NotFound, err.Error())
+	} else if err != nil {
+		k.Logger.Error("storer disable public keys error", zap.Error(err))
+		return nil, ErrInternal
+	}
+	k.publish(api.PublicKeyEventType_DISABLED, revoked)
+	k.Logger.Info("revoked public keys", logRevokePublicKeysRq(rq)...)
+	return &api.RevokePublicKeysResponse{}, nil
+}
+
+// RotatePublicKeys revokes a set of an entity's public keys and adds a replacement set of the
+// same key type in a single storage transaction. When the config requires proof of possession,
+// each new public key must have a valid signature by its private key.
+func (k *Key) RotatePublicKeys(
+	ctx context.Context, rq *api.RotatePublicKeysRequest,
+) (*api.RotatePublicKeysResponse, error) {
+	k.Logger.Debug("received rotate public keys request", logRotatePublicKeysRq(rq)...)
+	if err := api.ValidateRotatePublicKeysRequest(rq); err != nil {
+		k.Logger.Info("rotate public keys request invalid", zap.String(logErr, err.Error()))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if k.requireProofs() {
+		if err := api.VerifyRotatePublicKeysProofs(rq); err != nil {
+			k.Logger.Info("rotate public keys proofs invalid", zap.String(logErr, err.Error()))
+			return nil, proofError(err)
+		}
+	}
+	oldPKDs, err := k.getRevoked(ctx, rq.OldPublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	newPKDs := getNewPublicKeyDetails(rq)
+	audit := newAuditRecords(ctx, api.AuditAction_ROTATE, newPKDs, oldPKDs)
+	err = k.storer.RotatePublicKeys(ctx, rq.EntityId, rq.KeyType, rq.OldPublicKeys,
+		rq.NewPublicKeys, audit)
+	switch err {
+	case nil:
+	case api.ErrNoSuchPublicKey:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case storage.ErrPublicKeyExists:
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case storage.ErrTooManyActivePublicKeys:
+		return nil, ErrTooManyActivePublicKeys
+	default:
+		k.Logger.Error("storer rotate public keys error", zap.Error(err))
+		return nil, ErrInternal
+	}
+	k.publish(api.PublicKeyEventType_DISABLED, oldPKDs)
+	k.publish(api.PublicKeyEventType_ADDED, newPKDs)
+	k.Logger.Info("rotated public keys", logRotatePublicKeysRq(rq)...)
+	return &api.RotatePublicKeysResponse{}, nil
+}
+
+// ListPublicKeys returns a page of the public key details matching the request's filters, ordered
+// by public key. The response's next page token is empty when there are no more pages.
+func (k *Key) ListPublicKeys(
+	ctx context.Context, rq *api.ListPublicKeysRequest,
+) (*api.ListPublicKeysResponse, error) {
+	k.Logger.Debug("received list public keys request", logListPublicKeysRq(rq)...)
+	if err := api.ValidateListPublicKeysRequest(rq); err != nil {
+		k.Logger.Info("list public keys request invalid", zap.String(logErr, err.Error()))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	after, _ := api.DecodePageToken(rq.PageToken) // already validated above
+	pageSize := uint(rq.PageSize)
+	if pageSize == 0 {
+		pageSize = api.DefaultListPageSize
+	}
+	// get one extra public key detail to know whether there's another page
+	pkds, err := k.storer.ListPublicKeys(ctx, getListFilter(rq), after, pageSize+1)
+	if err != nil {
+		k.Logger.Error("storer list public keys error", zap.Error(err))
+		return nil, ErrInternal
+	}
+	rp := &api.ListPublicKeysResponse{PublicKeyDetails: pkds}
+	if uint(len(pkds)) > pageSize {
+		rp.PublicKeyDetails = pkds[:pageSize]
+		rp.NextPageToken = api.EncodePageToken(pkds[pageSize-1].PublicKey)
+	}
+	k.Logger.Info("listed public keys", logListPublicKeysRp(rq, rp)...)
+	return rp, nil
+}
@@ -1,17 +1,23 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/auth"
 	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/cache"
 	bserver "github.com/elixirhealth/service-base/pkg/server"
-	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
-	"github.com/elixirhealth/service-base/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -26,12 +32,22 @@ func TestNewKey_ok(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, config, c.config)
 	assert.NotEmpty(t, c.storer)
+	assert.NotNil(t, c.watcher)
+
+	// shared storage isn't watched
+	config = NewDefaultConfig().
+		WithGCPProjectID("some-project").
+		WithDatastoreEmulatorHost("localhost:8081")
+	config.Storage.Type = storage.DataStore
+	c, err = newKey(config)
+	assert.Nil(t, err)
+	assert.Nil(t, c.watcher)
 }
 
 func TestNewKey_err(t *testing.T) {
 	badConfigs := map[string]*Config{
 		"empty ProjectID": NewDefaultConfig().WithStorage(
-			&storage.Parameters{Type: bstorage.DataStore},
+			&storage.Parameters{Type: storage.DataStore},
 		),
 	}
 	for desc, badConfig := range badConfigs {
@@ -41,23 +57,128 @@ func TestNewKey_err(t *testing.T) {
 	}
 }
 
+func TestNewStorer(t *testing.T) {
+	lg := zap.NewNop()
+	st, err := NewStorer(NewDefaultConfig(), lg)
+	assert.Nil(t, err)
+	assert.NotNil(t, st)
+
+	config := NewDefaultConfig().
+		WithGCPProjectID("some-project").
+		WithDatastoreEmulatorHost("localhost:8081")
+	config.Storage.Type = storage.DataStore
+	st, err = NewStorer(config, lg)
+	assert.Nil(t, err)
+	assert.NotNil(t, st)
+
+	dir, err := ioutil.TempDir("", "key-server-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	config.WithBoltDBPath(filepath.Join(dir, "key.db"))
+	config.Storage.Type = storage.Bolt
+	st, err = NewStorer(config, lg)
+	assert.Nil(t, err)
+	assert.NotNil(t, st)
+	assert.Nil(t, st.Close())
+
+	config.WithMemorySnapshots(filepath.Join(dir, "key.snapshot"), time.Minute)
+	config.Storage.Type = storage.Memory
+	st, err = NewStorer(config, lg)
+	assert.Nil(t, err)
+	assert.NotNil(t, st)
+	assert.Nil(t, st.Close())
+
+	config.WithStorageCache(cache.NewDefaultParameters())
+	st, err = NewStorer(config, lg)
+	assert.Nil(t, err)
+	assert.NotNil(t, st)
+	assert.Nil(t, st.Close())
+
+	// shared storage isn't cached
+	config.Storage.Type = storage.DataStore
+	st, err = NewStorer(config, lg)
+	assert.Nil(t, err)
+	uncached, err := newBackendStorer(config, lg)
+	assert.Nil(t, err)
+	assert.IsType(t, uncached, st)
+
+	config.Storage.Type = storage.Unspecified
+	st, err = NewStorer(config, lg)
+	assert.Equal(t, ErrInvalidStorageType, err)
+	assert.Nil(t, st)
+}
+
 func TestKey_AddPublicKeys_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
+	st := &fixedStorer{}
 	k := &Key{
 		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
-		storer:     &fixedStorer{},
+		storer:     st,
 	}
 	rq := &api.AddPublicKeysRequest{
 		EntityId: "some entity ID",
 		KeyType:  api.KeyType_READER,
 		PublicKeys: [][]byte{
-			util.RandBytes(rng, 33),
-			util.RandBytes(rng, 33),
+			api.NewTestPublicKey(rng),
+			api.NewTestPublicKey(rng),
 		},
+		KeyFormat: api.KeyFormat_SECP256K1_COMPRESSED,
+	}
+	ctx := auth.NewContext(context.Background(), &auth.Identity{EntityID: "caller entity ID"})
+	rp, err := k.AddPublicKeys(ctx, rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+
+	// audited as added by the caller
+	assert.Len(t, st.auditRecords, 1)
+	r := st.auditRecords[0]
+	assert.Equal(t, api.AuditAction_ADD, r.Action)
+	assert.Equal(t, "caller entity ID", r.CallerEntityId)
+	assert.Equal(t, rq.EntityId, r.EntityId)
+	assert.Equal(t, rq.KeyType, r.KeyType)
+	assert.Equal(t, api.PublicKeyHashes(rq.PublicKeys), r.AddedPublicKeyHashes)
+	assert.Empty(t, r.DisabledPublicKeyHashes)
+	assert.NotZero(t, r.Time)
+}
+
+func TestKey_AddPublicKeys_proofs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", api.KeyType_READER
+	pks, sigs := api.NewTestProvenPublicKeys(rng, entityID, kt, 2)
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     NewDefaultConfig().WithRequireProofOfPossession(true),
+		storer:     &fixedStorer{},
+	}
+	rq := &api.AddPublicKeysRequest{
+		EntityId:   entityID,
+		KeyType:    kt,
+		PublicKeys: pks,
+		KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+		Signatures: sigs,
 	}
 	rp, err := k.AddPublicKeys(context.Background(), rq)
 	assert.Nil(t, err)
 	assert.NotNil(t, rp)
+
+	// public keys of an unspecified format can't be proven
+	rq.KeyFormat = api.KeyFormat_UNSPECIFIED_FORMAT
+	rp, err = k.AddPublicKeys(context.Background(), rq)
+	assert.Equal(t, codes.InvalidArgument, status.Convert(err).Code())
+	assert.Nil(t, rp)
+	rq.KeyFormat = api.KeyFormat_SECP256K1_COMPRESSED
+
+	// missing signatures
+	rq.Signatures = nil
+	rp, err = k.AddPublicKeys(context.Background(), rq)
+	assert.Equal(t, codes.InvalidArgument, status.Convert(err).Code())
+	assert.Nil(t, rp)
+
+	// signatures for another entity
+	rq.EntityId, rq.Signatures = "another entity ID", sigs
+	rp, err = k.AddPublicKeys(context.Background(), rq)
+	assert.Equal(t, codes.PermissionDenied, status.Convert(err).Code())
+	assert.Nil(t, rp)
 }
 
 func TestKey_AddPublicKeys_err(t *testing.T) {
@@ -67,9 +188,10 @@ func TestKey_AddPublicKeys_err(t *testing.T) {
 		EntityId: "some entity ID",
 		KeyType:  api.KeyType_READER,
 		PublicKeys: [][]byte{
-			util.RandBytes(rng, 33),
-			util.RandBytes(rng, 33),
+			api.NewTestPublicKey(rng),
+			api.NewTestPublicKey(rng),
 		},
+		KeyFormat: api.KeyFormat_SECP256K1_COMPRESSED,
 	}
 	cases := map[string]struct {
 		k        *Key
@@ -84,18 +206,43 @@ func TestKey_AddPublicKeys_err(t *testing.T) {
 			rq:       &api.AddPublicKeysRequest{},
 			expected: status.Error(codes.InvalidArgument, api.ErrEmptyEntityID.Error()),
 		},
-		"storer get count error": {
+		"malformed public key": {
 			k: &Key{
 				BaseServer: baseServer,
-				storer:     &fixedStorer{countEntityPKsErr: errTest},
+				storer:     &fixedStorer{},
+			},
+			rq: &api.AddPublicKeysRequest{
+				EntityId:   "some entity ID",
+				PublicKeys: [][]byte{{1, 2, 3}},
+				KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+			},
+			expected: status.Error(codes.InvalidArgument,
+				"SECP256K1_COMPRESSED public key 0: got 3 bytes, expected 33: "+
+					api.ErrInvalidPublicKeyLength.Error()),
+		},
+		"unspecified public key format": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{},
+			},
+			rq: &api.AddPublicKeysRequest{
+				EntityId:   "some entity ID",
+				PublicKeys: [][]byte{{1, 2, 3}},
+			},
+			expected: status.Error(codes.InvalidArgument, api.ErrUnspecifiedKeyFormat.Error()),
+		},
+		"already exists": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{addErr: storage.ErrPublicKeyExists},
 			},
 			rq:       okRq,
-			expected: ErrInternal,
+			expected: status.Error(codes.AlreadyExists, storage.ErrPublicKeyExists.Error()),
 		},
 		"too many added": {
 			k: &Key{
 				BaseServer: baseServer,
-				storer:     &fixedStorer{countEntityPKsValue: 255},
+				storer:     &fixedStorer{addErr: storage.ErrTooManyActivePublicKeys},
 			},
 			rq:       okRq,
 			expected: ErrTooManyActivePublicKeys,
@@ -108,6 +255,14 @@ func TestKey_AddPublicKeys_err(t *testing.T) {
 			rq:       okRq,
 			expected: ErrInternal,
 		},
+		"storer append audit records error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{appendAuditErr: errTest},
+			},
+			rq:       okRq,
+			expected: ErrInternal,
+		},
 	}
 	for desc, c := range cases {
 		rp, err := c.k.AddPublicKeys(context.Background(), c.rq)
@@ -130,6 +285,18 @@ func TestKey_GetPublicKeys_ok(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, rp)
 	assert.Equal(t, n, len(rp.PublicKeys))
+
+	// as of a time
+	storer := &fixedStorer{
+		getEntityPKs: api.NewTestPublicKeyDetails(rng, n),
+	}
+	k.storer = storer
+	rq = &api.GetPublicKeysRequest{EntityId: "some entity ID", AsOfTime: 1520000000000000}
+	rp, err = k.GetPublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+	assert.Equal(t, n, len(rp.PublicKeys))
+	assert.Equal(t, int64(1520000000000000), storer.asOf.UnixNano()/int64(time.Microsecond))
 }
 
 func TestKey_GetPublicKeys_err(t *testing.T) {
@@ -154,8 +321,8 @@ func TestKey_GetPublicKeys_err(t *testing.T) {
 func TestKey_GetPublicKeyDetails_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	pks := [][]byte{
-		util.RandBytes(rng, 33),
-		util.RandBytes(rng, 33),
+		api.NewTestPublicKey(rng),
+		api.NewTestPublicKey(rng),
 	}
 	k := &Key{
 		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
@@ -168,6 +335,33 @@ func TestKey_GetPublicKeyDetails_ok(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, rp)
 	assert.Equal(t, len(pks), len(rp.PublicKeyDetails))
+
+	// as of a time
+	storer := &fixedStorer{
+		getPKDs: api.NewTestPublicKeyDetails(rng, len(pks)),
+	}
+	k.storer = storer
+	rq = &api.GetPublicKeyDetailsRequest{PublicKeys: pks, AsOfTime: 1520000000000000}
+	rp, err = k.GetPublicKeyDetails(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+	assert.Equal(t, len(pks), len(rp.PublicKeyDetails))
+	assert.Equal(t, int64(1520000000000000), storer.asOf.UnixNano()/int64(time.Microsecond))
+
+	// partial, with a missing pub key
+	pkd := api.NewTestPublicKeyDetail(rng)
+	k.storer = &fixedStorer{
+		getPKDs: []*api.PublicKeyDetail{nil, pkd},
+	}
+	rq = &api.GetPublicKeyDetailsRequest{PublicKeys: pks, Partial: true}
+	rp, err = k.GetPublicKeyDetails(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+	assert.Empty(t, rp.PublicKeyDetails)
+	assert.Equal(t, []*api.PublicKeyResult{
+		{PublicKey: pks[0]},
+		{PublicKey: pks[1], Found: true, PublicKeyDetail: pkd},
+	}, rp.Results)
 }
 
 func TestKey_GetPublicKeyDetails_err(t *testing.T) {
@@ -186,8 +380,8 @@ func TestKey_GetPublicKeyDetails_err(t *testing.T) {
 	// storer error
 	rq = &api.GetPublicKeyDetailsRequest{
 		PublicKeys: [][]byte{
-			util.RandBytes(rng, 33),
-			util.RandBytes(rng, 33),
+			api.NewTestPublicKey(rng),
+			api.NewTestPublicKey(rng),
 		},
 	}
 	rp, err = k.GetPublicKeyDetails(context.Background(), rq)
@@ -197,7 +391,9 @@ func TestKey_GetPublicKeyDetails_err(t *testing.T) {
 	// no such pub key
 	k = &Key{
 		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
-		storer:     &fixedStorer{getErr: api.ErrNoSuchPublicKey},
+		storer: &fixedStorer{
+			getPKDs: []*api.PublicKeyDetail{api.NewTestPublicKeyDetail(rng), nil},
+		},
 	}
 	rp, err = k.GetPublicKeyDetails(context.Background(), rq)
 	assert.NotNil(t, err)
@@ -282,6 +478,341 @@ func TestKey_SamplePublicKeys_err(t *testing.T) {
 	assert.Nil(t, rp)
 }
 
+func TestKey_RevokePublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkds := []*api.PublicKeyDetail{
+		{
+			PublicKey: api.NewTestPublicKey(rng),
+			EntityId:  "some entity ID",
+			KeyType:   api.KeyType_READER,
+		},
+		{
+			PublicKey: api.NewTestPublicKey(rng),
+			EntityId:  "some entity ID",
+			KeyType:   api.KeyType_AUTHOR,
+		},
+		{
+			PublicKey: api.NewTestPublicKey(rng),
+			EntityId:  "some entity ID",
+			KeyType:   api.KeyType_AUTHOR,
+			Disabled:  true,
+		},
+	}
+	st := &fixedStorer{getPKDs: pkds}
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		storer:     st,
+	}
+	rq := &api.RevokePublicKeysRequest{
+		EntityId:   "some entity ID",
+		PublicKeys: [][]byte{pkds[0].PublicKey, pkds[1].PublicKey, pkds[2].PublicKey},
+	}
+	rp, err := k.RevokePublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+
+	// audited for each key type, without a caller since the request isn't authenticated, and
+	// without the public key that was already disabled
+	assert.Len(t, st.auditRecords, 2)
+	for i, r := range st.auditRecords {
+		assert.Equal(t, api.AuditAction_REVOKE, r.Action)
+		assert.Empty(t, r.CallerEntityId)
+		assert.Equal(t, rq.EntityId, r.EntityId)
+		assert.Empty(t, r.AddedPublicKeyHashes)
+		assert.Len(t, r.DisabledPublicKeyHashes, 1)
+		assert.Equal(t, uint64(i+1), r.Sequence)
+	}
+
+	// revoking only already disabled public keys audits nothing
+	st.auditRecords = nil
+	st.getPKDs = pkds[2:]
+	rq.PublicKeys = rq.PublicKeys[2:]
+	rp, err = k.RevokePublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+	assert.Empty(t, st.auditRecords)
+}
+
+func TestKey_RevokePublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	baseServer := bserver.NewBaseServer(bserver.NewDefaultBaseConfig())
+	okRq := &api.RevokePublicKeysRequest{
+		EntityId: "some entity ID",
+		PublicKeys: [][]byte{
+			api.NewTestPublicKey(rng),
+			api.NewTestPublicKey(rng),
+		},
+	}
+	cases := map[string]struct {
+		k        *Key
+		rq       *api.RevokePublicKeysRequest
+		expected error
+	}{
+		"bad request": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{},
+			},
+			rq:       &api.RevokePublicKeysRequest{},
+			expected: status.Error(codes.InvalidArgument, api.ErrEmptyEntityID.Error()),
+		},
+		"missing public key": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{disableErr: api.ErrNoSuchPublicKey},
+			},
+			rq:       okRq,
+			expected: status.Error(codes.NotFound, api.ErrNoSuchPublicKey.Error()),
+		},
+		"storer disable error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{disableErr: errTest},
+			},
+			rq:       okRq,
+			expected: ErrInternal,
+		},
+		"storer get revoked error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{getErr: errTest},
+			},
+			rq:       okRq,
+			expected: ErrInternal,
+		},
+		"storer append audit records error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer: &fixedStorer{
+					getPKDs:        api.NewTestPublicKeyDetails(rng, 1),
+					appendAuditErr: errTest,
+				},
+			},
+			rq:       okRq,
+			expected: ErrInternal,
+		},
+	}
+	for desc, c := range cases {
+		rp, err := c.k.RevokePublicKeys(context.Background(), c.rq)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, rp, desc)
+	}
+}
+
+func TestKey_ListPublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 3)
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		storer:     &fixedStorer{listPKDs: pkds},
+	}
+
+	// more pages
+	rq := &api.ListPublicKeysRequest{
+		EntityId:       "some entity ID",
+		KeyTypes:       []api.KeyType{api.KeyType_READER},
+		AddedAfter:     1520000000000000,
+		ModifiedAfter:  1520000000000000,
+		ModifiedBefore: 1530000000000000,
+		Status:         api.KeyStatus_ACTIVE,
+		PageSize:       2,
+	}
+	rp, err := k.ListPublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, pkds[:2], rp.PublicKeyDetails)
+	assert.Equal(t, api.EncodePageToken(pkds[1].PublicKey), rp.NextPageToken)
+
+	// last page
+	rq = &api.ListPublicKeysRequest{
+		PageToken: rp.NextPageToken,
+	}
+	rp, err = k.ListPublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.Equal(t, pkds, rp.PublicKeyDetails)
+	assert.Empty(t, rp.NextPageToken)
+}
+
+func TestKey_ListPublicKeys_err(t *testing.T) {
+	baseServer := bserver.NewBaseServer(bserver.NewDefaultBaseConfig())
+	cases := map[string]struct {
+		k        *Key
+		rq       *api.ListPublicKeysRequest
+		expected error
+	}{
+		"bad request": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{},
+			},
+			rq: &api.ListPublicKeysRequest{PageToken: "not a page token"},
+			expected: status.Error(codes.InvalidArgument,
+				api.ErrInvalidPageToken.Error()),
+		},
+		"storer list error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{listErr: errTest},
+			},
+			rq:       &api.ListPublicKeysRequest{},
+			expected: ErrInternal,
+		},
+	}
+	for desc, c := range cases {
+		rp, err := c.k.ListPublicKeys(context.Background(), c.rq)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, rp, desc)
+	}
+}
+
+func TestKey_RotatePublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	oldPKD := &api.PublicKeyDetail{
+		PublicKey: api.NewTestPublicKey(rng),
+		EntityId:  "some entity ID",
+		KeyType:   api.KeyType_READER,
+	}
+	st := &fixedStorer{getPKDs: []*api.PublicKeyDetail{oldPKD}}
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		storer:     st,
+	}
+	rq := &api.RotatePublicKeysRequest{
+		EntityId:      "some entity ID",
+		KeyType:       api.KeyType_READER,
+		OldPublicKeys: [][]byte{oldPKD.PublicKey},
+		NewPublicKeys: [][]byte{api.NewTestPublicKey(rng)},
+		NewKeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+	}
+	rp, err := k.RotatePublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+
+	// audited with both the new and the old public keys
+	assert.Len(t, st.auditRecords, 1)
+	r := st.auditRecords[0]
+	assert.Equal(t, api.AuditAction_ROTATE, r.Action)
+	assert.Equal(t, api.PublicKeyHashes(rq.NewPublicKeys), r.AddedPublicKeyHashes)
+	assert.Equal(t, api.PublicKeyHashes(rq.OldPublicKeys), r.DisabledPublicKeyHashes)
+
+	// an old public key that was already disabled isn't audited again
+	st.auditRecords = nil
+	oldPKD.Disabled = true
+	rq.NewPublicKeys = [][]byte{api.NewTestPublicKey(rng)}
+	rp, err = k.RotatePublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+	assert.Len(t, st.auditRecords, 1)
+	r = st.auditRecords[0]
+	assert.Equal(t, api.PublicKeyHashes(rq.NewPublicKeys), r.AddedPublicKeyHashes)
+	assert.Empty(t, r.DisabledPublicKeyHashes)
+}
+
+func TestKey_RotatePublicKeys_proofs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", api.KeyType_READER
+	newPKs, newSigs := api.NewTestProvenPublicKeys(rng, entityID, kt, 1)
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     NewDefaultConfig().WithRequireProofOfPossession(true),
+		storer:     &fixedStorer{},
+	}
+	rq := &api.RotatePublicKeysRequest{
+		EntityId:      entityID,
+		KeyType:       kt,
+		OldPublicKeys: [][]byte{api.NewTestPublicKey(rng)},
+		NewPublicKeys: newPKs,
+		NewKeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+		NewSignatures: newSigs,
+	}
+	rp, err := k.RotatePublicKeys(context.Background(), rq)
+	assert.Nil(t, err)
+	assert.NotNil(t, rp)
+
+	rq.KeyType = api.KeyType_AUTHOR
+	rp, err = k.RotatePublicKeys(context.Background(), rq)
+	assert.Equal(t, codes.PermissionDenied, status.Convert(err).Code())
+	assert.Nil(t, rp)
+}
+
+func TestKey_RotatePublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	baseServer := bserver.NewBaseServer(bserver.NewDefaultBaseConfig())
+	okRq := &api.RotatePublicKeysRequest{
+		EntityId:      "some entity ID",
+		KeyType:       api.KeyType_READER,
+		OldPublicKeys: [][]byte{api.NewTestPublicKey(rng)},
+		NewPublicKeys: [][]byte{api.NewTestPublicKey(rng)},
+		NewKeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+	}
+	cases := map[string]struct {
+		k        *Key
+		rq       *api.RotatePublicKeysRequest
+		expected error
+	}{
+		"bad request": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{},
+			},
+			rq:       &api.RotatePublicKeysRequest{},
+			expected: status.Error(codes.InvalidArgument, api.ErrEmptyEntityID.Error()),
+		},
+		"missing public key": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{rotateErr: api.ErrNoSuchPublicKey},
+			},
+			rq:       okRq,
+			expected: status.Error(codes.NotFound, api.ErrNoSuchPublicKey.Error()),
+		},
+		"existing public key": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{rotateErr: storage.ErrPublicKeyExists},
+			},
+			rq:       okRq,
+			expected: status.Error(codes.AlreadyExists, storage.ErrPublicKeyExists.Error()),
+		},
+		"too many active": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{rotateErr: storage.ErrTooManyActivePublicKeys},
+			},
+			rq:       okRq,
+			expected: ErrTooManyActivePublicKeys,
+		},
+		"storer rotate error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{rotateErr: errTest},
+			},
+			rq:       okRq,
+			expected: ErrInternal,
+		},
+		"storer get rotated error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{getErr: errTest},
+			},
+			rq:       okRq,
+			expected: ErrInternal,
+		},
+		"storer append audit records error": {
+			k: &Key{
+				BaseServer: baseServer,
+				storer:     &fixedStorer{appendAuditErr: errTest},
+			},
+			rq:       okRq,
+			expected: ErrInternal,
+		},
+	}
+	for desc, c := range cases {
+		rp, err := c.k.RotatePublicKeys(context.Background(), c.rq)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, rp, desc)
+	}
+}
+
 type fixedStorer struct {
 	addErr              error
 	getPKDs             []*api.PublicKeyDetail
@@ -290,26 +821,163 @@ type fixedStorer struct {
 	countEntityPKsErr   error
 	getEntityPKs        []*api.PublicKeyDetail
 	getEntityPKsErr     error
+	disableErr          error
+	rotateErr           error
+	listPKDs            []*api.PublicKeyDetail
+	listErr             error
+	listRecords         []*api.PublicKeyRecord
+	listRecordsErr      error
+	putRecordsErr       error
+	auditRecords        []*api.AuditRecord
+	appendAuditErr      error
+	listAuditErr        error
+	asOf                time.Time
 }
 
-func (f *fixedStorer) CountEntityPublicKeys(entityID string, kt api.KeyType) (int, error) {
+func (f *fixedStorer) CountEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) (int, error) {
 	return f.countEntityPKsValue, f.countEntityPKsErr
 }
 
 func (f *fixedStorer) GetEntityPublicKeys(
-	entityID string, kt api.KeyType,
+	ctx context.Context, entityID string, kt api.KeyType,
+) ([]*api.PublicKeyDetail, error) {
+	return f.getEntityPKs, f.getEntityPKsErr
+}
+
+func (f *fixedStorer) GetEntityPublicKeysAsOf(
+	ctx context.Context, entityID string, kt api.KeyType, asOf time.Time,
 ) ([]*api.PublicKeyDetail, error) {
+	f.asOf = asOf
 	return f.getEntityPKs, f.getEntityPKsErr
 }
 
-func (f *fixedStorer) AddPublicKeys(pkds []*api.PublicKeyDetail) error {
-	return f.addErr
+func (f *fixedStorer) AddPublicKeys(
+	ctx context.Context, pkds []*api.PublicKeyDetail, audit []*api.AuditRecord,
+) error {
+	if f.addErr != nil {
+		return f.addErr
+	}
+	return f.AppendAuditRecords(ctx, audit)
 }
 
-func (f *fixedStorer) GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error) {
+func (f *fixedStorer) GetPublicKeys(
+	ctx context.Context, pks [][]byte,
+) ([]*api.PublicKeyDetail, error) {
+	return f.getPKDs, f.getErr
+}
+
+func (f *fixedStorer) DisablePublicKeys(
+	ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+) error {
+	if f.disableErr != nil {
+		return f.disableErr
+	}
+	return f.AppendAuditRecords(ctx, audit)
+}
+
+func (f *fixedStorer) RotatePublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType, oldPKs, newPKs [][]byte,
+	audit []*api.AuditRecord,
+) error {
+	if f.rotateErr != nil {
+		return f.rotateErr
+	}
+	return f.AppendAuditRecords(ctx, audit)
+}
+
+func (f *fixedStorer) ListPublicKeys(
+	ctx context.Context, filter *storage.ListFilter, after []byte, limit uint,
+) ([]*api.PublicKeyDetail, error) {
+	if uint(len(f.listPKDs)) > limit {
+		return f.listPKDs[:limit], f.listErr
+	}
+	return f.listPKDs, f.listErr
+}
+
+func (f *fixedStorer) ListPublicKeyRecords(
+	ctx context.Context, after []byte, limit uint,
+) ([]*api.PublicKeyRecord, error) {
+	if f.listRecordsErr != nil {
+		return nil, f.listRecordsErr
+	}
+	records := make([]*api.PublicKeyRecord, 0, limit)
+	for _, r := range f.listRecords {
+		if uint(len(records)) == limit {
+			break
+		}
+		if after == nil || bytes.Compare(r.PublicKeyDetail.PublicKey, after) > 0 {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func (f *fixedStorer) PutPublicKeyRecords(
+	ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+) error {
+	if f.putRecordsErr != nil {
+		return f.putRecordsErr
+	}
+	return f.AppendAuditRecords(ctx, audit)
+}
+
+func (f *fixedStorer) AppendAuditRecords(
+	ctx context.Context, records []*api.AuditRecord,
+) error {
+	if f.appendAuditErr != nil {
+		return f.appendAuditErr
+	}
+	var last *api.AuditRecord
+	if len(f.auditRecords) > 0 {
+		last = f.auditRecords[len(f.auditRecords)-1]
+	}
+	storage.ChainAuditRecords(last, records)
+	f.auditRecords = append(f.auditRecords, records...)
+	return nil
+}
+
+func (f *fixedStorer) ListAuditRecords(
+	ctx context.Context, afterSeq uint64, limit uint,
+) ([]*api.AuditRecord, error) {
+	if f.listAuditErr != nil {
+		return nil, f.listAuditErr
+	}
+	if afterSeq >= uint64(len(f.auditRecords)) {
+		return []*api.AuditRecord{}, nil
+	}
+	end := afterSeq + uint64(limit)
+	if end > uint64(len(f.auditRecords)) {
+		end = uint64(len(f.auditRecords))
+	}
+	return f.auditRecords[afterSeq:end], nil
+}
+
+func (f *fixedStorer) GetPublicKeysAsOf(
+	ctx context.Context, pks [][]byte, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	f.asOf = asOf
 	return f.getPKDs, f.getErr
 }
 
 func (f *fixedStorer) Close() error {
 	return nil
 }
+
+func TestGetListFilter(t *testing.T) {
+	rq := &api.ListPublicKeysRequest{
+		EntityId:       "some entity ID",
+		KeyTypes:       []api.KeyType{api.KeyType_READER},
+		ModifiedAfter:  1520000000000000,
+		ModifiedBefore: 1530000000000000,
+		Status:         api.KeyStatus_REVOKED,
+	}
+	filter := getListFilter(rq)
+	assert.Equal(t, rq.EntityId, filter.EntityID)
+	assert.Equal(t, rq.KeyTypes, filter.KeyTypes)
+	assert.True(t, filter.AddedAfter.IsZero())
+	assert.Equal(t, time.Unix(1520000000, 0), filter.ModifiedAfter)
+	assert.Equal(t, time.Unix(1530000000, 0), filter.ModifiedBefore)
+	assert.Equal(t, rq.Status, filter.Status)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrResponsesNotSigned indicates when the signing key is requested from a server that
+	// doesn't sign responses.
+	ErrResponsesNotSigned = status.Error(codes.FailedPrecondition,
+		"server doesn't sign responses")
+
+	// ErrNoPrivateKey indicates when a signing key file has no PEM private key.
+	ErrNoPrivateKey = errors.New("no PEM private key found")
+
+	// ErrNotEd25519Key indicates when a signing key file's private key isn't an Ed25519 key.
+	ErrNotEd25519Key = errors.New("private key is not an Ed25519 key")
+)
+
+// LoadSigningKey returns the Ed25519 private key in the given PEM file, which has it in PKCS #8
+// form, as generated by "openssl genpkey -algorithm ed25519".
+func LoadSigningKey(keyFile string) (ed25519.PrivateKey, error) {
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, ErrNoPrivateKey
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrNotEd25519Key
+	}
+	return signingKey, nil
+}
+
+// GetSigningKey returns the public key that verifies the signatures of the server's responses.
+// Clients should check it against the signing key they got out of band before trusting it.
+func (k *Key) GetSigningKey(
+	ctx context.Context, rq *api.GetSigningKeyRequest,
+) (*api.GetSigningKeyResponse, error) {
+	k.Logger.Debug("received get signing key request")
+	if !k.signResponses() {
+		k.Logger.Info("responses not signed")
+		return nil, ErrResponsesNotSigned
+	}
+	rp := &api.GetSigningKeyResponse{
+		SigningKey: k.config.SigningKey.Public().(ed25519.PublicKey),
+		KeyFormat:  api.KeyFormat_ED25519,
+	}
+	k.Logger.Info("got signing key")
+	return rp, nil
+}
+
+func (k *Key) signResponses() bool {
+	return k.config != nil && k.config.SigningKey != nil
+}
+
+// signedTime returns the current time in epoch micros, for signing responses with.
+func signedTime() int64 {
+	return time.Now().UnixNano() / int64(time.Microsecond)
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	bserver "github.com/elixirhealth/service-base/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSigningKey_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	dir, err := ioutil.TempDir("", "key-signing-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	_, key, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err)
+	keyFile := filepath.Join(dir, "signing.key")
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER)
+
+	loaded, err := LoadSigningKey(keyFile)
+	assert.Nil(t, err)
+	assert.Equal(t, key, loaded)
+}
+
+func TestLoadSigningKey_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	dir, _, _, ecKeyFile := writeTestTLSFiles(t)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	notPEMFile := filepath.Join(dir, "not-pem.key")
+	assert.Nil(t, ioutil.WriteFile(notPEMFile, []byte("not PEM"), 0600))
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	assert.Nil(t, err)
+	ecKeyDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.Nil(t, err)
+	pkcs8ECKeyFile := filepath.Join(dir, "ec-pkcs8.key")
+	writePEM(t, pkcs8ECKeyFile, "PRIVATE KEY", ecKeyDER)
+
+	cases := map[string]struct {
+		keyFile  string
+		expected error
+	}{
+		"missing file": {keyFile: filepath.Join(dir, "does-not-exist.key")},
+		"not PEM":      {keyFile: notPEMFile, expected: ErrNoPrivateKey},
+		"not PKCS #8":  {keyFile: ecKeyFile},
+		"not Ed25519":  {keyFile: pkcs8ECKeyFile, expected: ErrNotEd25519Key},
+	}
+	for desc, c := range cases {
+		key, err := LoadSigningKey(c.keyFile)
+		assert.NotNil(t, err, desc)
+		if c.expected != nil {
+			assert.Equal(t, c.expected, err, desc)
+		}
+		assert.Nil(t, key, desc)
+	}
+}
+
+func TestKey_GetSigningKey(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     NewDefaultConfig().WithSigningKey(sk),
+	}
+	rp, err := k.GetSigningKey(context.Background(), &api.GetSigningKeyRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, &api.GetSigningKeyResponse{
+		SigningKey: pk,
+		KeyFormat:  api.KeyFormat_ED25519,
+	}, rp)
+
+	k.config.WithSigningKey(nil)
+	rp, err = k.GetSigningKey(context.Background(), &api.GetSigningKeyRequest{})
+	assert.Equal(t, ErrResponsesNotSigned, err)
+	assert.Nil(t, rp)
+}
+
+func TestKey_signedResponses(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	pkds := api.NewTestPublicKeyDetails(rng, 2)
+	k := &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     NewDefaultConfig().WithSigningKey(sk),
+		storer: &fixedStorer{
+			getEntityPKs: pkds,
+			getPKDs:      []*api.PublicKeyDetail{nil, pkds[1]},
+		},
+	}
+
+	rq1 := &api.GetPublicKeysRequest{EntityId: "some entity ID"}
+	rp1, err := k.GetPublicKeys(ctx, rq1)
+	assert.Nil(t, err)
+	assert.NotZero(t, rp1.Signature.Time)
+	assert.Nil(t, api.VerifyGetPublicKeysResponse(pk, rq1, rp1))
+
+	rq2 := &api.GetPublicKeyDetailsRequest{
+		PublicKeys: [][]byte{pkds[0].PublicKey, pkds[1].PublicKey},
+		Partial:    true,
+	}
+	rp2, err := k.GetPublicKeyDetails(ctx, rq2)
+	assert.Nil(t, err)
+	assert.Nil(t, api.VerifyGetPublicKeyDetailsResponse(pk, rq2, rp2))
+
+	rq3 := &api.SamplePublicKeysRequest{
+		OfEntityId:        "some entity ID",
+		RequesterEntityId: "another entity ID",
+		NPublicKeys:       2,
+	}
+	rp3, err := k.SamplePublicKeys(ctx, rq3)
+	assert.Nil(t, err)
+	assert.Nil(t, api.VerifySamplePublicKeysResponse(pk, rq3, rp3))
+
+	// responses aren't signed without a signing key
+	k.config.WithSigningKey(nil)
+	rp1, err = k.GetPublicKeys(ctx, rq1)
+	assert.Nil(t, err)
+	assert.Nil(t, rp1.Signature)
+}
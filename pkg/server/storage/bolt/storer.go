@@ -0,0 +1,672 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/golang/protobuf/proto"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultOpenTimeout is how long New waits for the lock on the DB file, which only one
+	// process can hold at a time.
+	DefaultOpenTimeout = 1 * time.Second
+
+	dbFileMode = 0600
+)
+
+var (
+	// publicKeysBucket maps each public key to its encoded public key record, so the records
+	// are ordered by public key.
+	publicKeysBucket = []byte("public_keys")
+
+	// entityKeyTypeIndexBucket indexes the public keys of each entity and key type, with keys
+	// of the entity key type prefix followed by the public key and empty values.
+	entityKeyTypeIndexBucket = []byte("entity_key_type_index")
+
+	// auditBucket maps each audit record's big-endian sequence number to the encoded audit
+	// record, so the records are ordered by sequence number.
+	auditBucket = []byte("audit")
+
+	errEmptyDBPath = errors.New("empty Bolt DB path")
+)
+
+type storer struct {
+	db     *bolt.DB
+	params *storage.Parameters
+	logger *zap.Logger
+}
+
+// New creates a new Storer backed by the embedded Bolt DB file at the given path, creating it if
+// it doesn't exist.
+func New(dbPath string, params *storage.Parameters, logger *zap.Logger) (storage.Storer, error) {
+	if dbPath == "" {
+		return nil, errEmptyDBPath
+	}
+	db, err := bolt.Open(dbPath, dbFileMode, &bolt.Options{Timeout: DefaultOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{publicKeysBucket, entityKeyTypeIndexBucket, auditBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	logger.Info("opened Bolt DB", zap.String(logDBPath, dbPath))
+	return &storer{
+		db:     db,
+		params: params,
+		logger: logger,
+	}, nil
+}
+
+func (s *storer) AddPublicKeys(
+	ctx context.Context, pkds []*api.PublicKeyDetail, audit []*api.AuditRecord,
+) error {
+	if err := api.ValidatePublicKeyDetails(pkds); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(pkds) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	ekts, counts := storage.CountEntityKeyTypes(pkds)
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		for _, pkd := range pkds {
+			if exists(tx, pkd.PublicKey) {
+				return storage.ErrPublicKeyExists
+			}
+		}
+		for _, ekt := range ekts {
+			nActive, err := countActive(tx, ekt.EntityID, ekt.KeyType)
+			if err != nil {
+				return err
+			}
+			if nActive+counts[ekt] > storage.MaxEntityKeyTypeKeys {
+				return storage.ErrTooManyActivePublicKeys
+			}
+		}
+		now := time.Now()
+		for _, pkd := range pkds {
+			if err := putRecord(tx, storage.NewPublicKeyRecord(pkd, now, time.Time{})); err != nil {
+				return err
+			}
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("added public keys to storage", zap.Int(logNPublicKeys, len(pkds)))
+	return nil
+}
+
+func (s *storer) GetPublicKeys(ctx context.Context, pks [][]byte) ([]*api.PublicKeyDetail, error) {
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return nil, err
+	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return nil, storage.ErrMaxBatchSizeExceeded
+	}
+	pkds := make([]*api.PublicKeyDetail, len(pks))
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		for i, pk := range pks {
+			r, err := getRecord(tx, pk)
+			if err != nil {
+				return err
+			}
+			if r != nil {
+				pkds[i] = r.PublicKeyDetail
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("got public keys from storage", zap.Int(logNPublicKeys, len(pkds)))
+	return pkds, nil
+}
+
+func (s *storer) GetPublicKeysAsOf(
+	ctx context.Context, pks [][]byte, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return nil, err
+	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return nil, storage.ErrMaxBatchSizeExceeded
+	}
+	pkds := make([]*api.PublicKeyDetail, len(pks))
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		for i, pk := range pks {
+			r, err := getRecord(tx, pk)
+			if err != nil {
+				return err
+			}
+			if r != nil {
+				pkds[i] = detailAsOf(r, asOf)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("got public keys as of time from storage",
+		logGetPubKeysAsOf(asOf, pkds)...)
+	return pkds, nil
+}
+
+func (s *storer) GetEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) ([]*api.PublicKeyDetail, error) {
+	if entityID == "" {
+		return nil, api.ErrEmptyEntityID
+	}
+	pkds := make([]*api.PublicKeyDetail, 0, storage.MaxEntityKeyTypeKeys)
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		records, err := getEntityRecords(tx, entityID, kt)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if !r.PublicKeyDetail.Disabled {
+				pkds = append(pkds, r.PublicKeyDetail)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("found public keys for entity", logGetEntityPubKeys(entityID, pkds)...)
+	return pkds, nil
+}
+
+func (s *storer) GetEntityPublicKeysAsOf(
+	ctx context.Context, entityID string, kt api.KeyType, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if entityID == "" {
+		return nil, api.ErrEmptyEntityID
+	}
+	pkds := make([]*api.PublicKeyDetail, 0, storage.MaxEntityKeyTypeKeys)
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		records, err := getEntityRecords(tx, entityID, kt)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if pkdAsOf := detailAsOf(r, asOf); pkdAsOf != nil && !pkdAsOf.Disabled {
+				pkds = append(pkds, pkdAsOf)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("found public keys for entity as of time",
+		logGetEntityPubKeysAsOf(entityID, asOf, pkds)...)
+	return pkds, nil
+}
+
+func (s *storer) CountEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) (int, error) {
+	if entityID == "" {
+		return 0, api.ErrEmptyEntityID
+	}
+	var c int
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		var err error
+		c, err = countActive(tx, entityID, kt)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Debug("counted public keys for entity", logCountEntityPubKeys(entityID, kt)...)
+	return c, nil
+}
+
+func (s *storer) DisablePublicKeys(
+	ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+) error {
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		records, err := getOwnedRecords(tx, entityID, pks, nil)
+		if err != nil {
+			return err
+		}
+		if err := disableRecords(tx, records, time.Now()); err != nil {
+			return err
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("disabled public keys in storage", logDisablePubKeys(entityID, pks)...)
+	return nil
+}
+
+func (s *storer) RotatePublicKeys(
+	ctx context.Context,
+	entityID string,
+	kt api.KeyType,
+	oldPKs, newPKs [][]byte,
+	audit []*api.AuditRecord,
+) error {
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(oldPKs); err != nil {
+		return err
+	}
+	if err := api.ValidatePublicKeys(newPKs); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(oldPKs) > int(s.params.MaxBatchSize) || len(newPKs) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		oldRecords, err := getOwnedRecords(tx, entityID, oldPKs, &kt)
+		if err != nil {
+			return err
+		}
+		for _, pk := range newPKs {
+			if exists(tx, pk) {
+				return storage.ErrPublicKeyExists
+			}
+		}
+		nActive, err := countActive(tx, entityID, kt)
+		if err != nil {
+			return err
+		}
+		nOldActive := 0
+		for _, r := range oldRecords {
+			if !r.PublicKeyDetail.Disabled {
+				nOldActive++
+			}
+		}
+		if nActive-nOldActive+len(newPKs) > storage.MaxEntityKeyTypeKeys {
+			return storage.ErrTooManyActivePublicKeys
+		}
+		now := time.Now()
+		if err := disableRecords(tx, oldRecords, now); err != nil {
+			return err
+		}
+		for _, pk := range newPKs {
+			pkd := &api.PublicKeyDetail{PublicKey: pk, EntityId: entityID, KeyType: kt}
+			if err := putRecord(tx, storage.NewPublicKeyRecord(pkd, now, time.Time{})); err != nil {
+				return err
+			}
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("rotated public keys in storage",
+		logRotatePubKeys(entityID, kt, oldPKs, newPKs)...)
+	return nil
+}
+
+func (s *storer) ListPublicKeys(
+	ctx context.Context, filter *storage.ListFilter, after []byte, limit uint,
+) ([]*api.PublicKeyDetail, error) {
+	pkds := make([]*api.PublicKeyDetail, 0)
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		return forEachRecordAfter(tx, after, func(r *api.PublicKeyRecord) bool {
+			if uint(len(pkds)) >= limit {
+				return false
+			}
+			added, disabled := storage.RecordTimes(r)
+			if filter.Matches(r.PublicKeyDetail, added, modified(added, disabled)) {
+				pkds = append(pkds, r.PublicKeyDetail)
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("listed public keys from storage", zap.Int(logNPublicKeys, len(pkds)))
+	return pkds, nil
+}
+
+func (s *storer) ListPublicKeyRecords(
+	ctx context.Context, after []byte, limit uint,
+) ([]*api.PublicKeyRecord, error) {
+	records := make([]*api.PublicKeyRecord, 0)
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		return forEachRecordAfter(tx, after, func(r *api.PublicKeyRecord) bool {
+			if uint(len(records)) >= limit {
+				return false
+			}
+			records = append(records, r)
+			return true
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("listed public key records from storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return records, nil
+}
+
+func (s *storer) PutPublicKeyRecords(
+	ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+) error {
+	if err := api.ValidatePublicKeyRecords(records); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(records) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		for _, r := range records {
+			if exists(tx, r.PublicKeyDetail.PublicKey) {
+				return storage.ErrPublicKeyExists
+			}
+		}
+		for _, r := range records {
+			if err := putRecord(tx, r); err != nil {
+				return err
+			}
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("put public key records into storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return nil
+}
+
+func (s *storer) AppendAuditRecords(ctx context.Context, records []*api.AuditRecord) error {
+	if err := api.ValidateAuditRecords(records); err != nil {
+		return err
+	}
+	err := s.update(ctx, func(tx *bolt.Tx) error {
+		return appendAuditRecords(tx, records)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("appended audit records to storage", logAppendedAuditRecords(records)...)
+	return nil
+}
+
+func (s *storer) ListAuditRecords(
+	ctx context.Context, afterSeq uint64, limit uint,
+) ([]*api.AuditRecord, error) {
+	records := make([]*api.AuditRecord, 0)
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		c := tx.Bucket(auditBucket).Cursor()
+		for k, v := c.Seek(sequenceKey(afterSeq + 1)); k != nil; k, v = c.Next() {
+			if uint(len(records)) >= limit {
+				return nil
+			}
+			r := &api.AuditRecord{}
+			if err := proto.Unmarshal(v, r); err != nil {
+				return err
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("listed audit records from storage", zap.Int(logNAuditRecords, len(records)))
+	return records, nil
+}
+
+func (s *storer) Close() error {
+	return s.db.Close()
+}
+
+// view runs the function within a read-only transaction, unless the context is already done.
+// Bolt transactions can't be interrupted, so the context is only checked before starting one.
+func (s *storer) view(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.View(fn)
+}
+
+// update runs the function within a read-write transaction, unless the context is already done,
+// committing it if the function returns nil and rolling it back otherwise.
+func (s *storer) update(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(fn)
+}
+
+// appendAuditRecords chains the audit records after the last one in the audit bucket and puts
+// them in it.
+func appendAuditRecords(tx *bolt.Tx, records []*api.AuditRecord) error {
+	b := tx.Bucket(auditBucket)
+	var last *api.AuditRecord
+	if _, value := b.Cursor().Last(); value != nil {
+		last = &api.AuditRecord{}
+		if err := proto.Unmarshal(value, last); err != nil {
+			return err
+		}
+	}
+	storage.ChainAuditRecords(last, records)
+	for _, r := range records {
+		value, err := proto.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(sequenceKey(r.Sequence), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exists(tx *bolt.Tx, pk []byte) bool {
+	return tx.Bucket(publicKeysBucket).Get(pk) != nil
+}
+
+// getRecord returns the public key's record, or nil if it doesn't exist.
+func getRecord(tx *bolt.Tx, pk []byte) (*api.PublicKeyRecord, error) {
+	value := tx.Bucket(publicKeysBucket).Get(pk)
+	if value == nil {
+		return nil, nil
+	}
+	return decodeRecord(value)
+}
+
+// putRecord writes the public key record and indexes its public key by entity and key type.
+func putRecord(tx *bolt.Tx, r *api.PublicKeyRecord) error {
+	value, err := proto.Marshal(r)
+	if err != nil {
+		return err
+	}
+	pkd := r.PublicKeyDetail
+	if err := tx.Bucket(publicKeysBucket).Put(pkd.PublicKey, value); err != nil {
+		return err
+	}
+	indexKey := append(entityKeyTypePrefix(pkd.EntityId, pkd.KeyType), pkd.PublicKey...)
+	return tx.Bucket(entityKeyTypeIndexBucket).Put(indexKey, []byte{})
+}
+
+func decodeRecord(value []byte) (*api.PublicKeyRecord, error) {
+	r := &api.PublicKeyRecord{}
+	if err := proto.Unmarshal(value, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// getOwnedRecords returns the records of the public keys, returning api.ErrNoSuchPublicKey if any
+// of them doesn't exist, belongs to another entity, or, if kt isn't nil, has another key type.
+func getOwnedRecords(
+	tx *bolt.Tx, entityID string, pks [][]byte, kt *api.KeyType,
+) ([]*api.PublicKeyRecord, error) {
+	records := make([]*api.PublicKeyRecord, len(pks))
+	for i, pk := range pks {
+		r, err := getRecord(tx, pk)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil || r.PublicKeyDetail.EntityId != entityID ||
+			(kt != nil && r.PublicKeyDetail.KeyType != *kt) {
+			return nil, api.ErrNoSuchPublicKey
+		}
+		records[i] = r
+	}
+	return records, nil
+}
+
+// disableRecords disables each of the records that isn't disabled already as of the given time.
+func disableRecords(tx *bolt.Tx, records []*api.PublicKeyRecord, now time.Time) error {
+	for _, r := range records {
+		if r.PublicKeyDetail.Disabled {
+			continue
+		}
+		disabled := *r.PublicKeyDetail
+		disabled.Disabled = true
+		added, _ := storage.RecordTimes(r)
+		if err := putRecord(tx, storage.NewPublicKeyRecord(&disabled, added, now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEntityRecords returns the records of all the public keys of the entity and key type, both
+// active and disabled, using the entity key type index.
+func getEntityRecords(
+	tx *bolt.Tx, entityID string, kt api.KeyType,
+) ([]*api.PublicKeyRecord, error) {
+	prefix := entityKeyTypePrefix(entityID, kt)
+	records := make([]*api.PublicKeyRecord, 0)
+	c := tx.Bucket(entityKeyTypeIndexBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		r, err := getRecord(tx, k[len(prefix):])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func countActive(tx *bolt.Tx, entityID string, kt api.KeyType) (int, error) {
+	records, err := getEntityRecords(tx, entityID, kt)
+	if err != nil {
+		return 0, err
+	}
+	c := 0
+	for _, r := range records {
+		if !r.PublicKeyDetail.Disabled {
+			c++
+		}
+	}
+	return c, nil
+}
+
+// forEachRecordAfter calls fn on each public key record in public key order, starting after the
+// given public key if it isn't nil, until fn returns false.
+func forEachRecordAfter(tx *bolt.Tx, after []byte, fn func(r *api.PublicKeyRecord) bool) error {
+	c := tx.Bucket(publicKeysBucket).Cursor()
+	k, v := c.First()
+	if after != nil {
+		k, v = c.Seek(after)
+		if k != nil && bytes.Equal(k, after) {
+			k, v = c.Next()
+		}
+	}
+	for ; k != nil; k, v = c.Next() {
+		r, err := decodeRecord(v)
+		if err != nil {
+			return err
+		}
+		if !fn(r) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// entityKeyTypePrefix returns the prefix of the entity key type index keys for the entity and key
+// type. The entity ID is length-prefixed so no entity's prefix is a prefix of another's.
+func entityKeyTypePrefix(entityID string, kt api.KeyType) []byte {
+	prefix := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(entityID)+4)
+	n := binary.PutUvarint(prefix, uint64(len(entityID)))
+	prefix = append(prefix[:n], entityID...)
+	ktBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(ktBytes, uint32(kt))
+	return append(prefix, ktBytes...)
+}
+
+// sequenceKey returns the audit bucket key of the sequence number, which is big-endian so the keys
+// sort in sequence order.
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// detailAsOf returns the record's public key detail as it was at the given time, or nil if it
+// didn't exist then.
+func detailAsOf(r *api.PublicKeyRecord, asOf time.Time) *api.PublicKeyDetail {
+	added, disabled := storage.RecordTimes(r)
+	if added.After(asOf) {
+		return nil
+	}
+	pkdAsOf := *r.PublicKeyDetail
+	pkdAsOf.Disabled = !disabled.IsZero() && !disabled.After(asOf)
+	return &pkdAsOf
+}
+
+// modified returns when the public key was last modified, i.e., when it was disabled or, if it
+// hasn't been, added.
+func modified(added, disabled time.Time) time.Time {
+	if !disabled.IsZero() {
+		return disabled
+	}
+	return added
+}
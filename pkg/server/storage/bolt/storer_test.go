@@ -0,0 +1,730 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/service-base/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNew_err(t *testing.T) {
+	s, err := New("", storage.NewDefaultParameters(), zap.NewNop())
+	assert.Equal(t, errEmptyDBPath, err)
+	assert.Nil(t, s)
+
+	s, err = New("/does/not/exist/key.db", storage.NewDefaultParameters(), zap.NewNop())
+	assert.NotNil(t, err)
+	assert.Nil(t, s)
+}
+
+func TestBoltStorer_reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-bolt-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	dbPath := filepath.Join(dir, "key.db")
+	params := storage.NewDefaultParameters()
+
+	s1, err := New(dbPath, params, zap.NewNop())
+	assert.Nil(t, err)
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 8)
+	err = s1.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	err = s1.DisablePublicKeys(context.Background(), pkds[0].EntityId,
+		[][]byte{pkds[0].PublicKey}, nil)
+	assert.Nil(t, err)
+	records1, err := s1.ListPublicKeyRecords(context.Background(), nil, 64)
+	assert.Nil(t, err)
+
+	// only one process can open the DB at a time
+	s2, err := New(dbPath, params, zap.NewNop())
+	assert.NotNil(t, err)
+	assert.Nil(t, s2)
+
+	// public keys survive closing and reopening the DB
+	err = s1.Close()
+	assert.Nil(t, err)
+	s2, err = New(dbPath, params, zap.NewNop())
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, s2.Close()) }()
+	records2, err := s2.ListPublicKeyRecords(context.Background(), nil, 64)
+	assert.Nil(t, err)
+	assert.Equal(t, records1, records2)
+	n, err := s2.CountEntityPublicKeys(context.Background(), pkds[0].EntityId, pkds[0].KeyType)
+	assert.Nil(t, err)
+	assert.NotZero(t, n)
+}
+
+func TestBoltStorer_AddGetPublicKeys_ok(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	pubKeys := make([][]byte, len(pkds1))
+	for i, pkd := range pkds1 {
+		pubKeys[i] = pkd.PublicKey
+	}
+	pkds2, err := s.GetPublicKeys(context.Background(), pubKeys)
+	assert.Nil(t, err)
+	assert.Equal(t, pkds1, pkds2)
+
+	// missing key has a nil detail in its place
+	missingPK := []byte{1, 2, 3}
+	pkds2, err = s.GetPublicKeys(context.Background(), [][]byte{missingPK, pubKeys[0]})
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil, pkds1[0]}, pkds2)
+}
+
+func TestBoltStorer_AddPublicKeys_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	params.MaxBatchSize = storage.MaxEntityKeyTypeKeys + 1
+	s, cleanup := newTestStorer(t, params)
+	defer cleanup()
+
+	// empty public key details
+	err := s.AddPublicKeys(context.Background(), nil, nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+
+	// too many active keys
+	rng := rand.New(rand.NewSource(0))
+	pkds := make([]*api.PublicKeyDetail, storage.MaxEntityKeyTypeKeys+1)
+	for i := range pkds {
+		pkds[i] = &api.PublicKeyDetail{
+			PublicKey: util.RandBytes(rng, 33),
+			EntityId:  "some entity ID",
+			KeyType:   api.KeyType_READER,
+		}
+	}
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+	err = s.AddPublicKeys(context.Background(), pkds[1:], nil)
+	assert.Nil(t, err)
+	err = s.AddPublicKeys(context.Background(), pkds[:1], nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+
+	// existing key
+	err = s.AddPublicKeys(context.Background(), pkds[1:2], nil)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+
+	// too many keys
+	err = s.AddPublicKeys(context.Background(), api.NewTestPublicKeyDetails(rng,
+		int(params.MaxBatchSize)+1), nil)
+	assert.Equal(t, storage.ErrMaxBatchSizeExceeded, err)
+
+	// done context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = s.AddPublicKeys(ctx, api.NewTestPublicKeyDetails(rng, 1), nil)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBoltStorer_AddPublicKeys_exists(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	s, cleanup := newTestStorer(t, params)
+	defer cleanup()
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 2)
+	err := s.AddPublicKeys(context.Background(), pkds1[:1], nil)
+	assert.Nil(t, err)
+	err = s.DisablePublicKeys(context.Background(), pkds1[0].EntityId,
+		[][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// re-adding a revoked key, alone, with another, or for another entity
+	another := *pkds1[0]
+	another.EntityId = "another entity ID"
+	for _, pkds := range [][]*api.PublicKeyDetail{pkds1[:1], pkds1, {&another}} {
+		err = s.AddPublicKeys(context.Background(), pkds, nil)
+		assert.Equal(t, storage.ErrPublicKeyExists, err)
+	}
+
+	pkds2, err := s.GetPublicKeys(context.Background(),
+		[][]byte{pkds1[0].PublicKey, pkds1[1].PublicKey})
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	assert.Equal(t, pkds1[0].EntityId, pkds2[0].EntityId)
+	assert.Nil(t, pkds2[1])
+}
+
+func TestBoltStorer_AddPublicKeys_concurrent(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+	rng := rand.New(rand.NewSource(0))
+	nAdders, nAdderKeys := 64, 8
+	entityID, kt := "some entity ID", api.KeyType_READER
+
+	adderPKDs := make([][]*api.PublicKeyDetail, nAdders)
+	for i := range adderPKDs {
+		adderPKDs[i] = make([]*api.PublicKeyDetail, nAdderKeys)
+		for j := range adderPKDs[i] {
+			adderPKDs[i][j] = &api.PublicKeyDetail{
+				PublicKey: util.RandBytes(rng, 33),
+				EntityId:  entityID,
+				KeyType:   kt,
+			}
+		}
+	}
+	errs := make(chan error, nAdders)
+	wg := new(sync.WaitGroup)
+	for _, pkds := range adderPKDs {
+		wg.Add(1)
+		go func(pkds []*api.PublicKeyDetail) {
+			defer wg.Done()
+			errs <- s.AddPublicKeys(context.Background(), pkds, nil)
+		}(pkds)
+	}
+	wg.Wait()
+	close(errs)
+
+	nAdded := 0
+	for err := range errs {
+		if err == nil {
+			nAdded += nAdderKeys
+		} else {
+			assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+		}
+	}
+	assert.Equal(t, storage.MaxEntityKeyTypeKeys, nAdded)
+	n, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, storage.MaxEntityKeyTypeKeys, n)
+}
+
+func TestBoltStorer_GetPublicKeys_err(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	// bad request
+	pkds, err := s.GetPublicKeys(context.Background(), nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+	assert.Nil(t, pkds)
+
+	// done context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pkds, err = s.GetPublicKeys(ctx, [][]byte{{1, 2, 3}})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, pkds)
+}
+
+func TestBoltStorer_GetCountEntityPublicKeys_ok(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	expected := make([]*api.PublicKeyDetail, 0)
+	for _, pkd1 := range pkds1 {
+		if pkd1.EntityId == entityID && pkd1.KeyType == kt {
+			expected = append(expected, pkd1)
+		}
+	}
+	pkds2, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, expected, pkds2)
+
+	n, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, len(expected), n)
+
+	// entity ID that's a prefix of another doesn't match its keys
+	pkds2, err = s.GetEntityPublicKeys(context.Background(), entityID[:len(entityID)-1], kt)
+	assert.Nil(t, err)
+	assert.Len(t, pkds2, 0)
+}
+
+func TestBoltStorer_GetCountEntityPublicKeys_err(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	pkds, err := s.GetEntityPublicKeys(context.Background(), "", api.KeyType_READER)
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+	assert.Nil(t, pkds)
+
+	n, err := s.CountEntityPublicKeys(context.Background(), "", api.KeyType_AUTHOR)
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+	assert.Zero(t, n)
+}
+
+func TestBoltStorer_DisablePublicKeys_ok(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	n1, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+
+	err = s.DisablePublicKeys(context.Background(), entityID, [][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// disabling again is a no-op
+	err = s.DisablePublicKeys(context.Background(), entityID, [][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	n2, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, n1-1, n2)
+
+	pkds2, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, n2, len(pkds2))
+	for _, pkd := range pkds2 {
+		assert.NotEqual(t, pkds1[0].PublicKey, pkd.PublicKey)
+	}
+
+	pkds3, err := s.GetPublicKeys(context.Background(), [][]byte{pkds1[0].PublicKey})
+	assert.Nil(t, err)
+	assert.True(t, pkds3[0].Disabled)
+	assert.False(t, pkds1[0].Disabled)
+}
+
+func TestBoltStorer_DisablePublicKeys_err(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	pkd := api.NewTestPublicKeyDetail(rng)
+	err := s.AddPublicKeys(context.Background(), []*api.PublicKeyDetail{pkd}, nil)
+	assert.Nil(t, err)
+
+	// empty entity ID
+	err = s.DisablePublicKeys(context.Background(), "", [][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+
+	// empty public keys
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId, nil, nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+
+	// missing key
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId, [][]byte{{1, 2, 3}}, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+
+	// key of another entity
+	err = s.DisablePublicKeys(context.Background(), "another entity ID",
+		[][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+}
+
+func TestBoltStorer_GetPublicKeysAsOf_ok(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	pks := [][]byte{pkds1[0].PublicKey}
+
+	beforeAdd := time.Now()
+	time.Sleep(time.Millisecond)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+	beforeDisable := time.Now()
+	time.Sleep(time.Millisecond)
+	err = s.DisablePublicKeys(context.Background(), entityID, pks, nil)
+	assert.Nil(t, err)
+
+	// key didn't exist before add
+	pkds2, err := s.GetPublicKeysAsOf(context.Background(), pks, beforeAdd)
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil}, pkds2)
+	pkds2, err = s.GetEntityPublicKeysAsOf(context.Background(), entityID, kt, beforeAdd)
+	assert.Nil(t, err)
+	assert.Len(t, pkds2, 0)
+
+	// key was active before disable
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), pks, beforeDisable)
+	assert.Nil(t, err)
+	assert.Equal(t, pkds1[0], pkds2[0])
+	pkds2, err = s.GetEntityPublicKeysAsOf(context.Background(), entityID, kt, beforeDisable)
+	assert.Nil(t, err)
+	assert.Contains(t, pkds2, pkds1[0])
+
+	// key is disabled now
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), pks, time.Now())
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	pkds2, err = s.GetEntityPublicKeysAsOf(context.Background(), entityID, kt, time.Now())
+	assert.Nil(t, err)
+	assert.NotContains(t, pkds2, pkds1[0])
+}
+
+func TestBoltStorer_GetPublicKeysAsOf_err(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	// bad request
+	pkds, err := s.GetPublicKeysAsOf(context.Background(), nil, time.Now())
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+	assert.Nil(t, pkds)
+
+	// missing entity ID
+	pkds, err = s.GetEntityPublicKeysAsOf(context.Background(), "", api.KeyType_READER, time.Now())
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+	assert.Nil(t, pkds)
+}
+
+func TestBoltStorer_ListPublicKeys_ok(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond)
+	beforeDisable := time.Now()
+	err = s.DisablePublicKeys(context.Background(), pkds1[0].EntityId,
+		[][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// page through all public keys
+	all := &storage.ListFilter{}
+	pkds2 := make([]*api.PublicKeyDetail, 0, len(pkds1))
+	var after []byte
+	for {
+		page, err := s.ListPublicKeys(context.Background(), all, after, 10)
+		assert.Nil(t, err)
+		assert.True(t, len(page) <= 10)
+		if len(page) == 0 {
+			break
+		}
+		pkds2 = append(pkds2, page...)
+		after = page[len(page)-1].PublicKey
+	}
+	assert.Equal(t, len(pkds1), len(pkds2))
+	for i := 1; i < len(pkds2); i++ {
+		assert.True(t, bytes.Compare(pkds2[i-1].PublicKey, pkds2[i].PublicKey) < 0)
+	}
+
+	// filtered
+	revoked := &storage.ListFilter{Status: api.KeyStatus_REVOKED}
+	pkds3, err := s.ListPublicKeys(context.Background(), revoked, nil, 10)
+	assert.Nil(t, err)
+	assert.Len(t, pkds3, 1)
+	assert.Equal(t, pkds1[0].PublicKey, pkds3[0].PublicKey)
+
+	entityKeyType := &storage.ListFilter{
+		EntityID: pkds1[0].EntityId,
+		KeyTypes: []api.KeyType{pkds1[0].KeyType},
+	}
+	pkds4, err := s.ListPublicKeys(context.Background(), entityKeyType, nil, 64)
+	assert.Nil(t, err)
+	for _, pkd := range pkds4 {
+		assert.Equal(t, pkds1[0].EntityId, pkd.EntityId)
+		assert.Equal(t, pkds1[0].KeyType, pkd.KeyType)
+	}
+
+	addedLater := &storage.ListFilter{AddedAfter: time.Now()}
+	pkds5, err := s.ListPublicKeys(context.Background(), addedLater, nil, 64)
+	assert.Nil(t, err)
+	assert.Len(t, pkds5, 0)
+
+	modifiedLater := &storage.ListFilter{ModifiedAfter: beforeDisable}
+	pkds6, err := s.ListPublicKeys(context.Background(), modifiedLater, nil, 64)
+	assert.Nil(t, err)
+	assert.Len(t, pkds6, 1)
+	assert.Equal(t, pkds1[0].PublicKey, pkds6[0].PublicKey)
+
+	modifiedEarlier := &storage.ListFilter{ModifiedBefore: beforeDisable}
+	pkds7, err := s.ListPublicKeys(context.Background(), modifiedEarlier, nil, 64)
+	assert.Nil(t, err)
+	assert.Len(t, pkds7, len(pkds1)-1)
+}
+
+func TestBoltStorer_ListPutPublicKeyRecords_ok(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	s1, cleanup1 := newTestStorer(t, params)
+	defer cleanup1()
+	s2, cleanup2 := newTestStorer(t, params)
+	defer cleanup2()
+
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 32)
+	err := s1.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	err = s1.DisablePublicKeys(context.Background(), pkds[0].EntityId,
+		[][]byte{pkds[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// page through all public key records, putting each page into the second storer
+	var after []byte
+	n := 0
+	for {
+		page, err := s1.ListPublicKeyRecords(context.Background(), after, 10)
+		assert.Nil(t, err)
+		assert.True(t, len(page) <= 10)
+		if len(page) == 0 {
+			break
+		}
+		err = s2.PutPublicKeyRecords(context.Background(), page, nil)
+		assert.Nil(t, err)
+		n += len(page)
+		after = page[len(page)-1].PublicKeyDetail.PublicKey
+	}
+	assert.Equal(t, len(pkds), n)
+
+	// second storer has the same details and times
+	records1, err := s1.ListPublicKeyRecords(context.Background(), nil, 64)
+	assert.Nil(t, err)
+	records2, err := s2.ListPublicKeyRecords(context.Background(), nil, 64)
+	assert.Nil(t, err)
+	assert.Equal(t, records1, records2)
+	for i := 1; i < len(records2); i++ {
+		pk1, pk2 := records2[i-1].PublicKeyDetail.PublicKey, records2[i].PublicKeyDetail.PublicKey
+		assert.True(t, bytes.Compare(pk1, pk2) < 0)
+	}
+	disabled, err := s2.GetPublicKeys(context.Background(), [][]byte{pkds[0].PublicKey})
+	assert.Nil(t, err)
+	assert.True(t, disabled[0].Disabled)
+
+	// put records are indexed by entity and key type
+	entityPKDs, err := s2.GetEntityPublicKeys(context.Background(), pkds[1].EntityId,
+		pkds[1].KeyType)
+	assert.Nil(t, err)
+	assert.Contains(t, entityPKDs, pkds[1])
+
+	// can't put existing records
+	err = s2.PutPublicKeyRecords(context.Background(), records1[:1], nil)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+}
+
+func TestBoltStorer_PutPublicKeyRecords_err(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	err := s.PutPublicKeyRecords(context.Background(), nil, nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+
+	err = s.PutPublicKeyRecords(context.Background(), []*api.PublicKeyRecord{{}}, nil)
+	assert.Equal(t, api.ErrEmptyPublicKeyDetail, err)
+}
+
+func TestBoltStorer_AppendListAuditRecords_ok(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	records := api.NewTestAuditRecords(rng, 8)
+	err := s.AppendAuditRecords(context.Background(), records[:3])
+	assert.Nil(t, err)
+	err = s.AppendAuditRecords(context.Background(), records[3:])
+	assert.Nil(t, err)
+
+	// page through all audit records, which are chained in sequence order
+	var prev *api.AuditRecord
+	listed := make([]*api.AuditRecord, 0, len(records))
+	for {
+		page, err := s.ListAuditRecords(context.Background(), uint64(len(listed)), 3)
+		assert.Nil(t, err)
+		assert.True(t, len(page) <= 3)
+		if len(page) == 0 {
+			break
+		}
+		for _, r := range page {
+			assert.Nil(t, api.VerifyAuditRecord(prev, r))
+			prev = r
+		}
+		listed = append(listed, page...)
+	}
+	assert.Equal(t, records, listed)
+}
+
+func TestBoltStorer_AppendAuditRecords_err(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	err := s.AppendAuditRecords(context.Background(), []*api.AuditRecord{nil})
+	assert.Equal(t, api.ErrEmptyAuditRecord, err)
+
+	err = s.AppendAuditRecords(context.Background(), []*api.AuditRecord{{}})
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+}
+
+func TestBoltStorer_mutations_audit(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	ctx, now := context.Background(), time.Now()
+	pkds := api.NewTestPublicKeyDetails(rng, 4)
+	added := storage.NewAuditRecords(api.AuditAction_ADD, "caller", now, pkds, nil)
+	err := s.AddPublicKeys(ctx, pkds, added)
+	assert.Nil(t, err)
+
+	// a failed mutation doesn't append its audit records
+	failed := storage.NewAuditRecords(api.AuditAction_ADD, "caller", now, pkds[:1], nil)
+	err = s.AddPublicKeys(ctx, pkds[:1], failed)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+	assert.Zero(t, failed[0].Sequence)
+
+	disabled := storage.NewAuditRecords(api.AuditAction_REVOKE, "caller", now, nil, pkds[:1])
+	err = s.DisablePublicKeys(ctx, pkds[0].EntityId, [][]byte{pkds[0].PublicKey}, disabled)
+	assert.Nil(t, err)
+
+	newPKD := &api.PublicKeyDetail{
+		PublicKey: util.RandBytes(rng, 33),
+		EntityId:  pkds[1].EntityId,
+		KeyType:   pkds[1].KeyType,
+	}
+	rotated := storage.NewAuditRecords(api.AuditAction_ROTATE, "caller", now,
+		[]*api.PublicKeyDetail{newPKD}, pkds[1:2])
+	err = s.RotatePublicKeys(ctx, newPKD.EntityId, newPKD.KeyType,
+		[][]byte{pkds[1].PublicKey}, [][]byte{newPKD.PublicKey}, rotated)
+	assert.Nil(t, err)
+
+	imported := api.NewTestPublicKeyDetail(rng)
+	put := storage.NewAuditRecords(api.AuditAction_IMPORT, "", now,
+		[]*api.PublicKeyDetail{imported}, nil)
+	records := []*api.PublicKeyRecord{storage.NewPublicKeyRecord(imported, now, time.Time{})}
+	err = s.PutPublicKeyRecords(ctx, records, put)
+	assert.Nil(t, err)
+
+	// the audit records of the successful mutations are chained in order
+	expected := append(append(append(added, disabled...), rotated...), put...)
+	listed, err := s.ListAuditRecords(ctx, 0, uint(len(expected)+1))
+	assert.Nil(t, err)
+	assert.Len(t, listed, len(expected))
+	var prev *api.AuditRecord
+	for i, r := range listed {
+		assert.Nil(t, api.VerifyAuditRecord(prev, r))
+		assert.Equal(t, expected[i].Action, r.Action)
+		assert.Equal(t, expected[i].EntityId, r.EntityId)
+		assert.Equal(t, expected[i].AddedPublicKeyHashes, r.AddedPublicKeyHashes)
+		assert.Equal(t, expected[i].DisabledPublicKeyHashes, r.DisabledPublicKeyHashes)
+		prev = r
+	}
+}
+
+func TestBoltStorer_RotatePublicKeys_ok(t *testing.T) {
+	s, cleanup := newTestStorer(t, storage.NewDefaultParameters())
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	n1, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+
+	oldPKs := [][]byte{pkds1[0].PublicKey}
+	newPKs := [][]byte{util.RandBytes(rng, 33), util.RandBytes(rng, 33)}
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, oldPKs, newPKs, nil)
+	assert.Nil(t, err)
+
+	n2, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, n1+1, n2)
+
+	pkds2, err := s.GetPublicKeys(context.Background(), append(oldPKs, newPKs...))
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	for _, pkd := range pkds2[1:] {
+		assert.False(t, pkd.Disabled)
+		assert.Equal(t, entityID, pkd.EntityId)
+		assert.Equal(t, kt, pkd.KeyType)
+	}
+}
+
+func TestBoltStorer_RotatePublicKeys_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	params.MaxBatchSize = storage.MaxEntityKeyTypeKeys
+	s, cleanup := newTestStorer(t, params)
+	defer cleanup()
+
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", api.KeyType_READER
+	pkds := make([]*api.PublicKeyDetail, storage.MaxEntityKeyTypeKeys)
+	for i := range pkds {
+		pkds[i] = &api.PublicKeyDetail{
+			PublicKey: util.RandBytes(rng, 33),
+			EntityId:  entityID,
+			KeyType:   kt,
+		}
+	}
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	oldPKs := [][]byte{pkds[0].PublicKey}
+	newPKs := [][]byte{util.RandBytes(rng, 33), util.RandBytes(rng, 33)}
+
+	// empty entity ID
+	err = s.RotatePublicKeys(context.Background(), "", kt, oldPKs, newPKs, nil)
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+
+	// empty old public keys
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, nil, newPKs, nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+
+	// empty new public keys
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, oldPKs, nil, nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+
+	// old key of another entity
+	err = s.RotatePublicKeys(context.Background(), "another entity ID", kt, oldPKs, newPKs, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+
+	// old key of another key type
+	err = s.RotatePublicKeys(context.Background(), entityID,
+		api.KeyType_AUTHOR, oldPKs, newPKs, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+
+	// new key already exists
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, oldPKs,
+		[][]byte{pkds[1].PublicKey}, nil)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+
+	// too many active keys after rotation
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, oldPKs, newPKs, nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+
+	// nothing changed
+	n, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, storage.MaxEntityKeyTypeKeys, n)
+}
+
+func TestEntityKeyTypePrefix(t *testing.T) {
+	p1 := entityKeyTypePrefix("entity", api.KeyType_READER)
+	p2 := entityKeyTypePrefix("entity", api.KeyType_AUTHOR)
+	p3 := entityKeyTypePrefix("entity2", api.KeyType_READER)
+	assert.NotEqual(t, p1, p2)
+	assert.False(t, bytes.HasPrefix(p3, p1))
+	assert.Equal(t, p1, entityKeyTypePrefix("entity", api.KeyType_READER))
+}
+
+func newTestStorer(t *testing.T, params *storage.Parameters) (storage.Storer, func()) {
+	dir, err := ioutil.TempDir("", "key-bolt-test")
+	assert.Nil(t, err)
+	s, err := New(filepath.Join(dir, "key.db"), params, zap.NewNop())
+	assert.Nil(t, err)
+	return s, func() {
+		assert.Nil(t, s.Close())
+		assert.Nil(t, os.RemoveAll(dir))
+	}
+}
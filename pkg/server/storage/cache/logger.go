@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	logPublicKeysSize       = "public_keys_size"
+	logPublicKeyTTL         = "public_key_ttl"
+	logEntityPublicKeysSize = "entity_public_keys_size"
+	logEntityPublicKeysTTL  = "entity_public_keys_ttl"
+	logNPublicKeys          = "n_public_keys"
+	logNMisses              = "n_misses"
+	logEntityID             = "entity_id"
+	logKeyType              = "key_type"
+)
+
+func logGetPubKeys(nPublicKeys, nMisses int) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNPublicKeys, nPublicKeys),
+		zap.Int(logNMisses, nMisses),
+	}
+}
+
+func logGetEntityPubKeys(ekt storage.EntityKeyType) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, ekt.EntityID),
+		zap.Stringer(logKeyType, ekt.KeyType),
+	}
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+)
+
+// lru is a least-recently-used cache of public key details by hex public key, whose entries also
+// expire after a time. It isn't safe for concurrent use.
+type lru struct {
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	pkd     *api.PublicKeyDetail
+	expires time.Time
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the public key detail for the key and whether it was in the cache and not expired
+// as of now, marking it as the most recently used.
+func (c *lru) get(key string, now time.Time) (*api.PublicKeyDetail, bool) {
+	elem, in := c.entries[key]
+	if !in {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !now.Before(entry.expires) {
+		c.removeElement(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.pkd, true
+}
+
+// put adds the public key detail for the key, expiring at the given time, evicting the least
+// recently used entry if the cache is full.
+func (c *lru) put(key string, pkd *api.PublicKeyDetail, expires time.Time) {
+	if c.size <= 0 {
+		return
+	}
+	if elem, in := c.entries[key]; in {
+		elem.Value = &lruEntry{key: key, pkd: pkd, expires: expires}
+		c.order.MoveToFront(elem)
+		return
+	}
+	if c.order.Len() >= c.size {
+		c.removeElement(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, pkd: pkd, expires: expires})
+}
+
+func (c *lru) remove(key string) {
+	if elem, in := c.entries[key]; in {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lru) len() int {
+	return c.order.Len()
+}
+
+func (c *lru) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
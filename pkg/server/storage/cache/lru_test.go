@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(time.Minute)
+	c := newLRU(2)
+	pkd1 := &api.PublicKeyDetail{EntityId: "entity 1"}
+	pkd2 := &api.PublicKeyDetail{EntityId: "entity 2"}
+	pkd3 := &api.PublicKeyDetail{EntityId: "entity 3"}
+
+	c.put("a", pkd1, expires)
+	c.put("b", pkd2, expires)
+	pkd, in := c.get("a", now)
+	assert.True(t, in)
+	assert.Equal(t, pkd1, pkd)
+
+	// b is least recently used, so it's evicted
+	c.put("c", pkd3, expires)
+	assert.Equal(t, 2, c.len())
+	_, in = c.get("b", now)
+	assert.False(t, in)
+
+	// replacing an entry doesn't evict another
+	c.put("c", pkd2, expires)
+	assert.Equal(t, 2, c.len())
+	pkd, in = c.get("c", now)
+	assert.True(t, in)
+	assert.Equal(t, pkd2, pkd)
+
+	// expired entries are removed
+	_, in = c.get("a", expires)
+	assert.False(t, in)
+	assert.Equal(t, 1, c.len())
+
+	c.remove("c")
+	assert.Equal(t, 0, c.len())
+
+	// zero-size cache holds nothing
+	c = newLRU(0)
+	c.put("a", pkd1, expires)
+	assert.Equal(t, 0, c.len())
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultPublicKeysSize is the default maximum number of public key details cached.
+	DefaultPublicKeysSize = 16384
+
+	// DefaultPublicKeyTTL is the default time a public key detail is cached for.
+	DefaultPublicKeyTTL = 5 * time.Minute
+
+	// DefaultEntityPublicKeysSize is the default maximum number of entity key types whose
+	// active public key details are cached.
+	DefaultEntityPublicKeysSize = 4096
+
+	// DefaultEntityPublicKeysTTL is the default time the active public key details of an entity
+	// key type are cached for.
+	DefaultEntityPublicKeysTTL = 5 * time.Second
+)
+
+// Parameters defines the sizes of the caches and how long their entries are cached for. Only writes
+// through the cache invalidate it, so it shouldn't front storage shared with other Key instances.
+type Parameters struct {
+	PublicKeysSize       int
+	PublicKeyTTL         time.Duration
+	EntityPublicKeysSize int
+	EntityPublicKeysTTL  time.Duration
+}
+
+// NewDefaultParameters returns a *Parameters object with default values.
+func NewDefaultParameters() *Parameters {
+	return &Parameters{
+		PublicKeysSize:       DefaultPublicKeysSize,
+		PublicKeyTTL:         DefaultPublicKeyTTL,
+		EntityPublicKeysSize: DefaultEntityPublicKeysSize,
+		EntityPublicKeysTTL:  DefaultEntityPublicKeysTTL,
+	}
+}
+
+// MarshalLogObject writes the parameters to the given object encoder.
+func (p *Parameters) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddInt(logPublicKeysSize, p.PublicKeysSize)
+	oe.AddDuration(logPublicKeyTTL, p.PublicKeyTTL)
+	oe.AddInt(logEntityPublicKeysSize, p.EntityPublicKeysSize)
+	oe.AddDuration(logEntityPublicKeysTTL, p.EntityPublicKeysTTL)
+	return nil
+}
+
+type storer struct {
+	storage.Storer
+	storageParams *storage.Parameters
+	params        *Parameters
+	logger        *zap.Logger
+	now           func() time.Time
+
+	mu         sync.Mutex
+	pkds       *lru
+	entityPKDs map[storage.EntityKeyType]*entityEntry
+
+	// generation is incremented by each invalidation, so lookups from the inner Storer that
+	// started before it aren't cached.
+	generation uint64
+}
+
+type entityEntry struct {
+	pkds    []*api.PublicKeyDetail
+	expires time.Time
+}
+
+// New creates a new Storer that caches the public key details found by GetPublicKeys and the
+// active public key details returned by GetEntityPublicKeys from the inner Storer, invalidating
+// them when public keys are added, disabled, or rotated through it. Missing public keys aren't
+// cached, and the other methods aren't cached at all. The storage parameters should be the inner
+// Storer's, so lookups served from the cache are limited the same way.
+func New(
+	inner storage.Storer, storageParams *storage.Parameters, params *Parameters, logger *zap.Logger,
+) storage.Storer {
+	return &storer{
+		Storer:        inner,
+		storageParams: storageParams,
+		params:        params,
+		logger:        logger,
+		now:           time.Now,
+		pkds:          newLRU(params.PublicKeysSize),
+		entityPKDs:    make(map[storage.EntityKeyType]*entityEntry),
+	}
+}
+
+func (s *storer) AddPublicKeys(
+	ctx context.Context, pkds []*api.PublicKeyDetail, audit []*api.AuditRecord,
+) error {
+	err := s.Storer.AddPublicKeys(ctx, pkds, audit)
+	pks := make([][]byte, len(pkds))
+	for i, pkd := range pkds {
+		pks[i] = pkd.GetPublicKey()
+	}
+	ekts, _ := storage.CountEntityKeyTypes(pkds)
+	s.invalidate(pks, ekts)
+	return err
+}
+
+func (s *storer) GetPublicKeys(ctx context.Context, pks [][]byte) ([]*api.PublicKeyDetail, error) {
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return nil, err
+	}
+	if len(pks) > int(s.storageParams.MaxBatchSize) {
+		return nil, storage.ErrMaxBatchSizeExceeded
+	}
+	pkds := make([]*api.PublicKeyDetail, len(pks))
+	missingPKs := make([][]byte, 0, len(pks))
+	missingIdxs := make([]int, 0, len(pks))
+	s.mu.Lock()
+	now, generation := s.now(), s.generation
+	for i, pk := range pks {
+		if pkd, in := s.pkds.get(hex.EncodeToString(pk), now); in {
+			pkds[i] = pkd
+			continue
+		}
+		missingPKs = append(missingPKs, pk)
+		missingIdxs = append(missingIdxs, i)
+	}
+	s.mu.Unlock()
+	if len(missingPKs) == 0 {
+		s.logger.Debug("got public keys from cache", logGetPubKeys(len(pks), 0)...)
+		return pkds, nil
+	}
+
+	found, err := s.Storer.GetPublicKeys(ctx, missingPKs)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cacheable := s.generation == generation
+	for j, pkd := range found {
+		pkds[missingIdxs[j]] = pkd
+		if cacheable && pkd != nil {
+			s.pkds.put(hex.EncodeToString(missingPKs[j]), pkd, now.Add(s.params.PublicKeyTTL))
+		}
+	}
+	s.logger.Debug("got public keys from cache", logGetPubKeys(len(pks), len(missingPKs))...)
+	return pkds, nil
+}
+
+func (s *storer) GetEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) ([]*api.PublicKeyDetail, error) {
+	if entityID == "" {
+		return nil, api.ErrEmptyEntityID
+	}
+	ekt := storage.EntityKeyType{EntityID: entityID, KeyType: kt}
+	s.mu.Lock()
+	now, generation := s.now(), s.generation
+	if entry, in := s.entityPKDs[ekt]; in {
+		if now.Before(entry.expires) {
+			s.mu.Unlock()
+			s.logger.Debug("got entity public keys from cache", logGetEntityPubKeys(ekt)...)
+			return append([]*api.PublicKeyDetail{}, entry.pkds...), nil
+		}
+		delete(s.entityPKDs, ekt)
+	}
+	s.mu.Unlock()
+
+	pkds, err := s.Storer.GetEntityPublicKeys(ctx, entityID, kt)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.generation == generation && s.hasEntityRoom(now) {
+		s.entityPKDs[ekt] = &entityEntry{
+			pkds:    append([]*api.PublicKeyDetail{}, pkds...),
+			expires: now.Add(s.params.EntityPublicKeysTTL),
+		}
+	}
+	return pkds, nil
+}
+
+func (s *storer) DisablePublicKeys(
+	ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+) error {
+	err := s.Storer.DisablePublicKeys(ctx, entityID, pks, audit)
+	s.invalidate(pks, entityKeyTypes(entityID))
+	return err
+}
+
+func (s *storer) RotatePublicKeys(
+	ctx context.Context,
+	entityID string,
+	kt api.KeyType,
+	oldPKs, newPKs [][]byte,
+	audit []*api.AuditRecord,
+) error {
+	err := s.Storer.RotatePublicKeys(ctx, entityID, kt, oldPKs, newPKs, audit)
+	pks := append(append([][]byte{}, oldPKs...), newPKs...)
+	s.invalidate(pks, []storage.EntityKeyType{{EntityID: entityID, KeyType: kt}})
+	return err
+}
+
+func (s *storer) PutPublicKeyRecords(
+	ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+) error {
+	err := s.Storer.PutPublicKeyRecords(ctx, records, audit)
+	pks := make([][]byte, 0, len(records))
+	pkds := make([]*api.PublicKeyDetail, 0, len(records))
+	for _, r := range records {
+		if pkd := r.GetPublicKeyDetail(); pkd != nil {
+			pks = append(pks, pkd.PublicKey)
+			pkds = append(pkds, pkd)
+		}
+	}
+	ekts, _ := storage.CountEntityKeyTypes(pkds)
+	s.invalidate(pks, ekts)
+	return err
+}
+
+// invalidate removes the public keys and entity key types from the caches. The writes calling it
+// invalidate even when they fail, since the inner Storer may have partially applied them.
+func (s *storer) invalidate(pks [][]byte, ekts []storage.EntityKeyType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	for _, pk := range pks {
+		s.pkds.remove(hex.EncodeToString(pk))
+	}
+	for _, ekt := range ekts {
+		delete(s.entityPKDs, ekt)
+	}
+}
+
+// hasEntityRoom returns whether another entity key type can be cached, removing the expired ones
+// first if the cache is full. The caller must hold the lock.
+func (s *storer) hasEntityRoom(now time.Time) bool {
+	if len(s.entityPKDs) < s.params.EntityPublicKeysSize {
+		return true
+	}
+	for ekt, entry := range s.entityPKDs {
+		if !now.Before(entry.expires) {
+			delete(s.entityPKDs, ekt)
+		}
+	}
+	return len(s.entityPKDs) < s.params.EntityPublicKeysSize
+}
+
+// entityKeyTypes returns the entity key types of the entity for every key type, since disabling
+// public keys doesn't say which key types they have.
+func entityKeyTypes(entityID string) []storage.EntityKeyType {
+	ekts := make([]storage.EntityKeyType, 0, len(api.KeyType_name))
+	for kt := range api.KeyType_name {
+		ekts = append(ekts, storage.EntityKeyType{EntityID: entityID, KeyType: api.KeyType(kt)})
+	}
+	return ekts
+}
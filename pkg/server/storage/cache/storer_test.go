@@ -0,0 +1,348 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/storage/memory"
+	"github.com/elixirhealth/service-base/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var (
+	errTest = errors.New("some test error")
+)
+
+func TestStorer_GetPublicKeys_ok(t *testing.T) {
+	s, inner := newTestStorer(NewDefaultParameters())
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 4)
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	missingPK := []byte{1, 2, 3}
+	pks := [][]byte{pkds[0].PublicKey, missingPK, pkds[1].PublicKey}
+
+	// first lookup misses
+	found, err := s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{pkds[0], nil, pkds[1]}, found)
+	assert.Equal(t, 3, inner.nGetPKs)
+
+	// second lookup hits, except for the missing public key
+	found, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{pkds[0], nil, pkds[1]}, found)
+	assert.Equal(t, 4, inner.nGetPKs)
+
+	// all hits don't call the inner storer
+	found, err = s.GetPublicKeys(context.Background(), pks[:1])
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{pkds[0]}, found)
+	assert.Equal(t, 4, inner.nGetPKs)
+	assert.Equal(t, 2, inner.nGetCalls)
+}
+
+func TestStorer_GetPublicKeys_expired(t *testing.T) {
+	params := NewDefaultParameters()
+	s, inner := newTestStorer(params)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 1)
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	pks := [][]byte{pkds[0].PublicKey}
+
+	_, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	_, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, inner.nGetPKs)
+
+	now = now.Add(params.PublicKeyTTL)
+	_, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.nGetPKs)
+}
+
+func TestStorer_GetPublicKeys_evicted(t *testing.T) {
+	params := NewDefaultParameters()
+	params.PublicKeysSize = 2
+	s, inner := newTestStorer(params)
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 3)
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+
+	for _, pkd := range pkds {
+		_, err = s.GetPublicKeys(context.Background(), [][]byte{pkd.PublicKey})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 3, inner.nGetPKs)
+	assert.Equal(t, 2, s.pkds.len())
+
+	// least recently used public key was evicted
+	_, err = s.GetPublicKeys(context.Background(), [][]byte{pkds[2].PublicKey})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, inner.nGetPKs)
+	_, err = s.GetPublicKeys(context.Background(), [][]byte{pkds[0].PublicKey})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, inner.nGetPKs)
+}
+
+func TestStorer_GetPublicKeys_invalidatedDuringGet(t *testing.T) {
+	s, inner := newTestStorer(NewDefaultParameters())
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 1)
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	pks := [][]byte{pkds[0].PublicKey}
+
+	// public key disabled after the inner storer got it isn't cached
+	inner.onGet = func() {
+		err := s.DisablePublicKeys(context.Background(), pkds[0].EntityId, pks, nil)
+		assert.Nil(t, err)
+	}
+	found, err := s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.False(t, found[0].Disabled)
+
+	inner.onGet = nil
+	found, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.True(t, found[0].Disabled)
+}
+
+func TestStorer_GetPublicKeys_err(t *testing.T) {
+	s, inner := newTestStorer(NewDefaultParameters())
+
+	found, err := s.GetPublicKeys(context.Background(), nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+	assert.Nil(t, found)
+
+	// too many public keys, even when they're all cached
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, storage.DefaultMaxBatchSize+1)
+	err = s.AddPublicKeys(context.Background(), pkds[:storage.DefaultMaxBatchSize], nil)
+	assert.Nil(t, err)
+	err = s.AddPublicKeys(context.Background(), pkds[storage.DefaultMaxBatchSize:], nil)
+	assert.Nil(t, err)
+	pks := make([][]byte, len(pkds))
+	for i, pkd := range pkds {
+		pks[i] = pkd.PublicKey
+	}
+	_, err = s.GetPublicKeys(context.Background(), pks[:storage.DefaultMaxBatchSize])
+	assert.Nil(t, err)
+	_, err = s.GetPublicKeys(context.Background(), pks[storage.DefaultMaxBatchSize:])
+	assert.Nil(t, err)
+	nGetCalls := inner.nGetCalls
+	found, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Equal(t, storage.ErrMaxBatchSizeExceeded, err)
+	assert.Nil(t, found)
+	assert.Equal(t, nGetCalls, inner.nGetCalls)
+
+	inner.getErr = errTest
+	found, err = s.GetPublicKeys(context.Background(), [][]byte{{1, 2, 3}})
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, found)
+}
+
+func TestStorer_GetEntityPublicKeys_ok(t *testing.T) {
+	params := NewDefaultParameters()
+	s, inner := newTestStorer(params)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 16)
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	entityID, kt := pkds[0].EntityId, pkds[0].KeyType
+
+	found1, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Contains(t, found1, pkds[0])
+	found2, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, found1, found2)
+	assert.Equal(t, 1, inner.nGetEntityCalls)
+
+	// callers can't modify the cached public key details
+	found2[0] = nil
+	found3, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, found1, found3)
+
+	// expired
+	now = now.Add(params.EntityPublicKeysTTL)
+	_, err = s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.nGetEntityCalls)
+}
+
+func TestStorer_GetEntityPublicKeys_full(t *testing.T) {
+	params := NewDefaultParameters()
+	params.EntityPublicKeysSize = 1
+	s, inner := newTestStorer(params)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	kt := api.KeyType_READER
+
+	_, err := s.GetEntityPublicKeys(context.Background(), "entity 1", kt)
+	assert.Nil(t, err)
+	_, err = s.GetEntityPublicKeys(context.Background(), "entity 2", kt)
+	assert.Nil(t, err)
+	_, err = s.GetEntityPublicKeys(context.Background(), "entity 2", kt)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, inner.nGetEntityCalls)
+
+	// expired entity key type makes room for another
+	now = now.Add(params.EntityPublicKeysTTL)
+	_, err = s.GetEntityPublicKeys(context.Background(), "entity 2", kt)
+	assert.Nil(t, err)
+	_, err = s.GetEntityPublicKeys(context.Background(), "entity 2", kt)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, inner.nGetEntityCalls)
+}
+
+func TestStorer_GetEntityPublicKeys_err(t *testing.T) {
+	s, inner := newTestStorer(NewDefaultParameters())
+
+	found, err := s.GetEntityPublicKeys(context.Background(), "", api.KeyType_READER)
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+	assert.Nil(t, found)
+
+	inner.getEntityErr = errTest
+	found, err = s.GetEntityPublicKeys(context.Background(), "some entity ID",
+		api.KeyType_READER)
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, found)
+}
+
+func TestStorer_invalidation(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	entityID, kt := "some entity ID", api.KeyType_READER
+	newPKD := func() *api.PublicKeyDetail {
+		return &api.PublicKeyDetail{
+			PublicKey: util.RandBytes(rng, 33),
+			EntityId:  entityID,
+			KeyType:   kt,
+		}
+	}
+	cases := map[string]func(s storage.Storer, pkd *api.PublicKeyDetail) error{
+		"add": func(s storage.Storer, pkd *api.PublicKeyDetail) error {
+			return s.AddPublicKeys(context.Background(), []*api.PublicKeyDetail{newPKD()}, nil)
+		},
+		"disable": func(s storage.Storer, pkd *api.PublicKeyDetail) error {
+			return s.DisablePublicKeys(context.Background(), entityID,
+				[][]byte{pkd.PublicKey}, nil)
+		},
+		"rotate": func(s storage.Storer, pkd *api.PublicKeyDetail) error {
+			return s.RotatePublicKeys(context.Background(), entityID, kt,
+				[][]byte{pkd.PublicKey}, [][]byte{newPKD().PublicKey}, nil)
+		},
+		"put records": func(s storage.Storer, pkd *api.PublicKeyDetail) error {
+			r := storage.NewPublicKeyRecord(newPKD(), time.Now(), time.Time{})
+			return s.PutPublicKeyRecords(context.Background(), []*api.PublicKeyRecord{r}, nil)
+		},
+	}
+	for desc, write := range cases {
+		s, _ := newTestStorer(NewDefaultParameters())
+		pkd := newPKD()
+		err := s.AddPublicKeys(context.Background(), []*api.PublicKeyDetail{pkd}, nil)
+		assert.Nil(t, err, desc)
+		before, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+		assert.Nil(t, err, desc)
+		_, err = s.GetPublicKeys(context.Background(), [][]byte{pkd.PublicKey})
+		assert.Nil(t, err, desc)
+
+		err = write(s, pkd)
+		assert.Nil(t, err, desc)
+
+		after, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+		assert.Nil(t, err, desc)
+		assert.NotEqual(t, before, after, desc)
+		found, err := s.GetPublicKeys(context.Background(), [][]byte{pkd.PublicKey})
+		assert.Nil(t, err, desc)
+		if desc == "disable" || desc == "rotate" {
+			assert.True(t, found[0].Disabled, desc)
+		}
+	}
+}
+
+func TestStorer_invalidation_err(t *testing.T) {
+	s, inner := newTestStorer(NewDefaultParameters())
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 1)
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	pks := [][]byte{pkds[0].PublicKey}
+	_, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+
+	// failed write still invalidates
+	inner.disableErr = errTest
+	err = s.DisablePublicKeys(context.Background(), pkds[0].EntityId, pks, nil)
+	assert.Equal(t, errTest, err)
+	_, err = s.GetPublicKeys(context.Background(), pks)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, inner.nGetPKs)
+}
+
+func newTestStorer(params *Parameters) (*storer, *countingStorer) {
+	inner := &countingStorer{
+		Storer: memory.New(storage.NewDefaultParameters(), zap.NewNop()),
+	}
+	return New(inner, storage.NewDefaultParameters(), params, zap.NewNop()).(*storer), inner
+}
+
+// countingStorer is a storage.Storer that counts the lookups the cache makes of it.
+type countingStorer struct {
+	storage.Storer
+	nGetCalls       int
+	nGetPKs         int
+	nGetEntityCalls int
+	getErr          error
+	getEntityErr    error
+	disableErr      error
+	onGet           func()
+}
+
+func (c *countingStorer) GetPublicKeys(
+	ctx context.Context, pks [][]byte,
+) ([]*api.PublicKeyDetail, error) {
+	c.nGetCalls++
+	c.nGetPKs += len(pks)
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+	pkds, err := c.Storer.GetPublicKeys(ctx, pks)
+	if c.onGet != nil {
+		c.onGet()
+	}
+	return pkds, err
+}
+
+func (c *countingStorer) GetEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) ([]*api.PublicKeyDetail, error) {
+	c.nGetEntityCalls++
+	if c.getEntityErr != nil {
+		return nil, c.getEntityErr
+	}
+	return c.Storer.GetEntityPublicKeys(ctx, entityID, kt)
+}
+
+func (c *countingStorer) DisablePublicKeys(
+	ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+) error {
+	if c.disableErr != nil {
+		return c.disableErr
+	}
+	return c.Storer.DisablePublicKeys(ctx, entityID, pks, audit)
+}
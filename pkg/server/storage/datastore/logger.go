@@ -1,6 +1,8 @@
 package datastore
 
 import (
+	"time"
+
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -10,6 +12,12 @@ const (
 	logNPublicKeys = "n_public_keys"
 	logEntityID    = "entity_id"
 	logKeyType     = "key_type"
+	logAsOf        = "as_of"
+	logNOld        = "n_old_public_keys"
+	logNNew        = "n_new_public_keys"
+
+	logNAuditRecords = "n_audit_records"
+	logLastAuditSeq  = "last_audit_sequence"
 )
 
 func logGetEntityPubKeys(entityID string, pkds []*api.PublicKeyDetail) []zapcore.Field {
@@ -18,9 +26,52 @@ func logGetEntityPubKeys(entityID string, pkds []*api.PublicKeyDetail) []zapcore
 		zap.Int(logNPublicKeys, len(pkds)),
 	}
 }
+
+func logGetPubKeysAsOf(asOf time.Time, pkds []*api.PublicKeyDetail) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Time(logAsOf, asOf),
+		zap.Int(logNPublicKeys, len(pkds)),
+	}
+}
+
+func logGetEntityPubKeysAsOf(
+	entityID string, asOf time.Time, pkds []*api.PublicKeyDetail,
+) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Time(logAsOf, asOf),
+		zap.Int(logNPublicKeys, len(pkds)),
+	}
+}
+
 func logCountEntityPubKeys(entityID string, kt api.KeyType) []zapcore.Field {
 	return []zapcore.Field{
 		zap.String(logEntityID, entityID),
 		zap.Stringer(logKeyType, kt),
 	}
 }
+
+func logDisablePubKeys(entityID string, pks [][]byte) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Int(logNPublicKeys, len(pks)),
+	}
+}
+
+func logRotatePubKeys(
+	entityID string, kt api.KeyType, oldPKs, newPKs [][]byte,
+) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Stringer(logKeyType, kt),
+		zap.Int(logNOld, len(oldPKs)),
+		zap.Int(logNNew, len(newPKs)),
+	}
+}
+
+func logAppendedAuditRecords(records []*api.AuditRecord) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNAuditRecords, len(records)),
+		zap.Uint64(logLastAuditSeq, records[len(records)-1].Sequence),
+	}
+}
@@ -1,22 +1,42 @@
 package datastore
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
+	"sort"
 	"time"
 
 	"cloud.google.com/go/datastore"
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"github.com/elixirhealth/key/pkg/server/storage"
 	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
+	"github.com/golang/protobuf/proto"
 	"go.uber.org/zap"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 )
 
 const (
-	publicKeyKind = "public_key"
+	publicKeyKind     = "public_key"
+	entityKind        = "entity"
+	entityKeyTypeKind = "entity_key_type"
+	auditRecordKind   = "audit_record"
+	auditHeadKind     = "audit_head"
+	auditHeadName     = "head"
 
-	secsPerDay = int64(3600 * 24 * 24)
+	secsPerDay = int64(3600 * 24)
+
+	// maxModifiedDateQueries is the maximum number of days in a modified time range for which
+	// ListPublicKeys issues a separate query per modified date. Longer ranges are instead
+	// filtered by modified time as the results of a single query are read.
+	maxModifiedDateQueries = 31
+)
+
+var (
+	errEmptyGCPProjectID = errors.New("empty GCP project ID")
 )
 
 // PublicKeyDetail represents a public key and its publicKey, stored in DataStore.
@@ -31,55 +51,151 @@ type PublicKeyDetail struct {
 	DisabledTime time.Time      `datastore:"disabled_time,noindex"`
 }
 
+// EntityKeyType counts the active public keys of an entity and key type. It is written whenever
+// they're added or disabled, so that concurrent transactions changing them conflict and all but one
+// are retried, keeping the count exact.
+type EntityKeyType struct {
+	ModifiedTime time.Time `datastore:"modified_time,noindex"`
+	NActive      int       `datastore:"n_active,noindex"`
+
+	// Counted is false until NActive has been counted, e.g., for an entity key type written
+	// before its active public keys were counted in it.
+	Counted bool `datastore:"counted,noindex"`
+}
+
+// AuditRecord is an encoded audit record, stored in DataStore with its sequence number as the ID
+// of its key.
+type AuditRecord struct {
+	EntityID string `datastore:"entity_id"`
+	Record   []byte `datastore:"record,noindex"`
+}
+
+// AuditHead has the sequence number and hash of the last audit record. It is written whenever
+// audit records are appended, so concurrent transactions appending them conflict and all but one
+// are retried, chaining their records after the new last one.
+type AuditHead struct {
+	Sequence int64  `datastore:"sequence,noindex"`
+	Hash     []byte `datastore:"hash,noindex"`
+}
+
+// transaction is the subset of *datastore.Transaction methods used by the storer.
+type transaction interface {
+	Get(key *datastore.Key, dst interface{}) error
+	Put(key *datastore.Key, src interface{}) (*datastore.PendingKey, error)
+	GetMulti(keys []*datastore.Key, dst interface{}) error
+	PutMulti(keys []*datastore.Key, src interface{}) ([]*datastore.PendingKey, error)
+}
+
+// transactionRunner runs a function within a DataStore transaction, committing it if the
+// function returns nil and rolling it back otherwise.
+type transactionRunner interface {
+	RunInTransaction(ctx context.Context, f func(tx transaction) error) error
+}
+
+type transactionRunnerImpl struct {
+	inner *datastore.Client
+}
+
+func (r *transactionRunnerImpl) RunInTransaction(
+	ctx context.Context, f func(tx transaction) error,
+) error {
+	_, err := r.inner.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		return f(tx)
+	})
+	return err
+}
+
 type storer struct {
-	params *storage.Parameters
-	client bstorage.DatastoreClient
-	iter   bstorage.DatastoreIterator
-	logger *zap.Logger
+	params   *storage.Parameters
+	client   bstorage.DatastoreClient
+	txRunner transactionRunner
+	iter     bstorage.DatastoreIterator
+	logger   *zap.Logger
 }
 
-// New creates a new Storer backed by a GCP DataStore instance.
+// New creates a new Storer backed by a GCP DataStore instance, with any given client options.
 func New(
 	gcpProjectID string, params *storage.Parameters, logger *zap.Logger,
+	opts ...option.ClientOption,
 ) (storage.Storer, error) {
-	client, err := datastore.NewClient(context.Background(), gcpProjectID)
+	if gcpProjectID == "" {
+		return nil, errEmptyGCPProjectID
+	}
+	client, err := datastore.NewClient(context.Background(), gcpProjectID, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return &storer{
-		params: params,
-		client: &bstorage.DatastoreClientImpl{Inner: client},
-		iter:   &bstorage.DatastoreIteratorImpl{},
-		logger: logger,
+		params:   params,
+		client:   &bstorage.DatastoreClientImpl{Inner: client},
+		txRunner: &transactionRunnerImpl{inner: client},
+		iter:     &bstorage.DatastoreIteratorImpl{},
+		logger:   logger,
 	}, nil
 }
 
-func (s *storer) AddPublicKeys(pkds []*api.PublicKeyDetail) error {
+// EmulatorOptions returns the client options for connecting to the local DataStore emulator at
+// the given host, which doesn't use TLS or authentication, instead of GCP DataStore.
+func EmulatorOptions(host string) []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(host),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithInsecure()),
+	}
+}
+
+func (s *storer) AddPublicKeys(
+	ctx context.Context, pkds []*api.PublicKeyDetail, audit []*api.AuditRecord,
+) error {
 	if err := api.ValidatePublicKeyDetails(pkds); err != nil {
 		return err
 	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(pkds) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
 	sKeys, sDetails := toStoredMulti(pkds)
-	ctx, cancel := context.WithTimeout(context.Background(), s.params.AddQueryTimeout)
+	ekts, counts := storage.CountEntityKeyTypes(pkds)
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
 	defer cancel()
-	if _, err := s.client.PutMulti(ctx, sKeys, sDetails); err != nil {
+	err := s.txRunner.RunInTransaction(ctx, func(tx transaction) error {
+		err := tx.GetMulti(sKeys, make([]*PublicKeyDetail, len(sKeys)))
+		if exist, err := anyExist(err); err != nil {
+			return err
+		} else if exist {
+			return storage.ErrPublicKeyExists
+		}
+		for _, ekt := range ekts {
+			if err := s.checkEntityKeyTypeLimit(ctx, tx, ekt, counts[ekt]); err != nil {
+				return err
+			}
+		}
+		if _, err = tx.PutMulti(sKeys, sDetails); err != nil {
+			return err
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
 		return err
 	}
 	s.logger.Debug("added public keys to storage", zap.Int(logNPublicKeys, len(pkds)))
 	return nil
 }
 
-func (s *storer) GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error) {
+func (s *storer) GetPublicKeys(ctx context.Context, pks [][]byte) ([]*api.PublicKeyDetail, error) {
 	if err := api.ValidatePublicKeys(pks); err != nil {
 		return nil, err
 	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return nil, storage.ErrMaxBatchSizeExceeded
+	}
 	spkds := make([]*PublicKeyDetail, len(pks))
 	sKeys := toStoredKeys(pks)
-	ctx, cancel := context.WithTimeout(context.Background(), s.params.GetQueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetQueryTimeout)
 	defer cancel()
-	err := s.client.GetMulti(ctx, sKeys, spkds)
-	if err != nil && firstMultiErrNotNil(err) == datastore.ErrNoSuchEntity {
-		return nil, api.ErrNoSuchPublicKey
-	} else if err != nil {
+	if err := ignoreNoSuchEntity(s.client.GetMulti(ctx, sKeys, spkds)); err != nil {
 		return nil, err
 	}
 	pkds, err := fromStoredMulti(spkds)
@@ -90,6 +206,76 @@ func (s *storer) GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error) {
 	return pkds, nil
 }
 
+func (s *storer) GetPublicKeysAsOf(
+	ctx context.Context, pks [][]byte, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return nil, err
+	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return nil, storage.ErrMaxBatchSizeExceeded
+	}
+	spkds := make([]*PublicKeyDetail, len(pks))
+	sKeys := toStoredKeys(pks)
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetQueryTimeout)
+	defer cancel()
+	if err := ignoreNoSuchEntity(s.client.GetMulti(ctx, sKeys, spkds)); err != nil {
+		return nil, err
+	}
+	pkds := make([]*api.PublicKeyDetail, len(spkds))
+	for i, spkd := range spkds {
+		if spkd == nil {
+			continue
+		}
+		pkd, existed, err := fromStoredAsOf(spkd, asOf)
+		if err != nil {
+			return nil, err
+		}
+		if existed {
+			pkds[i] = pkd
+		}
+	}
+	s.logger.Debug("got public keys as of time from storage",
+		logGetPubKeysAsOf(asOf, pkds)...)
+	return pkds, nil
+}
+
+// anyExist returns whether any of the entities requested in a GetMulti exist, given the error it
+// returned.
+func anyExist(getMultiErr error) (bool, error) {
+	if getMultiErr == nil {
+		return true, nil
+	}
+	merr, ok := getMultiErr.(datastore.MultiError)
+	if !ok {
+		return false, getMultiErr
+	}
+	exist := false
+	for _, err := range merr {
+		if err == nil {
+			exist = true
+		} else if err != datastore.ErrNoSuchEntity {
+			return false, getMultiErr
+		}
+	}
+	return exist, nil
+}
+
+// ignoreNoSuchEntity returns nil if the error returned by a GetMulti only indicates that some of
+// the requested entities don't exist, in which case their destinations are left nil.
+func ignoreNoSuchEntity(getMultiErr error) error {
+	merr, ok := getMultiErr.(datastore.MultiError)
+	if !ok {
+		return getMultiErr
+	}
+	for _, err := range merr {
+		if err != nil && err != datastore.ErrNoSuchEntity {
+			return getMultiErr
+		}
+	}
+	return nil
+}
+
 func firstMultiErrNotNil(err error) error {
 	switch et := err.(type) {
 	case datastore.MultiError:
@@ -103,14 +289,14 @@ func firstMultiErrNotNil(err error) error {
 }
 
 func (s *storer) GetEntityPublicKeys(
-	entityID string, kt api.KeyType,
+	ctx context.Context, entityID string, kt api.KeyType,
 ) ([]*api.PublicKeyDetail, error) {
 	if entityID == "" {
 		return nil, api.ErrEmptyEntityID
 	}
 	q := getEntityPublicKeysQuery(entityID, kt).
 		Limit(storage.MaxEntityKeyTypeKeys)
-	ctx, cancel := context.WithTimeout(context.Background(), s.params.GetEntityQueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetEntityQueryTimeout)
 	defer cancel()
 	iter := s.client.Run(ctx, q)
 	s.iter.Init(iter)
@@ -133,8 +319,51 @@ func (s *storer) GetEntityPublicKeys(
 	return pkds, nil
 }
 
-func (s *storer) CountEntityPublicKeys(entityID string, kt api.KeyType) (int, error) {
-	n, err := s.client.Count(context.Background(), getEntityPublicKeysQuery(entityID, kt))
+func (s *storer) GetEntityPublicKeysAsOf(
+	ctx context.Context, entityID string, kt api.KeyType, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if entityID == "" {
+		return nil, api.ErrEmptyEntityID
+	}
+	// disabled keys may have been active at the as-of time, so we filter them below instead
+	q := datastore.NewQuery(publicKeyKind).
+		Filter("entity_id = ", entityID).
+		Filter("key_type = ", kt.String())
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetEntityQueryTimeout)
+	defer cancel()
+	iter := s.client.Run(ctx, q)
+	s.iter.Init(iter)
+	pkds := make([]*api.PublicKeyDetail, 0, storage.MaxEntityKeyTypeKeys)
+	for {
+		spkd := &PublicKeyDetail{}
+		if _, err := s.iter.Next(spkd); err == iterator.Done {
+			// no more results
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		pkd, existed, err := fromStoredAsOf(spkd, asOf)
+		if err != nil {
+			return nil, err
+		}
+		if existed && !pkd.Disabled {
+			pkds = append(pkds, pkd)
+		}
+	}
+	s.logger.Debug("found public keys for entity as of time",
+		logGetEntityPubKeysAsOf(entityID, asOf, pkds)...)
+	return pkds, nil
+}
+
+func (s *storer) CountEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) (int, error) {
+	if entityID == "" {
+		return 0, api.ErrEmptyEntityID
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetEntityQueryTimeout)
+	defer cancel()
+	n, err := s.client.Count(ctx, getEntityPublicKeysQuery(entityID, kt))
 	if err != nil {
 		return 0, err
 	}
@@ -142,10 +371,427 @@ func (s *storer) CountEntityPublicKeys(entityID string, kt api.KeyType) (int, er
 	return n, nil
 }
 
+func (s *storer) DisablePublicKeys(
+	ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+) error {
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	sKeys := toStoredKeys(pks)
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	err := s.txRunner.RunInTransaction(ctx, func(tx transaction) error {
+		spkds := make([]*PublicKeyDetail, len(pks))
+		err := tx.GetMulti(sKeys, spkds)
+		if err != nil && firstMultiErrNotNil(err) == datastore.ErrNoSuchEntity {
+			return api.ErrNoSuchPublicKey
+		} else if err != nil {
+			return err
+		}
+		now := time.Now()
+		nDisabled := make(map[storage.EntityKeyType]int)
+		for _, spkd := range spkds {
+			if spkd.EntityID != entityID {
+				return api.ErrNoSuchPublicKey
+			}
+			ekt := storage.EntityKeyType{
+				EntityID: spkd.EntityID,
+				KeyType:  api.KeyType(api.KeyType_value[spkd.KeyType]),
+			}
+			n := nDisabled[ekt]
+			if !spkd.Disabled {
+				n++
+			}
+			nDisabled[ekt] = n
+			disableStored(spkd, now)
+		}
+		// like adding public keys, updating their entity key types makes concurrent transactions
+		// changing them conflict
+		for ekt, n := range nDisabled {
+			if err := s.updateEntityKeyType(ctx, tx, ekt, -n); err != nil {
+				return err
+			}
+		}
+		if _, err = tx.PutMulti(sKeys, spkds); err != nil {
+			return err
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("disabled public keys in storage", logDisablePubKeys(entityID, pks)...)
+	return nil
+}
+
+func (s *storer) RotatePublicKeys(
+	ctx context.Context,
+	entityID string,
+	kt api.KeyType,
+	oldPKs, newPKs [][]byte,
+	audit []*api.AuditRecord,
+) error {
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(oldPKs); err != nil {
+		return err
+	}
+	if err := api.ValidatePublicKeys(newPKs); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(oldPKs) > int(s.params.MaxBatchSize) || len(newPKs) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	newPKDs := make([]*api.PublicKeyDetail, len(newPKs))
+	for i, pk := range newPKs {
+		newPKDs[i] = &api.PublicKeyDetail{PublicKey: pk, EntityId: entityID, KeyType: kt}
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	err := s.txRunner.RunInTransaction(ctx, func(tx transaction) error {
+		oldKeys := toStoredKeys(oldPKs)
+		oldSPKDs := make([]*PublicKeyDetail, len(oldPKs))
+		err := tx.GetMulti(oldKeys, oldSPKDs)
+		if err != nil && firstMultiErrNotNil(err) == datastore.ErrNoSuchEntity {
+			return api.ErrNoSuchPublicKey
+		} else if err != nil {
+			return err
+		}
+		nOldActive := 0
+		for _, spkd := range oldSPKDs {
+			if spkd.EntityID != entityID || spkd.KeyType != kt.String() {
+				return api.ErrNoSuchPublicKey
+			}
+			if !spkd.Disabled {
+				nOldActive++
+			}
+		}
+		newKeys := toStoredKeys(newPKs)
+		err = tx.GetMulti(newKeys, make([]*PublicKeyDetail, len(newKeys)))
+		if exist, err := anyExist(err); err != nil {
+			return err
+		} else if exist {
+			return storage.ErrPublicKeyExists
+		}
+		ekt := storage.EntityKeyType{EntityID: entityID, KeyType: kt}
+		if err := s.checkEntityKeyTypeLimit(ctx, tx, ekt, len(newPKs)-nOldActive); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, spkd := range oldSPKDs {
+			disableStored(spkd, now)
+		}
+		newSPKDs := make([]*PublicKeyDetail, len(newPKDs))
+		for i, pkd := range newPKDs {
+			_, newSPKDs[i] = toStored(pkd, now)
+		}
+		_, err = tx.PutMulti(append(oldKeys, newKeys...), append(oldSPKDs, newSPKDs...))
+		if err != nil {
+			return err
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("rotated public keys in storage",
+		logRotatePubKeys(entityID, kt, oldPKs, newPKs)...)
+	return nil
+}
+
+func (s *storer) ListPublicKeys(
+	ctx context.Context, filter *storage.ListFilter, after []byte, limit uint,
+) ([]*api.PublicKeyDetail, error) {
+	queries := getListQueries(filter, after, time.Now())
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetEntityQueryTimeout)
+	defer cancel()
+	pkds := make([]*api.PublicKeyDetail, 0, limit)
+	for _, q := range queries {
+		qPKDs, err := s.listQuery(ctx, q, filter, limit)
+		if err != nil {
+			return nil, err
+		}
+		pkds = append(pkds, qPKDs...)
+	}
+	if len(queries) > 1 {
+		// each query's results are ordered by public key, but the combined results aren't
+		sort.Slice(pkds, func(i, j int) bool {
+			return bytes.Compare(pkds[i].PublicKey, pkds[j].PublicKey) < 0
+		})
+		if uint(len(pkds)) > limit {
+			pkds = pkds[:limit]
+		}
+	}
+	s.logger.Debug("listed public keys from storage", zap.Int(logNPublicKeys, len(pkds)))
+	return pkds, nil
+}
+
+// listQuery returns up to limit public key details from the query's results that match the
+// filter.
+func (s *storer) listQuery(
+	ctx context.Context, q *datastore.Query, filter *storage.ListFilter, limit uint,
+) ([]*api.PublicKeyDetail, error) {
+	iter := s.client.Run(ctx, q)
+	s.iter.Init(iter)
+	pkds := make([]*api.PublicKeyDetail, 0, limit)
+	for uint(len(pkds)) < limit {
+		spkd := &PublicKeyDetail{}
+		if _, err := s.iter.Next(spkd); err == iterator.Done {
+			// no more results
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		pkd, err := fromStored(spkd)
+		if err != nil {
+			return nil, err
+		}
+		// the added and modified times aren't indexed and there's no IN filter, so we
+		// filter those here
+		if filter.Matches(pkd, spkd.AddedTime, spkd.ModifiedTime) {
+			pkds = append(pkds, pkd)
+		}
+	}
+	return pkds, nil
+}
+
+func (s *storer) ListPublicKeyRecords(
+	ctx context.Context, after []byte, limit uint,
+) ([]*api.PublicKeyRecord, error) {
+	q := getListQuery(&storage.ListFilter{}, after).Limit(int(limit))
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetEntityQueryTimeout)
+	defer cancel()
+	iter := s.client.Run(ctx, q)
+	s.iter.Init(iter)
+	records := make([]*api.PublicKeyRecord, 0, limit)
+	for uint(len(records)) < limit {
+		spkd := &PublicKeyDetail{}
+		if _, err := s.iter.Next(spkd); err == iterator.Done {
+			// no more results
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		pkd, err := fromStored(spkd)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records,
+			storage.NewPublicKeyRecord(pkd, spkd.AddedTime, spkd.DisabledTime))
+	}
+	s.logger.Debug("listed public key records from storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return records, nil
+}
+
+func (s *storer) PutPublicKeyRecords(
+	ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+) error {
+	if err := api.ValidatePublicKeyRecords(records); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(records) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	sKeys := make([]*datastore.Key, len(records))
+	spkds := make([]*PublicKeyDetail, len(records))
+	active := make([]*api.PublicKeyDetail, 0, len(records))
+	for i, r := range records {
+		sKeys[i], spkds[i] = toStoredRecord(r)
+		if !r.PublicKeyDetail.Disabled {
+			active = append(active, r.PublicKeyDetail)
+		}
+	}
+	ekts, counts := storage.CountEntityKeyTypes(active)
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	err := s.txRunner.RunInTransaction(ctx, func(tx transaction) error {
+		err := tx.GetMulti(sKeys, make([]*PublicKeyDetail, len(sKeys)))
+		if exist, err := anyExist(err); err != nil {
+			return err
+		} else if exist {
+			return storage.ErrPublicKeyExists
+		}
+		for _, ekt := range ekts {
+			if err := s.updateEntityKeyType(ctx, tx, ekt, counts[ekt]); err != nil {
+				return err
+			}
+		}
+		if _, err = tx.PutMulti(sKeys, spkds); err != nil {
+			return err
+		}
+		return appendAuditRecords(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("put public key records into storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return nil
+}
+
+func (s *storer) AppendAuditRecords(ctx context.Context, records []*api.AuditRecord) error {
+	if err := api.ValidateAuditRecords(records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	err := s.txRunner.RunInTransaction(ctx, func(tx transaction) error {
+		return appendAuditRecords(tx, records)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug("appended audit records to storage", logAppendedAuditRecords(records)...)
+	return nil
+}
+
+func (s *storer) ListAuditRecords(
+	ctx context.Context, afterSeq uint64, limit uint,
+) ([]*api.AuditRecord, error) {
+	q := datastore.NewQuery(auditRecordKind)
+	if afterSeq > 0 {
+		// a zero ID would be an incomplete key, but no audit record has sequence number zero
+		q = q.Filter("__key__ > ", datastore.IDKey(auditRecordKind, int64(afterSeq), nil))
+	}
+	q = q.Order("__key__").Limit(int(limit))
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetEntityQueryTimeout)
+	defer cancel()
+	iter := s.client.Run(ctx, q)
+	s.iter.Init(iter)
+	records := make([]*api.AuditRecord, 0, limit)
+	for uint(len(records)) < limit {
+		sar := &AuditRecord{}
+		if _, err := s.iter.Next(sar); err == iterator.Done {
+			// no more results
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		r := &api.AuditRecord{}
+		if err := proto.Unmarshal(sar.Record, r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	s.logger.Debug("listed audit records from storage", zap.Int(logNAuditRecords, len(records)))
+	return records, nil
+}
+
 func (s *storer) Close() error {
 	return nil
 }
 
+// appendAuditRecords chains the audit records after the audit log's head within the transaction,
+// puts them, and updates the head to the last of them. Since every append writes the head,
+// concurrent transactions appending audit records conflict and are retried.
+func appendAuditRecords(tx transaction, records []*api.AuditRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	headKey := datastore.NameKey(auditHeadKind, auditHeadName, nil)
+	head := &AuditHead{}
+	var last *api.AuditRecord
+	if err := tx.Get(headKey, head); err == nil {
+		last = &api.AuditRecord{Sequence: uint64(head.Sequence), Hash: head.Hash}
+	} else if err != datastore.ErrNoSuchEntity {
+		return err
+	}
+	storage.ChainAuditRecords(last, records)
+	keys, sars, err := toStoredAuditRecords(records)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.PutMulti(keys, sars); err != nil {
+		return err
+	}
+	last = records[len(records)-1]
+	_, err = tx.Put(headKey, &AuditHead{Sequence: int64(last.Sequence), Hash: last.Hash})
+	return err
+}
+
+// checkEntityKeyTypeLimit checks that the entity key type would have no more than
+// MaxEntityKeyTypeKeys active public keys after nAdded more, which may be negative, and updates its
+// count of them.
+func (s *storer) checkEntityKeyTypeLimit(
+	ctx context.Context, tx transaction, ekt storage.EntityKeyType, nAdded int,
+) error {
+	sekt, err := s.getEntityKeyType(ctx, tx, ekt)
+	if err != nil {
+		return err
+	}
+	if sekt.NActive+nAdded > storage.MaxEntityKeyTypeKeys {
+		return storage.ErrTooManyActivePublicKeys
+	}
+	return putEntityKeyType(tx, ekt, sekt, nAdded)
+}
+
+// updateEntityKeyType adds nAdded, which may be negative, to the entity key type's count of active
+// public keys.
+func (s *storer) updateEntityKeyType(
+	ctx context.Context, tx transaction, ekt storage.EntityKeyType, nAdded int,
+) error {
+	sekt, err := s.getEntityKeyType(ctx, tx, ekt)
+	if err != nil {
+		return err
+	}
+	return putEntityKeyType(tx, ekt, sekt, nAdded)
+}
+
+// getEntityKeyType gets the entity key type's EntityKeyType entity in the transaction, so its
+// count of active public keys includes the writes of every transaction that committed before this
+// one and the transaction conflicts with any that commits after it reads the entity.
+func (s *storer) getEntityKeyType(
+	ctx context.Context, tx transaction, ekt storage.EntityKeyType,
+) (*EntityKeyType, error) {
+	sekt := &EntityKeyType{}
+	if err := tx.Get(toStoredEntityKeyTypeKey(ekt), sekt); err != nil &&
+		err != datastore.ErrNoSuchEntity {
+		return nil, err
+	}
+	if !sekt.Counted {
+		// queries can't be run in the transaction, so an uncounted entity key type's active public
+		// keys are counted outside it once; concurrent transactions counting them conflict when
+		// putting the entity, so all but one are retried with the count it puts
+		n, err := s.client.Count(ctx, getEntityPublicKeysQuery(ekt.EntityID, ekt.KeyType))
+		if err != nil {
+			return nil, err
+		}
+		sekt.NActive, sekt.Counted = n, true
+	}
+	return sekt, nil
+}
+
+// putEntityKeyType adds nAdded to the EntityKeyType entity's count of active public keys and puts
+// it in the transaction.
+func putEntityKeyType(
+	tx transaction, ekt storage.EntityKeyType, sekt *EntityKeyType, nAdded int,
+) error {
+	sekt.NActive += nAdded
+	sekt.ModifiedTime = time.Now()
+	_, err := tx.Put(toStoredEntityKeyTypeKey(ekt), sekt)
+	return err
+}
+
 func getEntityPublicKeysQuery(entityID string, kt api.KeyType) *datastore.Query {
 	return datastore.NewQuery(publicKeyKind).
 		Filter("entity_id = ", entityID).
@@ -153,6 +799,61 @@ func getEntityPublicKeysQuery(entityID string, kt api.KeyType) *datastore.Query
 		Filter("disabled = ", false)
 }
 
+func getListQuery(filter *storage.ListFilter, after []byte) *datastore.Query {
+	q := datastore.NewQuery(publicKeyKind)
+	if filter.EntityID != "" {
+		q = q.Filter("entity_id = ", filter.EntityID)
+	}
+	if len(filter.KeyTypes) == 1 {
+		q = q.Filter("key_type = ", filter.KeyTypes[0].String())
+	}
+	switch filter.Status {
+	case api.KeyStatus_ACTIVE:
+		q = q.Filter("disabled = ", false)
+	case api.KeyStatus_REVOKED:
+		q = q.Filter("disabled = ", true)
+	}
+	if after != nil {
+		// key names are hex-encoded public keys, so they have the same order
+		q = q.Filter("__key__ > ", toStoredKeys([][]byte{after})[0])
+	}
+	return q.Order("__key__")
+}
+
+// getListQueries returns the queries whose combined results are the public key details matching
+// the filter, apart from its added and modified times. When the filter has a modified time range
+// of at most maxModifiedDateQueries days, there is a query for each modified date in the range,
+// since the key order needed for paging rules out an inequality filter on the modified date.
+func getListQueries(
+	filter *storage.ListFilter, after []byte, now time.Time,
+) []*datastore.Query {
+	q := getListQuery(filter, after)
+	if filter.ModifiedAfter.IsZero() {
+		return []*datastore.Query{q}
+	}
+	last := now
+	if !filter.ModifiedBefore.IsZero() && filter.ModifiedBefore.Before(now) {
+		last = filter.ModifiedBefore
+	}
+	firstDate, lastDate := toModifiedDate(filter.ModifiedAfter), toModifiedDate(last)
+	if lastDate < firstDate {
+		lastDate = firstDate
+	}
+	if lastDate-firstDate >= maxModifiedDateQueries {
+		return []*datastore.Query{q}
+	}
+	qs := make([]*datastore.Query, 0, lastDate-firstDate+1)
+	for d := firstDate; d <= lastDate; d++ {
+		qs = append(qs, q.Filter("modified_date = ", d))
+	}
+	return qs
+}
+
+func toStoredEntityKeyTypeKey(ekt storage.EntityKeyType) *datastore.Key {
+	entityKey := datastore.NameKey(entityKind, ekt.EntityID, nil)
+	return datastore.NameKey(entityKeyTypeKind, ekt.KeyType.String(), entityKey)
+}
+
 func toStoredKeys(pks [][]byte) []*datastore.Key {
 	keys := make([]*datastore.Key, len(pks))
 	for i, pk := range pks {
@@ -171,10 +872,34 @@ func toStored(pkd *api.PublicKeyDetail, now time.Time) (*datastore.Key, *PublicK
 		Disabled:     false,
 		AddedTime:    now,
 		ModifiedTime: now,
-		ModifiedDate: int32(now.Unix() / secsPerDay),
+		ModifiedDate: toModifiedDate(now),
 	}
 }
 
+func toStoredRecord(r *api.PublicKeyRecord) (*datastore.Key, *PublicKeyDetail) {
+	added, disabled := storage.RecordTimes(r)
+	key, spkd := toStored(r.PublicKeyDetail, added)
+	if r.PublicKeyDetail.Disabled {
+		disableStored(spkd, disabled)
+	}
+	return key, spkd
+}
+
+func disableStored(spkd *PublicKeyDetail, now time.Time) {
+	if spkd.Disabled {
+		return
+	}
+	spkd.Disabled = true
+	spkd.DisabledTime = now
+	spkd.ModifiedTime = now
+	spkd.ModifiedDate = toModifiedDate(now)
+}
+
+// toModifiedDate returns the number of days between the epoch and the given time.
+func toModifiedDate(t time.Time) int32 {
+	return int32(t.Unix() / secsPerDay)
+}
+
 func toStoredMulti(pkds []*api.PublicKeyDetail) ([]*datastore.Key, []*PublicKeyDetail) {
 	keys := make([]*datastore.Key, len(pkds))
 	spkds := make([]*PublicKeyDetail, len(pkds))
@@ -185,6 +910,22 @@ func toStoredMulti(pkds []*api.PublicKeyDetail) ([]*datastore.Key, []*PublicKeyD
 	return keys, spkds
 }
 
+func toStoredAuditRecords(
+	records []*api.AuditRecord,
+) ([]*datastore.Key, []*AuditRecord, error) {
+	keys := make([]*datastore.Key, len(records))
+	sars := make([]*AuditRecord, len(records))
+	for i, r := range records {
+		value, err := proto.Marshal(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = datastore.IDKey(auditRecordKind, int64(r.Sequence), nil)
+		sars[i] = &AuditRecord{EntityID: r.EntityId, Record: value}
+	}
+	return keys, sars, nil
+}
+
 func fromStored(spkd *PublicKeyDetail) (*api.PublicKeyDetail, error) {
 	pk, err := hex.DecodeString(spkd.PublicKey.Name)
 	if err != nil {
@@ -194,12 +935,30 @@ func fromStored(spkd *PublicKeyDetail) (*api.PublicKeyDetail, error) {
 		PublicKey: pk,
 		EntityId:  spkd.EntityID,
 		KeyType:   api.KeyType(api.KeyType_value[spkd.KeyType]),
+		Disabled:  spkd.Disabled,
 	}, nil
 }
 
+// fromStoredAsOf returns the public key detail as it was at the given time and whether it existed
+// then.
+func fromStoredAsOf(spkd *PublicKeyDetail, asOf time.Time) (*api.PublicKeyDetail, bool, error) {
+	if spkd.AddedTime.After(asOf) {
+		return nil, false, nil
+	}
+	pkd, err := fromStored(spkd)
+	if err != nil {
+		return nil, false, err
+	}
+	pkd.Disabled = spkd.Disabled && !spkd.DisabledTime.After(asOf)
+	return pkd, true, nil
+}
+
 func fromStoredMulti(spkds []*PublicKeyDetail) ([]*api.PublicKeyDetail, error) {
 	pkds := make([]*api.PublicKeyDetail, len(spkds))
 	for i, spkd := range spkds {
+		if spkd == nil {
+			continue
+		}
 		pkd, err := fromStored(spkd)
 		if err != nil {
 			return nil, err
@@ -1,13 +1,18 @@
 package datastore
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"math/rand"
+	"sort"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/datastore"
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/service-base/pkg/util"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -18,53 +23,194 @@ var (
 	errTest = errors.New("test error")
 )
 
+func TestNew_err(t *testing.T) {
+	s, err := New("", storage.NewDefaultParameters(), zap.NewNop())
+	assert.Equal(t, errEmptyGCPProjectID, err)
+	assert.Nil(t, s)
+}
+
+func TestEmulatorOptions(t *testing.T) {
+	assert.Len(t, EmulatorOptions("localhost:8081"), 3)
+}
+
 func TestDatastoreStorer_AddGetPublicKeys_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	params := storage.NewDefaultParameters()
 	lg := zap.NewNop()
+	client := &fixedDatastoreClient{
+		publicKey: make(map[string]*PublicKeyDetail),
+	}
 	s := &storer{
-		params: params,
-		client: &fixedDatastoreClient{
-			publicKey: make(map[string]*PublicKeyDetail),
-		},
-		logger: lg,
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
 	}
 
 	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
-	err := s.AddPublicKeys(pkds1)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
 	assert.Nil(t, err)
 
 	pubKeys := make([][]byte, len(pkds1))
 	for i, pkd := range pkds1 {
 		pubKeys[i] = pkd.PublicKey
 	}
-	pkds2, err := s.GetPublicKeys(pubKeys)
+	pkds2, err := s.GetPublicKeys(context.Background(), pubKeys)
 	assert.Nil(t, err)
 	assert.Equal(t, pkds1, pkds2)
+
+	// missing key has a nil detail in its place
+	missingPK := api.NewTestPublicKey(rng)
+	pkds2, err = s.GetPublicKeys(context.Background(), [][]byte{missingPK, pubKeys[0]})
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil, pkds1[0]}, pkds2)
 }
 
 func TestDatastoreStorer_AddPublicKeys_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	params := storage.NewDefaultParameters()
 	lg := zap.NewNop()
+	client := &fixedDatastoreClient{
+		putMultiErr: errTest,
+	}
 	s := &storer{
-		params: params,
-		client: &fixedDatastoreClient{
-			putMultiErr: errTest,
-		},
-		logger: lg,
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
 	}
 	pkds := api.NewTestPublicKeyDetails(rng, 8)
 
 	// empty public key details
-	err := s.AddPublicKeys(nil)
+	err := s.AddPublicKeys(context.Background(), nil, nil)
 	assert.Equal(t, api.ErrEmptyPublicKeys, err)
 
 	// datastore client PutMulti error
-	err = s.AddPublicKeys(pkds)
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Equal(t, errTest, err)
+
+	// datastore client GetMulti error
+	client = &fixedDatastoreClient{getMultiErr: errTest}
+	s.client, s.txRunner = client, &fixedTransactionRunner{client: client}
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Equal(t, errTest, err)
+
+	// datastore client Count error
+	client = &fixedDatastoreClient{countErr: errTest}
+	s.client, s.txRunner = client, &fixedTransactionRunner{client: client}
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Equal(t, errTest, err)
+
+	// too many active public keys
+	client = &fixedDatastoreClient{countValue: storage.MaxEntityKeyTypeKeys}
+	s.client, s.txRunner = client, &fixedTransactionRunner{client: client}
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+
+	// too many keys
+	err = s.AddPublicKeys(context.Background(), api.NewTestPublicKeyDetails(rng,
+		int(params.MaxBatchSize)+1), nil)
+	assert.Equal(t, storage.ErrMaxBatchSizeExceeded, err)
+
+	// transaction error
+	s.txRunner = &fixedTransactionRunner{err: errTest}
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
 	assert.Equal(t, errTest, err)
 }
 
+func TestDatastoreStorer_AddPublicKeys_limit(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.MaxBatchSize = storage.MaxEntityKeyTypeKeys
+	lg := zap.NewNop()
+
+	// the count query never sees the added public keys, like one that isn't yet consistent
+	client := &fixedDatastoreClient{
+		publicKey: make(map[string]*PublicKeyDetail),
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	entityID, kt := "some entity ID", api.KeyType_READER
+	newPKDs := func(n int) []*api.PublicKeyDetail {
+		pkds := make([]*api.PublicKeyDetail, n)
+		for i := range pkds {
+			pkds[i] = &api.PublicKeyDetail{
+				PublicKey: api.NewTestPublicKey(rng),
+				EntityId:  entityID,
+				KeyType:   kt,
+			}
+		}
+		return pkds
+	}
+	pkds := newPKDs(storage.MaxEntityKeyTypeKeys)
+	err := s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+
+	err = s.AddPublicKeys(context.Background(), newPKDs(1), nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+	err = s.RotatePublicKeys(context.Background(), entityID, kt,
+		[][]byte{pkds[0].PublicKey}, newTestPublicKeys(rng, 2), nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+
+	// disabling a public key, even twice, makes room for only one more
+	for i := 0; i < 2; i++ {
+		err = s.DisablePublicKeys(context.Background(), entityID,
+			[][]byte{pkds[0].PublicKey}, nil)
+		assert.Nil(t, err)
+	}
+	err = s.AddPublicKeys(context.Background(), newPKDs(1), nil)
+	assert.Nil(t, err)
+	err = s.AddPublicKeys(context.Background(), newPKDs(1), nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+
+	// rotating a public key keeps the count
+	err = s.RotatePublicKeys(context.Background(), entityID, kt,
+		[][]byte{pkds[1].PublicKey}, newTestPublicKeys(rng, 1), nil)
+	assert.Nil(t, err)
+	err = s.AddPublicKeys(context.Background(), newPKDs(1), nil)
+	assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+}
+
+func TestDatastoreStorer_AddPublicKeys_exists(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	client := &fixedDatastoreClient{
+		publicKey: make(map[string]*PublicKeyDetail),
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	pkds1 := api.NewTestPublicKeyDetails(rng, 2)
+	err := s.AddPublicKeys(context.Background(), pkds1[:1], nil)
+	assert.Nil(t, err)
+	err = s.DisablePublicKeys(context.Background(), pkds1[0].EntityId,
+		[][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// re-adding a revoked key, alone, with another, or for another entity
+	another := *pkds1[0]
+	another.EntityId = "another entity ID"
+	for _, pkds := range [][]*api.PublicKeyDetail{pkds1[:1], pkds1, {&another}} {
+		err = s.AddPublicKeys(context.Background(), pkds, nil)
+		assert.Equal(t, storage.ErrPublicKeyExists, err)
+	}
+
+	pkds2, err := s.GetPublicKeys(context.Background(),
+		[][]byte{pkds1[0].PublicKey, pkds1[1].PublicKey})
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	assert.Equal(t, pkds1[0].EntityId, pkds2[0].EntityId)
+	assert.Nil(t, pkds2[1])
+}
+
 func TestDatastoreStorer_GetPublicKeys_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	params := storage.NewDefaultParameters()
@@ -81,21 +227,26 @@ func TestDatastoreStorer_GetPublicKeys_err(t *testing.T) {
 	}
 
 	// bad request
-	pkds, err := s.GetPublicKeys(nil)
+	pkds, err := s.GetPublicKeys(context.Background(), nil)
 	assert.Equal(t, api.ErrEmptyPublicKeys, err)
 	assert.Nil(t, pkds)
 
-	// missing key
-	s.client = &fixedDatastoreClient{
-		getMultiErr: datastore.MultiError{datastore.ErrNoSuchEntity},
-	}
-	pkds, err = s.GetPublicKeys(pubKeys)
-	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+	// too many keys
+	pkds, err = s.GetPublicKeys(context.Background(), newTestPublicKeys(rng,
+		int(params.MaxBatchSize)+1))
+	assert.Equal(t, storage.ErrMaxBatchSizeExceeded, err)
+	assert.Nil(t, pkds)
+
+	// missing key along with another datastore client GetMulti error
+	getMultiErr := datastore.MultiError{datastore.ErrNoSuchEntity, errTest}
+	s.client = &fixedDatastoreClient{getMultiErr: getMultiErr}
+	pkds, err = s.GetPublicKeys(context.Background(), pubKeys)
+	assert.Equal(t, getMultiErr, err)
 	assert.Nil(t, pkds)
 
 	// other datastore client GetMulti error
 	s.client = &fixedDatastoreClient{getMultiErr: errTest}
-	pkds, err = s.GetPublicKeys(pubKeys)
+	pkds, err = s.GetPublicKeys(context.Background(), pubKeys)
 	assert.Equal(t, errTest, err)
 	assert.Nil(t, pkds)
 }
@@ -116,7 +267,7 @@ func TestDatastoreStorer_GetEntityPublicKeys_ok(t *testing.T) {
 		logger: lg,
 	}
 
-	pkds2, err := s.GetEntityPublicKeys("some entity ID", api.KeyType_READER)
+	pkds2, err := s.GetEntityPublicKeys(context.Background(), "some entity ID", api.KeyType_READER)
 	assert.Nil(t, err)
 	assert.Equal(t, pkds1, pkds2)
 }
@@ -135,12 +286,12 @@ func TestDatastoreStorer_GetEntityPublicKeys_err(t *testing.T) {
 	}
 
 	// empty entity ID
-	pkds, err := s.GetEntityPublicKeys("", api.KeyType_READER)
+	pkds, err := s.GetEntityPublicKeys(context.Background(), "", api.KeyType_READER)
 	assert.Equal(t, api.ErrEmptyEntityID, err)
 	assert.Nil(t, pkds)
 
 	// next error
-	pkds, err = s.GetEntityPublicKeys("some entity ID", api.KeyType_READER)
+	pkds, err = s.GetEntityPublicKeys(context.Background(), "some entity ID", api.KeyType_READER)
 	assert.Equal(t, errTest, err)
 	assert.Nil(t, pkds)
 
@@ -156,11 +307,428 @@ func TestDatastoreStorer_GetEntityPublicKeys_err(t *testing.T) {
 		},
 		logger: lg,
 	}
-	pkds, err = s.GetEntityPublicKeys("some entity ID", api.KeyType_READER)
+	pkds, err = s.GetEntityPublicKeys(context.Background(), "some entity ID", api.KeyType_READER)
+	assert.NotNil(t, err)
+	assert.Nil(t, pkds)
+}
+
+func TestDatastoreStorer_ListPublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
+	keys, spkds := toStoredMulti(pkds1)
+	newStorer := func() *storer {
+		return &storer{
+			params: params,
+			client: &fixedDatastoreClient{},
+			iter: &fixedDatastoreIter{
+				keys:   keys,
+				values: spkds,
+			},
+			logger: lg,
+		}
+	}
+
+	// all
+	pkds2, err := newStorer().ListPublicKeys(context.Background(), &storage.ListFilter{}, nil, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, pkds1, pkds2)
+
+	// limited
+	pkds3, err := newStorer().ListPublicKeys(context.Background(), &storage.ListFilter{}, nil, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, pkds1[:3], pkds3)
+
+	// filtered by multiple key types & added time
+	filter := &storage.ListFilter{
+		KeyTypes:   []api.KeyType{api.KeyType_AUTHOR, api.KeyType_READER},
+		AddedAfter: spkds[0].AddedTime,
+	}
+	pkds4, err := newStorer().ListPublicKeys(context.Background(), filter, nil, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, pkds4)
+
+	// modified within the last few days, combining the queries for each day
+	sorted := append([]*api.PublicKeyDetail{}, pkds1...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].PublicKey, sorted[j].PublicKey) < 0
+	})
+	filter = &storage.ListFilter{
+		ModifiedAfter: spkds[0].ModifiedTime.Add(-3 * 24 * time.Hour),
+	}
+	pkds5, err := newStorer().ListPublicKeys(context.Background(), filter, nil, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, sorted[:5], pkds5)
+
+	// modified before any were added
+	filter = &storage.ListFilter{
+		ModifiedAfter:  spkds[0].ModifiedTime.Add(-3 * 24 * time.Hour),
+		ModifiedBefore: spkds[0].ModifiedTime.Add(-time.Second),
+	}
+	pkds6, err := newStorer().ListPublicKeys(context.Background(), filter, nil, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, pkds6)
+}
+
+func TestGetListQueries(t *testing.T) {
+	now := time.Date(2018, 3, 15, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	cases := map[string]struct {
+		filter   *storage.ListFilter
+		expected int
+	}{
+		"no modified range": {
+			filter:   &storage.ListFilter{},
+			expected: 1,
+		},
+		"modified before only": {
+			filter:   &storage.ListFilter{ModifiedBefore: now.Add(-day)},
+			expected: 1,
+		},
+		"modified since yesterday": {
+			filter:   &storage.ListFilter{ModifiedAfter: now.Add(-day)},
+			expected: 2,
+		},
+		"modified within a day": {
+			filter: &storage.ListFilter{
+				ModifiedAfter:  now.Add(-10 * day),
+				ModifiedBefore: now.Add(-10*day + time.Hour),
+			},
+			expected: 1,
+		},
+		"modified in the future": {
+			filter:   &storage.ListFilter{ModifiedAfter: now.Add(2 * day)},
+			expected: 1,
+		},
+		"max modified dates": {
+			filter: &storage.ListFilter{
+				ModifiedAfter: now.Add(-(maxModifiedDateQueries - 1) * day),
+			},
+			expected: maxModifiedDateQueries,
+		},
+		"too many modified dates": {
+			filter: &storage.ListFilter{
+				ModifiedAfter: now.Add(-maxModifiedDateQueries * day),
+			},
+			expected: 1,
+		},
+	}
+	for desc, c := range cases {
+		qs := getListQueries(c.filter, nil, now)
+		assert.Len(t, qs, c.expected, desc)
+	}
+}
+
+func TestToModifiedDate(t *testing.T) {
+	assert.Equal(t, int32(0), toModifiedDate(time.Unix(0, 0)))
+	assert.Equal(t, int32(0), toModifiedDate(time.Unix(secsPerDay-1, 0)))
+	assert.Equal(t, int32(1), toModifiedDate(time.Unix(secsPerDay, 0)))
+	assert.Equal(t, int32(17605),
+		toModifiedDate(time.Date(2018, 3, 15, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestDatastoreStorer_ListPublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	s := &storer{
+		params: params,
+		client: &fixedDatastoreClient{},
+		iter: &fixedDatastoreIter{
+			err: errTest,
+		},
+		logger: lg,
+	}
+
+	// next error
+	pkds, err := s.ListPublicKeys(context.Background(), &storage.ListFilter{}, nil, 10)
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, pkds)
+
+	// bad stored value
+	badKeys, badSpkds := toStoredMulti(api.NewTestPublicKeyDetails(rng, 1))
+	badSpkds[0].PublicKey = datastore.NameKey(publicKeyKind, "*", nil)
+	s.iter = &fixedDatastoreIter{
+		keys:   badKeys,
+		values: badSpkds,
+	}
+	pkds, err = s.ListPublicKeys(context.Background(), &storage.ListFilter{}, nil, 10)
 	assert.NotNil(t, err)
 	assert.Nil(t, pkds)
 }
 
+func TestDatastoreStorer_ListPutPublicKeyRecords_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	added := time.Unix(1520000000, 0)
+	pkds := api.NewTestPublicKeyDetails(rng, 4)
+	pkds[0].Disabled = true
+	records1 := make([]*api.PublicKeyRecord, len(pkds))
+	for i, pkd := range pkds {
+		records1[i] = storage.NewPublicKeyRecord(pkd, added, added.Add(time.Hour))
+	}
+	client := &fixedDatastoreClient{
+		publicKey: make(map[string]*PublicKeyDetail),
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	err := s.PutPublicKeyRecords(context.Background(), records1, nil)
+	assert.Nil(t, err)
+
+	// stored with their times preserved
+	pks := make([][]byte, len(pkds))
+	for i, pkd := range pkds {
+		pks[i] = pkd.PublicKey
+	}
+	pkds2, err := s.GetPublicKeysAsOf(context.Background(), pks, added.Add(time.Minute))
+	assert.Nil(t, err)
+	for _, pkd := range pkds2 {
+		assert.False(t, pkd.Disabled)
+	}
+	spkd := client.publicKey[toStoredKeys(pks[:1])[0].Name]
+	assert.True(t, added.Add(time.Hour).Equal(spkd.ModifiedTime))
+
+	// can't put existing records
+	err = s.PutPublicKeyRecords(context.Background(), records1[1:2], nil)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+
+	// list them back
+	keys, spkds := make([]*datastore.Key, len(pks)), make([]*PublicKeyDetail, len(pks))
+	for i, key := range toStoredKeys(pks) {
+		keys[i], spkds[i] = key, client.publicKey[key.Name]
+	}
+	s.iter = &fixedDatastoreIter{keys: keys, values: spkds}
+	records2, err := s.ListPublicKeyRecords(context.Background(), nil, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, records1[:3], records2)
+}
+
+func TestDatastoreStorer_ListPublicKeyRecords_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	s := &storer{
+		params: params,
+		client: &fixedDatastoreClient{},
+		iter: &fixedDatastoreIter{
+			err: errTest,
+		},
+		logger: lg,
+	}
+	records, err := s.ListPublicKeyRecords(context.Background(), nil, 10)
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, records)
+}
+
+func TestDatastoreStorer_PutPublicKeyRecords_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	records := []*api.PublicKeyRecord{
+		storage.NewPublicKeyRecord(api.NewTestPublicKeyDetail(rng), time.Now(), time.Time{}),
+	}
+	newStorer := func(client *fixedDatastoreClient) *storer {
+		client.publicKey = make(map[string]*PublicKeyDetail)
+		return &storer{
+			params:   params,
+			client:   client,
+			txRunner: &fixedTransactionRunner{client: client},
+			logger:   lg,
+		}
+	}
+	tooManyRecords := make([]*api.PublicKeyRecord, params.MaxBatchSize+1)
+	for i, pkd := range api.NewTestPublicKeyDetails(rng, len(tooManyRecords)) {
+		tooManyRecords[i] = storage.NewPublicKeyRecord(pkd, time.Now(), time.Time{})
+	}
+	cases := map[string]struct {
+		s        *storer
+		records  []*api.PublicKeyRecord
+		expected error
+	}{
+		"bad records": {
+			s:        &storer{params: params, logger: lg},
+			records:  nil,
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"too many records": {
+			s:        &storer{params: params, logger: lg},
+			records:  tooManyRecords,
+			expected: storage.ErrMaxBatchSizeExceeded,
+		},
+		"get error": {
+			s: &storer{
+				params: params,
+				txRunner: &fixedTransactionRunner{
+					client: &fixedDatastoreClient{getMultiErr: errTest},
+				},
+				logger: lg,
+			},
+			records:  records,
+			expected: errTest,
+		},
+		"count error": {
+			s:        newStorer(&fixedDatastoreClient{countErr: errTest}),
+			records:  records,
+			expected: errTest,
+		},
+		"put error": {
+			s:        newStorer(&fixedDatastoreClient{putMultiErr: errTest}),
+			records:  records,
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		err := c.s.PutPublicKeyRecords(context.Background(), c.records, nil)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestDatastoreStorer_AppendListAuditRecords_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	client := &fixedDatastoreClient{
+		auditRecord: make(map[int64]*AuditRecord),
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	records := api.NewTestAuditRecords(rng, 4)
+	err := s.AppendAuditRecords(context.Background(), records[:1])
+	assert.Nil(t, err)
+	err = s.AppendAuditRecords(context.Background(), records[1:])
+	assert.Nil(t, err)
+
+	// chained after the head written by the previous append
+	var prev *api.AuditRecord
+	for _, r := range records {
+		assert.Nil(t, api.VerifyAuditRecord(prev, r))
+		prev = r
+	}
+	assert.Equal(t, int64(len(records)), client.auditHead.Sequence)
+	assert.Equal(t, records[len(records)-1].Hash, client.auditHead.Hash)
+
+	// list them back
+	keys := make([]*datastore.Key, len(records))
+	sars := make([]*AuditRecord, len(records))
+	for i, r := range records {
+		keys[i] = datastore.IDKey(auditRecordKind, int64(r.Sequence), nil)
+		sars[i] = client.auditRecord[int64(r.Sequence)]
+	}
+	s.iter = &fixedDatastoreIter{keys: keys, auditRecords: sars}
+	listed, err := s.ListAuditRecords(context.Background(), 0, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, records[:3], listed)
+}
+
+func TestDatastoreStorer_AppendAuditRecords_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	cases := map[string]struct {
+		s        *storer
+		records  []*api.AuditRecord
+		expected error
+	}{
+		"bad records": {
+			s:        &storer{params: params, logger: lg},
+			records:  []*api.AuditRecord{{}},
+			expected: api.ErrEmptyEntityID,
+		},
+		"transaction error": {
+			s: &storer{
+				params:   params,
+				txRunner: &fixedTransactionRunner{err: errTest},
+				logger:   lg,
+			},
+			records:  api.NewTestAuditRecords(rng, 1),
+			expected: errTest,
+		},
+		"put error": {
+			s: &storer{
+				params: params,
+				txRunner: &fixedTransactionRunner{
+					client: &fixedDatastoreClient{putMultiErr: errTest},
+				},
+				logger: lg,
+			},
+			records:  api.NewTestAuditRecords(rng, 1),
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		err := c.s.AppendAuditRecords(context.Background(), c.records)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestDatastoreStorer_mutations_audit(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	client := &fixedDatastoreClient{
+		publicKey:   make(map[string]*PublicKeyDetail),
+		auditRecord: make(map[int64]*AuditRecord),
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	ctx, now := context.Background(), time.Now()
+	pkds := api.NewTestPublicKeyDetails(rng, 4)
+	added := storage.NewAuditRecords(api.AuditAction_ADD, "caller", now, pkds, nil)
+	err := s.AddPublicKeys(ctx, pkds, added)
+	assert.Nil(t, err)
+
+	// a failed mutation doesn't append its audit records
+	failed := storage.NewAuditRecords(api.AuditAction_ADD, "caller", now, pkds[:1], nil)
+	err = s.AddPublicKeys(ctx, pkds[:1], failed)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+	assert.Zero(t, failed[0].Sequence)
+
+	disabled := storage.NewAuditRecords(api.AuditAction_REVOKE, "caller", now, nil, pkds[:1])
+	err = s.DisablePublicKeys(ctx, pkds[0].EntityId, [][]byte{pkds[0].PublicKey}, disabled)
+	assert.Nil(t, err)
+
+	// the audit records of the successful mutations are chained in order
+	expected := append(added, disabled...)
+	var prev *api.AuditRecord
+	for _, r := range expected {
+		assert.Nil(t, api.VerifyAuditRecord(prev, r))
+		assert.NotNil(t, client.auditRecord[int64(r.Sequence)])
+		prev = r
+	}
+	assert.Len(t, client.auditRecord, len(expected))
+	assert.Equal(t, int64(len(expected)), client.auditHead.Sequence)
+	assert.Equal(t, prev.Hash, client.auditHead.Hash)
+}
+
+func TestDatastoreStorer_ListAuditRecords_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	s := &storer{
+		params: params,
+		client: &fixedDatastoreClient{},
+		iter: &fixedDatastoreIter{
+			err: errTest,
+		},
+		logger: lg,
+	}
+	records, err := s.ListAuditRecords(context.Background(), 0, 10)
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, records)
+}
+
 func TestDatastoreStorer_CountEntityPublicKeys(t *testing.T) {
 	params := storage.NewDefaultParameters()
 	lg := zap.NewNop()
@@ -174,10 +742,15 @@ func TestDatastoreStorer_CountEntityPublicKeys(t *testing.T) {
 	}
 
 	// ok
-	val, err := s.CountEntityPublicKeys("some entity ID", api.KeyType_READER)
+	val, err := s.CountEntityPublicKeys(context.Background(), "some entity ID", api.KeyType_READER)
 	assert.Nil(t, err)
 	assert.Equal(t, count, val)
 
+	// empty entity ID
+	val, err = s.CountEntityPublicKeys(context.Background(), "", api.KeyType_READER)
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+	assert.Zero(t, val)
+
 	// query err
 	s = &storer{
 		params: params,
@@ -186,11 +759,376 @@ func TestDatastoreStorer_CountEntityPublicKeys(t *testing.T) {
 		},
 		logger: lg,
 	}
-	val, err = s.CountEntityPublicKeys("some entity ID", api.KeyType_READER)
+	val, err = s.CountEntityPublicKeys(context.Background(), "some entity ID", api.KeyType_READER)
 	assert.Equal(t, errTest, err)
 	assert.Zero(t, val)
 }
 
+func TestDatastoreStorer_DisablePublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	client := &fixedDatastoreClient{
+		publicKey: make(map[string]*PublicKeyDetail),
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	err = s.DisablePublicKeys(context.Background(), pkds1[0].EntityId,
+		[][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	pkds2, err := s.GetPublicKeys(context.Background(),
+		[][]byte{pkds1[0].PublicKey, pkds1[1].PublicKey})
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	assert.False(t, pkds2[1].Disabled)
+}
+
+func TestDatastoreStorer_DisablePublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	pkd := api.NewTestPublicKeyDetail(rng)
+	client := &fixedDatastoreClient{
+		publicKey: make(map[string]*PublicKeyDetail),
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	err := s.AddPublicKeys(context.Background(), []*api.PublicKeyDetail{pkd}, nil)
+	assert.Nil(t, err)
+
+	// empty entity ID
+	err = s.DisablePublicKeys(context.Background(), "", [][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+
+	// bad public keys
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId, nil, nil)
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+
+	// too many keys
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId,
+		newTestPublicKeys(rng, int(params.MaxBatchSize)+1), nil)
+	assert.Equal(t, storage.ErrMaxBatchSizeExceeded, err)
+
+	// key of another entity
+	err = s.DisablePublicKeys(context.Background(), "another entity ID",
+		[][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+
+	// missing key
+	client = &fixedDatastoreClient{
+		getMultiErr: datastore.MultiError{datastore.ErrNoSuchEntity},
+	}
+	s.client, s.txRunner = client, &fixedTransactionRunner{client: client}
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId, [][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+
+	// other datastore client GetMulti error
+	client = &fixedDatastoreClient{getMultiErr: errTest}
+	s.client, s.txRunner = client, &fixedTransactionRunner{client: client}
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId, [][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, errTest, err)
+
+	// datastore client PutMulti error
+	client = &fixedDatastoreClient{
+		publicKey: map[string]*PublicKeyDetail{
+			hex.EncodeToString(pkd.PublicKey): {EntityID: pkd.EntityId},
+		},
+		putMultiErr: errTest,
+	}
+	s.client, s.txRunner = client, &fixedTransactionRunner{client: client}
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId, [][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, errTest, err)
+
+	// transaction error
+	s.txRunner = &fixedTransactionRunner{err: errTest}
+	err = s.DisablePublicKeys(context.Background(), pkd.EntityId, [][]byte{pkd.PublicKey}, nil)
+	assert.Equal(t, errTest, err)
+}
+
+func TestDatastoreStorer_GetPublicKeysAsOf_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	pkds1 := api.NewTestPublicKeyDetails(rng, 2)
+	keys, spkds := toStoredMulti(pkds1)
+	added := spkds[0].AddedTime
+	disabled := added.Add(time.Hour)
+	spkds[0].Disabled, spkds[0].DisabledTime = true, disabled
+	publicKey := make(map[string]*PublicKeyDetail)
+	for _, spkd := range spkds {
+		publicKey[spkd.PublicKey.Name] = spkd
+	}
+	s := &storer{
+		params: params,
+		client: &fixedDatastoreClient{publicKey: publicKey},
+		iter: &fixedDatastoreIter{
+			keys:   keys,
+			values: spkds,
+		},
+		logger: lg,
+	}
+	pks := [][]byte{pkds1[0].PublicKey, pkds1[1].PublicKey}
+
+	// keys didn't exist before they were added
+	pkds2, err := s.GetPublicKeysAsOf(context.Background(), pks, added.Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil, nil}, pkds2)
+
+	// missing key has a nil detail in its place
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), [][]byte{{1, 2, 3}, pks[1]},
+		disabled.Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil, pkds1[1]}, pkds2)
+
+	// keys were active before the first was disabled
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), pks, disabled.Add(-time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, pkds1, pkds2)
+
+	// first key is disabled afterwards
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), pks, disabled.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	assert.False(t, pkds2[1].Disabled)
+
+	// entity keys only include those active at the time
+	pkds2, err = s.GetEntityPublicKeysAsOf(context.Background(), "some entity ID",
+		api.KeyType_READER, disabled.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, pkds1[1:], pkds2)
+}
+
+func TestDatastoreStorer_GetPublicKeysAsOf_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	s := &storer{
+		params: params,
+		client: &fixedDatastoreClient{getMultiErr: errTest},
+		iter: &fixedDatastoreIter{
+			err: errTest,
+		},
+		logger: lg,
+	}
+
+	// bad request
+	pkds, err := s.GetPublicKeysAsOf(context.Background(), nil, time.Now())
+	assert.Equal(t, api.ErrEmptyPublicKeys, err)
+	assert.Nil(t, pkds)
+
+	// datastore client GetMulti error
+	pkds, err = s.GetPublicKeysAsOf(context.Background(), [][]byte{{1, 2, 3}}, time.Now())
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, pkds)
+
+	// empty entity ID
+	pkds, err = s.GetEntityPublicKeysAsOf(context.Background(), "", api.KeyType_READER, time.Now())
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+	assert.Nil(t, pkds)
+
+	// next error
+	pkds, err = s.GetEntityPublicKeysAsOf(context.Background(), "some entity ID",
+		api.KeyType_READER, time.Now())
+	assert.Equal(t, errTest, err)
+	assert.Nil(t, pkds)
+}
+
+func TestDatastoreStorer_RotatePublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	client := &fixedDatastoreClient{
+		publicKey:  make(map[string]*PublicKeyDetail),
+		countValue: 8,
+	}
+	s := &storer{
+		params:   params,
+		client:   client,
+		txRunner: &fixedTransactionRunner{client: client},
+		logger:   lg,
+	}
+	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
+	err := s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	oldPKs := [][]byte{pkds1[0].PublicKey}
+	newPKs := [][]byte{util.RandBytes(rng, 33), util.RandBytes(rng, 33)}
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, oldPKs, newPKs, nil)
+	assert.Nil(t, err)
+
+	pkds2, err := s.GetPublicKeys(context.Background(), append(oldPKs, newPKs...))
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	for _, pkd := range pkds2[1:] {
+		assert.False(t, pkd.Disabled)
+		assert.Equal(t, entityID, pkd.EntityId)
+		assert.Equal(t, kt, pkd.KeyType)
+	}
+}
+
+func TestDatastoreStorer_RotatePublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+	pkds := api.NewTestPublicKeyDetails(rng, 2)
+	pkds[1].EntityId, pkds[1].KeyType = pkds[0].EntityId, pkds[0].KeyType
+	entityID, kt := pkds[0].EntityId, pkds[0].KeyType
+	oldPKs := [][]byte{pkds[0].PublicKey}
+	newPKs := [][]byte{util.RandBytes(rng, 33), util.RandBytes(rng, 33)}
+	newStorer := func(client *fixedDatastoreClient) *storer {
+		keys, spkds := toStoredMulti(pkds)
+		if client.publicKey == nil {
+			client.publicKey = make(map[string]*PublicKeyDetail)
+		}
+		for i, key := range keys {
+			client.publicKey[key.Name] = spkds[i]
+		}
+		return &storer{
+			params:   params,
+			client:   client,
+			txRunner: &fixedTransactionRunner{client: client},
+			logger:   lg,
+		}
+	}
+
+	cases := map[string]struct {
+		s        *storer
+		entityID string
+		kt       api.KeyType
+		oldPKs   [][]byte
+		newPKs   [][]byte
+		expected error
+	}{
+		"bad entity ID": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: "",
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   newPKs,
+			expected: api.ErrEmptyEntityID,
+		},
+		"bad old public keys": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   nil,
+			newPKs:   newPKs,
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"bad new public keys": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   nil,
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"too many old keys": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   newTestPublicKeys(rng, int(params.MaxBatchSize)+1),
+			newPKs:   newPKs,
+			expected: storage.ErrMaxBatchSizeExceeded,
+		},
+		"too many new keys": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   newTestPublicKeys(rng, int(params.MaxBatchSize)+1),
+			expected: storage.ErrMaxBatchSizeExceeded,
+		},
+		"missing old key": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   [][]byte{{1, 2, 3}},
+			newPKs:   newPKs,
+			expected: api.ErrNoSuchPublicKey,
+		},
+		"old key of another entity": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: "another entity ID",
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   newPKs,
+			expected: api.ErrNoSuchPublicKey,
+		},
+		"old key of another key type": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: entityID,
+			kt:       api.KeyType((int(kt) + 1) % 2),
+			oldPKs:   oldPKs,
+			newPKs:   newPKs,
+			expected: api.ErrNoSuchPublicKey,
+		},
+		"new key exists": {
+			s:        newStorer(&fixedDatastoreClient{}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   [][]byte{newPKs[0], pkds[1].PublicKey},
+			expected: storage.ErrPublicKeyExists,
+		},
+		"count err": {
+			s:        newStorer(&fixedDatastoreClient{countErr: errTest}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   newPKs,
+			expected: errTest,
+		},
+		"too many active keys": {
+			s: newStorer(&fixedDatastoreClient{
+				countValue: storage.MaxEntityKeyTypeKeys,
+			}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   newPKs,
+			expected: storage.ErrTooManyActivePublicKeys,
+		},
+		"put err": {
+			s:        newStorer(&fixedDatastoreClient{putMultiErr: errTest}),
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   newPKs,
+			expected: errTest,
+		},
+		"transaction err": {
+			s: &storer{
+				params:   params,
+				txRunner: &fixedTransactionRunner{err: errTest},
+				logger:   lg,
+			},
+			entityID: entityID,
+			kt:       kt,
+			oldPKs:   oldPKs,
+			newPKs:   newPKs,
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		err := c.s.RotatePublicKeys(context.Background(), c.entityID, c.kt, c.oldPKs, c.newPKs, nil)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
 func TestToFromStoredMulti(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
@@ -209,12 +1147,23 @@ func TestToFromStoredMulti(t *testing.T) {
 	assert.Equal(t, pkds1, pkds2)
 }
 
+func newTestPublicKeys(rng *rand.Rand, n int) [][]byte {
+	pks := make([][]byte, n)
+	for i := range pks {
+		pks[i] = api.NewTestPublicKey(rng)
+	}
+	return pks
+}
+
 type fixedDatastoreClient struct {
-	publicKey   map[string]*PublicKeyDetail
-	putMultiErr error
-	getMultiErr error
-	countValue  int
-	countErr    error
+	publicKey     map[string]*PublicKeyDetail
+	auditRecord   map[int64]*AuditRecord
+	auditHead     *AuditHead
+	entityKeyType map[string]*EntityKeyType
+	putMultiErr   error
+	getMultiErr   error
+	countValue    int
+	countErr      error
 }
 
 func (f *fixedDatastoreClient) PutMulti(
@@ -224,7 +1173,12 @@ func (f *fixedDatastoreClient) PutMulti(
 		return nil, f.putMultiErr
 	}
 	for i, sKey := range keys {
-		f.publicKey[sKey.Name] = values.([]*PublicKeyDetail)[i]
+		switch vs := values.(type) {
+		case []*AuditRecord:
+			f.auditRecord[sKey.ID] = vs[i]
+		default:
+			f.publicKey[sKey.Name] = values.([]*PublicKeyDetail)[i]
+		}
 	}
 	return keys, nil
 }
@@ -235,8 +1189,17 @@ func (f *fixedDatastoreClient) GetMulti(
 	if f.getMultiErr != nil {
 		return f.getMultiErr
 	}
+	merr, missing := make(datastore.MultiError, len(keys)), false
 	for i, sKey := range keys {
-		dest.([]*PublicKeyDetail)[i] = f.publicKey[sKey.Name]
+		spkd, in := f.publicKey[sKey.Name]
+		if !in {
+			merr[i], missing = datastore.ErrNoSuchEntity, true
+			continue
+		}
+		dest.([]*PublicKeyDetail)[i] = spkd
+	}
+	if missing {
+		return merr
 	}
 	return nil
 }
@@ -267,11 +1230,71 @@ func (f *fixedDatastoreClient) Run(ctx context.Context, q *datastore.Query) *dat
 	return nil
 }
 
-type fixedDatastoreIter struct {
+type fixedTransactionRunner struct {
+	client *fixedDatastoreClient
 	err    error
-	keys   []*datastore.Key
-	values []*PublicKeyDetail
-	offset int
+}
+
+func (f *fixedTransactionRunner) RunInTransaction(
+	ctx context.Context, fn func(tx transaction) error,
+) error {
+	if f.err != nil {
+		return f.err
+	}
+	return fn(&fixedTransaction{client: f.client})
+}
+
+type fixedTransaction struct {
+	client *fixedDatastoreClient
+}
+
+func (f *fixedTransaction) Get(key *datastore.Key, dst interface{}) error {
+	switch v := dst.(type) {
+	case *AuditHead:
+		if f.client.auditHead != nil {
+			*v = *f.client.auditHead
+			return nil
+		}
+	case *EntityKeyType:
+		if sekt, in := f.client.entityKeyType[key.String()]; in {
+			*v = *sekt
+			return nil
+		}
+	}
+	return datastore.ErrNoSuchEntity
+}
+
+func (f *fixedTransaction) Put(key *datastore.Key, src interface{}) (*datastore.PendingKey, error) {
+	switch v := src.(type) {
+	case *AuditHead:
+		f.client.auditHead = v
+	case *EntityKeyType:
+		if f.client.entityKeyType == nil {
+			f.client.entityKeyType = make(map[string]*EntityKeyType)
+		}
+		sekt := *v
+		f.client.entityKeyType[key.String()] = &sekt
+	}
+	return nil, nil
+}
+
+func (f *fixedTransaction) GetMulti(keys []*datastore.Key, dst interface{}) error {
+	return f.client.GetMulti(context.Background(), keys, dst)
+}
+
+func (f *fixedTransaction) PutMulti(
+	keys []*datastore.Key, src interface{},
+) ([]*datastore.PendingKey, error) {
+	_, err := f.client.PutMulti(context.Background(), keys, src)
+	return nil, err
+}
+
+type fixedDatastoreIter struct {
+	err          error
+	keys         []*datastore.Key
+	values       []*PublicKeyDetail
+	auditRecords []*AuditRecord
+	offset       int
 }
 
 func (f *fixedDatastoreIter) Init(iter *datastore.Iterator) {}
@@ -281,7 +1304,14 @@ func (f *fixedDatastoreIter) Next(dst interface{}) (*datastore.Key, error) {
 		return nil, f.err
 	}
 	defer func() { f.offset++ }()
-	if f.offset == len(f.values) {
+	if sar, ok := dst.(*AuditRecord); ok {
+		if f.offset >= len(f.auditRecords) {
+			return nil, iterator.Done
+		}
+		*sar = *f.auditRecords[f.offset]
+		return f.keys[f.offset], nil
+	}
+	if f.offset >= len(f.values) {
 		return nil, iterator.Done
 	}
 	v := f.values[f.offset]
@@ -289,5 +1319,9 @@ func (f *fixedDatastoreIter) Next(dst interface{}) (*datastore.Key, error) {
 	dst.(*PublicKeyDetail).KeyType = v.KeyType
 	dst.(*PublicKeyDetail).PublicKey = v.PublicKey
 	dst.(*PublicKeyDetail).Disabled = v.Disabled
+	dst.(*PublicKeyDetail).AddedTime = v.AddedTime
+	dst.(*PublicKeyDetail).DisabledTime = v.DisabledTime
+	dst.(*PublicKeyDetail).ModifiedTime = v.ModifiedTime
+	dst.(*PublicKeyDetail).ModifiedDate = v.ModifiedDate
 	return f.keys[f.offset], nil
 }
@@ -4,4 +4,10 @@ const (
 	logType            = "type"
 	logAddQueryTimeout = "add_query_timeout"
 	logGetQueryTimeout = "get_query_timeout"
+	logEntityID        = "entity_id"
+	logKeyTypes        = "key_types"
+	logAddedAfter      = "added_after"
+	logModifiedAfter   = "modified_after"
+	logModifiedBefore  = "modified_before"
+	logStatus          = "status"
 )
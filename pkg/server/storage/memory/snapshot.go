@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSnapshotInterval is the default interval between snapshots of a storer created by
+	// NewWithSnapshots.
+	DefaultSnapshotInterval = 1 * time.Minute
+)
+
+var (
+	errTruncatedSnapshot = errors.New("truncated memory storer snapshot")
+)
+
+// NewWithSnapshots creates a new Storer backed by an in-memory map, restoring its public keys and
+// audit log from the snapshot file at the given path if it exists. It writes a new snapshot to the
+// file every interval when its public keys or audit log have changed, and again when it's closed.
+// A zero interval only writes the snapshot when the storer is closed.
+func NewWithSnapshots(
+	params *storage.Parameters, logger *zap.Logger, snapshotPath string, interval time.Duration,
+) (storage.Storer, error) {
+	s := New(params, logger).(*storer)
+	records, auditRecords, err := readSnapshot(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if err := api.ValidatePublicKeyRecord(r); err != nil {
+			return nil, err
+		}
+		added, disabled := storage.RecordTimes(r)
+		s.put(r.PublicKeyDetail, &period{added: added, disabled: disabled})
+	}
+	s.auditLog = auditRecords
+	s.snapshotPath = snapshotPath
+	s.snapshotVersion = s.version
+	s.stopSnapshots = make(chan struct{})
+	s.snapshotsDone = make(chan struct{})
+	go s.snapshotEvery(interval)
+	logger.Info("restored from snapshot",
+		logSnapshot(snapshotPath, len(records), len(auditRecords))...)
+	return s, nil
+}
+
+// snapshotEvery writes a snapshot every interval until snapshots are stopped.
+func (s *storer) snapshotEvery(interval time.Duration) {
+	defer close(s.snapshotsDone)
+	if interval <= 0 {
+		<-s.stopSnapshots
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopSnapshots:
+			return
+		case <-ticker.C:
+			if err := s.snapshot(); err != nil {
+				s.logger.Error("failed to write snapshot", zap.Error(err))
+			}
+		}
+	}
+}
+
+// snapshot writes the public key and audit records to the snapshot file if they've changed since
+// the last snapshot. Only one snapshot may be written at a time.
+func (s *storer) snapshot() error {
+	s.mu.RLock()
+	version := s.version
+	if version == s.snapshotVersion {
+		s.mu.RUnlock()
+		return nil
+	}
+	records := make([]*api.PublicKeyRecord, 0, len(s.pkds))
+	for pkHex, pkd := range s.pkds {
+		p := s.periods[pkHex]
+		records = append(records, storage.NewPublicKeyRecord(pkd, p.added, p.disabled))
+	}
+	// appended audit records aren't modified, so they can be written without the lock
+	auditRecords := s.auditLog[:len(s.auditLog):len(s.auditLog)]
+	s.mu.RUnlock()
+	sort.Slice(records, func(i, j int) bool {
+		pkI, pkJ := records[i].PublicKeyDetail.PublicKey, records[j].PublicKeyDetail.PublicKey
+		return bytes.Compare(pkI, pkJ) < 0
+	})
+	if err := writeSnapshot(s.snapshotPath, records, auditRecords); err != nil {
+		return err
+	}
+	s.snapshotVersion = version
+	s.logger.Debug("wrote snapshot",
+		logSnapshot(s.snapshotPath, len(records), len(auditRecords))...)
+	return nil
+}
+
+// writeSnapshot writes the number of public key records, the public key records and then the
+// audit records to the snapshot file, so both are always replaced together.
+func writeSnapshot(
+	snapshotPath string, records []*api.PublicKeyRecord, auditRecords []*api.AuditRecord,
+) error {
+	msgs := make([]proto.Message, 0, len(records)+len(auditRecords))
+	for _, r := range records {
+		msgs = append(msgs, r)
+	}
+	for _, r := range auditRecords {
+		msgs = append(msgs, r)
+	}
+	return writeDelimited(snapshotPath, uint64(len(records)), msgs)
+}
+
+// readSnapshot returns the public key and audit records in the snapshot file, or none if there is
+// no snapshot file. It doesn't verify the audit records, so a restored audit log that was tampered
+// with fails verification rather than stopping the storer from starting.
+func readSnapshot(snapshotPath string) ([]*api.PublicKeyRecord, []*api.AuditRecord, error) {
+	nRecords, msgs, err := readDelimited(snapshotPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if nRecords > uint64(len(msgs)) {
+		return nil, nil, errTruncatedSnapshot
+	}
+	records := make([]*api.PublicKeyRecord, nRecords)
+	for i := range records {
+		records[i] = &api.PublicKeyRecord{}
+		if err := proto.Unmarshal(msgs[i], records[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	auditRecords := make([]*api.AuditRecord, len(msgs)-len(records))
+	for i := range auditRecords {
+		auditRecords[i] = &api.AuditRecord{}
+		if err := proto.Unmarshal(msgs[len(records)+i], auditRecords[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return records, auditRecords, nil
+}
+
+// writeDelimited writes the uvarint header and then the messages to the file, each a protobuf
+// preceded by its uvarint length. It replaces the file atomically so an interruption never leaves
+// a partial snapshot.
+func writeDelimited(path string, header uint64, msgs []proto.Message) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	size := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(size, header)
+	if _, err := w.Write(size[:n]); err != nil {
+		_ = f.Close()
+		return err
+	}
+	for _, m := range msgs {
+		b, err := proto.Marshal(m)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		n := binary.PutUvarint(size, uint64(len(b)))
+		if _, err := w.Write(size[:n]); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// readDelimited returns the uvarint header and the uvarint length-delimited protobufs in the file,
+// or none if it doesn't exist.
+func readDelimited(path string) (uint64, [][]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil, nil
+	} else if err != nil {
+		return 0, nil, err
+	}
+	header, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, nil, errTruncatedSnapshot
+	}
+	b = b[n:]
+	msgs := make([][]byte, 0)
+	for len(b) > 0 {
+		size, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < size {
+			return 0, nil, errTruncatedSnapshot
+		}
+		msgs = append(msgs, b[n:n+int(size)])
+		b = b[n+int(size):]
+	}
+	return header, msgs, nil
+}
@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNewWithSnapshots_ok(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-memory-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	snapshotPath := filepath.Join(dir, "key.snapshot")
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+
+	s1, err := NewWithSnapshots(params, lg, snapshotPath, 0)
+	assert.Nil(t, err)
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 32)
+	err = s1.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	err = s1.DisablePublicKeys(context.Background(), pkds[0].EntityId,
+		[][]byte{pkds[0].PublicKey}, nil)
+	assert.Nil(t, err)
+	records1, err := s1.ListPublicKeyRecords(context.Background(), nil, 64)
+	assert.Nil(t, err)
+	n1, err := s1.CountEntityPublicKeys(context.Background(), pkds[0].EntityId, pkds[0].KeyType)
+	assert.Nil(t, err)
+	err = s1.AppendAuditRecords(context.Background(), api.NewTestAuditRecords(rng, 4))
+	assert.Nil(t, err)
+	auditRecords1, err := s1.ListAuditRecords(context.Background(), 0, 64)
+	assert.Nil(t, err)
+
+	// no snapshot until closed
+	_, err = os.Stat(snapshotPath)
+	assert.True(t, os.IsNotExist(err))
+	err = s1.Close()
+	assert.Nil(t, err)
+
+	// public keys and audit log are snapshotted to the one file
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+
+	// restored storer has the same records and indexes them
+	s2, err := NewWithSnapshots(params, lg, snapshotPath, 0)
+	assert.Nil(t, err)
+	records2, err := s2.ListPublicKeyRecords(context.Background(), nil, 64)
+	assert.Nil(t, err)
+	assert.Equal(t, records1, records2)
+	n2, err := s2.CountEntityPublicKeys(context.Background(), pkds[0].EntityId, pkds[0].KeyType)
+	assert.Nil(t, err)
+	assert.Equal(t, n1, n2)
+	auditRecords2, err := s2.ListAuditRecords(context.Background(), 0, 64)
+	assert.Nil(t, err)
+	assert.Equal(t, auditRecords1, auditRecords2)
+
+	// restored audit log is appended to after its last record
+	err = s2.AppendAuditRecords(context.Background(), api.NewTestAuditRecords(rng, 1))
+	assert.Nil(t, err)
+	auditRecords2, err = s2.ListAuditRecords(context.Background(), 4, 64)
+	assert.Nil(t, err)
+	assert.Nil(t, api.VerifyAuditRecord(auditRecords1[3], auditRecords2[0]))
+
+	err = s2.Close()
+	assert.Nil(t, err)
+
+	// unchanged storer doesn't rewrite its snapshot
+	s3, err := NewWithSnapshots(params, lg, snapshotPath, 0)
+	assert.Nil(t, err)
+	err = os.Remove(snapshotPath)
+	assert.Nil(t, err)
+	err = s3.Close()
+	assert.Nil(t, err)
+	_, err = os.Stat(snapshotPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewWithSnapshots_interval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-memory-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	snapshotPath := filepath.Join(dir, "key.snapshot")
+
+	s, err := NewWithSnapshots(storage.NewDefaultParameters(), zap.NewNop(), snapshotPath,
+		10*time.Millisecond)
+	assert.Nil(t, err)
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 8)
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+
+	// snapshot is written without closing the storer
+	var records []*api.PublicKeyRecord
+	for i := 0; i < 100 && len(records) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		records, _, err = readSnapshot(snapshotPath)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, records, len(pkds))
+
+	err = s.Close()
+	assert.Nil(t, err)
+}
+
+func TestNewWithSnapshots_err(t *testing.T) {
+	dir, err := ioutil.TempDir("", "key-memory-test")
+	assert.Nil(t, err)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	params := storage.NewDefaultParameters()
+	lg := zap.NewNop()
+
+	// truncated snapshot
+	truncatedPath := filepath.Join(dir, "truncated.snapshot")
+	err = ioutil.WriteFile(truncatedPath, []byte{0, 10, 1, 2}, 0600)
+	assert.Nil(t, err)
+	s, err := NewWithSnapshots(params, lg, truncatedPath, 0)
+	assert.Equal(t, errTruncatedSnapshot, err)
+	assert.Nil(t, s)
+
+	// invalid record
+	invalidPath := filepath.Join(dir, "invalid.snapshot")
+	err = writeSnapshot(invalidPath, []*api.PublicKeyRecord{{}}, nil)
+	assert.Nil(t, err)
+	s, err = NewWithSnapshots(params, lg, invalidPath, 0)
+	assert.Equal(t, api.ErrEmptyPublicKeyDetail, err)
+	assert.Nil(t, s)
+
+	// fewer public key records than the header says
+	missingPath := filepath.Join(dir, "missing.snapshot")
+	err = ioutil.WriteFile(missingPath, []byte{2, 1, 10}, 0600)
+	assert.Nil(t, err)
+	s, err = NewWithSnapshots(params, lg, missingPath, 0)
+	assert.Equal(t, errTruncatedSnapshot, err)
+	assert.Nil(t, s)
+
+	// empty snapshot without a header
+	emptyPath := filepath.Join(dir, "empty.snapshot")
+	err = ioutil.WriteFile(emptyPath, []byte{}, 0600)
+	assert.Nil(t, err)
+	s, err = NewWithSnapshots(params, lg, emptyPath, 0)
+	assert.Equal(t, errTruncatedSnapshot, err)
+	assert.Nil(t, s)
+
+	// snapshot path is a directory
+	s, err = NewWithSnapshots(params, lg, dir, 0)
+	assert.NotNil(t, err)
+	assert.Nil(t, s)
+}
@@ -1,8 +1,12 @@
 package memory
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
+	"sort"
 	"sync"
+	"time"
 
 	api "github.com/elixirhealth/key/pkg/keyapi"
 	"github.com/elixirhealth/key/pkg/server/storage"
@@ -10,66 +14,136 @@ import (
 )
 
 type storer struct {
-	pkds   map[string]*api.PublicKeyDetail
-	mu     sync.Mutex
+	pkds    map[string]*api.PublicKeyDetail
+	periods map[string]*period
+
+	// entityKeyTypes indexes the hex public keys of each entity and key type, both active and
+	// disabled.
+	entityKeyTypes map[storage.EntityKeyType]map[string]struct{}
+
+	// auditLog has the audit records in sequence order, so each is at the index one less than
+	// its sequence number.
+	auditLog []*api.AuditRecord
+
+	// version is incremented whenever a public key is added or disabled or an audit record is
+	// appended, so snapshots are only written when it has changed since the last one.
+	version uint64
+
+	mu     sync.RWMutex
 	params *storage.Parameters
 	logger *zap.Logger
+
+	// snapshotPath is the file snapshots are written to, if the storer was created by
+	// NewWithSnapshots.
+	snapshotPath    string
+	snapshotVersion uint64
+	stopSnapshots   chan struct{}
+	snapshotsDone   chan struct{}
 }
 
 // New creates a new Storer backed by an in-memory map.
 func New(params *storage.Parameters, logger *zap.Logger) storage.Storer {
 	return &storer{
-		pkds:   make(map[string]*api.PublicKeyDetail),
-		params: params,
-		logger: logger,
+		pkds:           make(map[string]*api.PublicKeyDetail),
+		periods:        make(map[string]*period),
+		entityKeyTypes: make(map[storage.EntityKeyType]map[string]struct{}),
+		params:         params,
+		logger:         logger,
 	}
 }
 
-func (s *storer) AddPublicKeys(pkds []*api.PublicKeyDetail) error {
+func (s *storer) AddPublicKeys(
+	ctx context.Context, pkds []*api.PublicKeyDetail, audit []*api.AuditRecord,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if err := api.ValidatePublicKeyDetails(pkds); err != nil {
 		return err
 	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	ekts, counts := storage.CountEntityKeyTypes(pkds)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	for _, pkd := range pkds {
-		pkHex := hex.EncodeToString(pkd.PublicKey)
-		s.mu.Lock()
-		s.pkds[pkHex] = pkd
-		s.mu.Unlock()
+		if _, in := s.pkds[hex.EncodeToString(pkd.PublicKey)]; in {
+			return storage.ErrPublicKeyExists
+		}
+	}
+	for _, ekt := range ekts {
+		if s.countActive(ekt.EntityID, ekt.KeyType)+counts[ekt] > storage.MaxEntityKeyTypeKeys {
+			return storage.ErrTooManyActivePublicKeys
+		}
+	}
+	now := time.Now()
+	for _, pkd := range pkds {
+		s.put(pkd, &period{added: now})
 	}
+	s.appendAudit(audit)
 	s.logger.Debug("added public keys to storage", zap.Int(logNPublicKeys, len(pkds)))
 	return nil
 }
 
-func (s *storer) GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error) {
+func (s *storer) GetPublicKeys(ctx context.Context, pks [][]byte) ([]*api.PublicKeyDetail, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if err := api.ValidatePublicKeys(pks); err != nil {
 		return nil, err
 	}
-	pkds := make([]*api.PublicKeyDetail, 0, len(pks))
-	for _, pk := range pks {
+	pkds := make([]*api.PublicKeyDetail, len(pks))
+	s.mu.RLock()
+	for i, pk := range pks {
+		pkds[i] = s.pkds[hex.EncodeToString(pk)]
+	}
+	s.mu.RUnlock()
+	s.logger.Debug("got public keys from storage", zap.Int(logNPublicKeys, len(pkds)))
+	return pkds, nil
+}
+
+func (s *storer) GetPublicKeysAsOf(
+	ctx context.Context, pks [][]byte, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pkds := make([]*api.PublicKeyDetail, len(pks))
+	for i, pk := range pks {
 		pkHex := hex.EncodeToString(pk)
-		s.mu.Lock()
 		pkd, in := s.pkds[pkHex]
 		if !in {
-			s.mu.Unlock()
-			return nil, api.ErrNoSuchPublicKey
+			continue
+		}
+		if pkdAsOf, existed := s.periods[pkHex].asOf(pkd, asOf); existed {
+			pkds[i] = pkdAsOf
 		}
-		pkds = append(pkds, pkd)
-		s.mu.Unlock()
 	}
-	s.logger.Debug("got public keys from storage", zap.Int(logNPublicKeys, len(pkds)))
+	s.logger.Debug("got public keys as of time from storage",
+		logGetPubKeysAsOf(asOf, pkds)...)
 	return pkds, nil
 }
 
 func (s *storer) GetEntityPublicKeys(
-	entityID string, kt api.KeyType,
+	ctx context.Context, entityID string, kt api.KeyType,
 ) ([]*api.PublicKeyDetail, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if entityID == "" {
 		return nil, api.ErrEmptyEntityID
 	}
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	pkds := make([]*api.PublicKeyDetail, 0, storage.MaxEntityKeyTypeKeys)
-	for _, pkd := range s.pkds {
-		if pkd.EntityId == entityID && pkd.KeyType == kt {
+	for pkHex := range s.entityKeyTypes[storage.EntityKeyType{EntityID: entityID, KeyType: kt}] {
+		if pkd := s.pkds[pkHex]; !pkd.Disabled {
 			pkds = append(pkds, pkd)
 		}
 	}
@@ -77,22 +151,342 @@ func (s *storer) GetEntityPublicKeys(
 	return pkds, nil
 }
 
-func (s *storer) CountEntityPublicKeys(entityID string, kt api.KeyType) (int, error) {
+func (s *storer) GetEntityPublicKeysAsOf(
+	ctx context.Context, entityID string, kt api.KeyType, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if entityID == "" {
+		return nil, api.ErrEmptyEntityID
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pkds := make([]*api.PublicKeyDetail, 0, storage.MaxEntityKeyTypeKeys)
+	for pkHex := range s.entityKeyTypes[storage.EntityKeyType{EntityID: entityID, KeyType: kt}] {
+		pkdAsOf, existed := s.periods[pkHex].asOf(s.pkds[pkHex], asOf)
+		if existed && !pkdAsOf.Disabled {
+			pkds = append(pkds, pkdAsOf)
+		}
+	}
+	s.logger.Debug("found public keys for entity as of time",
+		logGetEntityPubKeysAsOf(entityID, asOf, pkds)...)
+	return pkds, nil
+}
+
+func (s *storer) CountEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	if entityID == "" {
 		return 0, api.ErrEmptyEntityID
 	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := s.countActive(entityID, kt)
+	s.logger.Debug("counted public keys for entity", logCountEntityPubKeys(entityID, kt)...)
+	return c, nil
+}
+
+func (s *storer) DisablePublicKeys(
+	ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	for _, pk := range pks {
+		pkd, in := s.pkds[hex.EncodeToString(pk)]
+		if !in || pkd.EntityId != entityID {
+			return api.ErrNoSuchPublicKey
+		}
+	}
+	now := time.Now()
+	for _, pk := range pks {
+		s.disable(hex.EncodeToString(pk), now)
+	}
+	s.appendAudit(audit)
+	s.logger.Debug("disabled public keys in storage", logDisablePubKeys(entityID, pks)...)
+	return nil
+}
+
+func (s *storer) RotatePublicKeys(
+	ctx context.Context,
+	entityID string,
+	kt api.KeyType,
+	oldPKs, newPKs [][]byte,
+	audit []*api.AuditRecord,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(oldPKs); err != nil {
+		return err
+	}
+	if err := api.ValidatePublicKeys(newPKs); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nOldActive := 0
+	for _, pk := range oldPKs {
+		pkd, in := s.pkds[hex.EncodeToString(pk)]
+		if !in || pkd.EntityId != entityID || pkd.KeyType != kt {
+			return api.ErrNoSuchPublicKey
+		}
+		if !pkd.Disabled {
+			nOldActive++
+		}
+	}
+	for _, pk := range newPKs {
+		if _, in := s.pkds[hex.EncodeToString(pk)]; in {
+			return storage.ErrPublicKeyExists
+		}
+	}
+	if s.countActive(entityID, kt)-nOldActive+len(newPKs) > storage.MaxEntityKeyTypeKeys {
+		return storage.ErrTooManyActivePublicKeys
+	}
+	now := time.Now()
+	for _, pk := range oldPKs {
+		s.disable(hex.EncodeToString(pk), now)
+	}
+	for _, pk := range newPKs {
+		pkd := &api.PublicKeyDetail{
+			PublicKey: pk,
+			EntityId:  entityID,
+			KeyType:   kt,
+		}
+		s.put(pkd, &period{added: now})
+	}
+	s.appendAudit(audit)
+	s.logger.Debug("rotated public keys in storage",
+		logRotatePubKeys(entityID, kt, oldPKs, newPKs)...)
+	return nil
+}
+
+func (s *storer) ListPublicKeys(
+	ctx context.Context, filter *storage.ListFilter, after []byte, limit uint,
+) ([]*api.PublicKeyDetail, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	pkds := make([]*api.PublicKeyDetail, 0)
+	for pkHex, pkd := range s.pkds {
+		if after != nil && bytes.Compare(pkd.PublicKey, after) <= 0 {
+			continue
+		}
+		p := s.periods[pkHex]
+		if filter.Matches(pkd, p.added, p.modified()) {
+			pkds = append(pkds, pkd)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(pkds, func(i, j int) bool {
+		return bytes.Compare(pkds[i].PublicKey, pkds[j].PublicKey) < 0
+	})
+	if uint(len(pkds)) > limit {
+		pkds = pkds[:limit]
+	}
+	s.logger.Debug("listed public keys from storage", zap.Int(logNPublicKeys, len(pkds)))
+	return pkds, nil
+}
+
+func (s *storer) ListPublicKeyRecords(
+	ctx context.Context, after []byte, limit uint,
+) ([]*api.PublicKeyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	records := make([]*api.PublicKeyRecord, 0)
+	for pkHex, pkd := range s.pkds {
+		if after != nil && bytes.Compare(pkd.PublicKey, after) <= 0 {
+			continue
+		}
+		p := s.periods[pkHex]
+		records = append(records, storage.NewPublicKeyRecord(pkd, p.added, p.disabled))
+	}
+	s.mu.RUnlock()
+	sort.Slice(records, func(i, j int) bool {
+		pkI, pkJ := records[i].PublicKeyDetail.PublicKey, records[j].PublicKeyDetail.PublicKey
+		return bytes.Compare(pkI, pkJ) < 0
+	})
+	if uint(len(records)) > limit {
+		records = records[:limit]
+	}
+	s.logger.Debug("listed public key records from storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return records, nil
+}
+
+func (s *storer) PutPublicKeyRecords(
+	ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := api.ValidatePublicKeyRecords(records); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range records {
+		if _, in := s.pkds[hex.EncodeToString(r.PublicKeyDetail.PublicKey)]; in {
+			return storage.ErrPublicKeyExists
+		}
+	}
+	for _, r := range records {
+		pkd := *r.PublicKeyDetail
+		added, disabled := storage.RecordTimes(r)
+		s.put(&pkd, &period{added: added, disabled: disabled})
+	}
+	s.appendAudit(audit)
+	s.logger.Debug("put public key records into storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return nil
+}
+
+func (s *storer) AppendAuditRecords(ctx context.Context, records []*api.AuditRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(records); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appendAudit(records)
+	return nil
+}
+
+func (s *storer) ListAuditRecords(
+	ctx context.Context, afterSeq uint64, limit uint,
+) ([]*api.AuditRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if afterSeq >= uint64(len(s.auditLog)) {
+		return []*api.AuditRecord{}, nil
+	}
+	end := afterSeq + uint64(limit)
+	if end > uint64(len(s.auditLog)) {
+		end = uint64(len(s.auditLog))
+	}
+	records := make([]*api.AuditRecord, end-afterSeq)
+	copy(records, s.auditLog[afterSeq:end])
+	s.logger.Debug("listed audit records from storage", zap.Int(logNAuditRecords, len(records)))
+	return records, nil
+}
+
+func (s *storer) Close() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+	close(s.stopSnapshots)
+	<-s.snapshotsDone
+	return s.snapshot()
+}
+
+// countActive returns the number of active public keys for the entity and key type. The caller
+// must hold the lock.
+func (s *storer) countActive(entityID string, kt api.KeyType) int {
 	c := 0
-	for _, pkd := range s.pkds {
-		if pkd.EntityId == entityID && pkd.KeyType == kt {
+	for pkHex := range s.entityKeyTypes[storage.EntityKeyType{EntityID: entityID, KeyType: kt}] {
+		if !s.pkds[pkHex].Disabled {
 			c++
 		}
 	}
-	s.logger.Debug("counted public keys for entity", logCountEntityPubKeys(entityID, kt)...)
-	return c, nil
+	return c
 }
 
-func (s *storer) Close() error {
-	return nil
+// put stores the public key detail and its period and indexes it by entity and key type. The
+// caller must hold the write lock and have checked that the public key doesn't already exist.
+func (s *storer) put(pkd *api.PublicKeyDetail, p *period) {
+	pkHex := hex.EncodeToString(pkd.PublicKey)
+	ekt := storage.EntityKeyType{EntityID: pkd.EntityId, KeyType: pkd.KeyType}
+	if _, in := s.entityKeyTypes[ekt]; !in {
+		s.entityKeyTypes[ekt] = make(map[string]struct{})
+	}
+	s.entityKeyTypes[ekt][pkHex] = struct{}{}
+	s.pkds[pkHex] = pkd
+	s.periods[pkHex] = p
+	s.version++
+}
+
+// appendAudit chains the audit records after the last one in the audit log and appends them. The
+// caller must hold the write lock.
+func (s *storer) appendAudit(records []*api.AuditRecord) {
+	if len(records) == 0 {
+		return
+	}
+	var last *api.AuditRecord
+	if len(s.auditLog) > 0 {
+		last = s.auditLog[len(s.auditLog)-1]
+	}
+	storage.ChainAuditRecords(last, records)
+	s.auditLog = append(s.auditLog, records...)
+	s.version++
+	s.logger.Debug("appended audit records to storage", logAppendedAuditRecords(records)...)
+}
+
+// disable replaces the public key detail with a disabled copy, if it isn't disabled already. The
+// caller must hold the write lock.
+func (s *storer) disable(pkHex string, now time.Time) {
+	if s.pkds[pkHex].Disabled {
+		return
+	}
+	disabled := *s.pkds[pkHex]
+	disabled.Disabled = true
+	s.pkds[pkHex] = &disabled
+	s.periods[pkHex].disabled = now
+	s.version++
+}
+
+// period is when a public key was added and (if it has been) disabled.
+type period struct {
+	added    time.Time
+	disabled time.Time
+}
+
+// modified returns when the public key was last modified, i.e., when it was disabled or, if it
+// hasn't been, added.
+func (p *period) modified() time.Time {
+	if !p.disabled.IsZero() {
+		return p.disabled
+	}
+	return p.added
+}
+
+// asOf returns the public key detail as it was at the given time and whether it existed then.
+func (p *period) asOf(pkd *api.PublicKeyDetail, asOf time.Time) (*api.PublicKeyDetail, bool) {
+	if p.added.After(asOf) {
+		return nil, false
+	}
+	pkdAsOf := *pkd
+	pkdAsOf.Disabled = !p.disabled.IsZero() && !p.disabled.After(asOf)
+	return &pkdAsOf, true
 }
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+)
+
+const (
+	auditLogTable   = "audit_log"
+	fqAuditLogTable = keySchema + "." + auditLogTable
+
+	recordCol = "record"
+
+	// lockAuditLog locks the audit log against concurrent appends for the rest of the
+	// transaction while still allowing it to be read
+	lockAuditLog = "LOCK TABLE " + fqAuditLogTable + " IN EXCLUSIVE MODE"
+)
+
+// AppendAuditRecords locks the audit log, selects its last record, and inserts the audit records
+// chained after it in a transaction, so concurrent appends can't fork the chain.
+func (s *storer) AppendAuditRecords(ctx context.Context, records []*api.AuditRecord) error {
+	if err := api.ValidateAuditRecords(records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = s.appendAuditRecords(ctx, tx, records); err != nil {
+		return rollback(tx, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Debug("appended audit records to storage", logAppendedAuditRecords(records)...)
+	return nil
+}
+
+// appendAuditRecords locks the audit log, selects its last record, and inserts the audit records
+// chained after it within the transaction. Transactions changing public keys append their audit
+// records last, after inserting their outbox events, so they always take the outbox and audit log
+// locks in the same order.
+func (s *storer) appendAuditRecords(
+	ctx context.Context, tx *sql.Tx, records []*api.AuditRecord,
+) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, lockAuditLog); err != nil {
+		return err
+	}
+	q1 := psql.RunWith(tx).
+		Select(recordCol).
+		From(fqAuditLogTable).
+		OrderBy(sequenceCol + " DESC").
+		Limit(1)
+	var last *api.AuditRecord
+	var value []byte
+	err := s.qr.SelectQueryRowContext(ctx, q1).Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		last = &api.AuditRecord{}
+		if err = proto.Unmarshal(value, last); err != nil {
+			return err
+		}
+	}
+	storage.ChainAuditRecords(last, records)
+	q2 := psql.RunWith(tx).
+		Insert(fqAuditLogTable).
+		Columns(sequenceCol, entityIDCol, recordCol)
+	for _, r := range records {
+		value, err := proto.Marshal(r)
+		if err != nil {
+			return err
+		}
+		q2 = q2.Values(r.Sequence, r.EntityId, value)
+	}
+	s.logger.Debug("appending audit records to storage", logAppendingAuditRecords(q2, records)...)
+	_, err = s.qr.InsertExecContext(ctx, q2)
+	return err
+}
+
+func (s *storer) ListAuditRecords(
+	ctx context.Context, afterSeq uint64, limit uint,
+) ([]*api.AuditRecord, error) {
+	q := psql.RunWith(s.dbCache).
+		Select(recordCol).
+		From(fqAuditLogTable).
+		Where(sq.Gt{sequenceCol: afterSeq}).
+		OrderBy(sequenceCol).
+		Limit(uint64(limit))
+	s.logger.Debug("listing audit records from storage", logListingAuditRecords(q)...)
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetQueryTimeout)
+	defer cancel()
+	rows, err := s.qr.SelectQueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("error closing rows", zap.Error(err))
+		}
+	}()
+	records := make([]*api.AuditRecord, 0, limit)
+	for rows.Next() {
+		var value []byte
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		r := &api.AuditRecord{}
+		if err := proto.Unmarshal(value, r); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	s.logger.Debug("listed audit records from storage", zap.Int(logNAuditRecords, len(records)))
+	return records, nil
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/logging"
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/service-base/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestStorer_AppendListAuditRecords_ok(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+
+	// concurrent appends are chained one after another
+	records := api.NewTestAuditRecords(rng, 8)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < len(records); i += 2 {
+		wg.Add(1)
+		go func(batch []*api.AuditRecord) {
+			defer wg.Done()
+			err := s.AppendAuditRecords(context.Background(), batch)
+			assert.Nil(t, err)
+		}(records[i : i+2])
+	}
+	wg.Wait()
+
+	// page through all audit records, which are chained in sequence order
+	var prev *api.AuditRecord
+	listed := make([]*api.AuditRecord, 0, len(records))
+	for {
+		page, err := s.ListAuditRecords(context.Background(), uint64(len(listed)), 3)
+		assert.Nil(t, err)
+		assert.True(t, len(page) <= 3)
+		if len(page) == 0 {
+			break
+		}
+		for _, r := range page {
+			assert.Nil(t, api.VerifyAuditRecord(prev, r))
+			prev = r
+		}
+		listed = append(listed, page...)
+	}
+	assert.Len(t, listed, len(records))
+}
+
+func TestStorer_AppendAuditRecords_err(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	s, err := New(dbURL, params, zap.NewNop())
+	assert.Nil(t, err)
+
+	err = s.AppendAuditRecords(context.Background(), []*api.AuditRecord{nil})
+	assert.Equal(t, api.ErrEmptyAuditRecord, err)
+
+	err = s.AppendAuditRecords(context.Background(), []*api.AuditRecord{{}})
+	assert.Equal(t, api.ErrEmptyEntityID, err)
+}
+
+func TestStorer_mutations_audit(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	s, err := New(dbURL, params, zap.NewNop())
+	assert.Nil(t, err)
+
+	rng := rand.New(rand.NewSource(0))
+	ctx, now := context.Background(), time.Now()
+	pkds := api.NewTestPublicKeyDetails(rng, 4)
+	added := storage.NewAuditRecords(api.AuditAction_ADD, "caller", now, pkds, nil)
+	err = s.AddPublicKeys(ctx, pkds, added)
+	assert.Nil(t, err)
+
+	// a failed mutation doesn't append its audit records
+	failed := storage.NewAuditRecords(api.AuditAction_ADD, "caller", now, pkds[:1], nil)
+	err = s.AddPublicKeys(ctx, pkds[:1], failed)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+	assert.Zero(t, failed[0].Sequence)
+
+	disabled := storage.NewAuditRecords(api.AuditAction_REVOKE, "caller", now, nil, pkds[:1])
+	err = s.DisablePublicKeys(ctx, pkds[0].EntityId, [][]byte{pkds[0].PublicKey}, disabled)
+	assert.Nil(t, err)
+
+	newPKD := &api.PublicKeyDetail{
+		PublicKey: util.RandBytes(rng, 33),
+		EntityId:  pkds[1].EntityId,
+		KeyType:   pkds[1].KeyType,
+	}
+	rotated := storage.NewAuditRecords(api.AuditAction_ROTATE, "caller", now,
+		[]*api.PublicKeyDetail{newPKD}, pkds[1:2])
+	err = s.RotatePublicKeys(ctx, newPKD.EntityId, newPKD.KeyType,
+		[][]byte{pkds[1].PublicKey}, [][]byte{newPKD.PublicKey}, rotated)
+	assert.Nil(t, err)
+
+	imported := api.NewTestPublicKeyDetail(rng)
+	put := storage.NewAuditRecords(api.AuditAction_IMPORT, "", now,
+		[]*api.PublicKeyDetail{imported}, nil)
+	records := []*api.PublicKeyRecord{storage.NewPublicKeyRecord(imported, now, time.Time{})}
+	err = s.PutPublicKeyRecords(ctx, records, put)
+	assert.Nil(t, err)
+
+	// the audit records of the successful mutations are chained in order
+	expected := append(append(append(added, disabled...), rotated...), put...)
+	listed, err := s.ListAuditRecords(ctx, 0, uint(len(expected)+1))
+	assert.Nil(t, err)
+	assert.Len(t, listed, len(expected))
+	var prev *api.AuditRecord
+	for i, r := range listed {
+		assert.Nil(t, api.VerifyAuditRecord(prev, r))
+		assert.Equal(t, expected[i].Action, r.Action)
+		assert.Equal(t, expected[i].EntityId, r.EntityId)
+		assert.Equal(t, expected[i].AddedPublicKeyHashes, r.AddedPublicKeyHashes)
+		assert.Equal(t, expected[i].DisabledPublicKeyHashes, r.DisabledPublicKeyHashes)
+		prev = r
+	}
+}
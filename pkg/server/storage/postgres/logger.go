@@ -1,9 +1,12 @@
 package postgres
 
 import (
+	"time"
+
 	sq "github.com/Masterminds/squirrel"
 	"github.com/drausin/libri/libri/common/errors"
 	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -15,6 +18,19 @@ const (
 	logSQL         = "sql"
 	logArgs        = "args"
 	logCount       = "count"
+	logAsOf        = "as_of"
+	logNOld        = "n_old_public_keys"
+	logNNew        = "n_new_public_keys"
+	logFilter      = "filter"
+	logEventType   = "event_type"
+	logNEvents     = "n_events"
+	logFirstSeq    = "first_sequence"
+	logLastSeq     = "last_sequence"
+	logInterval    = "interval"
+	logBatchSize   = "batch_size"
+
+	logNAuditRecords = "n_audit_records"
+	logLastAuditSeq  = "last_audit_sequence"
 )
 
 func logAddingPublicKeys(q sq.InsertBuilder, pkds []*api.PublicKeyDetail) []zapcore.Field {
@@ -43,6 +59,50 @@ func logGettingPublicKeys(q sq.SelectBuilder, pks [][]byte) []zapcore.Field {
 	}
 }
 
+func logGettingPublicKeysAsOf(
+	q sq.SelectBuilder, pks [][]byte, asOf time.Time,
+) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.Int(logNPublicKeys, len(pks)),
+		zap.Time(logAsOf, asOf),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logListingPublicKeys(q sq.SelectBuilder, filter *storage.ListFilter) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.Object(logFilter, filter),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logListingPublicKeyRecords(q sq.SelectBuilder) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logPuttingPublicKeyRecords(
+	q sq.InsertBuilder, records []*api.PublicKeyRecord,
+) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.Int(logNPublicKeys, len(records)),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
 func logGettingEntityPubKeys(q sq.SelectBuilder, entityID string) []zapcore.Field {
 	qSQL, args, err := q.ToSql()
 	errors.MaybePanic(err)
@@ -53,6 +113,19 @@ func logGettingEntityPubKeys(q sq.SelectBuilder, entityID string) []zapcore.Fiel
 	}
 }
 
+func logGettingEntityPubKeysAsOf(
+	q sq.SelectBuilder, entityID string, asOf time.Time,
+) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Time(logAsOf, asOf),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
 func logGotEntityPubKeys(entityID string, pkds []*api.PublicKeyDetail) []zapcore.Field {
 	return []zapcore.Field{
 		zap.String(logEntityID, entityID),
@@ -79,6 +152,128 @@ func logCountEntityPubKeys(entityID string, kt api.KeyType, count int) []zapcore
 	}
 }
 
+func logClosingPublicKeys(q sq.UpdateBuilder, entityID string, pks [][]byte) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Int(logNPublicKeys, len(pks)),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logDisablingPublicKeys(q sq.InsertBuilder, entityID string, pks [][]byte) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Int(logNPublicKeys, len(pks)),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logDisabledPublicKeys(entityID string, pks [][]byte) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Int(logNPublicKeys, len(pks)),
+	}
+}
+
+func logAddingRotatedPublicKeys(
+	q sq.InsertBuilder, entityID string, newPKs [][]byte,
+) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Int(logNNew, len(newPKs)),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logRotatedPublicKeys(
+	entityID string, kt api.KeyType, oldPKs, newPKs [][]byte,
+) []zapcore.Field {
+	return []zapcore.Field{
+		zap.String(logEntityID, entityID),
+		zap.Stringer(logKeyType, kt),
+		zap.Int(logNOld, len(oldPKs)),
+		zap.Int(logNNew, len(newPKs)),
+	}
+}
+
+func logLockingEntityKeyType(q sq.SelectBuilder, ekt storage.EntityKeyType) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logEntityID, ekt.EntityID),
+		zap.Stringer(logKeyType, ekt.KeyType),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logAddingEvents(
+	q sq.InsertBuilder, typ api.PublicKeyEventType, pkds []*api.PublicKeyDetail,
+) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.Stringer(logEventType, typ),
+		zap.Int(logNEvents, len(pkds)),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logSelectingEvents(q sq.SelectBuilder) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logRelayedEvents(evs []*api.PublicKeyEvent) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNEvents, len(evs)),
+		zap.Uint64(logFirstSeq, evs[0].Sequence),
+		zap.Uint64(logLastSeq, evs[len(evs)-1].Sequence),
+	}
+}
+
+func logAppendingAuditRecords(
+	q sq.InsertBuilder, records []*api.AuditRecord,
+) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.Int(logNAuditRecords, len(records)),
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
+func logAppendedAuditRecords(records []*api.AuditRecord) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNAuditRecords, len(records)),
+		zap.Uint64(logLastAuditSeq, records[len(records)-1].Sequence),
+	}
+}
+
+func logListingAuditRecords(q sq.SelectBuilder) []zapcore.Field {
+	qSQL, args, err := q.ToSql()
+	errors.MaybePanic(err)
+	return []zapcore.Field{
+		zap.String(logSQL, qSQL),
+		zap.Array(logArgs, queryArgs(args)),
+	}
+}
+
 type queryArgs []interface{}
 
 func (qas queryArgs) MarshalLogArray(enc zapcore.ArrayEncoder) error {
@@ -2,6 +2,16 @@
 // sources:
 // sql/001_add-initial-tbl.down.sql
 // sql/001_add-initial-tbl.up.sql
+// sql/002_add-disabled-col.down.sql
+// sql/002_add-disabled-col.up.sql
+// sql/003_add-transaction-period-history.down.sql
+// sql/003_add-transaction-period-history.up.sql
+// sql/004_add-entity-key-type-tbl.down.sql
+// sql/004_add-entity-key-type-tbl.up.sql
+// sql/005_add-event-outbox-tbl.down.sql
+// sql/005_add-event-outbox-tbl.up.sql
+// sql/006_add-audit-log-tbl.down.sql
+// sql/006_add-audit-log-tbl.up.sql
 // DO NOT EDIT!
 
 package migrations
@@ -69,7 +79,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var __001_addInitialTblDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xc8\x4e\xad\xd4\x2b\x28\x4d\xca\xc9\x4c\x8e\xcf\x4e\xad\x8c\x4f\x49\x2d\x49\xcc\xcc\x51\x70\x76\x0c\x76\x76\x74\x71\xb5\xe6\x02\xab\x0c\x76\xf6\x70\xf5\x75\x04\x29\xb5\xe6\x02\x04\x00\x00\xff\xff\xad\x66\x95\xd3\x3b\x00\x00\x00")
+var __001_addInitialTblDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xc8\x4e\xad\xd4\x2b\x28\x4d\xca\xc9\x4c\x8e\xcf\x4e\xad\x8c\x4f\x49\x2d\x49\xcc\xcc\x51\x70\x76\x0c\x76\x76\x74\x71\xb5\xe6\x02\xab\x0c\x76\xf6\x70\xf5\x75\x04\x29\xb5\xe6\x02\x0c\x00\xad\x66\x95\xd3\x3b\x00\x00\x00")
 
 func _001_addInitialTblDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -89,7 +99,7 @@ func _001_addInitialTblDownSql() (*asset, error) {
 	return a, nil
 }
 
-var __001_addInitialTblUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x90\xd1\x4e\x83\x30\x18\x85\xef\x79\x8a\x73\x07\x4d\x88\x2f\xc0\x55\x65\xd5\x11\x59\xd1\x52\xd4\xe9\x05\x61\xa3\x9a\x66\x4b\x21\xec\x37\xa6\x3e\xbd\xc1\xe9\x30\x5b\xb8\x3d\xe7\xcf\x97\xef\xfc\xa9\x12\x5c\x0b\x94\xe9\x52\xac\x38\x76\xc6\x27\x41\xf0\x9b\x69\x7e\x9d\x8b\x31\xba\xea\x3f\x36\x7b\xbb\xad\x77\xc6\xd7\xad\xa1\xc6\xee\x11\x05\x00\x30\x74\x9f\xb5\x6d\x51\x0a\x95\xf1\x1c\xf7\x2a\x5b\x71\xb5\xc6\x9d\x58\xc7\x3f\x35\x0d\x8d\x3b\x34\x5b\xb2\x9d\xab\x7b\x33\xd8\xae\x85\x2e\xf5\x8b\xe2\xf2\x56\x40\x16\x1a\xb2\xca\x73\x2c\xc4\x0d\xaf\x72\x0d\x3a\xd0\xd7\xd0\xb8\x77\x13\xc9\xe2\x29\x62\x31\x42\xeb\xde\xac\xb3\xe4\xc3\x18\xe1\x2b\x0b\xd9\x91\x3a\xc9\x60\xe3\xc9\x34\x27\xd2\xb1\x36\x8e\x2c\xf9\x51\xeb\x91\xab\x74\xc9\xd5\x59\x3f\x8e\x20\xdf\x9b\x8b\x3a\x60\xd3\xf4\x4a\x66\x0f\x95\x40\x26\x17\xe2\x19\x17\xeb\xeb\x7f\x0a\x85\x9c\xfb\xd0\x14\xb1\xe4\x8f\x3b\x07\x9c\xa4\xe7\x79\xa7\x1b\x96\x04\xdf\x01\x00\x00\xff\xff\xec\xea\x9b\xf4\xb6\x01\x00\x00")
+var __001_addInitialTblUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x90\xd1\x4e\x83\x30\x18\x85\xef\x79\x8a\x73\x07\x4d\x88\x2f\xc0\x55\x65\xd5\x11\x59\xd1\x52\xd4\xe9\x45\xc3\x46\x35\xcd\x96\x42\x58\x8d\xa9\x4f\x6f\x70\x5a\x92\x19\x6e\xcf\xf9\xf3\xe5\x3b\x7f\x2e\x18\x95\x0c\x75\xbe\x66\x1b\x8a\x83\xf6\x59\x14\xfd\x66\x92\x5e\x97\x6c\x8a\xae\x86\x8f\xdd\xd1\xec\xd5\x41\x7b\xd5\x69\xd7\x9a\x23\x92\x08\x00\xc6\xfe\x53\x99\x0e\x35\x13\x05\x2d\x71\x2f\x8a\x0d\x15\x5b\xdc\xb1\x6d\xfa\x53\xbb\xb1\xb5\xa7\x76\xef\x4c\x6f\xd5\xa0\x47\xd3\x77\x90\xb5\x7c\x11\x94\xdf\x32\xf0\x4a\x82\x37\x65\x89\x15\xbb\xa1\x4d\x29\xe1\x4e\xee\x6b\x6c\xed\xbb\x4e\x78\xf5\x94\x90\x14\xb1\xb1\x6f\xc6\x1a\xe7\xe3\x14\xf1\x2b\x89\xc9\x99\x3a\xcb\x60\xe7\x9d\x6e\x03\xe9\x5c\x4f\x92\xce\x0f\x1a\x8f\x54\xe4\x6b\x2a\x2e\x6a\x6d\x9d\x71\x7e\xb2\xbe\xec\x23\x32\x4f\x6f\x78\xf1\xd0\x30\x14\x7c\xc5\x9e\xf1\x6f\xbd\x9a\x13\x54\x7c\xe9\x43\x73\x44\xb2\x3f\xee\x12\x30\x58\xa9\xa0\xbf\x0c\x0e\xc7\x69\x18\x4b\xb2\xe8\x7b\x00\x64\x00\x14\x3b\xc9\x01\x00\x00")
 
 func _001_addInitialTblUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -104,7 +114,207 @@ func _001_addInitialTblUpSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "001_add-initial-tbl.up.sql", size: 438, mode: os.FileMode(420), modTime: time.Unix(1523419559, 0)}
+	info := bindataFileInfo{name: "001_add-initial-tbl.up.sql", size: 457, mode: os.FileMode(420), modTime: time.Unix(1523735536, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __002_addDisabledColDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xc8\x4e\xad\xd4\x2b\x28\x4d\xca\xc9\x4c\x8e\xcf\x4e\xad\x8c\x4f\x49\x2d\x49\xcc\xcc\x89\x4f\xcd\x2b\xc9\x2c\xa9\x8c\xcf\x4c\x01\x0b\x96\x54\x16\xa4\x5a\x73\x39\x07\xb9\x3a\x86\xb8\x42\xb5\x11\xa3\x45\xc1\xdf\x0f\xbb\xf1\x0a\x1a\x70\xc5\x3a\x0a\x30\xd5\x9a\xd6\x5c\x5c\x8e\x3e\x21\xae\x41\x0a\x21\x8e\x4e\x3e\xae\x38\x74\x82\x5d\xee\xec\xef\x13\xea\xeb\xa7\x90\x92\x59\x9c\x98\x94\x93\x9a\x62\xcd\x05\x18\x00\x45\x5c\x5b\xd0\xd0\x00\x00\x00")
+
+func _002_addDisabledColDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_addDisabledColDownSql,
+		"002_add-disabled-col.down.sql",
+	)
+}
+
+func _002_addDisabledColDownSql() (*asset, error) {
+	bytes, err := _002_addDisabledColDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_add-disabled-col.down.sql", size: 208, mode: os.FileMode(420), modTime: time.Unix(1792320416, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __002_addDisabledColUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x8f\xb1\xca\xc2\x30\x14\x46\xf7\x3c\xc5\x37\xfe\x3f\x88\x2f\xd0\x29\x6d\x6e\x51\xb8\x26\x12\x53\x74\x0b\xad\xc9\x10\x5a\xa4\x60\x1c\xf2\xf6\x42\xb1\x4e\x0a\xae\x97\x73\x2e\xe7\x93\xec\xc8\xc2\xc9\x9a\x09\x63\x2c\xdb\xf9\x31\x4c\xe9\xea\xc7\x58\x7c\x88\xb9\x4f\x13\xa4\x52\x68\x0c\x77\x07\x8d\x90\xee\xfd\x30\xc5\x80\xda\x18\x26\xa9\xa1\x8d\x83\xee\x98\xa1\xa8\x95\x1d\x3b\xb4\x92\x4f\x54\x09\xa1\xac\x39\x62\xaf\x15\x5d\x3e\x7f\xf5\xf1\x96\x53\x2e\x3e\x85\xe5\x98\xcb\x1c\x2b\xd1\x58\x92\x8e\x5e\xda\x2f\x0a\x8c\xfe\x12\xfd\xf7\x86\x37\x58\xe9\x7f\x01\x00\xe7\x1d\x59\x5a\xc2\xd7\x35\x95\x78\x0e\x00\xaa\xe9\x00\x65\x05\x01\x00\x00")
+
+func _002_addDisabledColUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_addDisabledColUpSql,
+		"002_add-disabled-col.up.sql",
+	)
+}
+
+func _002_addDisabledColUpSql() (*asset, error) {
+	bytes, err := _002_addDisabledColUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_add-disabled-col.up.sql", size: 261, mode: os.FileMode(420), modTime: time.Unix(1792320416, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __003_addTransactionPeriodHistoryDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x90\xc1\x4a\xc4\x30\x10\x86\xef\x79\x8a\x39\x6e\x41\x7c\x81\x9c\xc4\x8e\xb8\xa0\x89\x86\x2e\x7a\x0b\xd9\x66\xc4\xa1\x25\x0d\x69\x7a\xc8\xdb\x0b\xa2\x8d\x07\xab\x65\xaf\x1f\xf3\xfd\x30\x5f\x6b\xf4\x13\x1c\x55\x8b\xaf\x30\x50\xb9\x8e\xcb\x79\xe4\xde\x0e\x54\xac\xa7\xec\x78\xb4\x14\x32\xe7\x62\xd9\x7f\xc2\x5c\x22\xd9\x77\x9e\xf3\x94\x8a\x14\x17\xc8\x52\xdc\x1a\xbc\xe9\xf0\x4b\xdb\xa3\x80\x56\xbf\xcf\xc3\x61\x3d\xbe\x82\xef\xeb\x46\x00\x00\xbc\xdc\xa3\x41\x50\xba\x03\xcf\xb3\x3b\x8f\xe4\xa5\x10\x2d\x3e\x60\x87\x70\x67\xf4\xe3\xc6\x60\xd5\x96\x18\x29\x59\x0e\x6f\x87\x9c\x5c\x98\x5d\x9f\x79\x0a\x36\x52\xe2\xc9\x37\x52\xfc\xff\xfa\x0f\xb2\xbf\x57\x25\x6b\xa7\x93\x3a\x3e\x9f\xb6\x73\x55\xf2\x47\xa6\x8a\x1a\x29\x3e\x06\x00\x0d\x4d\x4b\xc0\xf2\x01\x00\x00")
+
+func _003_addTransactionPeriodHistoryDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__003_addTransactionPeriodHistoryDownSql,
+		"003_add-transaction-period-history.down.sql",
+	)
+}
+
+func _003_addTransactionPeriodHistoryDownSql() (*asset, error) {
+	bytes, err := _003_addTransactionPeriodHistoryDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "003_add-transaction-period-history.down.sql", size: 498, mode: os.FileMode(420), modTime: time.Unix(1792320731, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __003_addTransactionPeriodHistoryUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x90\xcb\x6a\xf3\x30\x14\x84\xf7\x7e\x8a\x59\x3a\x90\xf8\x05\xbc\x0a\xbf\x05\x7f\x36\x76\x6b\x12\xda\x9d\x50\xec\x13\x7c\x88\x91\x54\xf9\xb8\x45\x6f\x5f\x92\xe6\x06\x29\x71\x4b\xb7\xc3\x5c\x3e\x66\xb1\x80\xb3\x7d\x84\x74\x84\x66\x0c\x81\xac\xe0\x9d\xc2\xc0\xce\xc2\xed\x60\xe0\xc7\x6d\xcf\x0d\xf6\x14\x91\x72\x46\xd9\x1c\x1f\x2c\x1d\x8c\x85\xf3\x64\x21\xc1\xd8\xc1\x34\x72\xf0\x7b\x0a\xec\xda\x19\x78\xc0\x68\xf9\x6d\xa4\xa4\xa8\xab\x27\xac\xca\x42\xbd\x1e\x0a\xb2\xaf\x2e\xbd\xa7\xa8\x5b\x12\xc3\xbd\xbe\x2a\x79\xf2\xaf\x56\xcb\xb5\xc2\xa6\x5c\x3d\x6f\xd4\x29\xf5\x28\x81\xaa\xfc\xbe\x15\xe9\x55\x9a\x25\x00\xf0\xf2\x5f\xd5\x0a\xa3\xf7\x14\x34\xdb\x5d\x7a\x43\xad\x4f\xd4\x97\xfd\xe9\x61\xdd\xf1\x20\x2e\xfc\x10\x20\x4f\xa6\x6f\x20\x2b\x2c\x51\x73\x7b\x14\x25\x7a\x9a\xc4\xb9\x8f\x3c\xc0\xb9\x98\xe7\x38\xbb\x6f\x8f\x29\xab\x35\x5a\x1e\xcc\xb6\xa7\x16\xcb\xb2\xf8\xe3\x53\xf7\x68\xe7\xc7\x8e\x9b\xbf\xc3\xcc\x93\xcf\x01\x00\x70\xe8\x0f\xdb\xa3\x02\x00\x00")
+
+func _003_addTransactionPeriodHistoryUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__003_addTransactionPeriodHistoryUpSql,
+		"003_add-transaction-period-history.up.sql",
+	)
+}
+
+func _003_addTransactionPeriodHistoryUpSql() (*asset, error) {
+	bytes, err := _003_addTransactionPeriodHistoryUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "003_add-transaction-period-history.up.sql", size: 675, mode: os.FileMode(420), modTime: time.Unix(1792320731, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __004_addEntityKeyTypeTblDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xc8\x4e\xad\xd4\x4b\xcd\x2b\xc9\x2c\xa9\x8c\xcf\x4e\xad\x8c\x2f\xa9\x2c\x48\xb5\xe6\x02\x0c\x00\x44\xda\x8a\xab\x20\x00\x00\x00")
+
+func _004_addEntityKeyTypeTblDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__004_addEntityKeyTypeTblDownSql,
+		"004_add-entity-key-type-tbl.down.sql",
+	)
+}
+
+func _004_addEntityKeyTypeTblDownSql() (*asset, error) {
+	bytes, err := _004_addEntityKeyTypeTblDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "004_add-entity-key-type-tbl.down.sql", size: 32, mode: os.FileMode(420), modTime: time.Unix(1792321048, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __004_addEntityKeyTypeTblUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x8e\xcd\x4a\xc4\x30\x14\x85\xf7\x79\x8a\xb3\x9c\x81\xd6\x17\x70\x15\x87\x82\x62\x1d\x25\x54\x61\x56\x25\x93\x5c\xa7\x97\x36\x49\x49\x53\xc7\xf8\xf4\xd2\x2a\xc5\x85\xdb\xf3\xf3\xf1\x95\x25\x82\x27\xc4\x70\xc5\x48\x11\xe4\x13\xa7\x0c\xed\x2d\x7a\xca\x48\x79\xa4\x02\x43\x30\x3d\x59\x5c\x3b\xf2\xd0\xd6\xb2\xbf\x60\x9c\xcf\x03\x9b\x65\x33\x21\x05\x4c\x14\x59\x0f\xfc\x45\x48\x1d\xc1\x74\x64\x7a\xe8\x8b\x66\x3f\x25\x51\x96\x6b\xe8\xf4\x27\xbb\xd9\xc1\xcf\xee\x4c\x11\xe1\x1d\xda\x24\xfe\xa0\xbf\x28\x71\x50\x95\x6c\x2a\x34\xf2\xae\xae\x96\xe4\xe6\xc7\xa7\xed\x29\xb7\x8b\x0b\x76\x02\xc0\xaf\x65\xcb\x16\x6f\x52\x1d\xee\xa5\xc2\xf1\xb9\xc1\xf1\xb5\xae\x8b\xb5\xdf\xe6\xff\xd7\x2f\xea\xe1\x49\xaa\x13\x1e\xab\x13\x76\x1b\xab\xd8\x6e\x7b\xb1\xbf\x15\xdf\x03\x00\x52\x30\xdb\xa8\x19\x01\x00\x00")
+
+func _004_addEntityKeyTypeTblUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__004_addEntityKeyTypeTblUpSql,
+		"004_add-entity-key-type-tbl.up.sql",
+	)
+}
+
+func _004_addEntityKeyTypeTblUpSql() (*asset, error) {
+	bytes, err := _004_addEntityKeyTypeTblUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "004_add-entity-key-type-tbl.up.sql", size: 281, mode: os.FileMode(420), modTime: time.Unix(1792321048, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __005_addEventOutboxTblDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xc8\x4e\xad\xd4\x2b\x28\x4d\xca\xc9\x4c\x8e\xcf\x4e\xad\x8c\x4f\x2d\x4b\xcd\x2b\x89\xcf\x2f\x2d\x49\xca\xaf\xb0\xe6\x02\x0c\x00\x47\xe6\x2d\x62\x28\x00\x00\x00")
+
+func _005_addEventOutboxTblDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__005_addEventOutboxTblDownSql,
+		"005_add-event-outbox-tbl.down.sql",
+	)
+}
+
+func _005_addEventOutboxTblDownSql() (*asset, error) {
+	bytes, err := _005_addEventOutboxTblDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "005_add-event-outbox-tbl.down.sql", size: 40, mode: os.FileMode(420), modTime: time.Unix(1792325203, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __005_addEventOutboxTblUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8e\xc1\x4e\xeb\x30\x14\x44\xf7\xf9\x8a\x59\xb6\x52\xf3\x7e\xe0\xad\xdc\x62\x20\x22\x4d\xaa\xd4\x05\x95\x4d\xe4\xc4\x17\xc5\x6a\xea\x94\xf8\x16\xf0\xdf\xa3\x24\x15\xa0\x4a\xb0\x9e\x33\x33\x27\x8e\x41\x6f\xe4\xd8\xe3\xa5\xeb\xc1\x0d\xe1\x74\xae\x5a\x5b\xe3\x40\x01\x86\x58\xdb\xd6\xc3\x3a\x4f\x3d\x93\x41\x15\x40\xba\x6e\xc0\xbd\x76\x5e\xd7\x6c\x3b\xb7\xc0\x7b\x6f\x99\xc9\xc1\xba\xb1\xef\xf5\x91\x7e\x02\x51\x1c\x43\x3b\x03\x43\x2d\x5d\x36\x06\xac\x3b\x73\xd5\x7d\xa0\xa7\x56\x07\x74\xae\x26\x58\x46\xa3\xfd\xf4\xef\x1b\x32\x03\x76\x8c\x56\x85\x14\x4a\x42\x89\x65\x2a\x07\xa9\x7f\x93\x5f\x79\xa0\x50\x8e\xe6\xe5\x65\x69\x16\x01\x80\xa7\xd7\x33\x0d\x6b\xcb\xe4\x6e\x2b\x8b\x44\xa4\xd8\x14\xc9\x5a\x14\x7b\x3c\xc8\xfd\x62\x64\xa6\x1a\x87\x13\xe1\x51\x14\xab\x7b\x51\x20\xcb\x15\xb2\x5d\x9a\x4e\xc0\xf7\x05\xaa\xc0\xa4\xaf\xe2\xe1\xfa\x8f\x36\x39\xb6\x1c\x4a\x6b\x7e\xc9\x8d\xf5\xba\x6a\xc9\x60\x99\xe7\xa9\x14\xd9\x75\x7d\xb2\xb3\x47\x82\x4a\xd6\x72\xab\xc4\x7a\xa3\x9e\xbf\x20\xdc\xc8\x5b\xb1\x4b\x15\xb2\xfc\x69\x36\x8f\xe6\xff\xa3\xcf\x01\x00\x13\x9c\x97\xf4\xc3\x01\x00\x00")
+
+func _005_addEventOutboxTblUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__005_addEventOutboxTblUpSql,
+		"005_add-event-outbox-tbl.up.sql",
+	)
+}
+
+func _005_addEventOutboxTblUpSql() (*asset, error) {
+	bytes, err := _005_addEventOutboxTblUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "005_add-event-outbox-tbl.up.sql", size: 451, mode: os.FileMode(420), modTime: time.Unix(1792325203, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __006_addAuditLogTblDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\xc8\x4e\xad\xd4\x4b\x2c\x4d\xc9\x2c\x89\xcf\xc9\x4f\xb7\xe6\x02\x0c\x00\xc9\x00\x2f\xfb\x1a\x00\x00\x00")
+
+func _006_addAuditLogTblDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__006_addAuditLogTblDownSql,
+		"006_add-audit-log-tbl.down.sql",
+	)
+}
+
+func _006_addAuditLogTblDownSql() (*asset, error) {
+	bytes, err := _006_addAuditLogTblDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "006_add-audit-log-tbl.down.sql", size: 26, mode: os.FileMode(420), modTime: time.Unix(1792325892, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __006_addAuditLogTblUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x44\x8e\xd1\x4a\xc3\x30\x18\x85\xef\xf3\x14\xe7\x52\x61\xf1\x05\xbc\xca\x46\xd1\x62\xad\x12\xaa\xb0\xab\x91\x26\xff\x4c\x58\x4c\x6a\xf2\x17\xe9\xdb\xcb\xea\xd8\xae\xbf\x73\xf8\x3e\x29\xe1\x4d\xf5\xd2\x7a\x13\x12\x39\x98\xd9\x05\x46\x21\x9b\x8b\xab\xc8\x47\x4c\xf3\x18\x83\xc5\x89\x16\x7c\xcf\x6c\x38\xe4\x54\x37\x30\xd3\x44\xc9\x91\x43\x48\xa8\xf4\x33\x53\xb2\x84\x5c\x1c\x15\xfc\xfa\x10\x09\xec\x09\x6c\xc6\x48\x42\x4a\x84\x8a\x98\xed\x89\x1c\x6a\x06\x19\xeb\x2f\x82\x33\xb8\x8a\x8f\x4c\x65\xbd\x45\x53\x19\x39\x91\xd8\xe9\x46\x0d\x0d\x06\xb5\xed\x9a\x73\xc0\xc3\x1a\x77\x88\xf9\x0b\x77\x02\xc0\xcd\xbc\x6d\x9f\xda\x7e\xc0\xbb\x6e\x5f\x95\xde\xe3\xa5\xd9\x6f\xd6\x01\x25\x0e\xbc\x1c\x82\xc3\xa7\xd2\xbb\x67\xa5\xd1\xbf\x0d\xe8\x3f\xba\xee\x9f\x5f\x32\xc6\x85\xc9\x5c\x91\xb8\x7f\x14\x7f\x03\x00\x1b\x84\xe2\x95\x17\x01\x00\x00")
+
+func _006_addAuditLogTblUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__006_addAuditLogTblUpSql,
+		"006_add-audit-log-tbl.up.sql",
+	)
+}
+
+func _006_addAuditLogTblUpSql() (*asset, error) {
+	bytes, err := _006_addAuditLogTblUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "006_add-audit-log-tbl.up.sql", size: 279, mode: os.FileMode(420), modTime: time.Unix(1792325892, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -161,8 +371,18 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"001_add-initial-tbl.down.sql": _001_addInitialTblDownSql,
-	"001_add-initial-tbl.up.sql":   _001_addInitialTblUpSql,
+	"001_add-initial-tbl.down.sql":                _001_addInitialTblDownSql,
+	"001_add-initial-tbl.up.sql":                  _001_addInitialTblUpSql,
+	"002_add-disabled-col.down.sql":               _002_addDisabledColDownSql,
+	"002_add-disabled-col.up.sql":                 _002_addDisabledColUpSql,
+	"003_add-transaction-period-history.down.sql": _003_addTransactionPeriodHistoryDownSql,
+	"003_add-transaction-period-history.up.sql":   _003_addTransactionPeriodHistoryUpSql,
+	"004_add-entity-key-type-tbl.down.sql":        _004_addEntityKeyTypeTblDownSql,
+	"004_add-entity-key-type-tbl.up.sql":          _004_addEntityKeyTypeTblUpSql,
+	"005_add-event-outbox-tbl.down.sql":           _005_addEventOutboxTblDownSql,
+	"005_add-event-outbox-tbl.up.sql":             _005_addEventOutboxTblUpSql,
+	"006_add-audit-log-tbl.down.sql":              _006_addAuditLogTblDownSql,
+	"006_add-audit-log-tbl.up.sql":                _006_addAuditLogTblUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -206,8 +426,18 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"001_add-initial-tbl.down.sql": &bintree{_001_addInitialTblDownSql, map[string]*bintree{}},
-	"001_add-initial-tbl.up.sql":   &bintree{_001_addInitialTblUpSql, map[string]*bintree{}},
+	"001_add-initial-tbl.down.sql":                &bintree{_001_addInitialTblDownSql, map[string]*bintree{}},
+	"001_add-initial-tbl.up.sql":                  &bintree{_001_addInitialTblUpSql, map[string]*bintree{}},
+	"002_add-disabled-col.down.sql":               &bintree{_002_addDisabledColDownSql, map[string]*bintree{}},
+	"002_add-disabled-col.up.sql":                 &bintree{_002_addDisabledColUpSql, map[string]*bintree{}},
+	"003_add-transaction-period-history.down.sql": &bintree{_003_addTransactionPeriodHistoryDownSql, map[string]*bintree{}},
+	"003_add-transaction-period-history.up.sql":   &bintree{_003_addTransactionPeriodHistoryUpSql, map[string]*bintree{}},
+	"004_add-entity-key-type-tbl.down.sql":        &bintree{_004_addEntityKeyTypeTblDownSql, map[string]*bintree{}},
+	"004_add-entity-key-type-tbl.up.sql":          &bintree{_004_addEntityKeyTypeTblUpSql, map[string]*bintree{}},
+	"005_add-event-outbox-tbl.down.sql":           &bintree{_005_addEventOutboxTblDownSql, map[string]*bintree{}},
+	"005_add-event-outbox-tbl.up.sql":             &bintree{_005_addEventOutboxTblUpSql, map[string]*bintree{}},
+	"006_add-audit-log-tbl.down.sql":              &bintree{_006_addAuditLogTblDownSql, map[string]*bintree{}},
+	"006_add-audit-log-tbl.up.sql":                &bintree{_006_addAuditLogTblUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,4 @@
+DROP INDEX key.public_key_detail_entity_id_key_type;
+CREATE INDEX public_key_detail_entity_id_key_type ON key.public_key_detail (entity_id, key_type);
+
+ALTER TABLE key.public_key_detail DROP COLUMN disabled;
@@ -0,0 +1,5 @@
+ALTER TABLE key.public_key_detail ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+DROP INDEX key.public_key_detail_entity_id_key_type;
+CREATE INDEX public_key_detail_entity_id_key_type ON key.public_key_detail (entity_id, key_type)
+    WHERE NOT disabled;
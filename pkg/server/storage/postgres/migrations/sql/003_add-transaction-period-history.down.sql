@@ -0,0 +1,10 @@
+DROP INDEX key.public_key_detail_entity_id_key_type_history;
+DROP INDEX key.public_key_detail_entity_id_key_type;
+CREATE INDEX public_key_detail_entity_id_key_type ON key.public_key_detail (entity_id, key_type)
+    WHERE NOT disabled;
+
+DELETE FROM key.public_key_detail WHERE NOT upper_inf(transaction_period);
+
+DROP INDEX key.public_key_detail_public_key_history;
+DROP INDEX key.public_key_detail_public_key;
+CREATE UNIQUE INDEX public_key_detail_public_key ON key.public_key_detail (public_key);
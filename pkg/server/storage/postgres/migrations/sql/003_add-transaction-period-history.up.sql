@@ -0,0 +1,11 @@
+-- only the current version of a public key (i.e., with an open transaction period) is unique
+DROP INDEX key.public_key_detail_public_key;
+CREATE UNIQUE INDEX public_key_detail_public_key ON key.public_key_detail (public_key)
+    WHERE upper_inf(transaction_period);
+CREATE INDEX public_key_detail_public_key_history ON key.public_key_detail (public_key);
+
+DROP INDEX key.public_key_detail_entity_id_key_type;
+CREATE INDEX public_key_detail_entity_id_key_type ON key.public_key_detail (entity_id, key_type)
+    WHERE NOT disabled AND upper_inf(transaction_period);
+CREATE INDEX public_key_detail_entity_id_key_type_history
+    ON key.public_key_detail (entity_id, key_type);
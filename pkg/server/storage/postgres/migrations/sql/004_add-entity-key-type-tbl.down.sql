@@ -0,0 +1 @@
+DROP TABLE key.entity_key_type;
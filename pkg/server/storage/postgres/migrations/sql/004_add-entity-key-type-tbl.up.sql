@@ -0,0 +1,7 @@
+-- one row per entity and key type, locked when adding public keys to serialize the check against
+-- the maximum number of active public keys
+CREATE TABLE key.entity_key_type (
+    entity_id VARCHAR NOT NULL,
+    key_type VARCHAR NOT NULL,
+    PRIMARY KEY (entity_id, key_type)
+);
@@ -0,0 +1 @@
+DROP TABLE key.public_key_event_outbox;
@@ -0,0 +1,11 @@
+-- events for the public key details inserted by each transaction, written in the same transaction
+-- and deleted by the outbox relay once it has published them
+CREATE TABLE key.public_key_event_outbox (
+    sequence BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR NOT NULL,
+    public_key bytea NOT NULL,
+    key_type VARCHAR NOT NULL,
+    entity_id VARCHAR NOT NULL,
+    disabled BOOLEAN NOT NULL,
+    event_time TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
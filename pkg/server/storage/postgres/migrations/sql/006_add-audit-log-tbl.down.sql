@@ -0,0 +1 @@
+DROP TABLE key.audit_log;
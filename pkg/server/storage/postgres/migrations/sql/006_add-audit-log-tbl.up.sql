@@ -0,0 +1,7 @@
+-- hash-chained audit records of public key mutations, appended in sequence order while the table
+-- is locked so each record is chained after the last one
+CREATE TABLE key.audit_log (
+    sequence BIGINT PRIMARY KEY,
+    entity_id VARCHAR NOT NULL,
+    record bytea NOT NULL
+);
@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	errors2 "github.com/drausin/libri/libri/common/errors"
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/events"
+	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	eventOutboxTable   = "public_key_event_outbox"
+	fqEventOutboxTable = keySchema + "." + eventOutboxTable
+
+	sequenceCol  = "sequence"
+	eventTypeCol = "event_type"
+	eventTimeCol = "event_time"
+
+	// relayLock takes the transaction-level advisory lock held while relaying events, so only
+	// one relay among the instances sharing the DB publishes them at a time and they stay in
+	// order
+	relayLock = "pg_try_advisory_xact_lock(hashtext('" + fqEventOutboxTable + "'))"
+
+	// lockEventInserts takes the transaction-level advisory lock held from inserting events until
+	// the transaction ends. Sequence numbers are assigned when events are inserted rather than
+	// when they're committed, so serializing the inserting transactions keeps the outbox's
+	// sequence order the same as its commit order, and a relay never sees an event before an
+	// earlier-sequenced one that has yet to commit.
+	lockEventInserts = "SELECT pg_advisory_xact_lock(hashtext('" + fqEventOutboxTable +
+		".insert'))"
+
+	// DefaultRelayInterval is the default interval between relays of the outbox events.
+	DefaultRelayInterval = 1 * time.Second
+
+	// DefaultRelayBatchSize is the default maximum number of outbox events published at once.
+	DefaultRelayBatchSize = 100
+)
+
+// insertEvents adds an event of the given type for each of the public key details to the outbox
+// within the transaction, so the events are relayed if and only if the transaction commits. It
+// first locks the outbox against other transactions' inserts until this one ends.
+func (s *storer) insertEvents(
+	ctx context.Context, tx *sql.Tx, typ api.PublicKeyEventType, pkds []*api.PublicKeyDetail,
+) error {
+	if len(pkds) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, lockEventInserts); err != nil {
+		return err
+	}
+	q := psql.RunWith(tx).
+		Insert(fqEventOutboxTable).
+		Columns(append([]string{eventTypeCol}, append(pkdSQLCols, disabledCol)...)...)
+	for _, pkd := range pkds {
+		values := append([]interface{}{typ.String()}, getPKDSQLValues(pkd)...)
+		q = q.Values(append(values, pkd.Disabled)...)
+	}
+	s.logger.Debug("adding public key events to outbox", logAddingEvents(q, typ, pkds)...)
+	_, err := s.qr.InsertExecContext(ctx, q)
+	return err
+}
+
+// disabledPKD returns a disabled copy of the public key detail.
+func disabledPKD(pkd *api.PublicKeyDetail) *api.PublicKeyDetail {
+	disabled := *pkd
+	disabled.Disabled = true
+	return &disabled
+}
+
+// RelayParameters defines how often and in what batch sizes a Relay publishes the outbox events.
+type RelayParameters struct {
+	Interval  time.Duration
+	BatchSize uint
+}
+
+// NewDefaultRelayParameters returns a *RelayParameters object with default values.
+func NewDefaultRelayParameters() *RelayParameters {
+	return &RelayParameters{
+		Interval:  DefaultRelayInterval,
+		BatchSize: DefaultRelayBatchSize,
+	}
+}
+
+// MarshalLogObject writes the parameters to the given object encoder.
+func (p *RelayParameters) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddDuration(logInterval, p.Interval)
+	oe.AddUint(logBatchSize, p.BatchSize)
+	return nil
+}
+
+// Relay publishes the events the storer adds to the outbox with an EventPublisher, deleting them
+// from the outbox once they have been published. Since events are only deleted after being
+// published, each is published at least once.
+type Relay struct {
+	db        *sql.DB
+	qr        bstorage.Querier
+	publisher events.EventPublisher
+	params    *RelayParameters
+	logger    *zap.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRelay creates a new Relay of the events in the outbox of the Postgres DB at the given dbURL.
+func NewRelay(
+	dbURL string, publisher events.EventPublisher, params *RelayParameters, logger *zap.Logger,
+) (*Relay, error) {
+	if dbURL == "" {
+		return nil, errEmptyDBUrl
+	}
+	db, err := sql.Open("postgres", dbURL)
+	errors2.MaybePanic(err)
+	return &Relay{
+		db:        db,
+		qr:        bstorage.NewQuerier(),
+		publisher: publisher,
+		params:    params,
+		logger:    logger,
+	}, nil
+}
+
+// Start starts relaying the outbox events every interval until Stop is called.
+func (r *Relay) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel, r.done = cancel, make(chan struct{})
+	go r.run(ctx, r.done)
+}
+
+// Stop stops relaying the outbox events, waiting for any relay in progress to end, and closes
+// the DB connection. The events not yet published remain in the outbox for the next relay.
+func (r *Relay) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+		r.cancel = nil
+	}
+	return r.db.Close()
+}
+
+func (r *Relay) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.params.Interval)
+	defer ticker.Stop()
+	for {
+		n, err := r.RelayEvents(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error("error relaying public key events", zap.Error(err))
+		}
+		if err == nil && n == int(r.params.BatchSize) && ctx.Err() == nil {
+			// more events are probably waiting, so relay them without waiting
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayEvents publishes the next batch of outbox events in order of their sequence numbers and
+// deletes them from the outbox, returning how many it published. It publishes none if another
+// relay holds the outbox lock.
+func (r *Relay) RelayEvents(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	q1 := psql.RunWith(tx).Select(relayLock)
+	var locked bool
+	if err = r.qr.SelectQueryRowContext(ctx, q1).Scan(&locked); err != nil {
+		return 0, rollback(tx, err)
+	}
+	if !locked {
+		r.logger.Debug("outbox locked by another relay")
+		return 0, rollback(tx, nil)
+	}
+	cols, _, _ := prepEventScan()
+	q2 := psql.RunWith(tx).
+		Select(cols...).
+		From(fqEventOutboxTable).
+		OrderBy(sequenceCol).
+		Limit(uint64(r.params.BatchSize))
+	r.logger.Debug("selecting public key events from outbox", logSelectingEvents(q2)...)
+	evs, err := r.selectEvents(ctx, q2)
+	if err != nil {
+		return 0, rollback(tx, err)
+	}
+	if len(evs) == 0 {
+		return 0, rollback(tx, nil)
+	}
+	if err = r.publisher.Publish(ctx, evs); err != nil {
+		return 0, rollback(tx, err)
+	}
+	seqs := make([]uint64, len(evs))
+	for i, e := range evs {
+		seqs[i] = e.Sequence
+	}
+	q3 := psql.RunWith(tx).
+		Delete(fqEventOutboxTable).
+		Where(sq.Eq{sequenceCol: seqs})
+	if _, err = r.qr.DeleteExecContext(ctx, q3); err != nil {
+		return 0, rollback(tx, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	r.logger.Debug("relayed public key events", logRelayedEvents(evs)...)
+	return len(evs), nil
+}
+
+func (r *Relay) selectEvents(
+	ctx context.Context, q sq.SelectBuilder,
+) ([]*api.PublicKeyEvent, error) {
+	rows, err := r.qr.SelectQueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			r.logger.Error("error closing rows", zap.Error(err))
+		}
+	}()
+	evs := make([]*api.PublicKeyEvent, 0, r.params.BatchSize)
+	for rows.Next() {
+		_, dest, create := prepEventScan()
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		evs = append(evs, create())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return evs, nil
+}
+
+func prepEventScan() ([]string, []interface{}, func() *api.PublicKeyEvent) {
+	var seq int64
+	var typeStr string
+	var eventTime time.Time
+	pkdCols, pkdDests, createPKD := prepPKDScan()
+	cols := append([]string{sequenceCol, eventTypeCol, eventTimeCol}, pkdCols...)
+	dests := append([]interface{}{&seq, &typeStr, &eventTime}, pkdDests...)
+	return cols, dests, func() *api.PublicKeyEvent {
+		return &api.PublicKeyEvent{
+			Sequence:        uint64(seq),
+			Type:            api.PublicKeyEventType(api.PublicKeyEventType_value[typeStr]),
+			PublicKeyDetail: createPKD(),
+			Time:            eventTime.UnixNano() / 1e3,
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/drausin/libri/libri/common/logging"
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/events"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/service-base/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRelay_RelayEvents_ok(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+	pkds := api.NewTestPublicKeyDetails(rng, 4)
+	entityID, kt := pkds[0].EntityId, pkds[0].KeyType
+	newPK := util.RandBytes(rng, 33)
+	record := storage.NewPublicKeyRecord(api.NewTestPublicKeyDetails(rng, 1)[0],
+		time.Now().Add(-time.Hour), time.Now().Add(-time.Minute))
+	record.PublicKeyDetail.Disabled = true
+
+	err = s.AddPublicKeys(context.Background(), pkds, nil)
+	assert.Nil(t, err)
+	err = s.DisablePublicKeys(context.Background(), entityID, [][]byte{pkds[0].PublicKey}, nil)
+	assert.Nil(t, err)
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, [][]byte{pkds[0].PublicKey},
+		[][]byte{newPK}, nil)
+	assert.Nil(t, err)
+	err = s.PutPublicKeyRecords(context.Background(), []*api.PublicKeyRecord{record}, nil)
+	assert.Nil(t, err)
+
+	p := events.NewMemoryPublisher()
+	relayParams := &RelayParameters{Interval: time.Second, BatchSize: 3}
+	r, err := NewRelay(dbURL, p, relayParams, lg)
+	assert.Nil(t, err)
+	for _, expected := range []int{3, 3, 2, 0} {
+		n, err := r.RelayEvents(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, expected, n)
+	}
+
+	activeRecordPKD := *record.PublicKeyDetail
+	activeRecordPKD.Disabled = false
+	expected := []struct {
+		typ api.PublicKeyEventType
+		pkd *api.PublicKeyDetail
+	}{
+		{api.PublicKeyEventType_ADDED, pkds[0]},
+		{api.PublicKeyEventType_ADDED, pkds[1]},
+		{api.PublicKeyEventType_ADDED, pkds[2]},
+		{api.PublicKeyEventType_ADDED, pkds[3]},
+		{api.PublicKeyEventType_DISABLED, disabledPKD(pkds[0])},
+		{api.PublicKeyEventType_ADDED,
+			&api.PublicKeyDetail{PublicKey: newPK, KeyType: kt, EntityId: entityID}},
+		{api.PublicKeyEventType_ADDED, &activeRecordPKD},
+		{api.PublicKeyEventType_DISABLED, record.PublicKeyDetail},
+	}
+	published := p.Events()
+	assert.Len(t, published, len(expected))
+	for i, e := range published {
+		assert.Equal(t, expected[i].typ, e.Type)
+		assert.Equal(t, expected[i].pkd, e.PublicKeyDetail)
+		assert.NotZero(t, e.Time)
+		if i > 0 {
+			assert.True(t, e.Sequence > published[i-1].Sequence)
+		}
+	}
+
+	err = r.Stop()
+	assert.Nil(t, err)
+}
+
+func TestRelay_RelayEvents_err(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+	err = s.AddPublicKeys(context.Background(), api.NewTestPublicKeyDetails(rng, 2), nil)
+	assert.Nil(t, err)
+
+	// events aren't deleted from the outbox when publishing them fails
+	p := events.NewMemoryPublisher()
+	assert.Nil(t, p.Close())
+	r, err := NewRelay(dbURL, p, NewDefaultRelayParameters(), lg)
+	assert.Nil(t, err)
+	n, err := r.RelayEvents(context.Background())
+	assert.Equal(t, events.ErrPublisherClosed, err)
+	assert.Zero(t, n)
+
+	r.publisher = events.NewMemoryPublisher()
+	n, err = r.RelayEvents(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	assert.Nil(t, r.Stop())
+}
+
+func TestStorer_insertEvents_commitOrder(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	st, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+	s := st.(*storer)
+	pkds := api.NewTestPublicKeyDetails(rng, 2)
+	ctx := context.Background()
+
+	// the second transaction can't insert its event until the first one commits
+	tx1, err := s.db.BeginTx(ctx, nil)
+	assert.Nil(t, err)
+	err = s.insertEvents(ctx, tx1, api.PublicKeyEventType_ADDED, pkds[:1])
+	assert.Nil(t, err)
+	inserted := make(chan error)
+	go func() {
+		tx2, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			inserted <- err
+			return
+		}
+		err = s.insertEvents(ctx, tx2, api.PublicKeyEventType_ADDED, pkds[1:])
+		if err != nil {
+			inserted <- rollback(tx2, err)
+			return
+		}
+		inserted <- tx2.Commit()
+	}()
+	select {
+	case err = <-inserted:
+		assert.Fail(t, "second insert didn't wait for the first commit", "%v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Nil(t, tx1.Commit())
+	assert.Nil(t, <-inserted)
+
+	p := events.NewMemoryPublisher()
+	r, err := NewRelay(dbURL, p, NewDefaultRelayParameters(), lg)
+	assert.Nil(t, err)
+	n, err := r.RelayEvents(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+	published := p.Events()
+	assert.Equal(t, pkds[0], published[0].PublicKeyDetail)
+	assert.Equal(t, pkds[1], published[1].PublicKeyDetail)
+	assert.Nil(t, r.Stop())
+}
+
+func TestRelay_StartStop(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+	p := events.NewMemoryPublisher()
+	relayParams := &RelayParameters{Interval: 10 * time.Millisecond, BatchSize: 2}
+	r, err := NewRelay(dbURL, p, relayParams, lg)
+	assert.Nil(t, err)
+
+	r.Start()
+	r.Start() // no-op when already started
+	err = s.AddPublicKeys(context.Background(), api.NewTestPublicKeyDetails(rng, 5), nil)
+	assert.Nil(t, err)
+	for len(p.Events()) < 5 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, r.Stop())
+	assert.Len(t, p.Events(), 5)
+}
+
+func TestNewRelay_err(t *testing.T) {
+	r, err := NewRelay("", events.NewMemoryPublisher(), NewDefaultRelayParameters(),
+		zap.NewNop())
+	assert.Equal(t, errEmptyDBUrl, err)
+	assert.Nil(t, r)
+}
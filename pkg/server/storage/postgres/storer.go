@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"errors"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	errors2 "github.com/drausin/libri/libri/common/errors"
@@ -17,18 +18,43 @@ import (
 const (
 	keySchema            = "key"
 	publicKeyDetailTable = "public_key_detail"
+	entityKeyTypeTable   = "entity_key_type"
 
 	publicKeyCol = "public_key"
 	keyTypeCol   = "key_type"
 	entityIDCol  = "entity_id"
+	disabledCol  = "disabled"
+
+	transactionPeriodCol = "transaction_period"
 
 	count = "COUNT(*)"
+
+	// infinity is the upper bound of an open transaction period
+	infinity = "infinity"
 )
 
 var (
 	psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
 	fqPublicKeyDetailTable = keySchema + "." + publicKeyDetailTable
+	fqEntityKeyTypeTable   = keySchema + "." + entityKeyTypeTable
+
+	// isCurrent selects the current version of each public key detail, i.e., those whose
+	// transaction period is still open
+	isCurrent = sq.Expr("upper_inf(" + transactionPeriodCol + ")")
+
+	// addedTimeCol selects when the first version of each public key detail started
+	addedTimeCol = "(SELECT min(lower(h." + transactionPeriodCol + ")) FROM " +
+		fqPublicKeyDetailTable + " h WHERE h." + publicKeyCol + " = " + fqPublicKeyDetailTable +
+		"." + publicKeyCol + ")"
+
+	// modifiedTimeCol selects when the current version of each public key detail started
+	modifiedTimeCol = "lower(" + transactionPeriodCol + ")"
+
+	// closeTransactionPeriod ends the transaction period of a public key detail version at the
+	// start of the current transaction
+	closeTransactionPeriod = sq.Expr("tstzrange(lower(" + transactionPeriodCol +
+		"), NOW(), '[)')")
 
 	errEmptyDBUrl            = errors.New("empty DB URL")
 	errUnexpectedStorageType = errors.New("unexpected storage type")
@@ -47,7 +73,7 @@ func New(dbURL string, params *storage.Parameters, logger *zap.Logger) (storage.
 	if dbURL == "" {
 		return nil, errEmptyDBUrl
 	}
-	if params.Type != bstorage.Postgres {
+	if params.Type != storage.Postgres {
 		return nil, errUnexpectedStorageType
 	}
 	db, err := sql.Open("postgres", dbURL)
@@ -61,31 +87,77 @@ func New(dbURL string, params *storage.Parameters, logger *zap.Logger) (storage.
 	}, nil
 }
 
-func (s *storer) AddPublicKeys(pkds []*api.PublicKeyDetail) error {
+// AddPublicKeys inserts the public key details in a transaction that first locks each of their
+// entity key types and checks its number of active public keys, so concurrent adds can't together
+// exceed MaxEntityKeyTypeKeys.
+func (s *storer) AddPublicKeys(
+	ctx context.Context, pkds []*api.PublicKeyDetail, audit []*api.AuditRecord,
+) error {
 	if err := api.ValidatePublicKeyDetails(pkds); err != nil {
 		return err
 	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
 	if len(pkds) > int(s.params.MaxBatchSize) {
 		return storage.ErrMaxBatchSizeExceeded
 	}
-	q := psql.RunWith(s.db).
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	ekts, counts := storage.CountEntityKeyTypes(pkds)
+	for _, ekt := range ekts {
+		if err = s.lockEntityKeyType(ctx, tx, ekt); err != nil {
+			return rollback(tx, err)
+		}
+	}
+	pks := make([][]byte, len(pkds))
+	for i, pkd := range pkds {
+		pks[i] = pkd.PublicKey
+	}
+	existing, err := s.selectCurrentForUpdate(ctx, tx, sq.Eq{publicKeyCol: pks}, len(pks))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if len(existing) > 0 {
+		return rollback(tx, storage.ErrPublicKeyExists)
+	}
+	for _, ekt := range ekts {
+		nActive, err := s.countActive(ctx, tx, ekt.EntityID, ekt.KeyType)
+		if err != nil {
+			return rollback(tx, err)
+		}
+		if nActive+counts[ekt] > storage.MaxEntityKeyTypeKeys {
+			return rollback(tx, storage.ErrTooManyActivePublicKeys)
+		}
+	}
+	q := psql.RunWith(tx).
 		Insert(fqPublicKeyDetailTable).
 		Columns(pkdSQLCols...)
 	for _, pkd := range pkds {
 		q = q.Values(getPKDSQLValues(pkd)...)
 	}
 	s.logger.Debug("adding public keys to storage", logAddingPublicKeys(q, pkds)...)
-	ctx, cancel := context.WithTimeout(context.Background(), s.params.AddQueryTimeout)
-	defer cancel()
-	_, err := s.qr.InsertExecContext(ctx, q)
-	if err != nil {
+	if _, err = s.qr.InsertExecContext(ctx, q); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.insertEvents(ctx, tx, api.PublicKeyEventType_ADDED, pkds); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.appendAuditRecords(ctx, tx, audit); err != nil {
+		return rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 	s.logger.Debug("added public keys to storage", logAddedPublicKeys(pkds)...)
 	return nil
 }
 
-func (s *storer) GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error) {
+func (s *storer) GetPublicKeys(ctx context.Context, pks [][]byte) ([]*api.PublicKeyDetail, error) {
 	if err := api.ValidatePublicKeys(pks); err != nil {
 		return nil, err
 	}
@@ -96,9 +168,10 @@ func (s *storer) GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error) {
 	q := psql.RunWith(s.dbCache).
 		Select(cols...).
 		From(fqPublicKeyDetailTable).
-		Where(sq.Eq{publicKeyCol: pks})
+		Where(sq.Eq{publicKeyCol: pks}).
+		Where(isCurrent)
 	s.logger.Debug("getting public keys from storage", logGettingPublicKeys(q, pks)...)
-	pkds, err := s.getPKDsFromQuery(q, len(pks))
+	pkds, err := s.getPKDsFromQuery(ctx, q, len(pks))
 	if err != nil {
 		return nil, err
 	}
@@ -106,8 +179,34 @@ func (s *storer) GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error) {
 	return orderPKDs(pkds, pks), nil
 }
 
+func (s *storer) GetPublicKeysAsOf(
+	ctx context.Context, pks [][]byte, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return nil, err
+	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return nil, storage.ErrMaxBatchSizeExceeded
+	}
+	cols, _, _ := prepPKDScan()
+	q := psql.RunWith(s.dbCache).
+		Select(cols...).
+		From(fqPublicKeyDetailTable).
+		Where(sq.Eq{publicKeyCol: pks}).
+		Where(isAsOf(asOf))
+	s.logger.Debug("getting public keys as of time from storage",
+		logGettingPublicKeysAsOf(q, pks, asOf)...)
+	pkds, err := s.getPKDsFromQuery(ctx, q, len(pks))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("got public keys as of time from storage",
+		zap.Int(logNPublicKeys, len(pkds)))
+	return orderPKDs(pkds, pks), nil
+}
+
 func (s *storer) GetEntityPublicKeys(
-	entityID string, kt api.KeyType,
+	ctx context.Context, entityID string, kt api.KeyType,
 ) ([]*api.PublicKeyDetail, error) {
 	if entityID == "" {
 		return nil, api.ErrEmptyEntityID
@@ -116,10 +215,11 @@ func (s *storer) GetEntityPublicKeys(
 	q := psql.RunWith(s.dbCache).
 		Select(cols...).
 		From(fqPublicKeyDetailTable).
-		Where(sq.Eq{entityIDCol: entityID, keyTypeCol: kt.String()})
+		Where(sq.Eq{entityIDCol: entityID, keyTypeCol: kt.String(), disabledCol: false}).
+		Where(isCurrent)
 	s.logger.Debug("getting entity public keys from storage",
 		logGettingEntityPubKeys(q, entityID)...)
-	pkds, err := s.getPKDsFromQuery(q, storage.MaxEntityKeyTypeKeys)
+	pkds, err := s.getPKDsFromQuery(ctx, q, storage.MaxEntityKeyTypeKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +228,32 @@ func (s *storer) GetEntityPublicKeys(
 	return pkds, nil
 }
 
-func (s *storer) CountEntityPublicKeys(entityID string, kt api.KeyType) (int, error) {
+func (s *storer) GetEntityPublicKeysAsOf(
+	ctx context.Context, entityID string, kt api.KeyType, asOf time.Time,
+) ([]*api.PublicKeyDetail, error) {
+	if entityID == "" {
+		return nil, api.ErrEmptyEntityID
+	}
+	cols, _, _ := prepPKDScan()
+	q := psql.RunWith(s.dbCache).
+		Select(cols...).
+		From(fqPublicKeyDetailTable).
+		Where(sq.Eq{entityIDCol: entityID, keyTypeCol: kt.String(), disabledCol: false}).
+		Where(isAsOf(asOf))
+	s.logger.Debug("getting entity public keys as of time from storage",
+		logGettingEntityPubKeysAsOf(q, entityID, asOf)...)
+	pkds, err := s.getPKDsFromQuery(ctx, q, storage.MaxEntityKeyTypeKeys)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("got entity public keys as of time from storage",
+		logGotEntityPubKeys(entityID, pkds)...)
+	return pkds, nil
+}
+
+func (s *storer) CountEntityPublicKeys(
+	ctx context.Context, entityID string, kt api.KeyType,
+) (int, error) {
 	if entityID == "" {
 		return 0, api.ErrEmptyEntityID
 	}
@@ -138,10 +263,12 @@ func (s *storer) CountEntityPublicKeys(entityID string, kt api.KeyType) (int, er
 		Where(sq.Eq{
 			entityIDCol: entityID,
 			keyTypeCol:  kt.String(),
-		})
+			disabledCol: false,
+		}).
+		Where(isCurrent)
 	s.logger.Debug("counting public keys for entity",
 		logCountingEntityPubKeys(q, entityID, kt)...)
-	ctx, cancel := context.WithTimeout(context.Background(), s.params.GetEntityQueryTimeout)
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetEntityQueryTimeout)
 	defer cancel()
 	row := s.qr.SelectQueryRowContext(ctx, q)
 	var count int
@@ -153,13 +280,273 @@ func (s *storer) CountEntityPublicKeys(entityID string, kt api.KeyType) (int, er
 	return count, nil
 }
 
-func (s *storer) getPKDsFromQuery(q sq.SelectBuilder, size int) ([]*api.PublicKeyDetail, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), s.params.GetQueryTimeout)
+// DisablePublicKeys closes the transaction period of the current version of each public key and
+// adds a new disabled version, so the history of each public key is preserved.
+func (s *storer) DisablePublicKeys(
+	ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+) error {
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(pks); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(pks) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	pkds, err := s.selectCurrentForUpdate(ctx, tx, sq.Eq{entityIDCol: entityID, publicKeyCol: pks},
+		len(pks))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if len(pkds) != len(pks) {
+		// at least one of the public keys doesn't exist or belongs to another entity
+		return rollback(tx, api.ErrNoSuchPublicKey)
+	}
+	if err = s.disablePKDs(ctx, tx, entityID, pkds); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.appendAuditRecords(ctx, tx, audit); err != nil {
+		return rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Debug("disabled public keys in storage", logDisabledPublicKeys(entityID, pks)...)
+	return nil
+}
+
+// RotatePublicKeys disables the old public keys and adds the new ones within a single
+// transaction.
+func (s *storer) RotatePublicKeys(
+	ctx context.Context,
+	entityID string,
+	kt api.KeyType,
+	oldPKs, newPKs [][]byte,
+	audit []*api.AuditRecord,
+) error {
+	if entityID == "" {
+		return api.ErrEmptyEntityID
+	}
+	if err := api.ValidatePublicKeys(oldPKs); err != nil {
+		return err
+	}
+	if err := api.ValidatePublicKeys(newPKs); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(oldPKs) > int(s.params.MaxBatchSize) || len(newPKs) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	ekt := storage.EntityKeyType{EntityID: entityID, KeyType: kt}
+	if err = s.lockEntityKeyType(ctx, tx, ekt); err != nil {
+		return rollback(tx, err)
+	}
+	oldPKDs, err := s.selectCurrentForUpdate(ctx, tx, sq.Eq{
+		entityIDCol:  entityID,
+		keyTypeCol:   kt.String(),
+		publicKeyCol: oldPKs,
+	}, len(oldPKs))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if len(oldPKDs) != len(oldPKs) {
+		// at least one of the old public keys doesn't exist or belongs to another entity or
+		// key type
+		return rollback(tx, api.ErrNoSuchPublicKey)
+	}
+	existingPKDs, err := s.selectCurrentForUpdate(ctx, tx, sq.Eq{publicKeyCol: newPKs},
+		len(newPKs))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if len(existingPKDs) > 0 {
+		return rollback(tx, storage.ErrPublicKeyExists)
+	}
+	nActive, err := s.countActive(ctx, tx, entityID, kt)
+	if err != nil {
+		return rollback(tx, err)
+	}
+	nOldActive := 0
+	for _, pkd := range oldPKDs {
+		if !pkd.Disabled {
+			nOldActive++
+		}
+	}
+	if nActive-nOldActive+len(newPKs) > storage.MaxEntityKeyTypeKeys {
+		return rollback(tx, storage.ErrTooManyActivePublicKeys)
+	}
+	if err = s.disablePKDs(ctx, tx, entityID, oldPKDs); err != nil {
+		return rollback(tx, err)
+	}
+	q := psql.RunWith(tx).
+		Insert(fqPublicKeyDetailTable).
+		Columns(pkdSQLCols...)
+	newPKDs := make([]*api.PublicKeyDetail, len(newPKs))
+	for i, pk := range newPKs {
+		newPKDs[i] = &api.PublicKeyDetail{PublicKey: pk, KeyType: kt, EntityId: entityID}
+		q = q.Values(getPKDSQLValues(newPKDs[i])...)
+	}
+	s.logger.Debug("adding rotated public keys to storage",
+		logAddingRotatedPublicKeys(q, entityID, newPKs)...)
+	if _, err = s.qr.InsertExecContext(ctx, q); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.insertEvents(ctx, tx, api.PublicKeyEventType_ADDED, newPKDs); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.appendAuditRecords(ctx, tx, audit); err != nil {
+		return rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Debug("rotated public keys in storage",
+		logRotatedPublicKeys(entityID, kt, oldPKs, newPKs)...)
+	return nil
+}
+
+// lockEntityKeyType locks the row for the entity and key type, inserting it first if it doesn't
+// exist yet, so transactions adding public keys for it are serialized.
+func (s *storer) lockEntityKeyType(
+	ctx context.Context, tx *sql.Tx, ekt storage.EntityKeyType,
+) error {
+	q1 := psql.RunWith(tx).
+		Insert(fqEntityKeyTypeTable).
+		Columns(entityIDCol, keyTypeCol).
+		Values(ekt.EntityID, ekt.KeyType.String()).
+		Suffix("ON CONFLICT DO NOTHING")
+	if _, err := s.qr.InsertExecContext(ctx, q1); err != nil {
+		return err
+	}
+	q2 := psql.RunWith(tx).
+		Select(entityIDCol).
+		From(fqEntityKeyTypeTable).
+		Where(sq.Eq{entityIDCol: ekt.EntityID, keyTypeCol: ekt.KeyType.String()}).
+		Suffix("FOR UPDATE")
+	s.logger.Debug("locking entity key type", logLockingEntityKeyType(q2, ekt)...)
+	var entityID string
+	return s.qr.SelectQueryRowContext(ctx, q2).Scan(&entityID)
+}
+
+// selectCurrentForUpdate selects and locks the current versions of the public key details
+// matching the given predicate.
+func (s *storer) selectCurrentForUpdate(
+	ctx context.Context, tx *sql.Tx, pred sq.Eq, size int,
+) ([]*api.PublicKeyDetail, error) {
+	cols, _, _ := prepPKDScan()
+	q := psql.RunWith(tx).
+		Select(cols...).
+		From(fqPublicKeyDetailTable).
+		Where(pred).
+		Where(isCurrent).
+		Suffix("FOR UPDATE")
+	return s.selectPKDs(ctx, q, size)
+}
+
+// countActive counts the active public keys for the entity and key type within the transaction.
+func (s *storer) countActive(
+	ctx context.Context, tx *sql.Tx, entityID string, kt api.KeyType,
+) (int, error) {
+	q := psql.RunWith(tx).
+		Select(count).
+		From(fqPublicKeyDetailTable).
+		Where(sq.Eq{
+			entityIDCol: entityID,
+			keyTypeCol:  kt.String(),
+			disabledCol: false,
+		}).
+		Where(isCurrent)
+	s.logger.Debug("counting public keys for entity",
+		logCountingEntityPubKeys(q, entityID, kt)...)
+	var n int
+	if err := s.qr.SelectQueryRowContext(ctx, q).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// disablePKDs closes the transaction period of each public key detail not already disabled and
+// adds a new disabled version of it.
+func (s *storer) disablePKDs(
+	ctx context.Context, tx *sql.Tx, entityID string, pkds []*api.PublicKeyDetail,
+) error {
+	toDisable := make([]*api.PublicKeyDetail, 0, len(pkds))
+	toDisablePKs := make([][]byte, 0, len(pkds))
+	for _, pkd := range pkds {
+		if !pkd.Disabled {
+			toDisable = append(toDisable, disabledPKD(pkd))
+			toDisablePKs = append(toDisablePKs, pkd.PublicKey)
+		}
+	}
+	if len(toDisable) == 0 {
+		// all already disabled
+		return nil
+	}
+	q1 := psql.RunWith(tx).
+		Update(fqPublicKeyDetailTable).
+		Set(transactionPeriodCol, closeTransactionPeriod).
+		Where(sq.Eq{publicKeyCol: toDisablePKs}).
+		Where(isCurrent)
+	s.logger.Debug("closing public key transaction periods in storage",
+		logClosingPublicKeys(q1, entityID, toDisablePKs)...)
+	if _, err := s.qr.UpdateExecContext(ctx, q1); err != nil {
+		return err
+	}
+	q2 := psql.RunWith(tx).
+		Insert(fqPublicKeyDetailTable).
+		Columns(append(pkdSQLCols, disabledCol)...)
+	for _, pkd := range toDisable {
+		q2 = q2.Values(append(getPKDSQLValues(pkd), true)...)
+	}
+	s.logger.Debug("adding disabled public key versions to storage",
+		logDisablingPublicKeys(q2, entityID, toDisablePKs)...)
+	if _, err := s.qr.InsertExecContext(ctx, q2); err != nil {
+		return err
+	}
+	return s.insertEvents(ctx, tx, api.PublicKeyEventType_DISABLED, toDisable)
+}
+
+// getPKDsFromQuery selects the public key details from the query, bounded by the get query
+// timeout as well as the context's deadline.
+func (s *storer) getPKDsFromQuery(
+	ctx context.Context, q sq.SelectBuilder, size int,
+) ([]*api.PublicKeyDetail, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetQueryTimeout)
 	defer cancel()
+	return s.selectPKDs(ctx, q, size)
+}
+
+func (s *storer) selectPKDs(
+	ctx context.Context, q sq.SelectBuilder, size int,
+) ([]*api.PublicKeyDetail, error) {
 	rows, err := s.qr.SelectQueryContext(ctx, q)
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		// release the connection even when returning early or after a cancellation
+		if err := rows.Close(); err != nil {
+			s.logger.Error("error closing rows", zap.Error(err))
+		}
+	}()
 	pkds := make([]*api.PublicKeyDetail, size)
 	i := 0
 	for rows.Next() {
@@ -176,22 +563,225 @@ func (s *storer) getPKDsFromQuery(q sq.SelectBuilder, size int) ([]*api.PublicKe
 	return pkds[:i], nil
 }
 
+func (s *storer) ListPublicKeys(
+	ctx context.Context, filter *storage.ListFilter, after []byte, limit uint,
+) ([]*api.PublicKeyDetail, error) {
+	cols, _, _ := prepPKDScan()
+	q := psql.RunWith(s.dbCache).
+		Select(cols...).
+		From(fqPublicKeyDetailTable).
+		Where(isCurrent)
+	for _, pred := range getListPreds(filter) {
+		q = q.Where(pred)
+	}
+	if after != nil {
+		q = q.Where(sq.Gt{publicKeyCol: after})
+	}
+	q = q.OrderBy(publicKeyCol).Limit(uint64(limit))
+	s.logger.Debug("listing public keys from storage", logListingPublicKeys(q, filter)...)
+	pkds, err := s.getPKDsFromQuery(ctx, q, int(limit))
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug("listed public keys from storage", zap.Int(logNPublicKeys, len(pkds)))
+	return pkds, nil
+}
+
+// ListPublicKeyRecords selects the current version of each public key detail along with when
+// its first version started, which is when it was added, and when the current version started,
+// which is when it was disabled if it has been.
+func (s *storer) ListPublicKeyRecords(
+	ctx context.Context, after []byte, limit uint,
+) ([]*api.PublicKeyRecord, error) {
+	cols, _, _ := prepPKDScan()
+	q := psql.RunWith(s.dbCache).
+		Select(append(cols, addedTimeCol, modifiedTimeCol)...).
+		From(fqPublicKeyDetailTable).
+		Where(isCurrent)
+	if after != nil {
+		q = q.Where(sq.Gt{publicKeyCol: after})
+	}
+	q = q.OrderBy(publicKeyCol).Limit(uint64(limit))
+	s.logger.Debug("listing public key records from storage",
+		logListingPublicKeyRecords(q)...)
+	ctx, cancel := context.WithTimeout(ctx, s.params.GetQueryTimeout)
+	defer cancel()
+	rows, err := s.qr.SelectQueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("error closing rows", zap.Error(err))
+		}
+	}()
+	records := make([]*api.PublicKeyRecord, 0, limit)
+	for rows.Next() {
+		_, dest, create := prepPKDScan()
+		var added, modified time.Time
+		if err := rows.Scan(append(dest, &added, &modified)...); err != nil {
+			return nil, err
+		}
+		records = append(records, storage.NewPublicKeyRecord(create(), added, modified))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	s.logger.Debug("listed public key records from storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return records, nil
+}
+
+// PutPublicKeyRecords inserts a version of each public key detail with a transaction period
+// starting when it was added and, for those that have been disabled, a disabled version starting
+// when it was disabled, so their history is the same as if they had been added and disabled at
+// those times.
+func (s *storer) PutPublicKeyRecords(
+	ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+) error {
+	if err := api.ValidatePublicKeyRecords(records); err != nil {
+		return err
+	}
+	if err := api.ValidateAuditRecords(audit); err != nil {
+		return err
+	}
+	if len(records) > int(s.params.MaxBatchSize) {
+		return storage.ErrMaxBatchSizeExceeded
+	}
+	pks := make([][]byte, len(records))
+	for i, r := range records {
+		pks[i] = r.PublicKeyDetail.PublicKey
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.params.AddQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	existing, err := s.selectCurrentForUpdate(ctx, tx, sq.Eq{publicKeyCol: pks}, len(pks))
+	if err != nil {
+		return rollback(tx, err)
+	}
+	if len(existing) > 0 {
+		return rollback(tx, storage.ErrPublicKeyExists)
+	}
+	q := psql.RunWith(tx).
+		Insert(fqPublicKeyDetailTable).
+		Columns(append(pkdSQLCols, disabledCol, transactionPeriodCol)...)
+	addedPKDs := make([]*api.PublicKeyDetail, 0, len(records))
+	disabledPKDs := make([]*api.PublicKeyDetail, 0, len(records))
+	for _, r := range records {
+		values := getPKDSQLValues(r.PublicKeyDetail)
+		added, disabled := storage.RecordTimes(r)
+		if !r.PublicKeyDetail.Disabled {
+			q = q.Values(append(values, false, transactionPeriod(added, infinity))...)
+			addedPKDs = append(addedPKDs, r.PublicKeyDetail)
+			continue
+		}
+		q = q.Values(append(values, false, transactionPeriod(added, disabled))...)
+		q = q.Values(append(values, true, transactionPeriod(disabled, infinity))...)
+		activePKD := *r.PublicKeyDetail
+		activePKD.Disabled = false
+		addedPKDs = append(addedPKDs, &activePKD)
+		disabledPKDs = append(disabledPKDs, r.PublicKeyDetail)
+	}
+	s.logger.Debug("putting public key records into storage",
+		logPuttingPublicKeyRecords(q, records)...)
+	if _, err = s.qr.InsertExecContext(ctx, q); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.insertEvents(ctx, tx, api.PublicKeyEventType_ADDED, addedPKDs); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.insertEvents(ctx, tx, api.PublicKeyEventType_DISABLED, disabledPKDs); err != nil {
+		return rollback(tx, err)
+	}
+	if err = s.appendAuditRecords(ctx, tx, audit); err != nil {
+		return rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.logger.Debug("put public key records into storage",
+		zap.Int(logNPublicKeys, len(records)))
+	return nil
+}
+
 func (s *storer) Close() error {
 	return s.db.Close()
 }
 
+// rollback rolls back the transaction and returns the error that caused it.
+func rollback(tx *sql.Tx, err error) error {
+	if err2 := tx.Rollback(); err2 != nil {
+		return err2
+	}
+	return err
+}
+
+// transactionPeriod returns the transaction period from the lower time until the upper one, which
+// may be infinity.
+func transactionPeriod(lower, upper interface{}) sq.Sqlizer {
+	return sq.Expr("tstzrange(?::timestamptz, ?::timestamptz, '[)')", lower, upper)
+}
+
+// isAsOf selects the version of each public key detail whose transaction period contains the
+// given time.
+func isAsOf(asOf time.Time) sq.Sqlizer {
+	return sq.Expr(transactionPeriodCol+" @> ?::timestamptz", asOf)
+}
+
+// isAddedAfter selects the public key details whose first version started after the given time.
+func isAddedAfter(t time.Time) sq.Sqlizer {
+	return sq.Expr("NOT EXISTS (SELECT 1 FROM "+fqPublicKeyDetailTable+" h WHERE h."+
+		publicKeyCol+" = "+fqPublicKeyDetailTable+"."+publicKeyCol+" AND lower(h."+
+		transactionPeriodCol+") <= ?::timestamptz)", t)
+}
+
+func getListPreds(filter *storage.ListFilter) []sq.Sqlizer {
+	preds := make([]sq.Sqlizer, 0, 6)
+	if filter.EntityID != "" {
+		preds = append(preds, sq.Eq{entityIDCol: filter.EntityID})
+	}
+	if len(filter.KeyTypes) > 0 {
+		kts := make([]string, len(filter.KeyTypes))
+		for i, kt := range filter.KeyTypes {
+			kts[i] = kt.String()
+		}
+		preds = append(preds, sq.Eq{keyTypeCol: kts})
+	}
+	if !filter.AddedAfter.IsZero() {
+		preds = append(preds, isAddedAfter(filter.AddedAfter))
+	}
+	// the current version of a public key detail started when it was last modified
+	if !filter.ModifiedAfter.IsZero() {
+		preds = append(preds, sq.Expr("lower("+transactionPeriodCol+") > ?::timestamptz",
+			filter.ModifiedAfter))
+	}
+	if !filter.ModifiedBefore.IsZero() {
+		preds = append(preds, sq.Expr("lower("+transactionPeriodCol+") < ?::timestamptz",
+			filter.ModifiedBefore))
+	}
+	switch filter.Status {
+	case api.KeyStatus_ACTIVE:
+		preds = append(preds, sq.Eq{disabledCol: false})
+	case api.KeyStatus_REVOKED:
+		preds = append(preds, sq.Eq{disabledCol: true})
+	}
+	return preds
+}
+
+// orderPKDs returns a public key detail for each of the given public keys in the same order, with
+// nil for the public keys without one.
 func orderPKDs(pkds []*api.PublicKeyDetail, byPKs [][]byte) []*api.PublicKeyDetail {
 	pkdsMap := make(map[string]*api.PublicKeyDetail)
 	for _, pkd := range pkds {
 		pkHex := hex.EncodeToString(pkd.PublicKey)
 		pkdsMap[pkHex] = pkd
 	}
-	ordered := make([]*api.PublicKeyDetail, 0, len(pkds))
-	for _, byPK := range byPKs {
-		pkHex := hex.EncodeToString(byPK)
-		if pkd, in := pkdsMap[pkHex]; in {
-			ordered = append(ordered, pkd)
-		}
+	ordered := make([]*api.PublicKeyDetail, len(byPKs))
+	for i, byPK := range byPKs {
+		ordered[i] = pkdsMap[hex.EncodeToString(byPK)]
 	}
 	return ordered
 }
@@ -217,11 +807,13 @@ func prepPKDScan() ([]string, []interface{}, func() *api.PublicKeyDetail) {
 		{publicKeyCol, &pkd.PublicKey},
 		{keyTypeCol, &keyTypeStr},
 		{entityIDCol, &pkd.EntityId},
+		{disabledCol, &pkd.Disabled},
 	})
 	return cols, dests, func() *api.PublicKeyDetail {
 		pkd.PublicKey = *dests[0].(*[]byte)
 		pkd.KeyType = api.KeyType(api.KeyType_value[*dests[1].(*string)])
 		pkd.EntityId = *dests[2].(*string)
+		pkd.Disabled = *dests[3].(*bool)
 		return pkd
 	}
 }
@@ -1,11 +1,15 @@
 package postgres
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"math/rand"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	errors2 "github.com/drausin/libri/libri/common/errors"
@@ -14,6 +18,7 @@ import (
 	"github.com/elixirhealth/key/pkg/server/storage"
 	"github.com/elixirhealth/key/pkg/server/storage/postgres/migrations"
 	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
+	"github.com/elixirhealth/service-base/pkg/util"
 	"github.com/mattes/migrate/source/go-bindata"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -48,28 +53,33 @@ func TestStorer_AddGetPublicKeys_ok(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	pkds1 := api.NewTestPublicKeyDetails(rng, 8)
 	params := storage.NewDefaultParameters()
-	params.Type = bstorage.Postgres
+	params.Type = storage.Postgres
 	lg := logging.NewDevLogger(zap.DebugLevel)
 	s, err := New(dbURL, params, lg)
 	assert.Nil(t, err)
 
-	err = s.AddPublicKeys(pkds1)
+	err = s.AddPublicKeys(context.Background(), pkds1, nil)
 	assert.Nil(t, err)
 
 	pubKeys := make([][]byte, len(pkds1))
 	for i, pkd := range pkds1 {
 		pubKeys[i] = pkd.PublicKey
 	}
-	pkds2, err := s.GetPublicKeys(pubKeys)
+	pkds2, err := s.GetPublicKeys(context.Background(), pubKeys)
 	assert.Nil(t, err)
 	assert.Equal(t, len(pkds1), len(pkds2))
+
+	// missing key has a nil detail in its place
+	missingPK := api.NewTestPublicKey(rng)
+	pkds2, err = s.GetPublicKeys(context.Background(), [][]byte{missingPK, pubKeys[0]})
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil, pkds1[0]}, pkds2)
 }
 
 func TestStorer_AddPublicKeys_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	params := storage.NewDefaultParameters()
-	params.Type = bstorage.Postgres
-	lg := logging.NewDevLogger(zap.DebugLevel)
+	params.Type = storage.Postgres
 
 	cases := map[string]struct {
 		s        *storer
@@ -86,28 +96,117 @@ func TestStorer_AddPublicKeys_err(t *testing.T) {
 			pkds:     api.NewTestPublicKeyDetails(rng, 128),
 			expected: storage.ErrMaxBatchSizeExceeded,
 		},
-		"insert err": {
-			s: &storer{
-				params: params,
-				logger: lg,
-				qr: &fixedQuerier{
-					insertErr: errTest,
-				},
-			},
-			pkds:     api.NewTestPublicKeyDetails(rng, 8),
-			expected: errTest,
-		},
 	}
 	for desc, c := range cases {
-		err := c.s.AddPublicKeys(c.pkds)
+		err := c.s.AddPublicKeys(context.Background(), c.pkds, nil)
 		assert.Equal(t, c.expected, err, desc)
 	}
 }
 
+func TestStorer_AddPublicKeys_exists(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.InfoLevel)
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+	rng := rand.New(rand.NewSource(0))
+	pkds1 := api.NewTestPublicKeyDetails(rng, 2)
+	err = s.AddPublicKeys(context.Background(), pkds1[:1], nil)
+	assert.Nil(t, err)
+	err = s.DisablePublicKeys(context.Background(), pkds1[0].EntityId,
+		[][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// re-adding a revoked key, alone, with another, or for another entity
+	another := *pkds1[0]
+	another.EntityId = "another entity ID"
+	for _, pkds := range [][]*api.PublicKeyDetail{pkds1[:1], pkds1, {&another}} {
+		err = s.AddPublicKeys(context.Background(), pkds, nil)
+		assert.Equal(t, storage.ErrPublicKeyExists, err)
+	}
+
+	pkds2, err := s.GetPublicKeys(context.Background(),
+		[][]byte{pkds1[0].PublicKey, pkds1[1].PublicKey})
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	assert.Equal(t, pkds1[0].EntityId, pkds2[0].EntityId)
+	assert.Nil(t, pkds2[1])
+}
+
+func TestStorer_AddPublicKeys_limit(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.InfoLevel)
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+	entityID, kt := "some entity ID", api.KeyType_READER
+	nAdders, nAdderKeys := 64, 8
+
+	adderPKDs := make([][]*api.PublicKeyDetail, nAdders)
+	for i := range adderPKDs {
+		adderPKDs[i] = make([]*api.PublicKeyDetail, nAdderKeys)
+		for j := range adderPKDs[i] {
+			adderPKDs[i][j] = &api.PublicKeyDetail{
+				PublicKey: util.RandBytes(rng, 33),
+				EntityId:  entityID,
+				KeyType:   kt,
+			}
+		}
+	}
+	errs := make(chan error, nAdders)
+	wg := new(sync.WaitGroup)
+	for _, pkds := range adderPKDs {
+		wg.Add(1)
+		go func(pkds []*api.PublicKeyDetail) {
+			defer wg.Done()
+			errs <- s.AddPublicKeys(context.Background(), pkds, nil)
+		}(pkds)
+	}
+	wg.Wait()
+	close(errs)
+
+	nAdded := 0
+	for err := range errs {
+		if err == nil {
+			nAdded += nAdderKeys
+		} else {
+			assert.Equal(t, storage.ErrTooManyActivePublicKeys, err)
+		}
+	}
+	assert.Equal(t, storage.MaxEntityKeyTypeKeys, nAdded)
+	n, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, storage.MaxEntityKeyTypeKeys, n)
+
+	// adding an existing public key for another entity errors on insert
+	added, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	pkd := &api.PublicKeyDetail{
+		PublicKey: added[0].PublicKey,
+		EntityId:  "another entity ID",
+		KeyType:   kt,
+	}
+	err = s.AddPublicKeys(context.Background(), []*api.PublicKeyDetail{pkd}, nil)
+	assert.NotNil(t, err)
+}
+
 func TestStorer_GetPublicKeys_err(t *testing.T) {
 	rng := rand.New(rand.NewSource(0))
 	params := storage.NewDefaultParameters()
-	params.Type = bstorage.Postgres
+	params.Type = storage.Postgres
 	lg := logging.NewDevLogger(zap.DebugLevel)
 	n := 128
 	pubKeys := make([][]byte, n)
@@ -170,7 +269,7 @@ func TestStorer_GetPublicKeys_err(t *testing.T) {
 		},
 	}
 	for desc, c := range cases {
-		pkds, err := c.s.GetPublicKeys(c.pks)
+		pkds, err := c.s.GetPublicKeys(context.Background(), c.pks)
 		assert.Equal(t, c.expected, err, desc)
 		assert.Nil(t, pkds)
 	}
@@ -185,18 +284,18 @@ func TestStorer_GetCountEntityPublicKeys_ok(t *testing.T) {
 
 	rng := rand.New(rand.NewSource(0))
 	params := storage.NewDefaultParameters()
-	params.Type = bstorage.Postgres
+	params.Type = storage.Postgres
 	lg := logging.NewDevLogger(zap.DebugLevel)
 	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
 
 	s, err := New(dbURL, params, lg)
 	assert.Nil(t, err)
 
-	err = s.AddPublicKeys(pkds1)
+	err = s.AddPublicKeys(context.Background(), pkds1, nil)
 	assert.Nil(t, err)
 
 	entityID := pkds1[0].EntityId
-	pkds2, err := s.GetEntityPublicKeys(entityID, api.KeyType_READER)
+	pkds2, err := s.GetEntityPublicKeys(context.Background(), entityID, api.KeyType_READER)
 	assert.Nil(t, err)
 	assert.True(t, len(pkds2) > 1)
 	for _, pkd := range pkds2 {
@@ -205,14 +304,14 @@ func TestStorer_GetCountEntityPublicKeys_ok(t *testing.T) {
 		assert.NotEmpty(t, pkd.PublicKey)
 	}
 
-	n, err := s.CountEntityPublicKeys(entityID, api.KeyType_READER)
+	n, err := s.CountEntityPublicKeys(context.Background(), entityID, api.KeyType_READER)
 	assert.Nil(t, err)
 	assert.Equal(t, len(pkds2), n)
 }
 
 func TestStorer_GetEntityPublicKeys_err(t *testing.T) {
 	params := storage.NewDefaultParameters()
-	params.Type = bstorage.Postgres
+	params.Type = storage.Postgres
 	lg := logging.NewDevLogger(zap.DebugLevel)
 	entityID := "some entity ID"
 
@@ -266,15 +365,288 @@ func TestStorer_GetEntityPublicKeys_err(t *testing.T) {
 		},
 	}
 	for desc, c := range cases {
-		pkds, err := c.s.GetEntityPublicKeys(c.entityID, api.KeyType_READER)
+		pkds, err := c.s.GetEntityPublicKeys(context.Background(), c.entityID, api.KeyType_READER)
 		assert.Equal(t, c.expected, err, desc)
 		assert.Nil(t, pkds)
 	}
 }
 
+func TestStorer_ListPublicKeys_ok(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+
+	err = s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+	beforeDisable := time.Now()
+	err = s.DisablePublicKeys(context.Background(), pkds1[0].EntityId,
+		[][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// page through all public keys
+	all := &storage.ListFilter{}
+	pkds2 := make([]*api.PublicKeyDetail, 0, len(pkds1))
+	var after []byte
+	for {
+		page, err := s.ListPublicKeys(context.Background(), all, after, 10)
+		assert.Nil(t, err)
+		assert.True(t, len(page) <= 10)
+		if len(page) == 0 {
+			break
+		}
+		pkds2 = append(pkds2, page...)
+		after = page[len(page)-1].PublicKey
+	}
+	assert.Equal(t, len(pkds1), len(pkds2))
+	for i := 1; i < len(pkds2); i++ {
+		assert.True(t, bytes.Compare(pkds2[i-1].PublicKey, pkds2[i].PublicKey) < 0)
+	}
+
+	// filtered
+	revoked := &storage.ListFilter{Status: api.KeyStatus_REVOKED}
+	pkds3, err := s.ListPublicKeys(context.Background(), revoked, nil, 10)
+	assert.Nil(t, err)
+	assert.Len(t, pkds3, 1)
+	assert.Equal(t, pkds1[0].PublicKey, pkds3[0].PublicKey)
+
+	entityKeyType := &storage.ListFilter{
+		EntityID: pkds1[0].EntityId,
+		KeyTypes: []api.KeyType{pkds1[0].KeyType},
+		Status:   api.KeyStatus_ACTIVE,
+	}
+	pkds4, err := s.ListPublicKeys(context.Background(), entityKeyType, nil, 64)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, pkds4)
+	for _, pkd := range pkds4 {
+		assert.Equal(t, pkds1[0].EntityId, pkd.EntityId)
+		assert.Equal(t, pkds1[0].KeyType, pkd.KeyType)
+		assert.False(t, pkd.Disabled)
+	}
+
+	// the disabled key is still added after, even though its current version started later
+	addedBefore := &storage.ListFilter{AddedAfter: time.Now().Add(-time.Hour)}
+	pkds5, err := s.ListPublicKeys(context.Background(), addedBefore, nil, 64)
+	assert.Nil(t, err)
+	assert.Len(t, pkds5, len(pkds1))
+
+	addedLater := &storage.ListFilter{AddedAfter: time.Now()}
+	pkds6, err := s.ListPublicKeys(context.Background(), addedLater, nil, 64)
+	assert.Nil(t, err)
+	assert.Len(t, pkds6, 0)
+
+	// only the disabled key was modified after it was added
+	modifiedLater := &storage.ListFilter{ModifiedAfter: beforeDisable}
+	pkds7, err := s.ListPublicKeys(context.Background(), modifiedLater, nil, 64)
+	assert.Nil(t, err)
+	assert.Len(t, pkds7, 1)
+	assert.Equal(t, pkds1[0].PublicKey, pkds7[0].PublicKey)
+
+	modifiedEarlier := &storage.ListFilter{ModifiedBefore: beforeDisable}
+	pkds8, err := s.ListPublicKeys(context.Background(), modifiedEarlier, nil, 64)
+	assert.Nil(t, err)
+	assert.Len(t, pkds8, len(pkds1)-1)
+}
+
+func TestStorer_ListPublicKeys_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	filter := &storage.ListFilter{
+		EntityID:   "some entity ID",
+		KeyTypes:   []api.KeyType{api.KeyType_READER},
+		AddedAfter: time.Now(),
+		Status:     api.KeyStatus_ACTIVE,
+	}
+
+	cases := map[string]struct {
+		s        *storer
+		expected error
+	}{
+		"select err": {
+			s: &storer{
+				params: params,
+				logger: lg,
+				qr: &fixedQuerier{
+					selectErr: errTest,
+				},
+			},
+			expected: errTest,
+		},
+		"rows scan err": {
+			s: &storer{
+				params: params,
+				logger: lg,
+				qr: &fixedQuerier{
+					selectResult: &fixedRowScanner{
+						next:    true,
+						scanErr: errTest,
+					},
+				},
+			},
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		pkds, err := c.s.ListPublicKeys(context.Background(), filter, []byte{1, 2, 3}, 10)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, pkds)
+	}
+}
+
+func TestStorer_ListPutPublicKeyRecords_ok(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	added := time.Now().Add(-time.Hour)
+	pkds := api.NewTestPublicKeyDetails(rng, 16)
+	pkds[0].Disabled = true
+	records1 := make([]*api.PublicKeyRecord, len(pkds))
+	for i, pkd := range pkds {
+		records1[i] = storage.NewPublicKeyRecord(pkd, added, added.Add(time.Minute))
+	}
+
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+
+	err = s.PutPublicKeyRecords(context.Background(), records1, nil)
+	assert.Nil(t, err)
+
+	// can't put existing records
+	err = s.PutPublicKeyRecords(context.Background(), records1[1:2], nil)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+
+	// disabled key was active before it was disabled
+	pks := [][]byte{pkds[0].PublicKey}
+	pkds2, err := s.GetPublicKeysAsOf(context.Background(), pks, added.Add(time.Second))
+	assert.Nil(t, err)
+	assert.False(t, pkds2[0].Disabled)
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), pks, added.Add(-time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil}, pkds2)
+
+	// page through all public key records
+	records2 := make([]*api.PublicKeyRecord, 0, len(records1))
+	var after []byte
+	for {
+		page, err := s.ListPublicKeyRecords(context.Background(), after, 5)
+		assert.Nil(t, err)
+		assert.True(t, len(page) <= 5)
+		if len(page) == 0 {
+			break
+		}
+		records2 = append(records2, page...)
+		after = page[len(page)-1].PublicKeyDetail.PublicKey
+	}
+	sort.Slice(records1, func(i, j int) bool {
+		pkI, pkJ := records1[i].PublicKeyDetail.PublicKey, records1[j].PublicKeyDetail.PublicKey
+		return bytes.Compare(pkI, pkJ) < 0
+	})
+	assert.Equal(t, records1, records2)
+}
+
+func TestStorer_ListPublicKeyRecords_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+
+	cases := map[string]struct {
+		s        *storer
+		expected error
+	}{
+		"select err": {
+			s: &storer{
+				params: params,
+				logger: lg,
+				qr: &fixedQuerier{
+					selectErr: errTest,
+				},
+			},
+			expected: errTest,
+		},
+		"rows scan err": {
+			s: &storer{
+				params: params,
+				logger: lg,
+				qr: &fixedQuerier{
+					selectResult: &fixedRowScanner{
+						next:    true,
+						scanErr: errTest,
+					},
+				},
+			},
+			expected: errTest,
+		},
+		"rows err": {
+			s: &storer{
+				params: params,
+				logger: lg,
+				qr: &fixedQuerier{
+					selectResult: &fixedRowScanner{
+						errErr: errTest,
+					},
+				},
+			},
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		records, err := c.s.ListPublicKeyRecords(context.Background(), []byte{1, 2, 3}, 10)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, records)
+	}
+}
+
+func TestStorer_PutPublicKeyRecords_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	n := 128
+	records := make([]*api.PublicKeyRecord, n)
+	for i, pkd := range api.NewTestPublicKeyDetails(rng, n) {
+		records[i] = storage.NewPublicKeyRecord(pkd, time.Now(), time.Time{})
+	}
+
+	cases := map[string]struct {
+		records  []*api.PublicKeyRecord
+		expected error
+	}{
+		"bad records": {
+			records:  []*api.PublicKeyRecord{},
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"batch too large": {
+			records:  records,
+			expected: storage.ErrMaxBatchSizeExceeded,
+		},
+	}
+	for desc, c := range cases {
+		s := &storer{params: params}
+		err := s.PutPublicKeyRecords(context.Background(), c.records, nil)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
 func TestStorer_CountEntityPublicKeys_err(t *testing.T) {
 	params := storage.NewDefaultParameters()
-	params.Type = bstorage.Postgres
+	params.Type = storage.Postgres
 	lg := logging.NewDevLogger(zap.DebugLevel)
 	entityID := "some entity ID"
 
@@ -303,12 +675,349 @@ func TestStorer_CountEntityPublicKeys_err(t *testing.T) {
 		},
 	}
 	for desc, c := range cases {
-		pkds, err := c.s.CountEntityPublicKeys(c.entityID, api.KeyType_READER)
+		pkds, err := c.s.CountEntityPublicKeys(context.Background(), c.entityID, api.KeyType_READER)
 		assert.Equal(t, c.expected, err, desc)
 		assert.Zero(t, pkds)
 	}
 }
 
+func TestStorer_DisablePublicKeys_ok(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+
+	err = s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	n1, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+
+	err = s.DisablePublicKeys(context.Background(), entityID, [][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	// disabling again is a no-op
+	err = s.DisablePublicKeys(context.Background(), entityID, [][]byte{pkds1[0].PublicKey}, nil)
+	assert.Nil(t, err)
+
+	n2, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, n1-1, n2)
+
+	pkds2, err := s.GetEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, n2, len(pkds2))
+
+	pkds3, err := s.GetPublicKeys(context.Background(), [][]byte{pkds1[0].PublicKey})
+	assert.Nil(t, err)
+	assert.True(t, pkds3[0].Disabled)
+
+	// key of another entity shouldn't be disabled
+	err = s.DisablePublicKeys(context.Background(), "another entity ID",
+		[][]byte{pkds1[1].PublicKey}, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+	pkds4, err := s.GetPublicKeys(context.Background(), [][]byte{pkds1[1].PublicKey})
+	assert.Nil(t, err)
+	assert.False(t, pkds4[0].Disabled)
+}
+
+func TestStorer_GetPublicKeysAsOf_ok(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	pks := [][]byte{pkds1[0].PublicKey}
+
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+
+	beforeAdd := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	err = s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+	time.Sleep(10 * time.Millisecond)
+	beforeDisable := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	err = s.DisablePublicKeys(context.Background(), entityID, pks, nil)
+	assert.Nil(t, err)
+
+	// key didn't exist before add
+	pkds2, err := s.GetPublicKeysAsOf(context.Background(), pks, beforeAdd)
+	assert.Nil(t, err)
+	assert.Equal(t, []*api.PublicKeyDetail{nil}, pkds2)
+	pkds2, err = s.GetEntityPublicKeysAsOf(context.Background(), entityID, kt, beforeAdd)
+	assert.Nil(t, err)
+	assert.Len(t, pkds2, 0)
+
+	// key was active before disable
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), pks, beforeDisable)
+	assert.Nil(t, err)
+	assert.Equal(t, pkds1[:1], pkds2)
+	n, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	pkds2, err = s.GetEntityPublicKeysAsOf(context.Background(), entityID, kt, beforeDisable)
+	assert.Nil(t, err)
+	assert.Equal(t, n+1, len(pkds2))
+	assert.Contains(t, pkds2, pkds1[0])
+
+	// key is disabled now
+	pkds2, err = s.GetPublicKeysAsOf(context.Background(), pks, time.Now())
+	assert.Nil(t, err)
+	assert.True(t, pkds2[0].Disabled)
+	pkds2, err = s.GetEntityPublicKeysAsOf(context.Background(), entityID, kt, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, n, len(pkds2))
+}
+
+func TestStorer_GetPublicKeysAsOf_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	n := 128
+	pubKeys := make([][]byte, n)
+	for i, pkd := range api.NewTestPublicKeyDetails(rng, n) {
+		pubKeys[i] = pkd.PublicKey
+	}
+
+	cases := map[string]struct {
+		s        *storer
+		pks      [][]byte
+		expected error
+	}{
+		"bad PKDs": {
+			s:        &storer{params: params},
+			pks:      [][]byte{},
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"batch too large": {
+			s:        &storer{params: params},
+			pks:      pubKeys,
+			expected: storage.ErrMaxBatchSizeExceeded,
+		},
+		"select err": {
+			s: &storer{
+				params: params,
+				logger: lg,
+				qr: &fixedQuerier{
+					selectErr: errTest,
+				},
+			},
+			pks:      pubKeys[:8],
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		pkds, err := c.s.GetPublicKeysAsOf(context.Background(), c.pks, time.Now())
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, pkds)
+	}
+}
+
+func TestStorer_GetEntityPublicKeysAsOf_err(t *testing.T) {
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+
+	cases := map[string]struct {
+		s        *storer
+		entityID string
+		expected error
+	}{
+		"bad entityID": {
+			s:        &storer{params: params},
+			entityID: "",
+			expected: api.ErrEmptyEntityID,
+		},
+		"select err": {
+			s: &storer{
+				params: params,
+				logger: lg,
+				qr: &fixedQuerier{
+					selectErr: errTest,
+				},
+			},
+			entityID: "some entity ID",
+			expected: errTest,
+		},
+	}
+	for desc, c := range cases {
+		pkds, err := c.s.GetEntityPublicKeysAsOf(context.Background(), c.entityID,
+			api.KeyType_READER, time.Now())
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, pkds)
+	}
+}
+
+func TestStorer_DisablePublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	n := 128
+	pubKeys := make([][]byte, n)
+	for i, pkd := range api.NewTestPublicKeyDetails(rng, n) {
+		pubKeys[i] = pkd.PublicKey
+	}
+
+	cases := map[string]struct {
+		entityID string
+		pks      [][]byte
+		expected error
+	}{
+		"bad entityID": {
+			entityID: "",
+			pks:      pubKeys[:8],
+			expected: api.ErrEmptyEntityID,
+		},
+		"bad PKs": {
+			entityID: "some entity ID",
+			pks:      [][]byte{},
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"batch too large": {
+			entityID: "some entity ID",
+			pks:      pubKeys,
+			expected: storage.ErrMaxBatchSizeExceeded,
+		},
+	}
+	for desc, c := range cases {
+		s := &storer{params: params}
+		err := s.DisablePublicKeys(context.Background(), c.entityID, c.pks, nil)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestStorer_RotatePublicKeys_ok(t *testing.T) {
+	dbURL, tearDown := setUpPostgresTest()
+	defer func() {
+		err := tearDown()
+		assert.Nil(t, err)
+	}()
+
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	lg := logging.NewDevLogger(zap.DebugLevel)
+	pkds1 := api.NewTestPublicKeyDetails(rng, 64)
+
+	s, err := New(dbURL, params, lg)
+	assert.Nil(t, err)
+
+	err = s.AddPublicKeys(context.Background(), pkds1, nil)
+	assert.Nil(t, err)
+
+	entityID, kt := pkds1[0].EntityId, pkds1[0].KeyType
+	n1, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+
+	oldPKs := [][]byte{pkds1[0].PublicKey}
+	newPKs := [][]byte{util.RandBytes(rng, 33), util.RandBytes(rng, 33)}
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, oldPKs, newPKs, nil)
+	assert.Nil(t, err)
+
+	n2, err := s.CountEntityPublicKeys(context.Background(), entityID, kt)
+	assert.Nil(t, err)
+	assert.Equal(t, n1+1, n2)
+
+	pkds2, err := s.GetPublicKeys(context.Background(), append(oldPKs, newPKs...))
+	assert.Nil(t, err)
+	assert.Len(t, pkds2, 3)
+	assert.True(t, pkds2[0].Disabled)
+	for _, pkd := range pkds2[1:] {
+		assert.False(t, pkd.Disabled)
+		assert.Equal(t, entityID, pkd.EntityId)
+		assert.Equal(t, kt, pkd.KeyType)
+	}
+
+	// new key already exists
+	err = s.RotatePublicKeys(context.Background(), entityID, kt, newPKs[:1],
+		[][]byte{pkds1[1].PublicKey}, nil)
+	assert.Equal(t, storage.ErrPublicKeyExists, err)
+
+	// old key of another entity
+	err = s.RotatePublicKeys(context.Background(), "another entity ID", kt, newPKs[:1],
+		[][]byte{util.RandBytes(rng, 33)}, nil)
+	assert.Equal(t, api.ErrNoSuchPublicKey, err)
+}
+
+func TestStorer_RotatePublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := storage.NewDefaultParameters()
+	params.Type = storage.Postgres
+	n := 128
+	pubKeys := make([][]byte, n)
+	for i, pkd := range api.NewTestPublicKeyDetails(rng, n) {
+		pubKeys[i] = pkd.PublicKey
+	}
+
+	cases := map[string]struct {
+		entityID string
+		oldPKs   [][]byte
+		newPKs   [][]byte
+		expected error
+	}{
+		"bad entityID": {
+			entityID: "",
+			oldPKs:   pubKeys[:8],
+			newPKs:   pubKeys[8:16],
+			expected: api.ErrEmptyEntityID,
+		},
+		"bad old PKs": {
+			entityID: "some entity ID",
+			oldPKs:   [][]byte{},
+			newPKs:   pubKeys[8:16],
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"bad new PKs": {
+			entityID: "some entity ID",
+			oldPKs:   pubKeys[:8],
+			newPKs:   [][]byte{},
+			expected: api.ErrEmptyPublicKeys,
+		},
+		"batch too large": {
+			entityID: "some entity ID",
+			oldPKs:   pubKeys[:8],
+			newPKs:   pubKeys[8:],
+			expected: storage.ErrMaxBatchSizeExceeded,
+		},
+	}
+	for desc, c := range cases {
+		s := &storer{params: params}
+		err := s.RotatePublicKeys(context.Background(), c.entityID, api.KeyType_READER,
+			c.oldPKs, c.newPKs, nil)
+		assert.Equal(t, c.expected, err, desc)
+	}
+}
+
+func TestOrderPKDs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 2)
+	missingPK := api.NewTestPublicKey(rng)
+	byPKs := [][]byte{pkds[1].PublicKey, missingPK, pkds[0].PublicKey}
+
+	ordered := orderPKDs(pkds, byPKs)
+	assert.Equal(t, []*api.PublicKeyDetail{pkds[1], nil, pkds[0]}, ordered)
+}
+
 type fixedQuerier struct {
 	selectResult    bstorage.QueryRows
 	selectErr       error
@@ -358,7 +1067,7 @@ func (f *fixedRowScanner) Next() bool {
 }
 
 func (f *fixedRowScanner) Close() error {
-	panic("implement me")
+	return nil
 }
 
 func (f *fixedRowScanner) Err() error {
@@ -1,17 +1,20 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	api "github.com/elixirhealth/key/pkg/keyapi"
-	bstorage "github.com/elixirhealth/service-base/pkg/server/storage"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 )
 
 const (
 	// DefaultType is the default storage type.
-	DefaultType = bstorage.Memory
+	DefaultType = Memory
 
 	// DefaultMaxBatchSize is the maximum size of a batch of public keys.
 	DefaultMaxBatchSize = 64
@@ -24,25 +27,336 @@ const (
 	DefaultQueryTimeout = 1 * time.Second
 )
 
+// Type is a kind of storage. The service-base storage types only include in-memory and external
+// storage, so this service has its own.
+type Type int
+
+const (
+	// Unspecified is the zero value of an unset storage type.
+	Unspecified Type = iota
+
+	// Memory is in-memory storage.
+	Memory
+
+	// DataStore is GCP DataStore storage.
+	DataStore
+
+	// Postgres is Postgres DB storage.
+	Postgres
+
+	// Bolt is embedded Bolt DB file storage.
+	Bolt
+)
+
+var typeNames = map[Type]string{
+	Unspecified: "Unspecified",
+	Memory:      "Memory",
+	DataStore:   "DataStore",
+	Postgres:    "Postgres",
+	Bolt:        "Bolt",
+}
+
+// String returns the name of the storage type.
+func (t Type) String() string {
+	if name, in := typeNames[t]; in {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// Shared returns whether the storage type is external storage that multiple Key instances may
+// share, so writes made through one aren't seen by the caches or watchers of the others.
+func (t Type) Shared() bool {
+	return t == DataStore || t == Postgres
+}
+
 var (
 	// ErrMaxBatchSizeExceeded indicates when the number of public keys an in an add or get
 	// request ot the storer exceeds the maximum size.
 	ErrMaxBatchSizeExceeded = errors.New("number of public keys in request exceeeds max " +
 		"batch size")
+
+	// ErrTooManyActivePublicKeys indicates when adding public keys would bring the number of
+	// active public keys for an entity and key type above MaxEntityKeyTypeKeys.
+	ErrTooManyActivePublicKeys = errors.New("too many active public keys for the entity and " +
+		"key type")
+
+	// ErrPublicKeyExists indicates when a public key to be added already exists.
+	ErrPublicKeyExists = errors.New("public key already exists")
 )
 
-// Storer manages public key details.
+// Storer manages public key details. Each method stops when its context is done, and the
+// Parameters query timeouts bound how long it runs even if the context has a later deadline.
+//
+// The methods changing public keys take the audit records of the change, which they chain after
+// the last one in the audit log and append atomically with it, as AppendAuditRecords does, so a
+// change is never stored without its audit records or vice versa.
 type Storer interface {
-	AddPublicKeys(pkds []*api.PublicKeyDetail) error
-	GetPublicKeys(pks [][]byte) ([]*api.PublicKeyDetail, error)
-	GetEntityPublicKeys(entityID string, kt api.KeyType) ([]*api.PublicKeyDetail, error)
-	CountEntityPublicKeys(entityID string, kt api.KeyType) (int, error)
+	// AddPublicKeys atomically adds the public key details. It returns ErrPublicKeyExists if any
+	// of the public keys already exist, including disabled ones, and ErrTooManyActivePublicKeys
+	// if adding them would bring an entity and key type above MaxEntityKeyTypeKeys active public
+	// keys.
+	AddPublicKeys(ctx context.Context, pkds []*api.PublicKeyDetail, audit []*api.AuditRecord) error
+
+	// GetPublicKeys returns a public key detail for each given public key in the same order,
+	// with a nil detail for each public key that doesn't exist. Disabled public keys are
+	// included.
+	GetPublicKeys(ctx context.Context, pks [][]byte) ([]*api.PublicKeyDetail, error)
+
+	// GetPublicKeysAsOf is like GetPublicKeys but returns the public key details as they were at
+	// the given time, so public keys added after it are nil and ones disabled after it are
+	// active.
+	GetPublicKeysAsOf(
+		ctx context.Context, pks [][]byte, asOf time.Time,
+	) ([]*api.PublicKeyDetail, error)
+
+	// GetEntityPublicKeys returns the active public key details of the entity and key type.
+	GetEntityPublicKeys(
+		ctx context.Context, entityID string, kt api.KeyType,
+	) ([]*api.PublicKeyDetail, error)
+
+	// GetEntityPublicKeysAsOf is like GetEntityPublicKeys but returns the public key details
+	// that were active at the given time.
+	GetEntityPublicKeysAsOf(
+		ctx context.Context, entityID string, kt api.KeyType, asOf time.Time,
+	) ([]*api.PublicKeyDetail, error)
+
+	// CountEntityPublicKeys returns the number of active public keys of the entity and key
+	// type.
+	CountEntityPublicKeys(ctx context.Context, entityID string, kt api.KeyType) (int, error)
+
+	// DisablePublicKeys disables the entity's public keys, leaving already disabled ones as they
+	// are. It returns api.ErrNoSuchPublicKey if any of them don't exist or belong to another
+	// entity.
+	DisablePublicKeys(
+		ctx context.Context, entityID string, pks [][]byte, audit []*api.AuditRecord,
+	) error
+
+	// RotatePublicKeys atomically disables the entity's old public keys of the key type and adds
+	// the new ones. It returns api.ErrNoSuchPublicKey if any old public key doesn't exist or
+	// belongs to another entity or key type, ErrPublicKeyExists if any new public key already
+	// exists, and ErrTooManyActivePublicKeys if the rotation would leave more than
+	// MaxEntityKeyTypeKeys active public keys.
+	RotatePublicKeys(
+		ctx context.Context, entityID string, kt api.KeyType, oldPKs, newPKs [][]byte,
+		audit []*api.AuditRecord,
+	) error
+
+	// ListPublicKeys returns up to limit public key details matching the filter, ordered by
+	// public key and starting after the given public key if it isn't nil.
+	ListPublicKeys(
+		ctx context.Context, filter *ListFilter, after []byte, limit uint,
+	) ([]*api.PublicKeyDetail, error)
+
+	// ListPublicKeyRecords returns up to limit public key records, with the times each public
+	// key was added and disabled, ordered by public key and starting after the given public key
+	// if it isn't nil.
+	ListPublicKeyRecords(
+		ctx context.Context, after []byte, limit uint,
+	) ([]*api.PublicKeyRecord, error)
+
+	// PutPublicKeyRecords atomically adds the public key records with their times preserved, for
+	// restoring them from a backup or another storer. It returns ErrPublicKeyExists if any of
+	// them already exist.
+	PutPublicKeyRecords(
+		ctx context.Context, records []*api.PublicKeyRecord, audit []*api.AuditRecord,
+	) error
+
+	// AppendAuditRecords atomically chains the audit records after the last one in the audit
+	// log, setting their sequence numbers, previous hashes, and hashes, and appends them.
+	AppendAuditRecords(ctx context.Context, records []*api.AuditRecord) error
+
+	// ListAuditRecords returns up to limit audit records in sequence order, starting after the
+	// given sequence number.
+	ListAuditRecords(
+		ctx context.Context, afterSeq uint64, limit uint,
+	) ([]*api.AuditRecord, error)
+
+	// Close releases the storer's resources.
 	Close() error
 }
 
+// NewPublicKeyRecord returns a public key record for the public key detail added and, if it is
+// disabled, disabled at the given times.
+func NewPublicKeyRecord(pkd *api.PublicKeyDetail, added, disabled time.Time) *api.PublicKeyRecord {
+	r := &api.PublicKeyRecord{
+		PublicKeyDetail: pkd,
+		AddedTime:       toEpochMicros(added),
+	}
+	if pkd.Disabled {
+		r.DisabledTime = toEpochMicros(disabled)
+	}
+	return r
+}
+
+// RecordTimes returns the times the public key record was added and, if it is disabled, disabled.
+func RecordTimes(r *api.PublicKeyRecord) (time.Time, time.Time) {
+	var disabled time.Time
+	if r.DisabledTime != 0 {
+		disabled = fromEpochMicros(r.DisabledTime)
+	}
+	return fromEpochMicros(r.AddedTime), disabled
+}
+
+// NewAuditRecords returns an audit record of the action at the given time for each entity and key
+// type of its added and disabled public key details, attributed to the caller entity, if any.
+func NewAuditRecords(
+	action api.AuditAction,
+	callerEntityID string,
+	t time.Time,
+	added, disabled []*api.PublicKeyDetail,
+) []*api.AuditRecord {
+	all := append(append(make([]*api.PublicKeyDetail, 0, len(added)+len(disabled)), added...),
+		disabled...)
+	ekts, _ := CountEntityKeyTypes(all)
+	records := make([]*api.AuditRecord, len(ekts))
+	ektRecords := make(map[EntityKeyType]*api.AuditRecord, len(ekts))
+	for i, ekt := range ekts {
+		records[i] = &api.AuditRecord{
+			Time:           toEpochMicros(t),
+			CallerEntityId: callerEntityID,
+			Action:         action,
+			EntityId:       ekt.EntityID,
+			KeyType:        ekt.KeyType,
+		}
+		ektRecords[ekt] = records[i]
+	}
+	for _, pkd := range added {
+		r := ektRecords[EntityKeyType{EntityID: pkd.EntityId, KeyType: pkd.KeyType}]
+		r.AddedPublicKeyHashes = append(r.AddedPublicKeyHashes,
+			api.PublicKeyHashes([][]byte{pkd.PublicKey})...)
+	}
+	for _, pkd := range disabled {
+		r := ektRecords[EntityKeyType{EntityID: pkd.EntityId, KeyType: pkd.KeyType}]
+		r.DisabledPublicKeyHashes = append(r.DisabledPublicKeyHashes,
+			api.PublicKeyHashes([][]byte{pkd.PublicKey})...)
+	}
+	return records
+}
+
+// ChainAuditRecords chains each audit record after the previous one, starting after the last
+// record in the audit log, which is nil if the audit log is empty.
+func ChainAuditRecords(last *api.AuditRecord, records []*api.AuditRecord) {
+	for _, r := range records {
+		api.ChainAuditRecord(last, r)
+		last = r
+	}
+}
+
+func toEpochMicros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+func fromEpochMicros(micros int64) time.Time {
+	return time.Unix(0, micros*int64(time.Microsecond))
+}
+
+// ListFilter defines which public key details ListPublicKeys returns. Empty fields match all
+// public key details. A public key detail's modified time is when it was added or, if it has
+// been disabled, when it was disabled.
+type ListFilter struct {
+	EntityID       string
+	KeyTypes       []api.KeyType
+	AddedAfter     time.Time
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	Status         api.KeyStatus
+}
+
+// Matches returns whether the public key detail, added and last modified at the given times,
+// matches the filter.
+func (f *ListFilter) Matches(pkd *api.PublicKeyDetail, added, modified time.Time) bool {
+	if f.EntityID != "" && pkd.EntityId != f.EntityID {
+		return false
+	}
+	if !f.MatchesKeyType(pkd.KeyType) {
+		return false
+	}
+	if !f.AddedAfter.IsZero() && !added.After(f.AddedAfter) {
+		return false
+	}
+	if !f.MatchesModified(modified) {
+		return false
+	}
+	switch f.Status {
+	case api.KeyStatus_ACTIVE:
+		return !pkd.Disabled
+	case api.KeyStatus_REVOKED:
+		return pkd.Disabled
+	}
+	return true
+}
+
+// MarshalLogObject writes the filter to the given object encoder.
+func (f *ListFilter) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	kts := make([]string, len(f.KeyTypes))
+	for i, kt := range f.KeyTypes {
+		kts[i] = kt.String()
+	}
+	oe.AddString(logEntityID, f.EntityID)
+	oe.AddString(logKeyTypes, strings.Join(kts, ","))
+	oe.AddTime(logAddedAfter, f.AddedAfter)
+	oe.AddTime(logModifiedAfter, f.ModifiedAfter)
+	oe.AddTime(logModifiedBefore, f.ModifiedBefore)
+	oe.AddString(logStatus, f.Status.String())
+	return nil
+}
+
+// MatchesKeyType returns whether the key type matches the filter.
+func (f *ListFilter) MatchesKeyType(kt api.KeyType) bool {
+	if len(f.KeyTypes) == 0 {
+		return true
+	}
+	for _, fkt := range f.KeyTypes {
+		if kt == fkt {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesModified returns whether the modified time is within the filter's modified time range.
+func (f *ListFilter) MatchesModified(modified time.Time) bool {
+	if !f.ModifiedAfter.IsZero() && !modified.After(f.ModifiedAfter) {
+		return false
+	}
+	if !f.ModifiedBefore.IsZero() && !modified.Before(f.ModifiedBefore) {
+		return false
+	}
+	return true
+}
+
+// EntityKeyType identifies the public keys of an entity with a given key type, whose number of
+// active keys is limited by MaxEntityKeyTypeKeys.
+type EntityKeyType struct {
+	EntityID string
+	KeyType  api.KeyType
+}
+
+// CountEntityKeyTypes returns the number of public key details for each entity and key type along
+// with the entity key types, sorted by entity ID and then key type so that callers acquiring
+// locks for them do so in a consistent order.
+func CountEntityKeyTypes(pkds []*api.PublicKeyDetail) ([]EntityKeyType, map[EntityKeyType]int) {
+	counts := make(map[EntityKeyType]int)
+	for _, pkd := range pkds {
+		counts[EntityKeyType{EntityID: pkd.EntityId, KeyType: pkd.KeyType}]++
+	}
+	ekts := make([]EntityKeyType, 0, len(counts))
+	for ekt := range counts {
+		ekts = append(ekts, ekt)
+	}
+	sort.Slice(ekts, func(i, j int) bool {
+		if ekts[i].EntityID != ekts[j].EntityID {
+			return ekts[i].EntityID < ekts[j].EntityID
+		}
+		return ekts[i].KeyType < ekts[j].KeyType
+	})
+	return ekts, counts
+}
+
 // Parameters defines the parameters of the Storer.
 type Parameters struct {
-	Type                  bstorage.Type
+	Type                  Type
 	MaxBatchSize          uint
 	AddQueryTimeout       time.Duration
 	GetQueryTimeout       time.Duration
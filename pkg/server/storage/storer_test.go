@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"math/rand"
 	"testing"
+	"time"
 
+	api "github.com/elixirhealth/key/pkg/keyapi"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -11,3 +14,153 @@ func TestNewDefaultParameters(t *testing.T) {
 	assert.NotNil(t, p)
 	// TODO assert.NotEmpty on other params
 }
+
+func TestType_String(t *testing.T) {
+	assert.Equal(t, "Memory", Memory.String())
+	assert.Equal(t, "Postgres", Postgres.String())
+	assert.Equal(t, "Bolt", Bolt.String())
+	assert.Equal(t, "Type(100)", Type(100).String())
+}
+
+func TestType_Shared(t *testing.T) {
+	assert.True(t, Postgres.Shared())
+	assert.True(t, DataStore.Shared())
+	assert.False(t, Memory.Shared())
+	assert.False(t, Bolt.Shared())
+}
+
+func TestCountEntityKeyTypes(t *testing.T) {
+	pkds := []*api.PublicKeyDetail{
+		{PublicKey: []byte{1}, EntityId: "B", KeyType: api.KeyType_READER},
+		{PublicKey: []byte{2}, EntityId: "A", KeyType: api.KeyType_READER},
+		{PublicKey: []byte{3}, EntityId: "B", KeyType: api.KeyType_AUTHOR},
+		{PublicKey: []byte{4}, EntityId: "B", KeyType: api.KeyType_READER},
+	}
+	ekts, counts := CountEntityKeyTypes(pkds)
+	assert.Equal(t, []EntityKeyType{
+		{EntityID: "A", KeyType: api.KeyType_READER},
+		{EntityID: "B", KeyType: api.KeyType_AUTHOR},
+		{EntityID: "B", KeyType: api.KeyType_READER},
+	}, ekts)
+	assert.Equal(t, map[EntityKeyType]int{
+		{EntityID: "A", KeyType: api.KeyType_READER}: 1,
+		{EntityID: "B", KeyType: api.KeyType_AUTHOR}: 1,
+		{EntityID: "B", KeyType: api.KeyType_READER}: 2,
+	}, counts)
+}
+
+func TestNewPublicKeyRecord(t *testing.T) {
+	added := time.Unix(1520000000, 123456000)
+	disabled := added.Add(time.Hour)
+	pkd := &api.PublicKeyDetail{PublicKey: []byte{1}, EntityId: "A"}
+
+	r := NewPublicKeyRecord(pkd, added, disabled)
+	assert.Equal(t, pkd, r.PublicKeyDetail)
+	assert.Equal(t, int64(1520000000123456), r.AddedTime)
+	assert.Zero(t, r.DisabledTime)
+	gotAdded, gotDisabled := RecordTimes(r)
+	assert.True(t, added.Equal(gotAdded))
+	assert.True(t, gotDisabled.IsZero())
+
+	pkd.Disabled = true
+	r = NewPublicKeyRecord(pkd, added, disabled)
+	assert.Equal(t, int64(1520003600123456), r.DisabledTime)
+	gotAdded, gotDisabled = RecordTimes(r)
+	assert.True(t, added.Equal(gotAdded))
+	assert.True(t, disabled.Equal(gotDisabled))
+}
+
+func TestChainAuditRecords(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	records := api.NewTestAuditRecords(rng, 4)
+	ChainAuditRecords(nil, records[:2])
+	ChainAuditRecords(records[1], records[2:])
+	var prev *api.AuditRecord
+	for _, r := range records {
+		assert.Nil(t, api.VerifyAuditRecord(prev, r))
+		prev = r
+	}
+	assert.Equal(t, uint64(4), records[3].Sequence)
+}
+
+func TestListFilter_Matches(t *testing.T) {
+	added := time.Now()
+	modified := added.Add(time.Hour)
+	pkd := &api.PublicKeyDetail{
+		PublicKey: []byte{1},
+		EntityId:  "A",
+		KeyType:   api.KeyType_READER,
+	}
+	disabled := &api.PublicKeyDetail{
+		PublicKey: []byte{2},
+		EntityId:  "A",
+		KeyType:   api.KeyType_READER,
+		Disabled:  true,
+	}
+	cases := map[string]struct {
+		f        *ListFilter
+		pkd      *api.PublicKeyDetail
+		expected bool
+	}{
+		"empty": {
+			f:        &ListFilter{},
+			pkd:      pkd,
+			expected: true,
+		},
+		"all fields": {
+			f: &ListFilter{
+				EntityID:       "A",
+				KeyTypes:       []api.KeyType{api.KeyType_AUTHOR, api.KeyType_READER},
+				AddedAfter:     added.Add(-time.Second),
+				ModifiedAfter:  modified.Add(-time.Second),
+				ModifiedBefore: modified.Add(time.Second),
+				Status:         api.KeyStatus_ACTIVE,
+			},
+			pkd:      pkd,
+			expected: true,
+		},
+		"other entity ID": {
+			f:        &ListFilter{EntityID: "B"},
+			pkd:      pkd,
+			expected: false,
+		},
+		"other key type": {
+			f:        &ListFilter{KeyTypes: []api.KeyType{api.KeyType_AUTHOR}},
+			pkd:      pkd,
+			expected: false,
+		},
+		"added before": {
+			f:        &ListFilter{AddedAfter: added},
+			pkd:      pkd,
+			expected: false,
+		},
+		"modified before range": {
+			f:        &ListFilter{ModifiedAfter: modified},
+			pkd:      pkd,
+			expected: false,
+		},
+		"modified after range": {
+			f:        &ListFilter{ModifiedBefore: modified},
+			pkd:      pkd,
+			expected: false,
+		},
+		"active": {
+			f:        &ListFilter{Status: api.KeyStatus_ACTIVE},
+			pkd:      disabled,
+			expected: false,
+		},
+		"revoked": {
+			f:        &ListFilter{Status: api.KeyStatus_REVOKED},
+			pkd:      disabled,
+			expected: true,
+		},
+		"not revoked": {
+			f:        &ListFilter{Status: api.KeyStatus_REVOKED},
+			pkd:      pkd,
+			expected: false,
+		},
+	}
+	for desc, c := range cases {
+		assert.Equal(t, c.expected, c.f.Matches(c.pkd, added, modified), desc)
+	}
+}
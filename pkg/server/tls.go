@@ -0,0 +1,104 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var (
+	// ErrIncompleteTLSKeyPair indicates when only one of the TLS certificate and key files is
+	// given.
+	ErrIncompleteTLSKeyPair = errors.New("TLS certificate and key files must be given together")
+
+	// ErrClientCAWithoutTLS indicates when a TLS client CA file is given without a TLS
+	// certificate and key.
+	ErrClientCAWithoutTLS = errors.New("TLS client CA requires a TLS certificate and key")
+
+	// ErrNoCertificates indicates when a certificate file has no PEM certificates.
+	ErrNoCertificates = errors.New("no PEM certificates found")
+)
+
+// LoadCertPool returns a pool of the PEM certificates in the given file.
+func LoadCertPool(certFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, ErrNoCertificates
+	}
+	return pool, nil
+}
+
+// serverTLSConfig returns the TLS config for serving with the config's certificate and key. When
+// the config has a client CA, clients must present a certificate signed by it.
+func (c *Config) serverTLSConfig() (*tls.Config, error) {
+	if err := c.validateTLS(); err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.TLSClientCAFile != "" {
+		clientCAs, err := LoadCertPool(c.TLSClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+func (c *Config) validateTLS() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return ErrIncompleteTLSKeyPair
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return ErrClientCAWithoutTLS
+	}
+	return nil
+}
+
+// serveTLS serves the Key and health services over TLS until the server is stopped. The base
+// server creates its gRPC server without transport credentials, so serving with them needs a
+// separate gRPC server, which doesn't have the base server's metrics and profiler endpoints.
+func (k *Key) serveTLS(registerServer func(s *grpc.Server), onServing func()) error {
+	tlsConfig, err := k.config.serverTLSConfig()
+	if err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", k.config.ServerPort))
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	registerServer(s)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+	k.tlsServer = s
+
+	k.Logger.Info("serving over TLS",
+		zap.Uint(logServerPort, k.config.ServerPort),
+		zap.Bool(logMutualTLS, tlsConfig.ClientCAs != nil),
+	)
+	onServing()
+	return s.Serve(lis)
+}
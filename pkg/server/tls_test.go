@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCertPool(t *testing.T) {
+	dir, caFile, _, _ := writeTestTLSFiles(t)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+
+	pool, err := LoadCertPool(caFile)
+	assert.Nil(t, err)
+	assert.NotNil(t, pool)
+
+	pool, err = LoadCertPool(filepath.Join(dir, "does-not-exist.crt"))
+	assert.NotNil(t, err)
+	assert.Nil(t, pool)
+
+	notPEMFile := filepath.Join(dir, "not-pem.crt")
+	assert.Nil(t, ioutil.WriteFile(notPEMFile, []byte("not PEM"), 0600))
+	pool, err = LoadCertPool(notPEMFile)
+	assert.Equal(t, ErrNoCertificates, err)
+	assert.Nil(t, pool)
+}
+
+func TestConfig_serverTLSConfig_ok(t *testing.T) {
+	dir, caFile, certFile, keyFile := writeTestTLSFiles(t)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+
+	c := NewDefaultConfig().WithTLS(certFile, keyFile, "")
+	assert.True(t, c.tls())
+	tlsConfig, err := c.serverTLSConfig()
+	assert.Nil(t, err)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Nil(t, tlsConfig.ClientCAs)
+	assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+
+	c.WithTLS(certFile, keyFile, caFile)
+	tlsConfig, err = c.serverTLSConfig()
+	assert.Nil(t, err)
+	assert.NotNil(t, tlsConfig.ClientCAs)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+}
+
+func TestConfig_serverTLSConfig_err(t *testing.T) {
+	dir, caFile, certFile, keyFile := writeTestTLSFiles(t)
+	defer func() { assert.Nil(t, os.RemoveAll(dir)) }()
+	missingFile := filepath.Join(dir, "does-not-exist")
+
+	cases := map[string]struct {
+		certFile, keyFile, clientCAFile string
+		expected                        error
+	}{
+		"cert without key": {
+			certFile: certFile,
+			expected: ErrIncompleteTLSKeyPair,
+		},
+		"key without cert": {
+			keyFile:  keyFile,
+			expected: ErrIncompleteTLSKeyPair,
+		},
+		"client CA without TLS": {
+			clientCAFile: caFile,
+			expected:     ErrClientCAWithoutTLS,
+		},
+		"missing cert file": {
+			certFile: missingFile,
+			keyFile:  keyFile,
+		},
+		"missing client CA file": {
+			certFile:     certFile,
+			keyFile:      keyFile,
+			clientCAFile: missingFile,
+		},
+	}
+	for desc, c := range cases {
+		config := NewDefaultConfig().WithTLS(c.certFile, c.keyFile, c.clientCAFile)
+		tlsConfig, err := config.serverTLSConfig()
+		assert.NotNil(t, err, desc)
+		if c.expected != nil {
+			assert.Equal(t, c.expected, err, desc)
+		}
+		assert.Nil(t, tlsConfig, desc)
+	}
+}
+
+// writeTestTLSFiles writes a self-signed CA certificate and a server certificate and key signed
+// by it to PEM files in a new temporary directory.
+func writeTestTLSFiles(t *testing.T) (string, string, string, string) {
+	rng := rand.New(rand.NewSource(0))
+	dir, err := ioutil.TempDir("", "key-tls-test")
+	assert.Nil(t, err)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	assert.Nil(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rng, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.Nil(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	assert.Nil(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rng)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rng, template, ca, &key.PublicKey, caKey)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	writePEM(t, caFile, "CERTIFICATE", caDER)
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return dir, caFile, certFile, keyFile
+}
+
+func writePEM(t *testing.T, filename, blockType string, der []byte) {
+	b := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	assert.Nil(t, ioutil.WriteFile(filename, b, 0600))
+}
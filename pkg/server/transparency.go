@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/transparency"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetTreeHead returns the current tree head of the transparency log of every public key detail
+// added, which is signed when the config has a signing key. Clients can check that a later tree
+// head is consistent with one they saw earlier with GetConsistencyProof.
+func (k *Key) GetTreeHead(
+	ctx context.Context, rq *api.GetTreeHeadRequest,
+) (*api.GetTreeHeadResponse, error) {
+	k.Logger.Debug("received get tree head request")
+	th, err := k.getTreeHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rp := &api.GetTreeHeadResponse{TreeHead: th}
+	k.Logger.Info("got tree head", logTreeHead(th)...)
+	return rp, nil
+}
+
+// GetConsistencyProof returns the hashes proving that the transparency log of the first tree size
+// is a prefix of the log of the second tree size, so the server can't have rewritten any public
+// key details it logged between the two tree heads.
+func (k *Key) GetConsistencyProof(
+	ctx context.Context, rq *api.GetConsistencyProofRequest,
+) (*api.GetConsistencyProofResponse, error) {
+	k.Logger.Debug("received get consistency proof request", logGetConsistencyProofRq(rq)...)
+	if err := api.ValidateGetConsistencyProofRequest(rq); err != nil {
+		k.Logger.Info("get consistency proof request invalid", zap.String(logErr, err.Error()))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := k.syncTransparencyLog(ctx); err != nil {
+		return nil, err
+	}
+	hashes, err := k.transparencyLog.ConsistencyProof(rq.FirstTreeSize, rq.SecondTreeSize)
+	if err == transparency.ErrTreeSizeTooLarge {
+		k.Logger.Info("consistency proof tree size too large",
+			logGetConsistencyProofRq(rq)...)
+		return nil, status.Error(codes.OutOfRange, err.Error())
+	} else if err != nil {
+		k.Logger.Error("transparency log consistency proof error", zap.Error(err))
+		return nil, ErrInternal
+	}
+	rp := &api.GetConsistencyProofResponse{Hashes: hashes}
+	k.Logger.Info("got consistency proof", logGetConsistencyProofRq(rq)...)
+	return rp, nil
+}
+
+// getTreeHead syncs the transparency log and returns its tree head, signed when the config has a
+// signing key.
+func (k *Key) getTreeHead(ctx context.Context) (*api.TreeHead, error) {
+	if err := k.syncTransparencyLog(ctx); err != nil {
+		return nil, err
+	}
+	size := k.transparencyLog.Size()
+	root, err := k.transparencyLog.RootHash(size)
+	if err != nil {
+		k.Logger.Error("transparency log root hash error", zap.Error(err))
+		return nil, ErrInternal
+	}
+	th := &api.TreeHead{TreeSize: size, RootHash: root, Time: signedTime()}
+	if k.signResponses() {
+		api.SignTreeHead(k.config.SigningKey, th)
+	}
+	return th, nil
+}
+
+// getInclusionProofs returns a tree head and a proof that each public key detail is in its tree.
+// Public key details that are nil or not in the transparency log have proofs that aren't found.
+func (k *Key) getInclusionProofs(
+	ctx context.Context, pkds []*api.PublicKeyDetail,
+) (*api.TreeHead, []*api.InclusionProof, error) {
+	th, err := k.getTreeHead(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	proofs := make([]*api.InclusionProof, len(pkds))
+	for i, pkd := range pkds {
+		proofs[i] = &api.InclusionProof{}
+		if pkd == nil {
+			continue
+		}
+		index, in := k.transparencyLog.LeafIndex(api.PublicKeyDetailLeafHash(pkd))
+		if !in || index >= th.TreeSize {
+			continue
+		}
+		hashes, err := k.transparencyLog.InclusionProof(index, th.TreeSize)
+		if err != nil {
+			k.Logger.Error("transparency log inclusion proof error", zap.Error(err))
+			return nil, nil, ErrInternal
+		}
+		proofs[i] = &api.InclusionProof{Found: true, LeafIndex: index, Hashes: hashes}
+	}
+	return th, proofs, nil
+}
+
+func (k *Key) syncTransparencyLog(ctx context.Context) error {
+	if err := k.transparencyLog.Sync(ctx, k.storer); err != nil {
+		k.Logger.Error("transparency log sync error", zap.Error(err))
+		return ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package transparency
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"sync"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrTreeSizeTooLarge indicates when a tree size is larger than the transparency log.
+	ErrTreeSizeTooLarge = errors.New("tree size larger than transparency log")
+
+	// ErrLeafIndexOutOfRange indicates when a leaf index isn't less than the tree size.
+	ErrLeafIndexOutOfRange = errors.New("leaf index not less than tree size")
+
+	// ErrAlreadySynced indicates when a Log is backfilled after it has been synced.
+	ErrAlreadySynced = errors.New("transparency log already synced")
+)
+
+// AuditLog lists the audit records whose added public keys are the transparency log's leaves.
+type AuditLog interface {
+	ListAuditRecords(ctx context.Context, afterSeq uint64, limit uint) ([]*api.AuditRecord, error)
+}
+
+// Storer lists the stored public key records and the audit log.
+type Storer interface {
+	AuditLog
+	ListPublicKeyRecords(
+		ctx context.Context, after []byte, limit uint,
+	) ([]*api.PublicKeyRecord, error)
+}
+
+// Log is an append-only Merkle tree, as in RFC 6962, of every public key detail added. Its first
+// leaves are the stored public key details that no audit record added, backfilled in public key
+// order, followed by the rest in the order of the audit records that added them. Since every
+// instance sharing the storage gets its leaves from the same storage, they all have the same tree
+// for each tree size.
+//
+// It keeps the hash of each complete subtree in memory, so appending a leaf takes amortized
+// constant time and root hashes and proofs take time logarithmic in the tree size.
+type Log struct {
+	logger *zap.Logger
+
+	// syncMu serializes syncs, each of which appends the leaves after the last audit record
+	// synced
+	syncMu   sync.Mutex
+	auditSeq uint64
+
+	mu sync.RWMutex
+	// levels[i][j] is the hash of the complete subtree of the 2^i leaves starting at j*2^i
+	levels [][][]byte
+	// indexes has the index of the first leaf with each leaf hash
+	indexes map[string]uint64
+}
+
+// New creates a new, empty Log.
+func New(logger *zap.Logger) *Log {
+	return &Log{
+		logger:  logger,
+		levels:  [][][]byte{{}},
+		indexes: make(map[string]uint64),
+	}
+}
+
+// Backfill appends the leaves of the stored public key details that no audit record added, such as
+// those stored before auditing, in public key order. Since public keys are audited atomically as
+// they're added, the same public key details need backfilling on every instance. It must be
+// called before the first Sync.
+func (l *Log) Backfill(ctx context.Context, s Storer) error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	if l.auditSeq != 0 || l.Size() != 0 {
+		return ErrAlreadySynced
+	}
+
+	// list the public key records before the audit records, so any added in between are audited
+	leaves := make([][]byte, 0, api.DefaultListPageSize)
+	var after []byte
+	for {
+		records, err := s.ListPublicKeyRecords(ctx, after, api.DefaultListPageSize)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			leaves = append(leaves, api.PublicKeyDetailLeafHash(r.PublicKeyDetail))
+		}
+		if len(records) < api.DefaultListPageSize {
+			break
+		}
+		after = records[len(records)-1].PublicKeyDetail.PublicKey
+	}
+	audited := make(map[string]struct{}, len(leaves))
+	var auditSeq uint64
+	for {
+		records, err := s.ListAuditRecords(ctx, auditSeq, api.DefaultListPageSize)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			for _, pkHash := range r.AddedPublicKeyHashes {
+				audited[string(api.LogLeafHash(r.EntityId, r.KeyType, pkHash))] = struct{}{}
+			}
+			auditSeq = r.Sequence
+		}
+		if len(records) < api.DefaultListPageSize {
+			break
+		}
+	}
+
+	l.mu.Lock()
+	for _, leaf := range leaves {
+		if _, in := audited[string(leaf)]; !in {
+			l.append(leaf)
+		}
+	}
+	size := l.size()
+	l.mu.Unlock()
+	l.logger.Info("backfilled transparency log", logBackfill(len(leaves), size)...)
+	return nil
+}
+
+// Sync appends the leaves of the public keys added by the audit records after the last one
+// synced.
+func (l *Log) Sync(ctx context.Context, al AuditLog) error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	for {
+		records, err := al.ListAuditRecords(ctx, l.auditSeq, api.DefaultListPageSize)
+		if err != nil {
+			return err
+		}
+		l.mu.Lock()
+		for _, r := range records {
+			for _, pkHash := range r.AddedPublicKeyHashes {
+				l.append(api.LogLeafHash(r.EntityId, r.KeyType, pkHash))
+			}
+			l.auditSeq = r.Sequence
+		}
+		size := l.size()
+		l.mu.Unlock()
+		if len(records) > 0 {
+			l.logger.Debug("synced transparency log", logSync(l.auditSeq, size)...)
+		}
+		if len(records) < api.DefaultListPageSize {
+			return nil
+		}
+	}
+}
+
+// Size returns the number of leaves in the log.
+func (l *Log) Size() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.size()
+}
+
+// RootHash returns the root hash of the tree of the first size leaves.
+func (l *Log) RootHash(size uint64) ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if size > l.size() {
+		return nil, ErrTreeSizeTooLarge
+	}
+	return l.subtreeHash(0, size), nil
+}
+
+// LeafIndex returns the index of the first leaf with the leaf hash and whether there is one.
+func (l *Log) LeafIndex(leafHash []byte) (uint64, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	index, in := l.indexes[string(leafHash)]
+	return index, in
+}
+
+// InclusionProof returns the hashes proving that the leaf at the index is in the tree of the
+// first size leaves.
+func (l *Log) InclusionProof(index, size uint64) ([][]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if size > l.size() {
+		return nil, ErrTreeSizeTooLarge
+	}
+	if index >= size {
+		return nil, ErrLeafIndexOutOfRange
+	}
+	return l.path(index, 0, size), nil
+}
+
+// ConsistencyProof returns the hashes proving that the tree of the first size leaves is a prefix
+// of the tree of the second size leaves.
+func (l *Log) ConsistencyProof(firstSize, secondSize uint64) ([][]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if firstSize == 0 || firstSize > secondSize {
+		return nil, api.ErrInvalidTreeSizes
+	}
+	if secondSize > l.size() {
+		return nil, ErrTreeSizeTooLarge
+	}
+	if firstSize == secondSize {
+		return [][]byte{}, nil
+	}
+	return l.subproof(firstSize, 0, secondSize, true), nil
+}
+
+func (l *Log) size() uint64 {
+	return uint64(len(l.levels[0]))
+}
+
+func (l *Log) append(leafHash []byte) {
+	if _, in := l.indexes[string(leafHash)]; !in {
+		l.indexes[string(leafHash)] = l.size()
+	}
+	h := leafHash
+	for i := 0; ; i++ {
+		if i == len(l.levels) {
+			l.levels = append(l.levels, nil)
+		}
+		l.levels[i] = append(l.levels[i], h)
+		n := len(l.levels[i])
+		if n%2 == 1 {
+			return
+		}
+		// the last two subtrees at this level complete one at the next level
+		h = api.LogNodeHash(l.levels[i][n-2], l.levels[i][n-1])
+	}
+}
+
+// subtreeHash returns the Merkle tree hash of the leaves from lo to hi. The left subtree of each
+// split is complete and aligned, so its hash is already known.
+func (l *Log) subtreeHash(lo, hi uint64) []byte {
+	n := hi - lo
+	if n == 0 {
+		return api.EmptyLogRootHash()
+	}
+	if n&(n-1) == 0 && lo%n == 0 {
+		return l.levels[bits.TrailingZeros64(n)][lo/n]
+	}
+	k := splitSize(n)
+	return api.LogNodeHash(l.subtreeHash(lo, lo+k), l.subtreeHash(lo+k, hi))
+}
+
+// path returns the audit path of the leaf at the index in the subtree of the leaves from lo to
+// hi, as PATH in RFC 6962.
+func (l *Log) path(index, lo, hi uint64) [][]byte {
+	if hi-lo <= 1 {
+		return nil
+	}
+	k := splitSize(hi - lo)
+	if index < lo+k {
+		return append(l.path(index, lo, lo+k), l.subtreeHash(lo+k, hi))
+	}
+	return append(l.path(index, lo+k, hi), l.subtreeHash(lo, lo+k))
+}
+
+// subproof returns the consistency proof between the first end leaves and the subtree of the
+// leaves from lo to hi, as SUBPROOF in RFC 6962, where complete is whether the subtree of the
+// leaves from lo to end is one whose hash the verifier already has.
+func (l *Log) subproof(end, lo, hi uint64, complete bool) [][]byte {
+	if end == hi {
+		if complete {
+			return nil
+		}
+		return [][]byte{l.subtreeHash(lo, hi)}
+	}
+	k := splitSize(hi - lo)
+	if end <= lo+k {
+		return append(l.subproof(end, lo, lo+k, complete), l.subtreeHash(lo+k, hi))
+	}
+	return append(l.subproof(end, lo+k, hi, false), l.subtreeHash(lo, lo+k))
+}
+
+// splitSize returns the largest power of two less than n, which must be at least 2.
+func splitSize(n uint64) uint64 {
+	return 1 << uint(bits.Len64(n-1)-1)
+}
@@ -0,0 +1,285 @@
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/service-base/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var errTest = errors.New("some test error")
+
+func TestLog_RootHash(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	leaves := newTestLeafHashes(rng, 40)
+	l := New(zap.NewNop())
+	for _, leaf := range leaves {
+		l.append(leaf)
+	}
+	assert.Equal(t, uint64(len(leaves)), l.Size())
+	for size := range leaves {
+		root, err := l.RootHash(uint64(size))
+		assert.Nil(t, err)
+		assert.Equal(t, naiveRootHash(leaves[:size]), root, "size %d", size)
+	}
+
+	root, err := l.RootHash(uint64(len(leaves) + 1))
+	assert.Equal(t, ErrTreeSizeTooLarge, err)
+	assert.Nil(t, root)
+}
+
+func TestLog_InclusionProof(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	leaves := newTestLeafHashes(rng, 20)
+	l := New(zap.NewNop())
+	for _, leaf := range leaves {
+		l.append(leaf)
+	}
+	for size := uint64(1); size <= uint64(len(leaves)); size++ {
+		root, err := l.RootHash(size)
+		assert.Nil(t, err)
+		for index := uint64(0); index < size; index++ {
+			proof, err := l.InclusionProof(index, size)
+			assert.Nil(t, err)
+			err = api.VerifyInclusionProof(leaves[index], index, size, proof, root)
+			assert.Nil(t, err, "index %d, size %d", index, size)
+		}
+	}
+
+	proof, err := l.InclusionProof(2, 2)
+	assert.Equal(t, ErrLeafIndexOutOfRange, err)
+	assert.Nil(t, proof)
+	proof, err = l.InclusionProof(0, uint64(len(leaves)+1))
+	assert.Equal(t, ErrTreeSizeTooLarge, err)
+	assert.Nil(t, proof)
+}
+
+func TestLog_ConsistencyProof(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	leaves := newTestLeafHashes(rng, 20)
+	l := New(zap.NewNop())
+	for _, leaf := range leaves {
+		l.append(leaf)
+	}
+	for second := uint64(1); second <= uint64(len(leaves)); second++ {
+		secondRoot, err := l.RootHash(second)
+		assert.Nil(t, err)
+		for first := uint64(1); first <= second; first++ {
+			firstRoot, err := l.RootHash(first)
+			assert.Nil(t, err)
+			proof, err := l.ConsistencyProof(first, second)
+			assert.Nil(t, err)
+			err = api.VerifyConsistencyProof(first, second, firstRoot, secondRoot, proof)
+			assert.Nil(t, err, "first %d, second %d", first, second)
+		}
+	}
+
+	cases := map[string]struct {
+		first, second uint64
+		expected      error
+	}{
+		"zero first size":          {first: 0, second: 2, expected: api.ErrInvalidTreeSizes},
+		"first larger than second": {first: 3, second: 2, expected: api.ErrInvalidTreeSizes},
+		"second too large": {
+			first:    2,
+			second:   uint64(len(leaves) + 1),
+			expected: ErrTreeSizeTooLarge,
+		},
+	}
+	for desc, c := range cases {
+		proof, err := l.ConsistencyProof(c.first, c.second)
+		assert.Equal(t, c.expected, err, desc)
+		assert.Nil(t, proof, desc)
+	}
+}
+
+func TestLog_LeafIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	leaves := newTestLeafHashes(rng, 3)
+	l := New(zap.NewNop())
+	for _, leaf := range append(leaves, leaves[1]) {
+		l.append(leaf)
+	}
+	for i, leaf := range leaves {
+		index, in := l.LeafIndex(leaf)
+		assert.True(t, in)
+		assert.Equal(t, uint64(i), index)
+	}
+	_, in := l.LeafIndex(util.RandBytes(rng, 32))
+	assert.False(t, in)
+}
+
+func TestLog_Sync_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	al := &fixedAuditLog{}
+	var prev *api.AuditRecord
+	var expected [][]byte
+	for i, r := range api.NewTestAuditRecords(rng, 2*api.DefaultListPageSize+1) {
+		if i%3 == 0 {
+			// revocations don't add leaves
+			r.Action = api.AuditAction_REVOKE
+			r.DisabledPublicKeyHashes = r.AddedPublicKeyHashes
+			r.AddedPublicKeyHashes = nil
+		}
+		for _, pkHash := range r.AddedPublicKeyHashes {
+			expected = append(expected, api.LogLeafHash(r.EntityId, r.KeyType, pkHash))
+		}
+		api.ChainAuditRecord(prev, r)
+		al.records = append(al.records, r)
+		prev = r
+	}
+
+	l := New(zap.NewNop())
+	n := len(al.records)
+	al.records = al.records[:n-1]
+	err := l.Sync(context.Background(), al)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(len(expected)-1), l.Size())
+
+	// only the audit records after the last synced are listed
+	al.records = append(al.records, prev)
+	al.nCalls = 0
+	err = l.Sync(context.Background(), al)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, al.nCalls)
+	assert.Equal(t, uint64(len(expected)), l.Size())
+	root, err := l.RootHash(l.Size())
+	assert.Nil(t, err)
+	assert.Equal(t, naiveRootHash(expected), root)
+}
+
+func TestLog_Sync_err(t *testing.T) {
+	l := New(zap.NewNop())
+	err := l.Sync(context.Background(), &fixedAuditLog{listErr: errTest})
+	assert.Equal(t, errTest, err)
+	assert.Zero(t, l.Size())
+}
+
+func TestLog_Backfill_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	pkds := api.NewTestPublicKeyDetails(rng, 2*api.DefaultListPageSize+1)
+	sort.Slice(pkds, func(i, j int) bool {
+		return bytes.Compare(pkds[i].PublicKey, pkds[j].PublicKey) < 0
+	})
+	st := &fixedStorer{}
+	var prev *api.AuditRecord
+	var unaudited, audited [][]byte
+	for i, pkd := range pkds {
+		st.pkRecords = append(st.pkRecords, &api.PublicKeyRecord{PublicKeyDetail: pkd})
+		if i%3 != 0 {
+			unaudited = append(unaudited, api.PublicKeyDetailLeafHash(pkd))
+			continue
+		}
+		r := &api.AuditRecord{
+			Action:               api.AuditAction_IMPORT,
+			EntityId:             pkd.EntityId,
+			KeyType:              pkd.KeyType,
+			AddedPublicKeyHashes: api.PublicKeyHashes([][]byte{pkd.PublicKey}),
+		}
+		api.ChainAuditRecord(prev, r)
+		st.records = append(st.records, r)
+		audited = append(audited, api.PublicKeyDetailLeafHash(pkd))
+		prev = r
+	}
+
+	// the unaudited public key details are backfilled ahead of the audited ones
+	l := New(zap.NewNop())
+	err := l.Backfill(context.Background(), st)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(len(unaudited)), l.Size())
+	err = l.Sync(context.Background(), st)
+	assert.Nil(t, err)
+	expected := append(unaudited, audited...)
+	root, err := l.RootHash(l.Size())
+	assert.Nil(t, err)
+	assert.Equal(t, naiveRootHash(expected), root)
+	index, in := l.LeafIndex(unaudited[0])
+	assert.True(t, in)
+	assert.Zero(t, index)
+
+	err = l.Backfill(context.Background(), st)
+	assert.Equal(t, ErrAlreadySynced, err)
+}
+
+func TestLog_Backfill_err(t *testing.T) {
+	cases := map[string]*fixedStorer{
+		"list public key records error": {listRecordsErr: errTest},
+		"list audit records error":      {fixedAuditLog: fixedAuditLog{listErr: errTest}},
+	}
+	for desc, st := range cases {
+		l := New(zap.NewNop())
+		err := l.Backfill(context.Background(), st)
+		assert.Equal(t, errTest, err, desc)
+		assert.Zero(t, l.Size(), desc)
+	}
+}
+
+func newTestLeafHashes(rng *rand.Rand, n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i, pkd := range api.NewTestPublicKeyDetails(rng, n) {
+		leaves[i] = api.PublicKeyDetailLeafHash(pkd)
+	}
+	return leaves
+}
+
+// naiveRootHash computes the Merkle tree hash as defined in RFC 6962.
+func naiveRootHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return api.EmptyLogRootHash()
+	case 1:
+		return leaves[0]
+	}
+	k := splitSize(uint64(len(leaves)))
+	return api.LogNodeHash(naiveRootHash(leaves[:k]), naiveRootHash(leaves[k:]))
+}
+
+type fixedAuditLog struct {
+	records []*api.AuditRecord
+	listErr error
+	nCalls  int
+}
+
+func (f *fixedAuditLog) ListAuditRecords(
+	ctx context.Context, afterSeq uint64, limit uint,
+) ([]*api.AuditRecord, error) {
+	f.nCalls++
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	page := make([]*api.AuditRecord, 0, limit)
+	for _, r := range f.records {
+		if r.Sequence > afterSeq && uint(len(page)) < limit {
+			page = append(page, r)
+		}
+	}
+	return page, nil
+}
+
+type fixedStorer struct {
+	fixedAuditLog
+	pkRecords      []*api.PublicKeyRecord
+	listRecordsErr error
+}
+
+func (f *fixedStorer) ListPublicKeyRecords(
+	ctx context.Context, after []byte, limit uint,
+) ([]*api.PublicKeyRecord, error) {
+	if f.listRecordsErr != nil {
+		return nil, f.listRecordsErr
+	}
+	page := make([]*api.PublicKeyRecord, 0, limit)
+	for _, r := range f.pkRecords {
+		if bytes.Compare(r.PublicKeyDetail.PublicKey, after) > 0 && uint(len(page)) < limit {
+			page = append(page, r)
+		}
+	}
+	return page, nil
+}
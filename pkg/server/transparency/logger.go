@@ -0,0 +1,26 @@
+package transparency
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	logAuditSequence = "audit_sequence"
+	logTreeSize      = "tree_size"
+	logNRecords      = "n_public_key_records"
+)
+
+func logBackfill(nRecords int, size uint64) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNRecords, nRecords),
+		zap.Uint64(logTreeSize, size),
+	}
+}
+
+func logSync(auditSeq, size uint64) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Uint64(logAuditSequence, auditSeq),
+		zap.Uint64(logTreeSize, size),
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"math/rand"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage"
+	"github.com/elixirhealth/key/pkg/server/transparency"
+	bserver "github.com/elixirhealth/service-base/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKey_GetTreeHead_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	k := newTestTransparencyKey()
+
+	// empty log
+	rp, err := k.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Nil(t, err)
+	assert.Zero(t, rp.TreeHead.TreeSize)
+	assert.Equal(t, api.EmptyLogRootHash(), rp.TreeHead.RootHash)
+	assert.Empty(t, rp.TreeHead.Signature)
+
+	addTestPublicKeys(t, k, rng, 3)
+	k.config.WithSigningKey(sk)
+	rp, err = k.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), rp.TreeHead.TreeSize)
+	assert.NotEqual(t, api.EmptyLogRootHash(), rp.TreeHead.RootHash)
+	assert.NotZero(t, rp.TreeHead.Time)
+	assert.Nil(t, api.VerifyTreeHead(pk, rp.TreeHead))
+}
+
+func TestKey_GetTreeHead_err(t *testing.T) {
+	k := newTestTransparencyKey()
+	k.storer = &fixedStorer{listAuditErr: errTest}
+	rp, err := k.GetTreeHead(context.Background(), &api.GetTreeHeadRequest{})
+	assert.Equal(t, ErrInternal, err)
+	assert.Nil(t, rp)
+}
+
+func TestKey_GetConsistencyProof_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	k := newTestTransparencyKey()
+	addTestPublicKeys(t, k, rng, 3)
+	rp1, err := k.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Nil(t, err)
+	addTestPublicKeys(t, k, rng, 2)
+	rp2, err := k.GetTreeHead(ctx, &api.GetTreeHeadRequest{})
+	assert.Nil(t, err)
+
+	rp, err := k.GetConsistencyProof(ctx, &api.GetConsistencyProofRequest{
+		FirstTreeSize:  rp1.TreeHead.TreeSize,
+		SecondTreeSize: rp2.TreeHead.TreeSize,
+	})
+	assert.Nil(t, err)
+	err = api.VerifyConsistencyProof(rp1.TreeHead.TreeSize, rp2.TreeHead.TreeSize,
+		rp1.TreeHead.RootHash, rp2.TreeHead.RootHash, rp.Hashes)
+	assert.Nil(t, err)
+}
+
+func TestKey_GetConsistencyProof_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	k := newTestTransparencyKey()
+	addTestPublicKeys(t, k, rng, 3)
+	cases := map[string]struct {
+		k        *Key
+		rq       *api.GetConsistencyProofRequest
+		expected codes.Code
+	}{
+		"bad request": {
+			k:        k,
+			rq:       &api.GetConsistencyProofRequest{FirstTreeSize: 2, SecondTreeSize: 1},
+			expected: codes.InvalidArgument,
+		},
+		"tree size too large": {
+			k:        k,
+			rq:       &api.GetConsistencyProofRequest{FirstTreeSize: 1, SecondTreeSize: 4},
+			expected: codes.OutOfRange,
+		},
+		"sync error": {
+			k: &Key{
+				BaseServer:      bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+				storer:          &fixedStorer{listAuditErr: errTest},
+				transparencyLog: transparency.New(zap.NewNop()),
+			},
+			rq:       &api.GetConsistencyProofRequest{FirstTreeSize: 1, SecondTreeSize: 2},
+			expected: codes.Internal,
+		},
+	}
+	for desc, c := range cases {
+		rp, err := c.k.GetConsistencyProof(context.Background(), c.rq)
+		assert.Equal(t, c.expected, status.Code(err), desc)
+		assert.Nil(t, rp, desc)
+	}
+}
+
+func TestKey_GetPublicKeyDetails_inclusionProofs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	pk, sk, err := ed25519.GenerateKey(rng)
+	assert.Nil(t, err)
+	k := newTestTransparencyKey()
+	k.config.WithSigningKey(sk)
+	pkds := addTestPublicKeys(t, k, rng, 5)
+	st := k.storer.(*fixedStorer)
+
+	st.getPKDs = []*api.PublicKeyDetail{pkds[3], pkds[0]}
+	rq := &api.GetPublicKeyDetailsRequest{
+		PublicKeys:      [][]byte{pkds[3].PublicKey, pkds[0].PublicKey},
+		InclusionProofs: true,
+	}
+	rp, err := k.GetPublicKeyDetails(ctx, rq)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), rp.TreeHead.TreeSize)
+	assert.Nil(t, api.VerifyTreeHead(pk, rp.TreeHead))
+	assert.Nil(t, api.VerifyPublicKeyDetailsInclusion(rp))
+	assert.Equal(t, uint64(3), rp.InclusionProofs[0].LeafIndex)
+	assert.Equal(t, uint64(0), rp.InclusionProofs[1].LeafIndex)
+
+	// partial, with a missing and an unlogged public key
+	unlogged := api.NewTestPublicKeyDetail(rng)
+	st.getPKDs = []*api.PublicKeyDetail{pkds[1], nil, unlogged}
+	rq = &api.GetPublicKeyDetailsRequest{
+		PublicKeys:      [][]byte{pkds[1].PublicKey, api.NewTestPublicKey(rng), unlogged.PublicKey},
+		Partial:         true,
+		InclusionProofs: true,
+	}
+	rp, err = k.GetPublicKeyDetails(ctx, rq)
+	assert.Nil(t, err)
+	assert.Len(t, rp.InclusionProofs, 3)
+	assert.True(t, rp.InclusionProofs[0].Found)
+	assert.False(t, rp.InclusionProofs[1].Found)
+	assert.False(t, rp.InclusionProofs[2].Found)
+	err = api.VerifyInclusionProof(api.PublicKeyDetailLeafHash(pkds[1]), 1, 5,
+		rp.InclusionProofs[0].Hashes, rp.TreeHead.RootHash)
+	assert.Nil(t, err)
+	assert.Nil(t, api.VerifyGetPublicKeyDetailsResponse(pk, rq, rp))
+
+	// sync error
+	st.listAuditErr = errTest
+	rp, err = k.GetPublicKeyDetails(ctx, rq)
+	assert.Equal(t, ErrInternal, err)
+	assert.Nil(t, rp)
+}
+
+func TestKey_GetPublicKeyDetails_migratedInclusionProofs(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	ctx := context.Background()
+	k := newTestTransparencyKey()
+	st := k.storer.(*fixedStorer)
+
+	// one public key was migrated with an import audit record, and the other before migrations
+	// were audited, so it's only in the stored public key records
+	migrated := api.NewTestPublicKeyDetails(rng, 2)
+	now := time.Now()
+	for _, pkd := range migrated {
+		st.listRecords = append(st.listRecords, storage.NewPublicKeyRecord(pkd, now, time.Time{}))
+	}
+	audit := storage.NewAuditRecords(api.AuditAction_IMPORT, "", now, migrated[:1], nil)
+	err := st.AppendAuditRecords(ctx, audit)
+	assert.Nil(t, err)
+	err = k.transparencyLog.Backfill(ctx, k.storer)
+	assert.Nil(t, err)
+	pkds := addTestPublicKeys(t, k, rng, 2)
+
+	st.getPKDs = []*api.PublicKeyDetail{migrated[0], migrated[1], pkds[1]}
+	rq := &api.GetPublicKeyDetailsRequest{
+		PublicKeys:      [][]byte{migrated[0].PublicKey, migrated[1].PublicKey, pkds[1].PublicKey},
+		InclusionProofs: true,
+	}
+	rp, err := k.GetPublicKeyDetails(ctx, rq)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), rp.TreeHead.TreeSize)
+	assert.Nil(t, api.VerifyPublicKeyDetailsInclusion(rp))
+	for _, proof := range rp.InclusionProofs {
+		assert.True(t, proof.Found)
+	}
+
+	// the unaudited public key is backfilled first, ahead of the audited ones
+	assert.Equal(t, uint64(1), rp.InclusionProofs[0].LeafIndex)
+	assert.Equal(t, uint64(0), rp.InclusionProofs[1].LeafIndex)
+	assert.Equal(t, uint64(3), rp.InclusionProofs[2].LeafIndex)
+}
+
+func newTestTransparencyKey() *Key {
+	return &Key{
+		BaseServer:      bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:          NewDefaultConfig(),
+		storer:          &fixedStorer{},
+		transparencyLog: transparency.New(zap.NewNop()),
+	}
+}
+
+// addTestPublicKeys adds n public keys of a random entity, one request at a time.
+func addTestPublicKeys(t *testing.T, k *Key, rng *rand.Rand, n int) []*api.PublicKeyDetail {
+	pkds := api.NewTestPublicKeyDetails(rng, n)
+	for _, pkd := range pkds {
+		rq := &api.AddPublicKeysRequest{
+			EntityId:   pkd.EntityId,
+			KeyType:    pkd.KeyType,
+			PublicKeys: [][]byte{pkd.PublicKey},
+			KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+		}
+		_, err := k.AddPublicKeys(context.Background(), rq)
+		assert.Nil(t, err)
+	}
+	return pkds
+}
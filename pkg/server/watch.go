@@ -0,0 +1,101 @@
+package server
+
+import (
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/watch"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxWatchBatchSize is the maximum number of public key events in a WatchPublicKeys response.
+const maxWatchBatchSize = api.DefaultListPageSize
+
+var (
+	// ErrWatchSharedStorage indicates when public keys are watched on an instance whose storage
+	// is shared with other instances, whose events it wouldn't see. Their events are published
+	// from the Postgres outbox instead.
+	ErrWatchSharedStorage = status.Error(codes.Unimplemented,
+		"watching public keys is not implemented for shared storage")
+)
+
+// WatchPublicKeys streams events for the public keys of the requested entities, or of every
+// entity, as they are added and disabled through this instance. A watch resuming after a
+// sequence number first gets the retained events after it. If they're no longer retained, it
+// fails with OutOfRange, and the caller should reload any public keys it caches and watch again
+// from zero. A watch that falls too far behind fails with ResourceExhausted and can resume after
+// the last sequence number it got. Instances with shared storage fail with Unimplemented.
+func (k *Key) WatchPublicKeys(
+	rq *api.WatchPublicKeysRequest, stream api.Key_WatchPublicKeysServer,
+) error {
+	ctx := stream.Context()
+	k.Logger.Debug("received watch public keys request", logWatchPublicKeysRq(rq)...)
+	if k.watcher == nil {
+		k.Logger.Info("watch public keys unimplemented for shared storage")
+		return ErrWatchSharedStorage
+	}
+	if err := api.ValidateWatchPublicKeysRequest(rq); err != nil {
+		k.Logger.Info("watch public keys request invalid", zap.String(logErr, err.Error()))
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	replayed, sub, err := k.watcher.Subscribe(rq.EntityIds, rq.AfterSequence)
+	switch err {
+	case nil:
+	case watch.ErrSequenceUnavailable:
+		return status.Error(codes.OutOfRange, err.Error())
+	default:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer sub.Close()
+	for len(replayed) > 0 {
+		n := len(replayed)
+		if n > maxWatchBatchSize {
+			n = maxWatchBatchSize
+		}
+		if err := stream.Send(&api.WatchPublicKeysResponse{Events: replayed[:n]}); err != nil {
+			return err
+		}
+		replayed = replayed[n:]
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			k.Logger.Debug("watch public keys ended", logWatchPublicKeysRq(rq)...)
+			return ctx.Err()
+		case e, open := <-sub.Events():
+			if !open {
+				return watchError(sub.Err())
+			}
+			events := receiveBuffered(sub, []*api.PublicKeyEvent{e})
+			if err := stream.Send(&api.WatchPublicKeysResponse{Events: events}); err != nil {
+				return err
+			}
+			k.Logger.Debug("sent public key events", zap.Int(logNEvents, len(events)))
+		}
+	}
+}
+
+// receiveBuffered appends the events already buffered for the subscription, up to the maximum
+// batch size, to the given events.
+func receiveBuffered(sub *watch.Subscription, events []*api.PublicKeyEvent) []*api.PublicKeyEvent {
+	for len(events) < maxWatchBatchSize {
+		select {
+		case e, open := <-sub.Events():
+			if !open {
+				// the closed events channel is handled on the next receive
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+	return events
+}
+
+func watchError(err error) error {
+	if err == watch.ErrSubscriptionLagging {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Unavailable, watch.ErrClosed.Error())
+}
@@ -0,0 +1,235 @@
+package watch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// DefaultRetainedEvents is the default number of most recent events retained for resuming
+	// subscriptions.
+	DefaultRetainedEvents = 8192
+
+	// DefaultSubscriptionBufferSize is the default number of events buffered for each
+	// subscription before it is dropped for falling behind.
+	DefaultSubscriptionBufferSize = 1024
+)
+
+var (
+	// ErrSequenceUnavailable indicates when a subscription can't resume after a sequence number
+	// because some of the events after it are no longer retained or the sequence number was
+	// never published, e.g., because it is from before a restart.
+	ErrSequenceUnavailable = errors.New("events after sequence number no longer available")
+
+	// ErrSubscriptionLagging indicates when a subscription was dropped because it fell too far
+	// behind the published events.
+	ErrSubscriptionLagging = errors.New("subscription fell too far behind published events")
+
+	// ErrClosed indicates when the broadcaster has been closed.
+	ErrClosed = errors.New("broadcaster closed")
+)
+
+// Parameters defines how many events a Broadcaster retains and buffers.
+type Parameters struct {
+	RetainedEvents         uint
+	SubscriptionBufferSize uint
+}
+
+// NewDefaultParameters returns a *Parameters object with default values.
+func NewDefaultParameters() *Parameters {
+	return &Parameters{
+		RetainedEvents:         DefaultRetainedEvents,
+		SubscriptionBufferSize: DefaultSubscriptionBufferSize,
+	}
+}
+
+// MarshalLogObject writes the parameters to the given object encoder.
+func (p *Parameters) MarshalLogObject(oe zapcore.ObjectEncoder) error {
+	oe.AddUint(logRetainedEvents, p.RetainedEvents)
+	oe.AddUint(logSubscriptionBufferSize, p.SubscriptionBufferSize)
+	return nil
+}
+
+// Broadcaster assigns sequence numbers to published public key events and sends them to the
+// subscriptions watching their entities. It retains the most recent events so subscriptions can
+// resume after the last sequence number they saw.
+//
+// Sequence numbers start from the broadcaster's creation time in epoch micros rather than from
+// zero, so those published before a restart are lower than any published after it and resuming
+// from one fails rather than silently skipping events.
+type Broadcaster struct {
+	params *Parameters
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	retained []*api.PublicKeyEvent
+	nextSeq  uint64
+	nRetain  uint64
+	subs     map[*Subscription]struct{}
+	closed   bool
+}
+
+// New creates a new Broadcaster.
+func New(params *Parameters, logger *zap.Logger) *Broadcaster {
+	return &Broadcaster{
+		params:   params,
+		logger:   logger,
+		now:      time.Now,
+		retained: make([]*api.PublicKeyEvent, params.RetainedEvents),
+		nextSeq:  uint64(time.Now().UnixNano() / 1e3),
+		subs:     make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the next sequence numbers to events of the given type for each of the public
+// key details and sends them to the subscriptions watching their entities. Subscriptions too far
+// behind to take them are dropped with ErrSubscriptionLagging.
+func (b *Broadcaster) Publish(typ api.PublicKeyEventType, pkds []*api.PublicKeyDetail) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	eventTime := b.now().UnixNano() / 1e3
+	for _, pkd := range pkds {
+		e := &api.PublicKeyEvent{
+			Sequence:        b.nextSeq,
+			Type:            typ,
+			PublicKeyDetail: pkd,
+			Time:            eventTime,
+		}
+		b.nextSeq++
+		b.retain(e)
+		for s := range b.subs {
+			if !s.matches(e) {
+				continue
+			}
+			select {
+			case s.events <- e:
+			default:
+				b.logger.Info("dropping lagging subscription", logSubscription(s)...)
+				b.remove(s, ErrSubscriptionLagging)
+			}
+		}
+	}
+	b.logger.Debug("published public key events", logPublish(typ, len(pkds), b.nextSeq)...)
+}
+
+// Subscribe returns a subscription to the events for the given entities, or every entity if
+// there are none. If afterSeq is non-zero, it also returns the retained events for those entities
+// after that sequence number, which precede those the subscription receives. It returns
+// ErrSequenceUnavailable if any events after afterSeq are no longer retained.
+func (b *Broadcaster) Subscribe(
+	entityIDs []string, afterSeq uint64,
+) ([]*api.PublicKeyEvent, *Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+	s := &Subscription{
+		b:      b,
+		events: make(chan *api.PublicKeyEvent, b.params.SubscriptionBufferSize),
+	}
+	if len(entityIDs) > 0 {
+		s.entityIDs = make(map[string]struct{}, len(entityIDs))
+		for _, entityID := range entityIDs {
+			s.entityIDs[entityID] = struct{}{}
+		}
+	}
+	var replayed []*api.PublicKeyEvent
+	if afterSeq != 0 {
+		if afterSeq+1 < b.nextSeq-b.nRetain || afterSeq >= b.nextSeq {
+			return nil, nil, ErrSequenceUnavailable
+		}
+		for seq := afterSeq + 1; seq < b.nextSeq; seq++ {
+			if e := b.retained[seq%uint64(len(b.retained))]; s.matches(e) {
+				replayed = append(replayed, e)
+			}
+		}
+	}
+	b.subs[s] = struct{}{}
+	b.logger.Debug("added subscription", logSubscribe(s, afterSeq, len(replayed))...)
+	return replayed, s, nil
+}
+
+// NSubscriptions returns the number of active subscriptions.
+func (b *Broadcaster) NSubscriptions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close drops every subscription with ErrClosed and stops publishing events.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.remove(s, ErrClosed)
+	}
+}
+
+// retain stores the event in place of the oldest retained one. The caller must hold the lock.
+func (b *Broadcaster) retain(e *api.PublicKeyEvent) {
+	if len(b.retained) == 0 {
+		return
+	}
+	b.retained[e.Sequence%uint64(len(b.retained))] = e
+	if b.nRetain < uint64(len(b.retained)) {
+		b.nRetain++
+	}
+}
+
+// remove closes the subscription's events with the given error. The caller must hold the lock.
+func (b *Broadcaster) remove(s *Subscription, err error) {
+	if _, in := b.subs[s]; !in {
+		return
+	}
+	delete(b.subs, s)
+	s.err = err
+	close(s.events)
+}
+
+// Subscription receives the published events for the entities it watches.
+type Subscription struct {
+	b         *Broadcaster
+	entityIDs map[string]struct{}
+	events    chan *api.PublicKeyEvent
+	err       error
+}
+
+// Events returns the channel the subscription's events are sent on, which is closed when the
+// subscription is dropped or closed.
+func (s *Subscription) Events() <-chan *api.PublicKeyEvent {
+	return s.events
+}
+
+// Err returns why the subscription was dropped once its events channel has been closed, or nil if
+// it was closed by Close.
+func (s *Subscription) Err() error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	return s.err
+}
+
+// Close stops the subscription from receiving events.
+func (s *Subscription) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	s.b.remove(s, nil)
+}
+
+func (s *Subscription) matches(e *api.PublicKeyEvent) bool {
+	if s.entityIDs == nil {
+		return true
+	}
+	_, in := s.entityIDs[e.PublicKeyDetail.EntityId]
+	return in
+}
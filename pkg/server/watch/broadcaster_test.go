@@ -0,0 +1,159 @@
+package watch
+
+import (
+	"math/rand"
+	"testing"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestBroadcaster_PublishSubscribe_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	b := New(NewDefaultParameters(), zap.NewNop())
+	pkds := api.NewTestPublicKeyDetails(rng, 8)
+	entityID := pkds[0].EntityId
+
+	_, all, err := b.Subscribe(nil, 0)
+	assert.Nil(t, err)
+	_, one, err := b.Subscribe([]string{entityID}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, b.NSubscriptions())
+
+	b.Publish(api.PublicKeyEventType_ADDED, pkds)
+	b.Publish(api.PublicKeyEventType_DISABLED, pkds[:1])
+
+	allEvents := receive(all, len(pkds)+1)
+	for i, e := range allEvents[:len(pkds)] {
+		assert.Equal(t, api.PublicKeyEventType_ADDED, e.Type)
+		assert.Equal(t, pkds[i], e.PublicKeyDetail)
+		assert.NotZero(t, e.Time)
+		if i > 0 {
+			assert.Equal(t, allEvents[i-1].Sequence+1, e.Sequence)
+		}
+	}
+	assert.Equal(t, api.PublicKeyEventType_DISABLED, allEvents[len(pkds)].Type)
+
+	nEntity := 0
+	for _, pkd := range pkds {
+		if pkd.EntityId == entityID {
+			nEntity++
+		}
+	}
+	oneEvents := receive(one, nEntity+1)
+	for _, e := range oneEvents {
+		assert.Equal(t, entityID, e.PublicKeyDetail.EntityId)
+	}
+	assert.Len(t, one.Events(), 0)
+
+	one.Close()
+	_, open := <-one.Events()
+	assert.False(t, open)
+	assert.Nil(t, one.Err())
+	assert.Equal(t, 1, b.NSubscriptions())
+}
+
+func TestBroadcaster_Subscribe_resume(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.RetainedEvents = 4
+	b := New(params, zap.NewNop())
+	pkds := api.NewTestPublicKeyDetails(rng, 6)
+
+	_, s, err := b.Subscribe(nil, 0)
+	assert.Nil(t, err)
+	b.Publish(api.PublicKeyEventType_ADDED, pkds)
+	events := receive(s, len(pkds))
+
+	// resume after the third event replays the retained ones after it
+	replayed, _, err := b.Subscribe(nil, events[2].Sequence)
+	assert.Nil(t, err)
+	assert.Equal(t, events[3:], replayed)
+
+	// resume after the last event replays nothing
+	replayed, _, err = b.Subscribe(nil, events[5].Sequence)
+	assert.Nil(t, err)
+	assert.Empty(t, replayed)
+
+	// resume filters replayed events by entity
+	replayed, _, err = b.Subscribe([]string{pkds[4].EntityId}, events[1].Sequence)
+	assert.Nil(t, err)
+	for _, e := range replayed {
+		assert.Equal(t, pkds[4].EntityId, e.PublicKeyDetail.EntityId)
+	}
+	assert.Contains(t, replayed, events[4])
+
+	// events after the first are no longer retained
+	replayed, s, err = b.Subscribe(nil, events[0].Sequence)
+	assert.Equal(t, ErrSequenceUnavailable, err)
+	assert.Nil(t, replayed)
+	assert.Nil(t, s)
+
+	// sequence never published, e.g., from after a restart
+	_, _, err = b.Subscribe(nil, events[5].Sequence+1)
+	assert.Equal(t, ErrSequenceUnavailable, err)
+}
+
+func TestBroadcaster_Subscribe_restarted(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	b1 := New(NewDefaultParameters(), zap.NewNop())
+	_, s, err := b1.Subscribe(nil, 0)
+	assert.Nil(t, err)
+	b1.Publish(api.PublicKeyEventType_ADDED, api.NewTestPublicKeyDetails(rng, 2))
+	events := receive(s, 2)
+	b1.Close()
+
+	b2 := New(NewDefaultParameters(), zap.NewNop())
+	b2.Publish(api.PublicKeyEventType_ADDED, api.NewTestPublicKeyDetails(rng, 2))
+	_, _, err = b2.Subscribe(nil, events[0].Sequence)
+	assert.Equal(t, ErrSequenceUnavailable, err)
+}
+
+func TestBroadcaster_Publish_lagging(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	params := NewDefaultParameters()
+	params.SubscriptionBufferSize = 2
+	b := New(params, zap.NewNop())
+
+	_, s, err := b.Subscribe(nil, 0)
+	assert.Nil(t, err)
+	b.Publish(api.PublicKeyEventType_ADDED, api.NewTestPublicKeyDetails(rng, 3))
+
+	receive(s, 2)
+	_, open := <-s.Events()
+	assert.False(t, open)
+	assert.Equal(t, ErrSubscriptionLagging, s.Err())
+
+	// closing a dropped subscription is fine
+	s.Close()
+	assert.Equal(t, ErrSubscriptionLagging, s.Err())
+}
+
+func TestBroadcaster_Close(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	b := New(NewDefaultParameters(), zap.NewNop())
+	_, s, err := b.Subscribe(nil, 0)
+	assert.Nil(t, err)
+
+	b.Close()
+	_, open := <-s.Events()
+	assert.False(t, open)
+	assert.Equal(t, ErrClosed, s.Err())
+
+	// publishing after close doesn't panic
+	b.Publish(api.PublicKeyEventType_ADDED, api.NewTestPublicKeyDetails(rng, 1))
+
+	replayed, s, err := b.Subscribe(nil, 0)
+	assert.Equal(t, ErrClosed, err)
+	assert.Nil(t, replayed)
+	assert.Nil(t, s)
+}
+
+func receive(s *Subscription, n int) []*api.PublicKeyEvent {
+	events := make([]*api.PublicKeyEvent, n)
+	for i := range events {
+		events[i] = <-s.Events()
+	}
+	return events
+}
@@ -0,0 +1,42 @@
+package watch
+
+import (
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	logRetainedEvents         = "retained_events"
+	logSubscriptionBufferSize = "subscription_buffer_size"
+	logEventType              = "event_type"
+	logNEvents                = "n_events"
+	logNextSequence           = "next_sequence"
+	logAfterSequence          = "after_sequence"
+	logNReplayed              = "n_replayed"
+	logNEntityIDs             = "n_entity_ids"
+	logNBuffered              = "n_buffered"
+)
+
+func logPublish(typ api.PublicKeyEventType, nEvents int, nextSeq uint64) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Stringer(logEventType, typ),
+		zap.Int(logNEvents, nEvents),
+		zap.Uint64(logNextSequence, nextSeq),
+	}
+}
+
+func logSubscribe(s *Subscription, afterSeq uint64, nReplayed int) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNEntityIDs, len(s.entityIDs)),
+		zap.Uint64(logAfterSequence, afterSeq),
+		zap.Int(logNReplayed, nReplayed),
+	}
+}
+
+func logSubscription(s *Subscription) []zapcore.Field {
+	return []zapcore.Field{
+		zap.Int(logNEntityIDs, len(s.entityIDs)),
+		zap.Int(logNBuffered, len(s.events)),
+	}
+}
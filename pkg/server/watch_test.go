@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	api "github.com/elixirhealth/key/pkg/keyapi"
+	"github.com/elixirhealth/key/pkg/server/storage/memory"
+	"github.com/elixirhealth/key/pkg/server/watch"
+	bserver "github.com/elixirhealth/service-base/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKey_WatchPublicKeys_ok(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	k := newTestWatchKey()
+	entityID, kt := "some entity ID", api.KeyType_READER
+	pks := [][]byte{api.NewTestPublicKey(rng), api.NewTestPublicKey(rng)}
+	newPK := api.NewTestPublicKey(rng)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newFixedWatchStream(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- k.WatchPublicKeys(&api.WatchPublicKeysRequest{
+			EntityIds: []string{entityID},
+		}, stream)
+	}()
+	waitForSubscription(k)
+
+	// other entity's public keys aren't watched
+	_, err := k.AddPublicKeys(ctx, &api.AddPublicKeysRequest{
+		EntityId:   "other entity ID",
+		KeyType:    kt,
+		PublicKeys: [][]byte{api.NewTestPublicKey(rng)},
+		KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+	})
+	assert.Nil(t, err)
+	_, err = k.AddPublicKeys(ctx, &api.AddPublicKeysRequest{
+		EntityId:   entityID,
+		KeyType:    kt,
+		PublicKeys: pks,
+		KeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+	})
+	assert.Nil(t, err)
+	_, err = k.RevokePublicKeys(ctx, &api.RevokePublicKeysRequest{
+		EntityId:   entityID,
+		PublicKeys: pks[:1],
+	})
+	assert.Nil(t, err)
+	_, err = k.RotatePublicKeys(ctx, &api.RotatePublicKeysRequest{
+		EntityId:      entityID,
+		KeyType:       kt,
+		OldPublicKeys: pks[1:],
+		NewPublicKeys: [][]byte{newPK},
+		NewKeyFormat:  api.KeyFormat_SECP256K1_COMPRESSED,
+	})
+	assert.Nil(t, err)
+
+	events := stream.receive(5)
+	expected := []struct {
+		typ api.PublicKeyEventType
+		pk  []byte
+	}{
+		{api.PublicKeyEventType_ADDED, pks[0]},
+		{api.PublicKeyEventType_ADDED, pks[1]},
+		{api.PublicKeyEventType_DISABLED, pks[0]},
+		{api.PublicKeyEventType_DISABLED, pks[1]},
+		{api.PublicKeyEventType_ADDED, newPK},
+	}
+	for i, e := range events {
+		assert.Equal(t, expected[i].typ, e.Type)
+		assert.Equal(t, expected[i].pk, e.PublicKeyDetail.PublicKey)
+		assert.Equal(t, entityID, e.PublicKeyDetail.EntityId)
+		assert.Equal(t, kt, e.PublicKeyDetail.KeyType)
+		assert.Equal(t, e.Type == api.PublicKeyEventType_DISABLED, e.PublicKeyDetail.Disabled)
+	}
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	// resume after the second event
+	stream = newFixedWatchStream(context.Background())
+	go func() {
+		done <- k.WatchPublicKeys(&api.WatchPublicKeysRequest{
+			AfterSequence: events[1].Sequence,
+		}, stream)
+	}()
+	assert.Equal(t, events[2:], stream.receive(3))
+
+	// closing the watcher ends the stream
+	k.watcher.Close()
+	assert.Equal(t, status.Error(codes.Unavailable, watch.ErrClosed.Error()), <-done)
+}
+
+func TestKey_WatchPublicKeys_lagging(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	k := newTestWatchKey()
+	k.config.Watch.SubscriptionBufferSize = 1
+	k.watcher = watch.New(k.config.Watch, zap.NewNop())
+
+	// stream isn't received from, so it falls behind
+	stream := newFixedWatchStream(context.Background())
+	stream.sent = make(chan *api.WatchPublicKeysResponse)
+	done := make(chan error, 1)
+	go func() {
+		done <- k.WatchPublicKeys(&api.WatchPublicKeysRequest{}, stream)
+	}()
+	waitForSubscription(k)
+	for i := 0; i < 3; i++ {
+		k.publish(api.PublicKeyEventType_ADDED, api.NewTestPublicKeyDetails(rng, 1))
+	}
+	for {
+		select {
+		case <-stream.sent:
+		case err := <-done:
+			assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+			return
+		}
+	}
+}
+
+func TestKey_WatchPublicKeys_err(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	closed := watch.New(watch.NewDefaultParameters(), zap.NewNop())
+	closed.Close()
+	cases := map[string]struct {
+		rq       *api.WatchPublicKeysRequest
+		watcher  *watch.Broadcaster
+		stream   *fixedWatchStream
+		expected error
+	}{
+		"bad request": {
+			rq:      &api.WatchPublicKeysRequest{EntityIds: []string{""}},
+			watcher: watch.New(watch.NewDefaultParameters(), zap.NewNop()),
+			stream:  newFixedWatchStream(context.Background()),
+			expected: status.Error(codes.InvalidArgument,
+				api.ErrEmptyEntityID.Error()),
+		},
+		"sequence unavailable": {
+			rq:      &api.WatchPublicKeysRequest{AfterSequence: 1},
+			watcher: watch.New(watch.NewDefaultParameters(), zap.NewNop()),
+			stream:  newFixedWatchStream(context.Background()),
+			expected: status.Error(codes.OutOfRange,
+				watch.ErrSequenceUnavailable.Error()),
+		},
+		"shared storage": {
+			rq:       &api.WatchPublicKeysRequest{},
+			stream:   newFixedWatchStream(context.Background()),
+			expected: ErrWatchSharedStorage,
+		},
+		"watcher closed": {
+			rq:       &api.WatchPublicKeysRequest{},
+			watcher:  closed,
+			stream:   newFixedWatchStream(context.Background()),
+			expected: status.Error(codes.Unavailable, watch.ErrClosed.Error()),
+		},
+	}
+	for desc, c := range cases {
+		k := &Key{
+			BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+			config:     NewDefaultConfig(),
+			watcher:    c.watcher,
+		}
+		err := k.WatchPublicKeys(c.rq, c.stream)
+		assert.Equal(t, c.expected, err, desc)
+	}
+
+	// send error
+	k := newTestWatchKey()
+	stream := newFixedWatchStream(context.Background())
+	stream.sendErr = errTest
+	done := make(chan error, 1)
+	go func() {
+		done <- k.WatchPublicKeys(&api.WatchPublicKeysRequest{}, stream)
+	}()
+	waitForSubscription(k)
+	k.publish(api.PublicKeyEventType_ADDED, api.NewTestPublicKeyDetails(rng, 1))
+	assert.Equal(t, errTest, <-done)
+}
+
+func newTestWatchKey() *Key {
+	config := NewDefaultConfig()
+	return &Key{
+		BaseServer: bserver.NewBaseServer(bserver.NewDefaultBaseConfig()),
+		config:     config,
+		storer:     memory.New(config.Storage, zap.NewNop()),
+		watcher:    watch.New(config.Watch, zap.NewNop()),
+	}
+}
+
+// waitForSubscription waits until a WatchPublicKeys stream started in another goroutine has
+// subscribed, so it gets the events published after.
+func waitForSubscription(k *Key) {
+	for k.watcher.NSubscriptions() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type fixedWatchStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	sendErr error
+	sent    chan *api.WatchPublicKeysResponse
+}
+
+func newFixedWatchStream(ctx context.Context) *fixedWatchStream {
+	return &fixedWatchStream{
+		ctx:  ctx,
+		sent: make(chan *api.WatchPublicKeysResponse, 16),
+	}
+}
+
+func (f *fixedWatchStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fixedWatchStream) Send(rp *api.WatchPublicKeysResponse) error {
+	if f.sendErr != nil {
+		return f.sendErr
+	}
+	f.sent <- rp
+	return nil
+}
+
+// receive returns the next n events sent on the stream.
+func (f *fixedWatchStream) receive(n int) []*api.PublicKeyEvent {
+	events := make([]*api.PublicKeyEvent, 0, n)
+	for len(events) < n {
+		rp := <-f.sent
+		events = append(events, rp.Events...)
+	}
+	return events
+}